@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/dmitriysta/messenger/message/internal/api"
+	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/message/internal/pkg/breaker"
 	"github.com/dmitriysta/messenger/message/internal/pkg/cache"
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/message/internal/pkg/consumer"
+	"github.com/dmitriysta/messenger/message/internal/pkg/metrics"
 	"github.com/dmitriysta/messenger/message/internal/pkg/tracer"
+	"github.com/dmitriysta/messenger/message/internal/pkg/users"
 	"github.com/dmitriysta/messenger/message/internal/repository"
 	"github.com/dmitriysta/messenger/message/internal/service"
+	"github.com/dmitriysta/messenger/pkg/broker"
+	"github.com/dmitriysta/messenger/pkg/events"
+	"github.com/dmitriysta/messenger/pkg/health"
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/pkg/shutdown"
+	"github.com/dmitriysta/messenger/pkg/userclient"
+	"github.com/dmitriysta/messenger/pkg/userpb"
 
-	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
@@ -20,13 +38,29 @@ func main() {
 	logger.SetLevel(logrus.InfoLevel)
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
-	err := godotenv.Load()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"module": "main",
 			"func":   "main",
-			"error":  err.Error(),
-		}).Fatalf("failed to load .env file: %v", err)
+		}).Fatalf("failed to load config: %v", err)
+	}
+
+	if level, err := logrus.ParseLevel(cfg.Log.Level); err == nil {
+		logger.SetLevel(level)
+	}
+
+	logger.WithFields(cfg.Fields()).Info("effective configuration")
+
+	if len(args) > 0 && args[0] == "migrate" {
+		db := repository.DatabaseConnect(cfg.Database, logger)
+		defer db.Close()
+
+		runMigrate(db, logger, args[1:])
+		return
 	}
 
 	trace, closer, err := tracer.NewJaegerTracer("message", logger)
@@ -37,41 +71,174 @@ func main() {
 			"error":  err.Error(),
 		}).Fatalf("failed to create new tracer: %v", err)
 	}
-	defer closer.Close()
 
-	db := repository.DatabaseConnect(logger)
+	db := repository.DatabaseConnect(cfg.Database, logger)
+	migrator := newMigrator(db, logger)
+	migrator.LogVersion(context.Background())
+
+	cache.InitRedis(cfg.Redis, logger)
+
+	userConn, err := userclient.Dial(cfg.Users.Addr, cfg.Users.Token)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid user service configuration: %v", err)
+	}
 
-	cache.InitRedis(logger)
+	messageCache := cache.NewCache(cache.RedisClient, cfg.Cache, logger)
+	userBreaker := breaker.New(cfg.Users.BreakerThreshold, cfg.Users.BreakerCooldown, func(state breaker.State) {
+		metrics.BreakerState.WithLabelValues("user").Set(float64(state))
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"state":  state.String(),
+		}).Warn("user service circuit breaker changed state")
+	})
+	userClient := users.NewClient(userpb.NewUserServiceClient(userConn), messageCache, userBreaker, cfg.Users, logger)
 
 	messageRepo := repository.NewMessageRepository(db, logger)
-	messageService := service.NewMessageService(messageRepo, logger, cache.RedisClient)
+	channelRepo := repository.NewChannelRepository(db, logger)
+	messageService := service.NewMessageService(messageRepo, channelRepo, userClient, logger, messageCache, cfg.Users.FailOpen)
+
+	eventBroker, err := broker.New(cfg.Broker.Driver, cfg.Broker.StreamPrefix, cfg.Broker.StreamMaxLen, cache.RedisClient, logger)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid broker configuration: %v", err)
+	}
+	relay := newOutboxRelay(db, eventBroker, cfg.Outbox, logger)
+
+	if !models.ValidDeletedUserPolicy(cfg.Users.DeletedUsers) {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+		}).Fatalf("invalid deleted user policy %q", cfg.Users.DeletedUsers)
+	}
+	if cfg.Users.DeletedUserBatch <= 0 {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+		}).Fatalf("invalid deleted user batch size %d", cfg.Users.DeletedUserBatch)
+	}
+	userEventService := service.NewUserEventService(repository.NewUserDataRepository(db, logger), messageCache, cfg.Users.DeletedUsers, cfg.Users.DeletedUserBatch, logger)
+
+	policies, err := newRateLimitPolicies(cfg.RateLimit)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid rate limit configuration: %v", err)
+	}
+	rateLimiter := api.NewRateLimiter(ratelimit.NewLimiter(cache.RedisClient), policies, cfg.RateLimit.TrustForwarded, logger)
+
 	messageHandler := api.NewMessageHandler(messageService, logger, trace)
 
-	http.HandleFunc("/messages", api.PrometheusMiddleware(api.MessageRouteHandler(
+	http.HandleFunc("/messages", api.PrometheusMiddleware(rateLimiter.Middleware(api.MessageRouteHandler(
 		messageHandler.CreateMessageHandler,
 		messageHandler.GetMessagesByChannelIdHandler,
-	)))
-	http.HandleFunc("/messages/", api.PrometheusMiddleware(api.MessageIdRouteHandler(
+	))))
+	http.HandleFunc("/messages/", api.PrometheusMiddleware(rateLimiter.Middleware(api.MessageIdRouteHandler(
 		messageHandler.UpdateMessageHandler,
+		messageHandler.PatchMessageHandler,
 		messageHandler.DeleteMessageHandler,
-	)))
+		messageHandler.ForwardMessageHandler,
+		messageHandler.QuoteMessageHandler,
+	))))
 
 	http.Handle("/metrics", promhttp.Handler())
 
+	readiness := health.NewReadiness()
+	checker := health.NewChecker(readiness, health.Metrics{Up: metrics.DependencyUp, Latency: metrics.DependencyLatency}, logger,
+		health.PostgresCheck(db, cfg.Health.CheckTimeout),
+		health.RedisCheck(cache.RedisClient, cfg.Health.CheckTimeout),
+		health.MigrationsCheck(migrator, cfg.Health.CheckTimeout),
+	)
+
+	http.Handle("/livez", checker.LivenessHandler())
+	http.Handle("/readyz", checker.ReadinessHandler())
+
 	server := &http.Server{
-		Addr: ":" + os.Getenv("SERVER_PORT"),
+		Addr: ":" + cfg.Server.Port,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"module": "main",
 			"func":   "main",
 			"error":  err.Error(),
 		}).Fatalf("failed to start server: %v", err)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(listener)
+	}()
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx)
+		close(relayDone)
+	}()
+
+	// User events only reach this process through Redis; with the memory
+	// broker there is nothing to consume.
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	if cfg.Broker.Driver == broker.DriverRedis {
+		if cfg.Consumer.Name == "" {
+			cfg.Consumer.Name, _ = os.Hostname()
+		}
+
+		userEvents := consumer.NewRedisConsumer(cache.RedisClient, events.Stream(cfg.Broker.StreamPrefix, events.TopicUserDeleted), userEventService.HandleEvent, cfg.Consumer, logger)
+		go func() {
+			userEvents.Run(consumerCtx)
+			close(consumerDone)
+		}()
 	} else {
+		close(consumerDone)
 		logger.WithFields(logrus.Fields{
 			"module": "main",
 			"func":   "main",
-		}).Info("server started")
+			"broker": cfg.Broker.Driver,
+		}).Warn("not consuming user events, deleted users keep their messages and memberships")
+	}
+
+	readiness.SetReady(true)
+	logger.WithFields(logrus.Fields{
+		"module": "main",
+		"func":   "main",
+		"addr":   server.Addr,
+	}).Info("server started")
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.WithFields(logrus.Fields{
+				"module": "main",
+				"func":   "main",
+				"error":  err.Error(),
+			}).Fatalf("server stopped: %v", err)
+		}
+	case <-ctx.Done():
+		stop()
+		shutdown.Graceful(shutdown.Config{DrainDelay: cfg.Shutdown.DrainDelay, Timeout: cfg.Shutdown.Timeout}, readiness, logger,
+			[]shutdown.Drain{shutdown.HTTP(server)},
+			shutdown.Worker("user events", stopConsumer, consumerDone),
+			shutdown.Worker("outbox relay", stopRelay, relayDone),
+			shutdown.Closer("user service", userConn),
+			shutdown.Closer("tracer", closer),
+			shutdown.Closer("cache", cache.RedisClient),
+			shutdown.Closer("database", db),
+		)
 	}
 }
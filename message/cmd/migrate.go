@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dmitriysta/messenger/message/internal/pkg/database"
+	"github.com/dmitriysta/messenger/pkg/migrate"
+
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: message [flags] migrate up|down|status"
+
+func newMigrator(db *sql.DB, logger *logrus.Logger) *migrate.Migrator {
+	migrator, err := migrate.NewMigrator(db, database.Migrations(), database.MigrationsTable, database.MigrationsLockId, logger)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "newMigrator",
+			"error":  err.Error(),
+		}).Fatalf("failed to create migrator: %v", err)
+	}
+
+	return migrator
+}
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(db *sql.DB, logger *logrus.Logger, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	err := newMigrator(db, logger).Run(context.Background(), args[0], os.Stdout)
+	if errors.Is(err, migrate.ErrUnknownCommand) {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module":  "main",
+			"func":    "runMigrate",
+			"command": args[0],
+			"error":   err.Error(),
+		}).Fatalf("migrate %s failed: %v", args[0], err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/message/internal/pkg/database"
+	"github.com/dmitriysta/messenger/message/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/pkg/broker"
+	"github.com/dmitriysta/messenger/pkg/outbox"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newOutboxRelay returns the relay that publishes the events of the
+// message service from its outbox table to eventBroker.
+func newOutboxRelay(db *sql.DB, eventBroker broker.Broker, cfg config.OutboxConfig, logger *logrus.Logger) *outbox.Relay {
+	store := outbox.NewPostgresStore(db, database.OutboxTable, logger)
+
+	return outbox.NewRelay(store, eventBroker, outbox.Config{
+		PollInterval: cfg.PollInterval,
+		BatchSize:    cfg.BatchSize,
+		Retention:    cfg.Retention,
+		MaxAttempts:  cfg.MaxAttempts,
+		RetryMax:     cfg.RetryMax,
+	}, outbox.Metrics{
+		Published: metrics.OutboxPublished,
+		Failures:  metrics.OutboxPublishFailures,
+		Parked:    metrics.OutboxParked,
+	}, logger)
+}
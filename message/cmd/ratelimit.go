@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+)
+
+// newRateLimitPolicies parses the per-IP limits of the message service. It
+// has no per-user limits, since it doesn't know who its callers are.
+func newRateLimitPolicies(cfg config.RateLimitConfig) (ratelimit.Policies, error) {
+	ip, err := ratelimit.ParsePolicies(cfg.IP, cfg.IPRoutes)
+	if err != nil {
+		return ratelimit.Policies{}, err
+	}
+
+	return ratelimit.Policies{
+		Enabled: cfg.Enabled,
+		IP:      ip,
+	}, nil
+}
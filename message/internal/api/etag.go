@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// formatETag renders a row version as a strong entity tag.
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch returns the version a client expects to overwrite. ok is false
+// when the header is absent or "*", in which case the write is unconditional.
+func parseIfMatch(r *http.Request) (version int, ok bool, err error) {
+	value := strings.TrimSpace(r.Header.Get(HeaderIfMatch))
+	if value == "" || value == "*" {
+		return 0, false, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	version, err = strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, true, nil
+}
@@ -1,11 +1,16 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	stderrors "errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/dmitriysta/messenger/message/internal/interfaces"
+	"github.com/dmitriysta/messenger/message/internal/models"
 	"github.com/dmitriysta/messenger/message/internal/pkg/errors"
 
 	"github.com/opentracing/opentracing-go"
@@ -14,14 +19,19 @@ import (
 )
 
 const (
-	HeaderContentType   = "Content-Type"
-	MIMEApplicationJSON = "application/json"
+	HeaderContentType    = "Content-Type"
+	HeaderIdempotencyKey = "Idempotency-Key"
+	MIMEApplicationJSON  = "application/json"
+	ExpandAuthor         = "author"
+
+	maxIdempotencyKeyLength = 255
 )
 
 type MessageRequest struct {
-	UserId    int    `json:"userId"`
-	ChannelID int    `json:"channelId"`
-	Content   string `json:"content"`
+	UserId          int    `json:"userId"`
+	ChannelID       int    `json:"channelId"`
+	Content         string `json:"content"`
+	ClientMessageId string `json:"clientMessageId,omitempty"`
 }
 
 type MessageHandler struct {
@@ -57,20 +67,128 @@ func (h *MessageHandler) CreateMessageHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	message, err := h.messageService.CreateMessage(ctx, messageRequest.UserId, messageRequest.ChannelID, messageRequest.Content)
+	idempotencyKey := r.Header.Get(HeaderIdempotencyKey)
+	if idempotencyKey == "" {
+		idempotencyKey = messageRequest.ClientMessageId
+	}
+
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		http.Error(w, errors.ErrorIdempotencyKey, http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.messageService.CreateMessage(ctx, messageRequest.UserId, messageRequest.ChannelID, messageRequest.Content, idempotencyKey)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":         "message",
+			"handler":        "CreateMessageHandler",
+			"userId":         messageRequest.UserId,
+			"channelId":      messageRequest.ChannelID,
+			"content":        messageRequest.Content,
+			"idempotencyKey": idempotencyKey,
+			"traceId":        traceID,
+			"error":          err.Error(),
+		}).Error(errors.ErrorCreatingMessage)
+
+		switch {
+		case stderrors.Is(err, models.ErrIdempotencyInProgress):
+			http.Error(w, errors.ErrorRequestInProgress, http.StatusConflict)
+		case stderrors.Is(err, models.ErrIdempotencyKeyReused):
+			http.Error(w, errors.ErrorIdempotencyReused, http.StatusUnprocessableEntity)
+		case stderrors.Is(err, models.ErrIdempotentMessageGone):
+			http.Error(w, errors.ErrorIdempotentGone, http.StatusGone)
+		case stderrors.Is(err, models.ErrUnknownAuthor):
+			http.Error(w, errors.ErrorUnknownAuthor, http.StatusUnprocessableEntity)
+		case stderrors.Is(err, models.ErrUserServiceUnavailable):
+			http.Error(w, errors.ErrorUserService, http.StatusServiceUnavailable)
+		default:
+			http.Error(w, errors.ErrorCreatingMessage, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set(HeaderContentType, MIMEApplicationJSON)
+	w.Header().Set(HeaderETag, formatETag(message.Version))
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        message.Id,
+		"userId":    message.UserID,
+		"channelId": message.ChannelID,
+		"content":   message.Content,
+		"version":   message.Version,
+	})
+	if err != nil {
+		h.logger.Errorf(errors.ErrorEncodingResponse, err)
+		http.Error(w, errors.ErrorInternalServer, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *MessageHandler) ForwardMessageHandler(w http.ResponseWriter, r *http.Request) {
+	h.referenceMessageHandler(w, r, "ForwardMessageHandler", models.ReferenceKindForward, errors.ErrorForwardingMessage)
+}
+
+func (h *MessageHandler) QuoteMessageHandler(w http.ResponseWriter, r *http.Request) {
+	h.referenceMessageHandler(w, r, "QuoteMessageHandler", models.ReferenceKindQuote, errors.ErrorQuotingMessage)
+}
+
+func (h *MessageHandler) referenceMessageHandler(w http.ResponseWriter, r *http.Request, handler, kind, errorMessage string) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), handler)
+	defer span.Finish()
+
+	ctxId := r.Context().Value(contextKeyId)
+	sourceId, ok := ctxId.(int)
+	if !ok {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var messageRequest MessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&messageRequest); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "message",
+			"handler": handler,
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		http.Error(w, errors.InvalidRequestBody, http.StatusBadRequest)
+		return
+	}
+
+	var message *models.Message
+	var err error
+	if kind == models.ReferenceKindForward {
+		message, err = h.messageService.ForwardMessage(ctx, messageRequest.UserId, sourceId, messageRequest.ChannelID, messageRequest.Content)
+	} else {
+		message, err = h.messageService.QuoteMessage(ctx, messageRequest.UserId, sourceId, messageRequest.ChannelID, messageRequest.Content)
+	}
 	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
 		h.logger.WithFields(logrus.Fields{
 			"module":    "message",
-			"handler":   "CreateMessageHandler",
+			"handler":   handler,
 			"userId":    messageRequest.UserId,
+			"sourceId":  sourceId,
 			"channelId": messageRequest.ChannelID,
-			"content":   messageRequest.Content,
 			"traceId":   traceID,
 			"error":     err.Error(),
-		}).Error(errors.ErrorCreatingMessage)
-
-		http.Error(w, errors.ErrorCreatingMessage, http.StatusInternalServerError)
+		}).Error(errorMessage)
+
+		switch {
+		case stderrors.Is(err, sql.ErrNoRows):
+			http.Error(w, errors.ErrorMessageNotFound, http.StatusNotFound)
+		case stderrors.Is(err, models.ErrChannelAccessDenied):
+			http.Error(w, errors.ErrorChannelAccess, http.StatusForbidden)
+		case stderrors.Is(err, models.ErrUnknownAuthor):
+			http.Error(w, errors.ErrorUnknownAuthor, http.StatusUnprocessableEntity)
+		case stderrors.Is(err, models.ErrUserServiceUnavailable):
+			http.Error(w, errors.ErrorUserService, http.StatusServiceUnavailable)
+		default:
+			http.Error(w, errorMessage, http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -81,6 +199,7 @@ func (h *MessageHandler) CreateMessageHandler(w http.ResponseWriter, r *http.Req
 		"userId":    message.UserID,
 		"channelId": message.ChannelID,
 		"content":   message.Content,
+		"reference": message.Reference,
 	})
 	if err != nil {
 		h.logger.Errorf(errors.ErrorEncodingResponse, err)
@@ -120,6 +239,12 @@ func (h *MessageHandler) GetMessagesByChannelIdHandler(w http.ResponseWriter, r
 		return
 	}
 
+	expandAuthor, ok := parseExpand(r.URL.Query().Get("expand"))
+	if !ok {
+		http.Error(w, errors.ErrorInvalidExpand, http.StatusBadRequest)
+		return
+	}
+
 	messages, err := h.messageService.GetMessagesByChannelId(ctx, channelId)
 	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
@@ -135,9 +260,26 @@ func (h *MessageHandler) GetMessagesByChannelIdHandler(w http.ResponseWriter, r
 		return
 	}
 
+	var body interface{} = messages
+	if expandAuthor {
+		if body, err = h.withAuthors(ctx, messages); err != nil {
+			traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+			h.logger.WithFields(logrus.Fields{
+				"module":    "message",
+				"handler":   "GetMessagesByChannelIdHandler",
+				"channelId": channelId,
+				"traceId":   traceID,
+				"error":     err.Error(),
+			}).Error(errors.ErrorGettingMessages)
+
+			http.Error(w, errors.ErrorGettingMessages, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set(HeaderContentType, MIMEApplicationJSON)
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(messages)
+	err = json.NewEncoder(w).Encode(body)
 	if err != nil {
 		h.logger.Errorf(errors.ErrorEncodingResponse, err)
 		http.Error(w, errors.ErrorInternalServer, http.StatusInternalServerError)
@@ -145,11 +287,51 @@ func (h *MessageHandler) GetMessagesByChannelIdHandler(w http.ResponseWriter, r
 	}
 }
 
+// withAuthors embeds the author of each message. Authors the user service
+// doesn't know or couldn't be asked about are left out.
+func (h *MessageHandler) withAuthors(ctx context.Context, messages []models.Message) ([]models.MessageWithAuthor, error) {
+	userIds := make([]int, len(messages))
+	for i := range messages {
+		userIds[i] = messages[i].UserID
+	}
+
+	authors, err := h.messageService.GetAuthors(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.MessageWithAuthor, len(messages))
+	for i := range messages {
+		result[i].Message = messages[i]
+		if author, ok := authors[messages[i].UserID]; ok {
+			result[i].Author = &author
+		}
+	}
+
+	return result, nil
+}
+
+// parseExpand reads the expand query parameter, a comma separated list of
+// what to embed in the response. author is the only thing that can be.
+func parseExpand(value string) (author bool, ok bool) {
+	if value == "" {
+		return false, true
+	}
+
+	for _, field := range strings.Split(value, ",") {
+		if strings.TrimSpace(field) != ExpandAuthor {
+			return false, false
+		}
+	}
+
+	return true, true
+}
+
 func (h *MessageHandler) UpdateMessageHandler(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "UpdateMessageHandler")
 	defer span.Finish()
 
-	ctxId := r.Context().Value("id")
+	ctxId := r.Context().Value(contextKeyId)
 	messageId, ok := ctxId.(int)
 	if !ok {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
@@ -170,42 +352,110 @@ func (h *MessageHandler) UpdateMessageHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	message, err := h.messageService.GetMessageById(ctx, messageId)
+	h.updateMessage(ctx, span, w, r, "UpdateMessageHandler", messageId, &models.MessagePatch{Content: &updateReq.Content})
+}
+
+func (h *MessageHandler) PatchMessageHandler(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "PatchMessageHandler")
+	defer span.Finish()
+
+	ctxId := r.Context().Value(contextKeyId)
+	messageId, ok := ctxId.(int)
+	if !ok {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if !isMergePatch(r) {
+		http.Error(w, errors.ErrorUnsupportedPatch, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := decodeMessagePatch(r.Body)
+	if err == nil {
+		err = patch.Validate()
+	}
 	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
 		h.logger.WithFields(logrus.Fields{
 			"module":  "message",
-			"handler": "UpdateMessageHandler",
+			"handler": "PatchMessageHandler",
 			"traceId": traceID,
 			"error":   err.Error(),
-		}).Error(errors.ErrorGettingMessages)
+		}).Error(errors.ErrorInvalidPatch)
 
-		http.Error(w, errors.ErrorGettingMessages, http.StatusInternalServerError)
+		http.Error(w, errors.ErrorInvalidPatch+": "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.updateMessage(ctx, span, w, r, "PatchMessageHandler", messageId, patch)
+}
+
+// updateMessage is the read-modify-write shared by PUT and PATCH. It reads
+// the message from the database, not the cache, honours If-Match against
+// the stored version and maps a concurrent write to 409.
+func (h *MessageHandler) updateMessage(ctx context.Context, span opentracing.Span, w http.ResponseWriter, r *http.Request, handler string, messageId int, patch *models.MessagePatch) {
+	expectedVersion, hasIfMatch, err := parseIfMatch(r)
+	if err != nil {
+		http.Error(w, errors.ErrorInvalidIfMatch, http.StatusBadRequest)
 		return
 	}
 
-	message.Content = updateReq.Content
-	if err := h.messageService.UpdateMessage(ctx, message); err != nil {
+	message, err := h.messageService.GetMessageForUpdate(ctx, messageId)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		http.Error(w, errors.ErrorMessageNotFound, http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
 		h.logger.WithFields(logrus.Fields{
 			"module":  "message",
-			"handler": "UpdateMessageHandler",
-			"content": message.Content + " -> " + updateReq.Content,
+			"handler": handler,
 			"traceId": traceID,
 			"error":   err.Error(),
-		}).Error(errors.ErrorUpdatingMessage)
+		}).Error(errors.ErrorGettingMessages)
 
-		http.Error(w, errors.ErrorUpdatingMessage, http.StatusInternalServerError)
+		http.Error(w, errors.ErrorGettingMessages, http.StatusInternalServerError)
+		return
+	}
+
+	if hasIfMatch && message.Version != expectedVersion {
+		w.Header().Set(HeaderETag, formatETag(message.Version))
+		http.Error(w, errors.ErrorPreconditionFailed, http.StatusPreconditionFailed)
 		return
 	}
 
+	oldContent := message.Content
+	if patch.Apply(message) {
+		if err := h.messageService.UpdateMessage(ctx, message); err != nil {
+			traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+			h.logger.WithFields(logrus.Fields{
+				"module":  "message",
+				"handler": handler,
+				"content": oldContent + " -> " + message.Content,
+				"traceId": traceID,
+				"error":   err.Error(),
+			}).Error(errors.ErrorUpdatingMessage)
+
+			if stderrors.Is(err, models.ErrVersionConflict) {
+				http.Error(w, errors.ErrorVersionConflict, http.StatusConflict)
+				return
+			}
+
+			http.Error(w, errors.ErrorUpdatingMessage, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set(HeaderContentType, MIMEApplicationJSON)
+	w.Header().Set(HeaderETag, formatETag(message.Version))
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(map[string]interface{}{
 		"id":        message.Id,
 		"userId":    message.UserID,
 		"channelId": message.ChannelID,
 		"content":   message.Content,
+		"version":   message.Version,
 	})
 	if err != nil {
 		h.logger.Errorf(errors.ErrorEncodingResponse, err)
@@ -218,7 +468,7 @@ func (h *MessageHandler) DeleteMessageHandler(w http.ResponseWriter, r *http.Req
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "DeleteMessageHandler")
 	defer span.Finish()
 
-	ctxId := r.Context().Value("id")
+	ctxId := r.Context().Value(contextKeyId)
 	messageId, ok := ctxId.(int)
 	if !ok {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
@@ -234,6 +484,11 @@ func (h *MessageHandler) DeleteMessageHandler(w http.ResponseWriter, r *http.Req
 			"error":   err.Error(),
 		}).Error(errors.ErrorDeletingMessage)
 
+		if stderrors.Is(err, sql.ErrNoRows) {
+			http.Error(w, errors.ErrorMessageNotFound, http.StatusNotFound)
+			return
+		}
+
 		http.Error(w, errors.ErrorDeletingMessage, http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces/mocks"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uber/jaeger-client-go"
+)
+
+// withJaegerTracer installs a tracer that records nothing, since the
+// handlers read the trace id of their span off a jaeger span context.
+func withJaegerTracer(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(false), jaeger.NewNullReporter())
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() {
+		opentracing.SetGlobalTracer(previous)
+		closer.Close()
+	})
+}
+
+func TestMessageHandler_PatchMessageHandler_Lookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "missing message", err: sql.ErrNoRows, wantStatus: http.StatusNotFound},
+		{name: "database failure", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withJaegerTracer(t)
+			messageService := new(mocks.MessageService)
+			messageService.On("GetMessageForUpdate", mock.Anything, 7).Return(nil, tt.err)
+
+			logger, _ := test.NewNullLogger()
+			handler := NewMessageHandler(messageService, logger, opentracing.GlobalTracer())
+
+			r := httptest.NewRequest(http.MethodPatch, "/messages/7", strings.NewReader(`{"content":"hello"}`))
+			r.Header.Set(HeaderContentType, MIMEApplicationMergePatchJSON)
+			r = r.WithContext(context.WithValue(r.Context(), contextKeyId, 7))
+			w := httptest.NewRecorder()
+
+			handler.PatchMessageHandler(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			messageService.AssertNotCalled(t, "UpdateMessage", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/dmitriysta/messenger/message/internal/models"
+)
+
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
+// isMergePatch accepts RFC 7396 documents, and plain JSON for clients that
+// can't set a custom content type.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(HeaderContentType))
+	if err != nil {
+		return false
+	}
+
+	return mediaType == MIMEApplicationMergePatchJSON || mediaType == MIMEApplicationJSON
+}
+
+// decodeMessagePatch reads a merge patch document. Only content can be
+// patched; a null removes a member in merge patch semantics, which content
+// doesn't allow, and any other member is rejected rather than ignored.
+func decodeMessagePatch(body io.Reader) (*models.MessagePatch, error) {
+	var document map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	patch := &models.MessagePatch{}
+	for member, value := range document {
+		switch member {
+		case "content":
+			if string(value) == "null" {
+				return nil, fmt.Errorf("%s cannot be removed", member)
+			}
+
+			if err := json.Unmarshal(value, &patch.Content); err != nil {
+				return nil, fmt.Errorf("%s: %w", member, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s cannot be patched", member)
+		}
+	}
+
+	return patch, nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dmitriysta/messenger/message/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMessagePatch(t *testing.T) {
+	content := "hello"
+
+	tests := []struct {
+		name    string
+		body    string
+		want    *models.MessagePatch
+		wantErr string
+	}{
+		{name: "empty document", body: `{}`, want: &models.MessagePatch{}},
+		{name: "content", body: `{"content":"hello"}`, want: &models.MessagePatch{Content: &content}},
+		{name: "unknown member", body: `{"userId":2}`, wantErr: "userId cannot be patched"},
+		{name: "unknown member next to content", body: `{"content":"hello","channelId":2}`, wantErr: "channelId cannot be patched"},
+		{name: "explicit null", body: `{"content":null}`, wantErr: "content cannot be removed"},
+		{name: "wrong type", body: `{"content":1}`, wantErr: "content: json: cannot unmarshal number"},
+		{name: "empty body", body: ``, wantErr: "EOF"},
+		{name: "not an object", body: `"hello"`, wantErr: "cannot unmarshal string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := decodeMessagePatch(strings.NewReader(tt.body))
+			if tt.wantErr != "" {
+				// The wording of encoding/json errors differs between Go
+				// releases, so only their start is matched.
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, patch)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, patch)
+		})
+	}
+}
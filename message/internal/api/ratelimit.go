@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces"
+	"github.com/dmitriysta/messenger/message/internal/pkg/errors"
+	"github.com/dmitriysta/messenger/message/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+
+	"github.com/sirupsen/logrus"
+)
+
+const HeaderForwardedFor = "X-Forwarded-For"
+
+// RateLimiter enforces the per-IP limit on every request. There is no
+// per-user limit: the service doesn't authenticate its callers, and the
+// author named in a request body is whatever the client chose to send. If
+// redis can't be reached requests are let through: an outage of the
+// limiter shouldn't become an outage of the service.
+type RateLimiter struct {
+	limiter        interfaces.Limiter
+	policies       ratelimit.Policies
+	trustForwarded bool
+	logger         *logrus.Logger
+}
+
+func NewRateLimiter(limiter interfaces.Limiter, policies ratelimit.Policies, trustForwarded bool, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		limiter:        limiter,
+		policies:       policies,
+		trustForwarded: trustForwarded,
+		logger:         logger,
+	}
+}
+
+func (rl *RateLimiter) Middleware(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rl.allow(w, r, ratelimit.ScopeIP, rl.clientIP(r)) {
+			next(w, r)
+		}
+	}
+}
+
+func (rl *RateLimiter) allow(w http.ResponseWriter, r *http.Request, scope, subject string) bool {
+	if rl == nil || !rl.policies.Enabled {
+		return true
+	}
+
+	route := routePattern(r)
+	policy := rl.policies.For(scope, route)
+
+	result, err := rl.limiter.Allow(r.Context(), scope+":"+subject+":"+route, policy)
+	if err != nil {
+		rl.logger.WithFields(logrus.Fields{
+			"module": "api",
+			"func":   "RateLimiter.allow",
+			"scope":  scope,
+			"route":  route,
+			"error":  err.Error(),
+		}).Warnf("rate limit check failed, allowing request: %v", err)
+
+		return true
+	}
+
+	ratelimit.SetHeaders(w.Header(), result)
+
+	if result.Allowed {
+		return true
+	}
+
+	metrics.RateLimited.WithLabelValues(scope, route).Inc()
+	http.Error(w, errors.ErrorRateLimited, http.StatusTooManyRequests)
+
+	return false
+}
+
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.trustForwarded {
+		if forwarded := r.Header.Get(HeaderForwardedFor); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// unmatchedRoute names every request no route is registered for.
+const unmatchedRoute = "unmatched"
+
+// routeMethods lists the methods each route pattern is registered for.
+var routeMethods = map[string][]string{
+	"/messages":              {http.MethodGet, http.MethodPost},
+	"/messages/{id}":         {http.MethodPut, http.MethodPatch, http.MethodDelete},
+	"/messages/{id}/forward": {http.MethodPost},
+	"/messages/{id}/quote":   {http.MethodPost},
+}
+
+// routePattern names the route r is handled by, such as
+// "PUT /messages/{id}", so every message shares one bucket per route rather
+// than one per message id. Requests that match no route share a single
+// name, so made-up paths and methods can neither dodge the limit nor grow
+// the number of keys and metric labels.
+func routePattern(r *http.Request) string {
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(segments) > 1 {
+		if _, err := strconv.Atoi(segments[1]); err != nil {
+			return unmatchedRoute
+		}
+		segments[1] = "{id}"
+	}
+
+	pattern := "/" + strings.Join(segments, "/")
+	for _, method := range routeMethods[pattern] {
+		if method == r.Method {
+			return r.Method + " " + pattern
+		}
+	}
+
+	return unmatchedRoute
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testPolicies = ratelimit.Policies{
+	Enabled: true,
+	IP: ratelimit.ScopePolicies{
+		Default: ratelimit.Policy{Limit: 300, Period: time.Minute},
+		Routes:  map[string]ratelimit.Policy{"POST /messages": {Limit: 60, Period: time.Minute}},
+	},
+}
+
+// serveRateLimited sends a request from 1.2.3.4 through rl to a handler
+// that answers 200.
+func serveRateLimited(rl *RateLimiter, method, path string) *httptest.ResponseRecorder {
+	handler := rl.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	handler(w, r)
+
+	return w
+}
+
+func TestRoutePattern(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/messages", "GET /messages"},
+		{http.MethodPost, "/messages", "POST /messages"},
+		{http.MethodPut, "/messages/42", "PUT /messages/{id}"},
+		{http.MethodPatch, "/messages/7", "PATCH /messages/{id}"},
+		{http.MethodDelete, "/messages/7", "DELETE /messages/{id}"},
+		{http.MethodPost, "/messages/7/forward", "POST /messages/{id}/forward"},
+		{http.MethodPost, "/messages/7/quote", "POST /messages/{id}/quote"},
+		{http.MethodGet, "/messages/7/forward", unmatchedRoute},
+		{http.MethodDelete, "/messages", unmatchedRoute},
+		{"BREW", "/messages/7", unmatchedRoute},
+		{http.MethodPut, "/messages/abc", unmatchedRoute},
+		{http.MethodPut, "/messages/", unmatchedRoute},
+		{http.MethodPut, "/messages/7/", unmatchedRoute},
+		{http.MethodPost, "/messages/7/anything", unmatchedRoute},
+		{http.MethodPost, "/messages/7/forward/8", unmatchedRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+
+			assert.Equal(t, tt.want, routePattern(r))
+		})
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, "ip:1.2.3.4:POST /messages", ratelimit.Policy{Limit: 60, Period: time.Minute}).
+		Return(ratelimit.Result{Allowed: true, Limit: 60, Remaining: 59, Reset: 1500 * time.Millisecond}, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	w := serveRateLimited(NewRateLimiter(limiter, testPolicies, false, logger), http.MethodPost, "/messages")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "60", w.Header().Get(ratelimit.HeaderRateLimitLimit))
+	assert.Equal(t, "59", w.Header().Get(ratelimit.HeaderRateLimitRemaining))
+	assert.Equal(t, "2", w.Header().Get(ratelimit.HeaderRateLimitReset))
+	assert.Empty(t, w.Header().Get(ratelimit.HeaderRetryAfter))
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_Middleware_Refused(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, "ip:1.2.3.4:PUT /messages/{id}", ratelimit.Policy{Limit: 300, Period: time.Minute}).
+		Return(ratelimit.Result{Limit: 300, RetryAfter: 200 * time.Millisecond, Reset: time.Minute}, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	w := serveRateLimited(NewRateLimiter(limiter, testPolicies, false, logger), http.MethodPut, "/messages/42")
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(ratelimit.HeaderRetryAfter))
+	assert.Equal(t, "300", w.Header().Get(ratelimit.HeaderRateLimitLimit))
+	assert.Equal(t, "0", w.Header().Get(ratelimit.HeaderRateLimitRemaining))
+	assert.Equal(t, "60", w.Header().Get(ratelimit.HeaderRateLimitReset))
+	assert.Equal(t, "Too many requests\n", w.Body.String())
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_Middleware_TrustForwarded(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, "ip:9.9.9.9:GET /messages", mock.Anything).
+		Return(ratelimit.Result{Allowed: true}, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	handler := NewRateLimiter(limiter, testPolicies, true, logger).Middleware(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	r.Header.Set(HeaderForwardedFor, "9.9.9.9, 10.0.0.1")
+	handler(httptest.NewRecorder(), r)
+
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_Middleware_FailsOpen(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, mock.Anything, mock.Anything).Return(ratelimit.Result{}, errors.New("redis down")).Once()
+
+	logger, _ := test.NewNullLogger()
+	w := serveRateLimited(NewRateLimiter(limiter, testPolicies, false, logger), http.MethodPost, "/messages")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(ratelimit.HeaderRateLimitLimit))
+	limiter.AssertExpectations(t)
+}
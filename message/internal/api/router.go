@@ -24,15 +24,16 @@ func MessageRouteHandler(createHandler, getHandler http.HandlerFunc) http.Handle
 	}
 }
 
-func MessageIdRouteHandler(updateHandler, deleteHandler http.HandlerFunc) http.HandlerFunc {
+func MessageIdRouteHandler(updateHandler, patchHandler, deleteHandler, forwardHandler, quoteHandler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/messages/")
-		if path == "" {
+		idStr, action, _ := strings.Cut(path, "/")
+		if idStr == "" {
 			http.Error(w, "ID is required", http.StatusBadRequest)
 			return
 		}
 
-		id, err := strconv.Atoi(path)
+		id, err := strconv.Atoi(idStr)
 		if err != nil {
 			http.Error(w, "Invalid ID", http.StatusBadRequest)
 			return
@@ -41,13 +42,31 @@ func MessageIdRouteHandler(updateHandler, deleteHandler http.HandlerFunc) http.H
 		ctx := context.WithValue(r.Context(), contextKeyId, id)
 		r = r.WithContext(ctx)
 
-		switch r.Method {
-		case http.MethodPut:
-			updateHandler(w, r)
-		case http.MethodDelete:
-			deleteHandler(w, r)
+		switch action {
+		case "":
+			switch r.Method {
+			case http.MethodPut:
+				updateHandler(w, r)
+			case http.MethodPatch:
+				patchHandler(w, r)
+			case http.MethodDelete:
+				deleteHandler(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		case "forward", "quote":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			if action == "forward" {
+				forwardHandler(w, r)
+			} else {
+				quoteHandler(w, r)
+			}
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			http.NotFound(w, r)
 		}
 	}
 }
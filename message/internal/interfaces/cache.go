@@ -0,0 +1,37 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is the message service cache, shared by the replicas through redis.
+// Only SetNX and SetShared report errors; every other operation falls back
+// to memory while redis is unavailable.
+type Cache interface {
+	// Get returns the cached value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// GetMany looks up keys in one round trip. Keys that were found map to
+	// their value; keys known not to exist map to nil; keys absent from
+	// the result are misses.
+	GetMany(ctx context.Context, keys []string) map[string][]byte
+	// GetOrLoad returns the cached value of key, calling load once for all
+	// concurrent misses on it and caching the result.
+	GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	SetShared(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetMissing records that key has nothing behind it.
+	SetMissing(ctx context.Context, key string)
+	Del(ctx context.Context, keys ...string)
+	// Version returns the counter stored at key, and Versions many of
+	// them. Bump increments counters, invalidating the entries keyed by
+	// their previous values.
+	Version(ctx context.Context, key string) int64
+	Versions(ctx context.Context, keys []string) []int64
+	Bump(ctx context.Context, keys ...string)
+	// TTL returns the lifetime for a new entry.
+	TTL() time.Duration
+}
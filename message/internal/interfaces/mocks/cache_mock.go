@@ -0,0 +1,621 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// Cache is an autogenerated mock type for the Cache type
+type Cache struct {
+	mock.Mock
+}
+
+type Cache_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Cache) EXPECT() *Cache_Expecter {
+	return &Cache_Expecter{mock: &_m.Mock}
+}
+
+// Bump provides a mock function with given fields: ctx, keys
+func (_m *Cache) Bump(ctx context.Context, keys ...string) {
+	_va := make([]interface{}, len(keys))
+	for _i := range keys {
+		_va[_i] = keys[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
+// Cache_Bump_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Bump'
+type Cache_Bump_Call struct {
+	*mock.Call
+}
+
+// Bump is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keys ...string
+func (_e *Cache_Expecter) Bump(ctx interface{}, keys ...interface{}) *Cache_Bump_Call {
+	return &Cache_Bump_Call{Call: _e.mock.On("Bump",
+		append([]interface{}{ctx}, keys...)...)}
+}
+
+func (_c *Cache_Bump_Call) Run(run func(ctx context.Context, keys ...string)) *Cache_Bump_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Cache_Bump_Call) Return() *Cache_Bump_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Cache_Bump_Call) RunAndReturn(run func(context.Context, ...string)) *Cache_Bump_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Del provides a mock function with given fields: ctx, keys
+func (_m *Cache) Del(ctx context.Context, keys ...string) {
+	_va := make([]interface{}, len(keys))
+	for _i := range keys {
+		_va[_i] = keys[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
+// Cache_Del_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Del'
+type Cache_Del_Call struct {
+	*mock.Call
+}
+
+// Del is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keys ...string
+func (_e *Cache_Expecter) Del(ctx interface{}, keys ...interface{}) *Cache_Del_Call {
+	return &Cache_Del_Call{Call: _e.mock.On("Del",
+		append([]interface{}{ctx}, keys...)...)}
+}
+
+func (_c *Cache_Del_Call) Run(run func(ctx context.Context, keys ...string)) *Cache_Del_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Cache_Del_Call) Return() *Cache_Del_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Cache_Del_Call) RunAndReturn(run func(context.Context, ...string)) *Cache_Del_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function with given fields: ctx, key
+func (_m *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 []byte
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]byte, bool)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// Cache_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type Cache_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Cache_Expecter) Get(ctx interface{}, key interface{}) *Cache_Get_Call {
+	return &Cache_Get_Call{Call: _e.mock.On("Get", ctx, key)}
+}
+
+func (_c *Cache_Get_Call) Run(run func(ctx context.Context, key string)) *Cache_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Cache_Get_Call) Return(_a0 []byte, _a1 bool) *Cache_Get_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Cache_Get_Call) RunAndReturn(run func(context.Context, string) ([]byte, bool)) *Cache_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMany provides a mock function with given fields: ctx, keys
+func (_m *Cache) GetMany(ctx context.Context, keys []string) map[string][]byte {
+	ret := _m.Called(ctx, keys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMany")
+	}
+
+	var r0 map[string][]byte
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]byte); ok {
+		r0 = rf(ctx, keys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]byte)
+		}
+	}
+
+	return r0
+}
+
+// Cache_GetMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMany'
+type Cache_GetMany_Call struct {
+	*mock.Call
+}
+
+// GetMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keys []string
+func (_e *Cache_Expecter) GetMany(ctx interface{}, keys interface{}) *Cache_GetMany_Call {
+	return &Cache_GetMany_Call{Call: _e.mock.On("GetMany", ctx, keys)}
+}
+
+func (_c *Cache_GetMany_Call) Run(run func(ctx context.Context, keys []string)) *Cache_GetMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *Cache_GetMany_Call) Return(_a0 map[string][]byte) *Cache_GetMany_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Cache_GetMany_Call) RunAndReturn(run func(context.Context, []string) map[string][]byte) *Cache_GetMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOrLoad provides a mock function with given fields: ctx, key, load
+func (_m *Cache) GetOrLoad(ctx context.Context, key string, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	ret := _m.Called(ctx, key, load)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrLoad")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(context.Context) ([]byte, error)) ([]byte, error)); ok {
+		return rf(ctx, key, load)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(context.Context) ([]byte, error)) []byte); ok {
+		r0 = rf(ctx, key, load)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, func(context.Context) ([]byte, error)) error); ok {
+		r1 = rf(ctx, key, load)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Cache_GetOrLoad_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetOrLoad'
+type Cache_GetOrLoad_Call struct {
+	*mock.Call
+}
+
+// GetOrLoad is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - load func(context.Context)([]byte , error)
+func (_e *Cache_Expecter) GetOrLoad(ctx interface{}, key interface{}, load interface{}) *Cache_GetOrLoad_Call {
+	return &Cache_GetOrLoad_Call{Call: _e.mock.On("GetOrLoad", ctx, key, load)}
+}
+
+func (_c *Cache_GetOrLoad_Call) Run(run func(ctx context.Context, key string, load func(context.Context) ([]byte, error))) *Cache_GetOrLoad_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(func(context.Context) ([]byte, error)))
+	})
+	return _c
+}
+
+func (_c *Cache_GetOrLoad_Call) Return(_a0 []byte, _a1 error) *Cache_GetOrLoad_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Cache_GetOrLoad_Call) RunAndReturn(run func(context.Context, string, func(context.Context) ([]byte, error)) ([]byte, error)) *Cache_GetOrLoad_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Set provides a mock function with given fields: ctx, key, value, ttl
+func (_m *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	_m.Called(ctx, key, value, ttl)
+}
+
+// Cache_Set_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Set'
+type Cache_Set_Call struct {
+	*mock.Call
+}
+
+// Set is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - value []byte
+//   - ttl time.Duration
+func (_e *Cache_Expecter) Set(ctx interface{}, key interface{}, value interface{}, ttl interface{}) *Cache_Set_Call {
+	return &Cache_Set_Call{Call: _e.mock.On("Set", ctx, key, value, ttl)}
+}
+
+func (_c *Cache_Set_Call) Run(run func(ctx context.Context, key string, value []byte, ttl time.Duration)) *Cache_Set_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Cache_Set_Call) Return() *Cache_Set_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Cache_Set_Call) RunAndReturn(run func(context.Context, string, []byte, time.Duration)) *Cache_Set_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetMissing provides a mock function with given fields: ctx, key
+func (_m *Cache) SetMissing(ctx context.Context, key string) {
+	_m.Called(ctx, key)
+}
+
+// Cache_SetMissing_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetMissing'
+type Cache_SetMissing_Call struct {
+	*mock.Call
+}
+
+// SetMissing is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Cache_Expecter) SetMissing(ctx interface{}, key interface{}) *Cache_SetMissing_Call {
+	return &Cache_SetMissing_Call{Call: _e.mock.On("SetMissing", ctx, key)}
+}
+
+func (_c *Cache_SetMissing_Call) Run(run func(ctx context.Context, key string)) *Cache_SetMissing_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Cache_SetMissing_Call) Return() *Cache_SetMissing_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Cache_SetMissing_Call) RunAndReturn(run func(context.Context, string)) *Cache_SetMissing_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetNX provides a mock function with given fields: ctx, key, value, ttl
+func (_m *Cache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	ret := _m.Called(ctx, key, value, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetNX")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) (bool, error)); ok {
+		return rf(ctx, key, value, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) bool); ok {
+		r0 = rf(ctx, key, value, ttl)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []byte, time.Duration) error); ok {
+		r1 = rf(ctx, key, value, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Cache_SetNX_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetNX'
+type Cache_SetNX_Call struct {
+	*mock.Call
+}
+
+// SetNX is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - value []byte
+//   - ttl time.Duration
+func (_e *Cache_Expecter) SetNX(ctx interface{}, key interface{}, value interface{}, ttl interface{}) *Cache_SetNX_Call {
+	return &Cache_SetNX_Call{Call: _e.mock.On("SetNX", ctx, key, value, ttl)}
+}
+
+func (_c *Cache_SetNX_Call) Run(run func(ctx context.Context, key string, value []byte, ttl time.Duration)) *Cache_SetNX_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Cache_SetNX_Call) Return(_a0 bool, _a1 error) *Cache_SetNX_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Cache_SetNX_Call) RunAndReturn(run func(context.Context, string, []byte, time.Duration) (bool, error)) *Cache_SetNX_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetShared provides a mock function with given fields: ctx, key, value, ttl
+func (_m *Cache) SetShared(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ret := _m.Called(ctx, key, value, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetShared")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) error); ok {
+		r0 = rf(ctx, key, value, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Cache_SetShared_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetShared'
+type Cache_SetShared_Call struct {
+	*mock.Call
+}
+
+// SetShared is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - value []byte
+//   - ttl time.Duration
+func (_e *Cache_Expecter) SetShared(ctx interface{}, key interface{}, value interface{}, ttl interface{}) *Cache_SetShared_Call {
+	return &Cache_SetShared_Call{Call: _e.mock.On("SetShared", ctx, key, value, ttl)}
+}
+
+func (_c *Cache_SetShared_Call) Run(run func(ctx context.Context, key string, value []byte, ttl time.Duration)) *Cache_SetShared_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Cache_SetShared_Call) Return(_a0 error) *Cache_SetShared_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Cache_SetShared_Call) RunAndReturn(run func(context.Context, string, []byte, time.Duration) error) *Cache_SetShared_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TTL provides a mock function with given fields:
+func (_m *Cache) TTL() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TTL")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// Cache_TTL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TTL'
+type Cache_TTL_Call struct {
+	*mock.Call
+}
+
+// TTL is a helper method to define mock.On call
+func (_e *Cache_Expecter) TTL() *Cache_TTL_Call {
+	return &Cache_TTL_Call{Call: _e.mock.On("TTL")}
+}
+
+func (_c *Cache_TTL_Call) Run(run func()) *Cache_TTL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Cache_TTL_Call) Return(_a0 time.Duration) *Cache_TTL_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Cache_TTL_Call) RunAndReturn(run func() time.Duration) *Cache_TTL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Version provides a mock function with given fields: ctx, key
+func (_m *Cache) Version(ctx context.Context, key string) int64 {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Version")
+	}
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// Cache_Version_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Version'
+type Cache_Version_Call struct {
+	*mock.Call
+}
+
+// Version is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Cache_Expecter) Version(ctx interface{}, key interface{}) *Cache_Version_Call {
+	return &Cache_Version_Call{Call: _e.mock.On("Version", ctx, key)}
+}
+
+func (_c *Cache_Version_Call) Run(run func(ctx context.Context, key string)) *Cache_Version_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Cache_Version_Call) Return(_a0 int64) *Cache_Version_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Cache_Version_Call) RunAndReturn(run func(context.Context, string) int64) *Cache_Version_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Versions provides a mock function with given fields: ctx, keys
+func (_m *Cache) Versions(ctx context.Context, keys []string) []int64 {
+	ret := _m.Called(ctx, keys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Versions")
+	}
+
+	var r0 []int64
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []int64); ok {
+		r0 = rf(ctx, keys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	return r0
+}
+
+// Cache_Versions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Versions'
+type Cache_Versions_Call struct {
+	*mock.Call
+}
+
+// Versions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keys []string
+func (_e *Cache_Expecter) Versions(ctx interface{}, keys interface{}) *Cache_Versions_Call {
+	return &Cache_Versions_Call{Call: _e.mock.On("Versions", ctx, keys)}
+}
+
+func (_c *Cache_Versions_Call) Run(run func(ctx context.Context, keys []string)) *Cache_Versions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *Cache_Versions_Call) Return(_a0 []int64) *Cache_Versions_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Cache_Versions_Call) RunAndReturn(run func(context.Context, []string) []int64) *Cache_Versions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewCache creates a new instance of Cache. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCache(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Cache {
+	mock := &Cache{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,94 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ChannelRepository is an autogenerated mock type for the ChannelRepository type
+type ChannelRepository struct {
+	mock.Mock
+}
+
+type ChannelRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *ChannelRepository) EXPECT() *ChannelRepository_Expecter {
+	return &ChannelRepository_Expecter{mock: &_m.Mock}
+}
+
+// IsChannelMember provides a mock function with given fields: ctx, channelId, userId
+func (_m *ChannelRepository) IsChannelMember(ctx context.Context, channelId int, userId int) (bool, error) {
+	ret := _m.Called(ctx, channelId, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsChannelMember")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (bool, error)); ok {
+		return rf(ctx, channelId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) bool); ok {
+		r0 = rf(ctx, channelId, userId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, channelId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChannelRepository_IsChannelMember_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsChannelMember'
+type ChannelRepository_IsChannelMember_Call struct {
+	*mock.Call
+}
+
+// IsChannelMember is a helper method to define mock.On call
+//   - ctx context.Context
+//   - channelId int
+//   - userId int
+func (_e *ChannelRepository_Expecter) IsChannelMember(ctx interface{}, channelId interface{}, userId interface{}) *ChannelRepository_IsChannelMember_Call {
+	return &ChannelRepository_IsChannelMember_Call{Call: _e.mock.On("IsChannelMember", ctx, channelId, userId)}
+}
+
+func (_c *ChannelRepository_IsChannelMember_Call) Run(run func(ctx context.Context, channelId int, userId int)) *ChannelRepository_IsChannelMember_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *ChannelRepository_IsChannelMember_Call) Return(_a0 bool, _a1 error) *ChannelRepository_IsChannelMember_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ChannelRepository_IsChannelMember_Call) RunAndReturn(run func(context.Context, int, int) (bool, error)) *ChannelRepository_IsChannelMember_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewChannelRepository creates a new instance of ChannelRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewChannelRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ChannelRepository {
+	mock := &ChannelRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
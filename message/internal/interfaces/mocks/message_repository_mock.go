@@ -71,21 +71,33 @@ func (_c *MessageRepository_CreateMessage_Call) RunAndReturn(run func(context.Co
 }
 
 // DeleteMessage provides a mock function with given fields: ctx, messageId
-func (_m *MessageRepository) DeleteMessage(ctx context.Context, messageId int) error {
+func (_m *MessageRepository) DeleteMessage(ctx context.Context, messageId int) ([]models.Message, error) {
 	ret := _m.Called(ctx, messageId)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteMessage")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+	var r0 []models.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.Message, error)); ok {
+		return rf(ctx, messageId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.Message); ok {
 		r0 = rf(ctx, messageId)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Message)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, messageId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // MessageRepository_DeleteMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMessage'
@@ -107,12 +119,12 @@ func (_c *MessageRepository_DeleteMessage_Call) Run(run func(ctx context.Context
 	return _c
 }
 
-func (_c *MessageRepository_DeleteMessage_Call) Return(_a0 error) *MessageRepository_DeleteMessage_Call {
-	_c.Call.Return(_a0)
+func (_c *MessageRepository_DeleteMessage_Call) Return(_a0 []models.Message, _a1 error) *MessageRepository_DeleteMessage_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MessageRepository_DeleteMessage_Call) RunAndReturn(run func(context.Context, int) error) *MessageRepository_DeleteMessage_Call {
+func (_c *MessageRepository_DeleteMessage_Call) RunAndReturn(run func(context.Context, int) ([]models.Message, error)) *MessageRepository_DeleteMessage_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -235,6 +247,65 @@ func (_c *MessageRepository_GetMessagesByChannelId_Call) RunAndReturn(run func(c
 	return _c
 }
 
+// GetMessagesByIds provides a mock function with given fields: ctx, messageIds
+func (_m *MessageRepository) GetMessagesByIds(ctx context.Context, messageIds []int) ([]models.Message, error) {
+	ret := _m.Called(ctx, messageIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMessagesByIds")
+	}
+
+	var r0 []models.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) ([]models.Message, error)); ok {
+		return rf(ctx, messageIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) []models.Message); ok {
+		r0 = rf(ctx, messageIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, messageIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessageRepository_GetMessagesByIds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMessagesByIds'
+type MessageRepository_GetMessagesByIds_Call struct {
+	*mock.Call
+}
+
+// GetMessagesByIds is a helper method to define mock.On call
+//   - ctx context.Context
+//   - messageIds []int
+func (_e *MessageRepository_Expecter) GetMessagesByIds(ctx interface{}, messageIds interface{}) *MessageRepository_GetMessagesByIds_Call {
+	return &MessageRepository_GetMessagesByIds_Call{Call: _e.mock.On("GetMessagesByIds", ctx, messageIds)}
+}
+
+func (_c *MessageRepository_GetMessagesByIds_Call) Run(run func(ctx context.Context, messageIds []int)) *MessageRepository_GetMessagesByIds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *MessageRepository_GetMessagesByIds_Call) Return(_a0 []models.Message, _a1 error) *MessageRepository_GetMessagesByIds_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MessageRepository_GetMessagesByIds_Call) RunAndReturn(run func(context.Context, []int) ([]models.Message, error)) *MessageRepository_GetMessagesByIds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateMessage provides a mock function with given fields: ctx, message
 func (_m *MessageRepository) UpdateMessage(ctx context.Context, message *models.Message) error {
 	ret := _m.Called(ctx, message)
@@ -23,9 +23,9 @@ func (_m *MessageService) EXPECT() *MessageService_Expecter {
 	return &MessageService_Expecter{mock: &_m.Mock}
 }
 
-// CreateMessage provides a mock function with given fields: ctx, userId, channelId, content
-func (_m *MessageService) CreateMessage(ctx context.Context, userId int, channelId int, content string) (*models.Message, error) {
-	ret := _m.Called(ctx, userId, channelId, content)
+// CreateMessage provides a mock function with given fields: ctx, userId, channelId, content, idempotencyKey
+func (_m *MessageService) CreateMessage(ctx context.Context, userId int, channelId int, content string, idempotencyKey string) (*models.Message, error) {
+	ret := _m.Called(ctx, userId, channelId, content, idempotencyKey)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateMessage")
@@ -33,19 +33,19 @@ func (_m *MessageService) CreateMessage(ctx context.Context, userId int, channel
 
 	var r0 *models.Message
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, int, string) (*models.Message, error)); ok {
-		return rf(ctx, userId, channelId, content)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string, string) (*models.Message, error)); ok {
+		return rf(ctx, userId, channelId, content, idempotencyKey)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, int, string) *models.Message); ok {
-		r0 = rf(ctx, userId, channelId, content)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string, string) *models.Message); ok {
+		r0 = rf(ctx, userId, channelId, content, idempotencyKey)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Message)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, int, string) error); ok {
-		r1 = rf(ctx, userId, channelId, content)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, string, string) error); ok {
+		r1 = rf(ctx, userId, channelId, content, idempotencyKey)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -63,13 +63,14 @@ type MessageService_CreateMessage_Call struct {
 //   - userId int
 //   - channelId int
 //   - content string
-func (_e *MessageService_Expecter) CreateMessage(ctx interface{}, userId interface{}, channelId interface{}, content interface{}) *MessageService_CreateMessage_Call {
-	return &MessageService_CreateMessage_Call{Call: _e.mock.On("CreateMessage", ctx, userId, channelId, content)}
+//   - idempotencyKey string
+func (_e *MessageService_Expecter) CreateMessage(ctx interface{}, userId interface{}, channelId interface{}, content interface{}, idempotencyKey interface{}) *MessageService_CreateMessage_Call {
+	return &MessageService_CreateMessage_Call{Call: _e.mock.On("CreateMessage", ctx, userId, channelId, content, idempotencyKey)}
 }
 
-func (_c *MessageService_CreateMessage_Call) Run(run func(ctx context.Context, userId int, channelId int, content string)) *MessageService_CreateMessage_Call {
+func (_c *MessageService_CreateMessage_Call) Run(run func(ctx context.Context, userId int, channelId int, content string, idempotencyKey string)) *MessageService_CreateMessage_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(string))
+		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(string), args[4].(string))
 	})
 	return _c
 }
@@ -79,7 +80,7 @@ func (_c *MessageService_CreateMessage_Call) Return(_a0 *models.Message, _a1 err
 	return _c
 }
 
-func (_c *MessageService_CreateMessage_Call) RunAndReturn(run func(context.Context, int, int, string) (*models.Message, error)) *MessageService_CreateMessage_Call {
+func (_c *MessageService_CreateMessage_Call) RunAndReturn(run func(context.Context, int, int, string, string) (*models.Message, error)) *MessageService_CreateMessage_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -131,6 +132,127 @@ func (_c *MessageService_DeleteMessage_Call) RunAndReturn(run func(context.Conte
 	return _c
 }
 
+// ForwardMessage provides a mock function with given fields: ctx, userId, sourceId, channelId, content
+func (_m *MessageService) ForwardMessage(ctx context.Context, userId int, sourceId int, channelId int, content string) (*models.Message, error) {
+	ret := _m.Called(ctx, userId, sourceId, channelId, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForwardMessage")
+	}
+
+	var r0 *models.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, string) (*models.Message, error)); ok {
+		return rf(ctx, userId, sourceId, channelId, content)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, string) *models.Message); ok {
+		r0 = rf(ctx, userId, sourceId, channelId, content)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, int, string) error); ok {
+		r1 = rf(ctx, userId, sourceId, channelId, content)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessageService_ForwardMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ForwardMessage'
+type MessageService_ForwardMessage_Call struct {
+	*mock.Call
+}
+
+// ForwardMessage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - sourceId int
+//   - channelId int
+//   - content string
+func (_e *MessageService_Expecter) ForwardMessage(ctx interface{}, userId interface{}, sourceId interface{}, channelId interface{}, content interface{}) *MessageService_ForwardMessage_Call {
+	return &MessageService_ForwardMessage_Call{Call: _e.mock.On("ForwardMessage", ctx, userId, sourceId, channelId, content)}
+}
+
+func (_c *MessageService_ForwardMessage_Call) Run(run func(ctx context.Context, userId int, sourceId int, channelId int, content string)) *MessageService_ForwardMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(int), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *MessageService_ForwardMessage_Call) Return(_a0 *models.Message, _a1 error) *MessageService_ForwardMessage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MessageService_ForwardMessage_Call) RunAndReturn(run func(context.Context, int, int, int, string) (*models.Message, error)) *MessageService_ForwardMessage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAuthors provides a mock function with given fields: ctx, userIds
+func (_m *MessageService) GetAuthors(ctx context.Context, userIds []int) (map[int]models.Author, error) {
+	ret := _m.Called(ctx, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuthors")
+	}
+
+	var r0 map[int]models.Author
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) (map[int]models.Author, error)); ok {
+		return rf(ctx, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) map[int]models.Author); ok {
+		r0 = rf(ctx, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]models.Author)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessageService_GetAuthors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAuthors'
+type MessageService_GetAuthors_Call struct {
+	*mock.Call
+}
+
+// GetAuthors is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userIds []int
+func (_e *MessageService_Expecter) GetAuthors(ctx interface{}, userIds interface{}) *MessageService_GetAuthors_Call {
+	return &MessageService_GetAuthors_Call{Call: _e.mock.On("GetAuthors", ctx, userIds)}
+}
+
+func (_c *MessageService_GetAuthors_Call) Run(run func(ctx context.Context, userIds []int)) *MessageService_GetAuthors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *MessageService_GetAuthors_Call) Return(_a0 map[int]models.Author, _a1 error) *MessageService_GetAuthors_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MessageService_GetAuthors_Call) RunAndReturn(run func(context.Context, []int) (map[int]models.Author, error)) *MessageService_GetAuthors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetMessageById provides a mock function with given fields: ctx, messageId
 func (_m *MessageService) GetMessageById(ctx context.Context, messageId int) (*models.Message, error) {
 	ret := _m.Called(ctx, messageId)
@@ -190,6 +312,65 @@ func (_c *MessageService_GetMessageById_Call) RunAndReturn(run func(context.Cont
 	return _c
 }
 
+// GetMessageForUpdate provides a mock function with given fields: ctx, messageId
+func (_m *MessageService) GetMessageForUpdate(ctx context.Context, messageId int) (*models.Message, error) {
+	ret := _m.Called(ctx, messageId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMessageForUpdate")
+	}
+
+	var r0 *models.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Message, error)); ok {
+		return rf(ctx, messageId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Message); ok {
+		r0 = rf(ctx, messageId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, messageId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessageService_GetMessageForUpdate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMessageForUpdate'
+type MessageService_GetMessageForUpdate_Call struct {
+	*mock.Call
+}
+
+// GetMessageForUpdate is a helper method to define mock.On call
+//   - ctx context.Context
+//   - messageId int
+func (_e *MessageService_Expecter) GetMessageForUpdate(ctx interface{}, messageId interface{}) *MessageService_GetMessageForUpdate_Call {
+	return &MessageService_GetMessageForUpdate_Call{Call: _e.mock.On("GetMessageForUpdate", ctx, messageId)}
+}
+
+func (_c *MessageService_GetMessageForUpdate_Call) Run(run func(ctx context.Context, messageId int)) *MessageService_GetMessageForUpdate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *MessageService_GetMessageForUpdate_Call) Return(_a0 *models.Message, _a1 error) *MessageService_GetMessageForUpdate_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MessageService_GetMessageForUpdate_Call) RunAndReturn(run func(context.Context, int) (*models.Message, error)) *MessageService_GetMessageForUpdate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetMessagesByChannelId provides a mock function with given fields: ctx, channelId
 func (_m *MessageService) GetMessagesByChannelId(ctx context.Context, channelId int) ([]models.Message, error) {
 	ret := _m.Called(ctx, channelId)
@@ -249,6 +430,127 @@ func (_c *MessageService_GetMessagesByChannelId_Call) RunAndReturn(run func(cont
 	return _c
 }
 
+// GetMessagesByIds provides a mock function with given fields: ctx, messageIds
+func (_m *MessageService) GetMessagesByIds(ctx context.Context, messageIds []int) ([]models.Message, error) {
+	ret := _m.Called(ctx, messageIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMessagesByIds")
+	}
+
+	var r0 []models.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) ([]models.Message, error)); ok {
+		return rf(ctx, messageIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) []models.Message); ok {
+		r0 = rf(ctx, messageIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, messageIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessageService_GetMessagesByIds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMessagesByIds'
+type MessageService_GetMessagesByIds_Call struct {
+	*mock.Call
+}
+
+// GetMessagesByIds is a helper method to define mock.On call
+//   - ctx context.Context
+//   - messageIds []int
+func (_e *MessageService_Expecter) GetMessagesByIds(ctx interface{}, messageIds interface{}) *MessageService_GetMessagesByIds_Call {
+	return &MessageService_GetMessagesByIds_Call{Call: _e.mock.On("GetMessagesByIds", ctx, messageIds)}
+}
+
+func (_c *MessageService_GetMessagesByIds_Call) Run(run func(ctx context.Context, messageIds []int)) *MessageService_GetMessagesByIds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *MessageService_GetMessagesByIds_Call) Return(_a0 []models.Message, _a1 error) *MessageService_GetMessagesByIds_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MessageService_GetMessagesByIds_Call) RunAndReturn(run func(context.Context, []int) ([]models.Message, error)) *MessageService_GetMessagesByIds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QuoteMessage provides a mock function with given fields: ctx, userId, sourceId, channelId, content
+func (_m *MessageService) QuoteMessage(ctx context.Context, userId int, sourceId int, channelId int, content string) (*models.Message, error) {
+	ret := _m.Called(ctx, userId, sourceId, channelId, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QuoteMessage")
+	}
+
+	var r0 *models.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, string) (*models.Message, error)); ok {
+		return rf(ctx, userId, sourceId, channelId, content)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, string) *models.Message); ok {
+		r0 = rf(ctx, userId, sourceId, channelId, content)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, int, string) error); ok {
+		r1 = rf(ctx, userId, sourceId, channelId, content)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MessageService_QuoteMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QuoteMessage'
+type MessageService_QuoteMessage_Call struct {
+	*mock.Call
+}
+
+// QuoteMessage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - sourceId int
+//   - channelId int
+//   - content string
+func (_e *MessageService_Expecter) QuoteMessage(ctx interface{}, userId interface{}, sourceId interface{}, channelId interface{}, content interface{}) *MessageService_QuoteMessage_Call {
+	return &MessageService_QuoteMessage_Call{Call: _e.mock.On("QuoteMessage", ctx, userId, sourceId, channelId, content)}
+}
+
+func (_c *MessageService_QuoteMessage_Call) Run(run func(ctx context.Context, userId int, sourceId int, channelId int, content string)) *MessageService_QuoteMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(int), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *MessageService_QuoteMessage_Call) Return(_a0 *models.Message, _a1 error) *MessageService_QuoteMessage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MessageService_QuoteMessage_Call) RunAndReturn(run func(context.Context, int, int, int, string) (*models.Message, error)) *MessageService_QuoteMessage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateMessage provides a mock function with given fields: ctx, message
 func (_m *MessageService) UpdateMessage(ctx context.Context, message *models.Message) error {
 	ret := _m.Called(ctx, message)
@@ -137,6 +137,118 @@ func (_c *RedisClient_Get_Call) RunAndReturn(run func(context.Context, string) *
 	return _c
 }
 
+// Incr provides a mock function with given fields: ctx, key
+func (_m *RedisClient) Incr(ctx context.Context, key string) *redis.IntCmd {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Incr")
+	}
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string) *redis.IntCmd); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// RedisClient_Incr_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Incr'
+type RedisClient_Incr_Call struct {
+	*mock.Call
+}
+
+// Incr is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *RedisClient_Expecter) Incr(ctx interface{}, key interface{}) *RedisClient_Incr_Call {
+	return &RedisClient_Incr_Call{Call: _e.mock.On("Incr", ctx, key)}
+}
+
+func (_c *RedisClient_Incr_Call) Run(run func(ctx context.Context, key string)) *RedisClient_Incr_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *RedisClient_Incr_Call) Return(_a0 *redis.IntCmd) *RedisClient_Incr_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *RedisClient_Incr_Call) RunAndReturn(run func(context.Context, string) *redis.IntCmd) *RedisClient_Incr_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MGet provides a mock function with given fields: ctx, keys
+func (_m *RedisClient) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	_va := make([]interface{}, len(keys))
+	for _i := range keys {
+		_va[_i] = keys[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MGet")
+	}
+
+	var r0 *redis.SliceCmd
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) *redis.SliceCmd); ok {
+		r0 = rf(ctx, keys...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.SliceCmd)
+		}
+	}
+
+	return r0
+}
+
+// RedisClient_MGet_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MGet'
+type RedisClient_MGet_Call struct {
+	*mock.Call
+}
+
+// MGet is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keys ...string
+func (_e *RedisClient_Expecter) MGet(ctx interface{}, keys ...interface{}) *RedisClient_MGet_Call {
+	return &RedisClient_MGet_Call{Call: _e.mock.On("MGet",
+		append([]interface{}{ctx}, keys...)...)}
+}
+
+func (_c *RedisClient_MGet_Call) Run(run func(ctx context.Context, keys ...string)) *RedisClient_MGet_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *RedisClient_MGet_Call) Return(_a0 *redis.SliceCmd) *RedisClient_MGet_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *RedisClient_MGet_Call) RunAndReturn(run func(context.Context, ...string) *redis.SliceCmd) *RedisClient_MGet_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Set provides a mock function with given fields: ctx, key, value, expiration
 func (_m *RedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 	ret := _m.Called(ctx, key, value, expiration)
@@ -188,6 +300,57 @@ func (_c *RedisClient_Set_Call) RunAndReturn(run func(context.Context, string, i
 	return _c
 }
 
+// SetNX provides a mock function with given fields: ctx, key, value, expiration
+func (_m *RedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	ret := _m.Called(ctx, key, value, expiration)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetNX")
+	}
+
+	var r0 *redis.BoolCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}, time.Duration) *redis.BoolCmd); ok {
+		r0 = rf(ctx, key, value, expiration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.BoolCmd)
+		}
+	}
+
+	return r0
+}
+
+// RedisClient_SetNX_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetNX'
+type RedisClient_SetNX_Call struct {
+	*mock.Call
+}
+
+// SetNX is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - value interface{}
+//   - expiration time.Duration
+func (_e *RedisClient_Expecter) SetNX(ctx interface{}, key interface{}, value interface{}, expiration interface{}) *RedisClient_SetNX_Call {
+	return &RedisClient_SetNX_Call{Call: _e.mock.On("SetNX", ctx, key, value, expiration)}
+}
+
+func (_c *RedisClient_SetNX_Call) Run(run func(ctx context.Context, key string, value interface{}, expiration time.Duration)) *RedisClient_SetNX_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(interface{}), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *RedisClient_SetNX_Call) Return(_a0 *redis.BoolCmd) *RedisClient_SetNX_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *RedisClient_SetNX_Call) RunAndReturn(run func(context.Context, string, interface{}, time.Duration) *redis.BoolCmd) *RedisClient_SetNX_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewRedisClient creates a new instance of RedisClient. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewRedisClient(t interface {
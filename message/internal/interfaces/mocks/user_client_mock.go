@@ -0,0 +1,97 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/message/internal/models"
+)
+
+// UserClient is an autogenerated mock type for the UserClient type
+type UserClient struct {
+	mock.Mock
+}
+
+type UserClient_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *UserClient) EXPECT() *UserClient_Expecter {
+	return &UserClient_Expecter{mock: &_m.Mock}
+}
+
+// GetAuthors provides a mock function with given fields: ctx, userIds
+func (_m *UserClient) GetAuthors(ctx context.Context, userIds []int) (map[int]models.Author, error) {
+	ret := _m.Called(ctx, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuthors")
+	}
+
+	var r0 map[int]models.Author
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) (map[int]models.Author, error)); ok {
+		return rf(ctx, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) map[int]models.Author); ok {
+		r0 = rf(ctx, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]models.Author)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserClient_GetAuthors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAuthors'
+type UserClient_GetAuthors_Call struct {
+	*mock.Call
+}
+
+// GetAuthors is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userIds []int
+func (_e *UserClient_Expecter) GetAuthors(ctx interface{}, userIds interface{}) *UserClient_GetAuthors_Call {
+	return &UserClient_GetAuthors_Call{Call: _e.mock.On("GetAuthors", ctx, userIds)}
+}
+
+func (_c *UserClient_GetAuthors_Call) Run(run func(ctx context.Context, userIds []int)) *UserClient_GetAuthors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *UserClient_GetAuthors_Call) Return(_a0 map[int]models.Author, _a1 error) *UserClient_GetAuthors_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserClient_GetAuthors_Call) RunAndReturn(run func(context.Context, []int) (map[int]models.Author, error)) *UserClient_GetAuthors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewUserClient creates a new instance of UserClient. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserClient(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserClient {
+	mock := &UserClient{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
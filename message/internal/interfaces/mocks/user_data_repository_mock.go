@@ -0,0 +1,207 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/message/internal/models"
+)
+
+// UserDataRepository is an autogenerated mock type for the UserDataRepository type
+type UserDataRepository struct {
+	mock.Mock
+}
+
+type UserDataRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *UserDataRepository) EXPECT() *UserDataRepository_Expecter {
+	return &UserDataRepository_Expecter{mock: &_m.Mock}
+}
+
+// DeleteUserMessages provides a mock function with given fields: ctx, userId, policy, limit
+func (_m *UserDataRepository) DeleteUserMessages(ctx context.Context, userId int, policy string, limit int) (*models.UserDataDeletion, error) {
+	ret := _m.Called(ctx, userId, policy, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserMessages")
+	}
+
+	var r0 *models.UserDataDeletion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) (*models.UserDataDeletion, error)); ok {
+		return rf(ctx, userId, policy, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) *models.UserDataDeletion); ok {
+		r0 = rf(ctx, userId, policy, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserDataDeletion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, int) error); ok {
+		r1 = rf(ctx, userId, policy, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserDataRepository_DeleteUserMessages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteUserMessages'
+type UserDataRepository_DeleteUserMessages_Call struct {
+	*mock.Call
+}
+
+// DeleteUserMessages is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - policy string
+//   - limit int
+func (_e *UserDataRepository_Expecter) DeleteUserMessages(ctx interface{}, userId interface{}, policy interface{}, limit interface{}) *UserDataRepository_DeleteUserMessages_Call {
+	return &UserDataRepository_DeleteUserMessages_Call{Call: _e.mock.On("DeleteUserMessages", ctx, userId, policy, limit)}
+}
+
+func (_c *UserDataRepository_DeleteUserMessages_Call) Run(run func(ctx context.Context, userId int, policy string, limit int)) *UserDataRepository_DeleteUserMessages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(string), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *UserDataRepository_DeleteUserMessages_Call) Return(_a0 *models.UserDataDeletion, _a1 error) *UserDataRepository_DeleteUserMessages_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserDataRepository_DeleteUserMessages_Call) RunAndReturn(run func(context.Context, int, string, int) (*models.UserDataDeletion, error)) *UserDataRepository_DeleteUserMessages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FinishUserDeletion provides a mock function with given fields: ctx, topic, eventId, userId
+func (_m *UserDataRepository) FinishUserDeletion(ctx context.Context, topic string, eventId string, userId int) ([]int, error) {
+	ret := _m.Called(ctx, topic, eventId, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FinishUserDeletion")
+	}
+
+	var r0 []int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) ([]int, error)); ok {
+		return rf(ctx, topic, eventId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) []int); ok {
+		r0 = rf(ctx, topic, eventId, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, topic, eventId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserDataRepository_FinishUserDeletion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FinishUserDeletion'
+type UserDataRepository_FinishUserDeletion_Call struct {
+	*mock.Call
+}
+
+// FinishUserDeletion is a helper method to define mock.On call
+//   - ctx context.Context
+//   - topic string
+//   - eventId string
+//   - userId int
+func (_e *UserDataRepository_Expecter) FinishUserDeletion(ctx interface{}, topic interface{}, eventId interface{}, userId interface{}) *UserDataRepository_FinishUserDeletion_Call {
+	return &UserDataRepository_FinishUserDeletion_Call{Call: _e.mock.On("FinishUserDeletion", ctx, topic, eventId, userId)}
+}
+
+func (_c *UserDataRepository_FinishUserDeletion_Call) Run(run func(ctx context.Context, topic string, eventId string, userId int)) *UserDataRepository_FinishUserDeletion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *UserDataRepository_FinishUserDeletion_Call) Return(_a0 []int, _a1 error) *UserDataRepository_FinishUserDeletion_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserDataRepository_FinishUserDeletion_Call) RunAndReturn(run func(context.Context, string, string, int) ([]int, error)) *UserDataRepository_FinishUserDeletion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkUserDeleted provides a mock function with given fields: ctx, userId
+func (_m *UserDataRepository) MarkUserDeleted(ctx context.Context, userId int) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkUserDeleted")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserDataRepository_MarkUserDeleted_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkUserDeleted'
+type UserDataRepository_MarkUserDeleted_Call struct {
+	*mock.Call
+}
+
+// MarkUserDeleted is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *UserDataRepository_Expecter) MarkUserDeleted(ctx interface{}, userId interface{}) *UserDataRepository_MarkUserDeleted_Call {
+	return &UserDataRepository_MarkUserDeleted_Call{Call: _e.mock.On("MarkUserDeleted", ctx, userId)}
+}
+
+func (_c *UserDataRepository_MarkUserDeleted_Call) Run(run func(ctx context.Context, userId int)) *UserDataRepository_MarkUserDeleted_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *UserDataRepository_MarkUserDeleted_Call) Return(_a0 error) *UserDataRepository_MarkUserDeleted_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserDataRepository_MarkUserDeleted_Call) RunAndReturn(run func(context.Context, int) error) *UserDataRepository_MarkUserDeleted_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewUserDataRepository creates a new instance of UserDataRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserDataRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserDataRepository {
+	mock := &UserDataRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,13 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+)
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy ratelimit.Policy) (ratelimit.Result, error)
+}
@@ -12,6 +12,17 @@ type MessageRepository interface {
 	CreateMessage(ctx context.Context, message *models.Message) error
 	GetMessagesByChannelId(ctx context.Context, channelId int) ([]models.Message, error)
 	GetMessageById(ctx context.Context, messageId int) (*models.Message, error)
+	GetMessagesByIds(ctx context.Context, messageIds []int) ([]models.Message, error)
 	UpdateMessage(ctx context.Context, message *models.Message) error
-	DeleteMessage(ctx context.Context, messageId int) error
+	DeleteMessage(ctx context.Context, messageId int) ([]models.Message, error)
+}
+
+type ChannelRepository interface {
+	IsChannelMember(ctx context.Context, channelId, userId int) (bool, error)
+}
+
+type UserDataRepository interface {
+	MarkUserDeleted(ctx context.Context, userId int) error
+	DeleteUserMessages(ctx context.Context, userId int, policy string, limit int) (*models.UserDataDeletion, error)
+	FinishUserDeletion(ctx context.Context, topic, eventId string, userId int) ([]int, error)
 }
@@ -9,9 +9,14 @@ import (
 )
 
 type MessageService interface {
-	CreateMessage(ctx context.Context, userId, channelId int, content string) (*models.Message, error)
+	CreateMessage(ctx context.Context, userId, channelId int, content, idempotencyKey string) (*models.Message, error)
+	ForwardMessage(ctx context.Context, userId, sourceId, channelId int, content string) (*models.Message, error)
+	QuoteMessage(ctx context.Context, userId, sourceId, channelId int, content string) (*models.Message, error)
 	GetMessagesByChannelId(ctx context.Context, channelId int) ([]models.Message, error)
 	GetMessageById(ctx context.Context, messageId int) (*models.Message, error)
+	GetMessagesByIds(ctx context.Context, messageIds []int) ([]models.Message, error)
+	GetMessageForUpdate(ctx context.Context, messageId int) (*models.Message, error)
 	UpdateMessage(ctx context.Context, message *models.Message) error
 	DeleteMessage(ctx context.Context, messageId int) error
+	GetAuthors(ctx context.Context, userIds []int) (map[int]models.Author, error)
 }
@@ -0,0 +1,18 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+
+	"github.com/dmitriysta/messenger/message/internal/models"
+)
+
+// UserClient looks up users in the user service.
+type UserClient interface {
+	// GetAuthors returns the authors among userIds that exist. It fails
+	// with models.ErrUserServiceUnavailable when the user service can't be
+	// asked, and with models.ErrUserServiceRejected when it refuses the
+	// credentials of the message service.
+	GetAuthors(ctx context.Context, userIds []int) (map[int]models.Author, error)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "errors"
+
+var (
+	ErrUnknownAuthor          = errors.New("author does not exist")
+	ErrUserServiceUnavailable = errors.New("user service is unavailable")
+	// ErrUserServiceRejected means the user service refused the message
+	// service itself, because of a missing or wrong service token. Unlike
+	// ErrUserServiceUnavailable it won't go away by waiting.
+	ErrUserServiceRejected = errors.New("user service rejected the message service")
+)
+
+// DeletedUserId is the author of messages whose author deleted their
+// account and had them anonymised.
+const DeletedUserId = 0
+
+// DeletedAuthor is shown as the author of anonymised messages.
+var DeletedAuthor = Author{Id: DeletedUserId, Username: "deleted user"}
+
+// Author is the public face of the user who wrote a message, as the user
+// service knows it.
+type Author struct {
+	Id          int    `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName,omitempty"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
+}
+
+// MessageWithAuthor is a message with its author embedded. Author is nil
+// when the author couldn't be resolved.
+type MessageWithAuthor struct {
+	Message
+	Author *Author `json:"author,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+const (
+	TopicMessageCreated = "message.created"
+	TopicMessageUpdated = "message.updated"
+	TopicMessageDeleted = "message.deleted"
+)
+
+// MessageEvent is the payload of message.created and message.updated: the
+// message as it was committed.
+type MessageEvent struct {
+	MessageId int               `json:"messageId"`
+	ChannelId int               `json:"channelId"`
+	UserId    int               `json:"userId"`
+	Content   string            `json:"content"`
+	Reference *MessageReference `json:"reference,omitempty"`
+	Version   int               `json:"version"`
+}
+
+// MessageDeletedEvent is the payload of message.deleted.
+type MessageDeletedEvent struct {
+	MessageId int `json:"messageId"`
+	ChannelId int `json:"channelId"`
+	UserId    int `json:"userId"`
+}
+
+func NewMessageEvent(message *Message) MessageEvent {
+	return MessageEvent{
+		MessageId: message.Id,
+		ChannelId: message.ChannelID,
+		UserId:    message.UserID,
+		Content:   message.Content,
+		Reference: message.Reference,
+		Version:   message.Version,
+	}
+}
+
+// OutboxEvent is a domain event waiting in the outbox table to be
+// published by the outbox relay. It is written in the same transaction as
+// the change it describes, so the event exists if and only if the change
+// was committed.
+type OutboxEvent struct {
+	Topic   string
+	Key     string
+	Payload json.RawMessage
+}
+
+func NewOutboxEvent(topic string, key int, payload interface{}) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		Topic:   topic,
+		Key:     strconv.Itoa(key),
+		Payload: data,
+	}, nil
+}
@@ -6,19 +6,48 @@ import (
 )
 
 const (
-	ErrInvalidUserID    = "invalid user id"
-	ErrInvalidChannelID = "invalid channel id"
-	ErrInvalidContent   = "invalid content"
+	ErrInvalidUserID        = "invalid user id"
+	ErrInvalidChannelID     = "invalid channel id"
+	ErrInvalidContent       = "invalid content"
+	ErrInvalidReferenceKind = "invalid reference kind"
 )
 
+var (
+	ErrChannelAccessDenied   = errors.New("channel access denied")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+	ErrIdempotentMessageGone = errors.New("message created with this idempotency key no longer exists")
+	ErrVersionConflict       = errors.New("message was modified concurrently")
+)
+
+const (
+	ReferenceKindForward = "forward"
+	ReferenceKindQuote   = "quote"
+)
+
+// MessageReference points at the message a forward or a quote was made from.
+// UserID and Content are a snapshot of the source taken at the time of the
+// forward, so the reference still renders after the source is edited. Once
+// the source is deleted the snapshot content is cleared and Deleted is set.
+type MessageReference struct {
+	Kind      string `json:"kind"`
+	MessageID int    `json:"messageId"`
+	ChannelID int    `json:"channelId"`
+	UserID    int    `json:"userId"`
+	Content   string `json:"content"`
+	Deleted   bool   `json:"deleted"`
+}
+
 type Message struct {
-	Id        int       `json:"id"`
-	UserID    int       `json:"userId"`
-	ChannelID int       `json:"channelId"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	DeletedAt time.Time `json:"-"`
+	Id        int               `json:"id"`
+	UserID    int               `json:"userId"`
+	ChannelID int               `json:"channelId"`
+	Content   string            `json:"content"`
+	Reference *MessageReference `json:"reference,omitempty"`
+	Version   int               `json:"version"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	DeletedAt time.Time         `json:"-"`
 }
 
 func (m *Message) Validate() error {
@@ -30,6 +59,17 @@ func (m *Message) Validate() error {
 		return errors.New(ErrInvalidChannelID)
 	}
 
+	if m.Reference != nil {
+		if m.Reference.Kind != ReferenceKindForward && m.Reference.Kind != ReferenceKindQuote {
+			return errors.New(ErrInvalidReferenceKind)
+		}
+
+		// A forward may go out without a comment of its own, a quote may not.
+		if m.Reference.Kind == ReferenceKindForward {
+			return nil
+		}
+	}
+
 	if m.Content == "" {
 		return errors.New(ErrInvalidContent)
 	}
@@ -45,3 +85,46 @@ func NewMessage(userId, channelId int, content string) *Message {
 		CreatedAt: time.Now(),
 	}
 }
+
+// NewReferenceMessage builds a message that forwards or quotes source into
+// channelId on behalf of userId.
+func NewReferenceMessage(kind string, userId, channelId int, content string, source *Message) *Message {
+	message := NewMessage(userId, channelId, content)
+	message.Reference = &MessageReference{
+		Kind:      kind,
+		MessageID: source.Id,
+		ChannelID: source.ChannelID,
+		UserID:    source.UserID,
+		Content:   source.Content,
+	}
+
+	return message
+}
+
+// MessagePatch holds the fields of a JSON Merge Patch (RFC 7396) against a
+// message. A nil field was not present in the patch and stays unchanged.
+type MessagePatch struct {
+	Content *string
+}
+
+// Validate checks only the fields that were supplied.
+func (p *MessagePatch) Validate() error {
+	if p.Content != nil && *p.Content == "" {
+		return errors.New(ErrInvalidContent)
+	}
+
+	return nil
+}
+
+// Apply copies the supplied fields onto m and reports whether anything
+// actually changed.
+func (p *MessagePatch) Apply(m *Message) bool {
+	changed := false
+
+	if p.Content != nil && *p.Content != m.Content {
+		m.Content = *p.Content
+		changed = true
+	}
+
+	return changed
+}
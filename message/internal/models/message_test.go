@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestMessagePatch_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   MessagePatch
+		wantErr string
+	}{
+		{name: "empty patch", patch: MessagePatch{}},
+		{name: "content", patch: MessagePatch{Content: stringPtr("hello")}},
+		{name: "empty content", patch: MessagePatch{Content: stringPtr("")}, wantErr: ErrInvalidContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.patch.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMessagePatch_Apply(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       MessagePatch
+		wantChanged bool
+		wantContent string
+	}{
+		{name: "empty patch", patch: MessagePatch{}, wantContent: "hello"},
+		{name: "same content", patch: MessagePatch{Content: stringPtr("hello")}, wantContent: "hello"},
+		{name: "new content", patch: MessagePatch{Content: stringPtr("bye")}, wantChanged: true, wantContent: "bye"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Message{Content: "hello"}
+
+			assert.Equal(t, tt.wantChanged, tt.patch.Apply(m))
+			assert.Equal(t, tt.wantContent, m.Content)
+		})
+	}
+}
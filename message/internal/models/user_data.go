@@ -0,0 +1,34 @@
+package models
+
+import "errors"
+
+// What happens to the messages of a user who deleted their account.
+const (
+	// DeletedUserAnonymise keeps the messages but makes DeletedUserId their
+	// author.
+	DeletedUserAnonymise = "anonymise"
+	// DeletedUserSoftDelete hides the messages as if they were deleted.
+	DeletedUserSoftDelete = "soft-delete"
+	// DeletedUserKeep leaves the messages as they are.
+	DeletedUserKeep = "keep"
+)
+
+var ErrEventProcessed = errors.New("event was already processed")
+
+func ValidDeletedUserPolicy(policy string) bool {
+	switch policy {
+	case DeletedUserAnonymise, DeletedUserSoftDelete, DeletedUserKeep:
+		return true
+	default:
+		return false
+	}
+}
+
+// UserDataDeletion is what deleting the data of a user changed: the
+// messages it rewrote or hid, the forwards and quotes of them whose
+// snapshot changed, and every channel any of them sit in or the user left.
+type UserDataDeletion struct {
+	MessageIds   []int
+	ReferenceIds []int
+	ChannelIds   []int
+}
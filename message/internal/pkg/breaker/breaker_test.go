@@ -0,0 +1,119 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBreaker returns a breaker on a clock that only moves when told to,
+// recording every change of state.
+func testBreaker(threshold int, cooldown time.Duration) (*Breaker, *time.Time, *[]State) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var changes []State
+
+	b := New(threshold, cooldown, func(state State) {
+		changes = append(changes, state)
+	})
+	b.now = func() time.Time { return now }
+
+	return b, &now, &changes
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b, _, changes := testBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+	assert.Equal(t, []State{StateOpen}, *changes)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b, _, _ := testBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+
+	assert.NoError(t, b.Allow())
+	b.Success()
+
+	// Failures only count in a row.
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		succeed bool
+		want    State
+	}{
+		{name: "trial succeeds", succeed: true, want: StateClosed},
+		{name: "trial fails", succeed: false, want: StateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, now, changes := testBreaker(1, time.Minute)
+
+			assert.NoError(t, b.Allow())
+			b.Failure()
+
+			*now = now.Add(time.Minute - time.Second)
+			assert.ErrorIs(t, b.Allow(), ErrOpen, "still cooling down")
+
+			*now = now.Add(time.Second)
+			assert.NoError(t, b.Allow(), "the trial call")
+			assert.Equal(t, StateHalfOpen, b.State())
+			assert.ErrorIs(t, b.Allow(), ErrOpen, "only one trial call at a time")
+
+			if tt.succeed {
+				b.Success()
+			} else {
+				b.Failure()
+			}
+
+			assert.Equal(t, tt.want, b.State())
+			assert.Equal(t, []State{StateOpen, StateHalfOpen, tt.want}, *changes)
+		})
+	}
+}
+
+func TestBreaker_ReopensForAnotherCooldown(t *testing.T) {
+	b, now, _ := testBreaker(1, time.Minute)
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+
+	*now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Failure()
+
+	// The cooldown starts over with the failed trial.
+	*now = now.Add(time.Minute - time.Second)
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	*now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "closed", StateClosed.String())
+	assert.Equal(t, "open", StateOpen.String())
+	assert.Equal(t, "half-open", StateHalfOpen.String())
+}
@@ -0,0 +1,468 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces"
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/message/internal/pkg/metrics"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	backendRedis = "redis"
+	backendLocal = "local"
+
+	// tombstone marks a key whose loader found nothing. Cached values are
+	// JSON, which can't start with a NUL byte.
+	tombstone = "\x00"
+)
+
+// ErrNotFound is returned by a GetOrLoad loader when there is nothing to
+// load. The absence is cached for NegativeTTL and GetOrLoad keeps returning
+// ErrNotFound until then.
+var ErrNotFound = errors.New("cache: not found")
+
+// Cache is the message service cache. Redis is the primary store, shared by
+// all replicas. When a redis call fails the cache stops using redis for
+// RetryInterval and serves from a small in-process LRU instead, so an outage
+// degrades to per-replica caching rather than to every read hitting
+// PostgreSQL. Invalidations issued during the outage are replayed against
+// redis before it is used again.
+//
+// Besides plain entries the cache keeps version counters. Callers embed a
+// counter in the keys of the entries it covers and Bump it on writes; a
+// reader that loaded data before the bump then fills a key nobody looks up
+// any more, instead of putting stale data back under the live key.
+type Cache struct {
+	redis  interfaces.RedisClient
+	local  *lru
+	group  singleflight.Group
+	cfg    config.CacheConfig
+	logger *logrus.Logger
+
+	mu            sync.Mutex
+	downUntil     time.Time
+	pendingDel    map[string]struct{}
+	pendingBump   map[string]struct{}
+	replaying     bool
+	localVersions map[string]int64
+	now           func() time.Time
+}
+
+func NewCache(redis interfaces.RedisClient, cfg config.CacheConfig, logger *logrus.Logger) *Cache {
+	return &Cache{
+		redis:         redis,
+		local:         newLRU(cfg.LocalSize, cfg.LocalTTL),
+		cfg:           cfg,
+		logger:        logger,
+		pendingDel:    make(map[string]struct{}),
+		pendingBump:   make(map[string]struct{}),
+		localVersions: make(map[string]int64),
+		now:           time.Now,
+	}
+}
+
+// Get returns the cached value of key and whether it was found.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	if !c.redisAvailable(ctx) {
+		return c.getLocal(key)
+	}
+
+	value, err := c.redis.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		metrics.CacheMisses.WithLabelValues(backendRedis).Inc()
+		return nil, false
+	}
+	if err != nil {
+		c.redisFailed("get", key, err)
+		return c.getLocal(key)
+	}
+
+	metrics.CacheHits.WithLabelValues(backendRedis).Inc()
+
+	return value, true
+}
+
+// Set stores value under key for exactly ttl. Values written while redis is
+// down are kept in memory for LocalTTL at most.
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if c.redisAvailable(ctx) {
+		err := c.redis.Set(ctx, key, value, ttl).Err()
+		if err == nil {
+			return
+		}
+
+		c.redisFailed("set", key, err)
+	}
+
+	c.local.set(key, value)
+}
+
+// SetNX stores value under key only if the key does not exist yet. It needs
+// the shared store to mean anything, so unlike the other operations it has
+// no in-memory fallback and reports redis errors to the caller.
+func (c *Cache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if !c.redisAvailable(ctx) {
+		return false, errRedisUnavailable
+	}
+
+	ok, err := c.redis.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		c.redisFailed("setnx", key, err)
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// SetShared stores value under key in redis only and reports redis errors,
+// for values that are only correct if every replica sees them.
+func (c *Cache) SetShared(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if !c.redisAvailable(ctx) {
+		return errRedisUnavailable
+	}
+
+	if err := c.redis.Set(ctx, key, value, ttl).Err(); err != nil {
+		c.redisFailed("set", key, err)
+		return err
+	}
+
+	return nil
+}
+
+// Del removes keys from both stores. If redis can't be reached the keys are
+// remembered and deleted from redis once it is back.
+func (c *Cache) Del(ctx context.Context, keys ...string) {
+	c.local.del(keys...)
+
+	if c.redisAvailable(ctx) {
+		err := c.redis.Del(ctx, keys...).Err()
+		if err == nil {
+			return
+		}
+
+		c.redisFailed("del", keys[0], err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.pendingDel[key] = struct{}{}
+	}
+}
+
+// Version returns the counter stored at key, zero if it was never bumped.
+// While redis is down the counter is this replica's own, which only moves
+// on bumps made here.
+func (c *Cache) Version(ctx context.Context, key string) int64 {
+	if c.redisAvailable(ctx) {
+		version, err := c.redis.Get(ctx, key).Int64()
+		switch {
+		case err == nil:
+			return version
+		case errors.Is(err, redis.Nil):
+			return 0
+		default:
+			c.redisFailed("version", key, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.localVersions[key]
+}
+
+// Versions is Version for many keys in one round trip.
+func (c *Cache) Versions(ctx context.Context, keys []string) []int64 {
+	versions := make([]int64, len(keys))
+	if len(keys) == 0 {
+		return versions
+	}
+
+	if c.redisAvailable(ctx) {
+		results, err := c.redis.MGet(ctx, keys...).Result()
+		if err == nil {
+			for i, result := range results {
+				if value, ok := result.(string); ok {
+					versions[i], _ = strconv.ParseInt(value, 10, 64)
+				}
+			}
+
+			return versions
+		}
+
+		c.redisFailed("versions", keys[0], err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, key := range keys {
+		versions[i] = c.localVersions[key]
+	}
+
+	return versions
+}
+
+// Bump increments the counters stored at keys, invalidating every entry
+// keyed by their previous values. Bumps that can't reach redis are replayed
+// once it is back.
+func (c *Cache) Bump(ctx context.Context, keys ...string) {
+	c.mu.Lock()
+	for _, key := range keys {
+		c.localVersions[key]++
+	}
+	c.mu.Unlock()
+
+	for i, key := range keys {
+		if !c.redisAvailable(ctx) {
+			c.deferBump(keys[i:]...)
+			return
+		}
+
+		if err := c.redis.Incr(ctx, key).Err(); err != nil {
+			c.redisFailed("bump", key, err)
+			c.deferBump(keys[i:]...)
+			return
+		}
+	}
+}
+
+func (c *Cache) deferBump(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.pendingBump[key] = struct{}{}
+	}
+}
+
+// GetMany looks up keys in one round trip. Keys that were found map to
+// their value; keys known not to exist map to nil; keys absent from the
+// result are misses.
+func (c *Cache) GetMany(ctx context.Context, keys []string) map[string][]byte {
+	values := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return values
+	}
+
+	if c.redisAvailable(ctx) {
+		results, err := c.redis.MGet(ctx, keys...).Result()
+		if err == nil {
+			for i, result := range results {
+				value, ok := result.(string)
+				if !ok {
+					metrics.CacheMisses.WithLabelValues(backendRedis).Inc()
+					continue
+				}
+
+				metrics.CacheHits.WithLabelValues(backendRedis).Inc()
+				values[keys[i]] = decode([]byte(value))
+			}
+
+			return values
+		}
+
+		c.redisFailed("mget", keys[0], err)
+	}
+
+	for _, key := range keys {
+		if value, ok := c.getLocal(key); ok {
+			values[key] = decode(value)
+		}
+	}
+
+	return values
+}
+
+// SetMissing records that key has nothing behind it for NegativeTTL.
+func (c *Cache) SetMissing(ctx context.Context, key string) {
+	c.Set(ctx, key, []byte(tombstone), c.cfg.NegativeTTL)
+}
+
+// GetOrLoad returns the cached value of key, calling load on a miss and
+// caching its result for a jittered TTL. Concurrent misses on the same key
+// share a single call to load, so an expired hot key costs one query rather
+// than one per waiting request. The shared call runs on a context detached
+// from the caller that started it, bounded by LoadTimeout, so one request
+// giving up doesn't fail every other waiting on the same key.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if value, ok := c.Get(ctx, key); ok {
+		if value = decode(value); value == nil {
+			return nil, ErrNotFound
+		}
+
+		return value, nil
+	}
+
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.cfg.LoadTimeout)
+		defer cancel()
+
+		value, err := load(ctx)
+		if errors.Is(err, ErrNotFound) {
+			c.SetMissing(ctx, key)
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.Set(ctx, key, value, c.TTL())
+
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]byte), nil
+}
+
+// TTL returns the lifetime for a new entry. It spreads lifetimes by up to
+// TTLJitter of the base TTL in either direction, so entries filled together
+// don't all expire together.
+func (c *Cache) TTL() time.Duration {
+	if c.cfg.TTLJitter <= 0 {
+		return c.cfg.TTL
+	}
+
+	spread := float64(c.cfg.TTL) * c.cfg.TTLJitter
+	return c.cfg.TTL + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// decode maps a tombstone to nil.
+func decode(value []byte) []byte {
+	if string(value) == tombstone {
+		return nil
+	}
+
+	return value
+}
+
+func (c *Cache) getLocal(key string) ([]byte, bool) {
+	value, ok := c.local.get(key)
+	if !ok {
+		metrics.CacheMisses.WithLabelValues(backendLocal).Inc()
+		return nil, false
+	}
+
+	metrics.CacheHits.WithLabelValues(backendLocal).Inc()
+
+	return value, true
+}
+
+// redisAvailable reports whether redis should be tried. After a failure it
+// is skipped for RetryInterval; the first call after that replays the
+// invalidations that could not be delivered in the meantime. The replay
+// runs without c.mu held, and until it is done other callers keep using
+// memory, so nobody reads redis before it has caught up.
+func (c *Cache) redisAvailable(ctx context.Context) bool {
+	c.mu.Lock()
+	if c.now().Before(c.downUntil) || c.replaying {
+		c.mu.Unlock()
+		return false
+	}
+
+	if len(c.pendingDel) == 0 && len(c.pendingBump) == 0 {
+		c.mu.Unlock()
+		return true
+	}
+
+	pendingDel, pendingBump := c.pendingDel, c.pendingBump
+	c.pendingDel = make(map[string]struct{})
+	c.pendingBump = make(map[string]struct{})
+	c.replaying = true
+	c.mu.Unlock()
+
+	operation, err := c.replay(ctx, pendingDel, pendingBump)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.replaying = false
+
+	if err != nil {
+		metrics.CacheErrors.WithLabelValues(operation).Inc()
+		c.downUntil = c.now().Add(c.cfg.RetryInterval)
+
+		// What wasn't delivered is tried again next time, together with
+		// whatever was invalidated during the replay.
+		for key := range pendingDel {
+			c.pendingDel[key] = struct{}{}
+		}
+		for key := range pendingBump {
+			c.pendingBump[key] = struct{}{}
+		}
+
+		return false
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"module": "cache",
+		"func":   "redisAvailable",
+		"keys":   len(pendingDel) + len(pendingBump),
+	}).Info("redis is back, replayed pending invalidations")
+
+	return true
+}
+
+// replay delivers pending invalidations to redis, removing from
+// pendingBump the keys it bumped. On failure it returns the operation that
+// failed; pendingDel is then still to be delivered as a whole.
+func (c *Cache) replay(ctx context.Context, pendingDel, pendingBump map[string]struct{}) (string, error) {
+	if len(pendingDel) > 0 {
+		keys := make([]string, 0, len(pendingDel))
+		for key := range pendingDel {
+			keys = append(keys, key)
+		}
+
+		if err := c.redis.Del(ctx, keys...).Err(); err != nil {
+			return "del", err
+		}
+
+		for key := range pendingDel {
+			delete(pendingDel, key)
+		}
+	}
+
+	// One increment is enough however many bumps were missed: all that
+	// matters is that the counter moves past every value readers have seen.
+	for key := range pendingBump {
+		if err := c.redis.Incr(ctx, key).Err(); err != nil {
+			return "bump", err
+		}
+
+		delete(pendingBump, key)
+	}
+
+	return "", nil
+}
+
+func (c *Cache) redisFailed(operation, key string, err error) {
+	metrics.CacheErrors.WithLabelValues(operation).Inc()
+
+	c.mu.Lock()
+	c.downUntil = c.now().Add(c.cfg.RetryInterval)
+	c.mu.Unlock()
+
+	c.logger.WithFields(logrus.Fields{
+		"module":    "cache",
+		"func":      operation,
+		"error":     err.Error(),
+		"key":       key,
+		"retryIn":   c.cfg.RetryInterval.String(),
+		"fallsBack": backendLocal,
+	}).Warnf("redis %s failed, using in-memory cache: %v", operation, err)
+}
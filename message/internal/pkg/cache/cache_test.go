@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testCacheConfig = config.CacheConfig{
+	TTL:           time.Hour,
+	NegativeTTL:   time.Minute,
+	LocalSize:     16,
+	LocalTTL:      time.Minute,
+	RetryInterval: time.Minute,
+	LoadTimeout:   time.Second,
+}
+
+var errConnection = errors.New("connection refused")
+
+// newTestCache returns a cache on client whose clock the test moves.
+func newTestCache(client *mocks.RedisClient) (*Cache, *time.Time) {
+	logger, _ := test.NewNullLogger()
+	c := NewCache(client, testCacheConfig, logger)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	return c, &now
+}
+
+func TestCache_GetOrLoad_OutlivesCaller(t *testing.T) {
+	client := new(mocks.RedisClient)
+	client.On("Get", mock.Anything, "key").Return(redis.NewStringResult("", redis.Nil)).Once()
+	client.On("Set", mock.Anything, "key", []byte("value"), mock.Anything).Return(redis.NewStatusResult("OK", nil)).Once()
+	c, _ := newTestCache(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	value, err := c.GetOrLoad(ctx, "key", func(loadCtx context.Context) ([]byte, error) {
+		// The caller giving up doesn't cancel a load others may share.
+		cancel()
+		assert.NoError(t, loadCtx.Err())
+
+		deadline, ok := loadCtx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(testCacheConfig.LoadTimeout), deadline, time.Second)
+
+		return []byte("value"), nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	client.AssertExpectations(t)
+}
+
+func TestCache_ReplaysInvalidations(t *testing.T) {
+	client := new(mocks.RedisClient)
+	ctx := context.Background()
+	c, now := newTestCache(client)
+
+	client.On("Del", ctx, "gone").Return(redis.NewIntResult(0, errConnection)).Once()
+	c.Del(ctx, "gone")
+	// Down: the bump is this replica's own until redis is back.
+	c.Bump(ctx, "counter")
+	assert.Equal(t, int64(1), c.Version(ctx, "counter"))
+
+	*now = now.Add(testCacheConfig.RetryInterval)
+	client.On("Del", ctx, "gone").Return(redis.NewIntResult(1, nil)).Once()
+	client.On("Incr", ctx, "counter").Return(redis.NewIntResult(4, nil)).Once()
+	client.On("Get", ctx, "counter").Return(redis.NewStringResult("4", nil)).Once()
+
+	assert.Equal(t, int64(4), c.Version(ctx, "counter"))
+	client.AssertExpectations(t)
+	assert.Empty(t, c.pendingDel)
+	assert.Empty(t, c.pendingBump)
+}
+
+func TestCache_Replay_KeepsUndelivered(t *testing.T) {
+	client := new(mocks.RedisClient)
+	ctx := context.Background()
+	c, now := newTestCache(client)
+
+	client.On("Del", ctx, "gone").Return(redis.NewIntResult(0, errConnection)).Once()
+	c.Del(ctx, "gone")
+
+	*now = now.Add(testCacheConfig.RetryInterval)
+	client.On("Del", ctx, "gone").Return(redis.NewIntResult(0, errConnection)).Once()
+
+	assert.False(t, c.redisAvailable(ctx))
+	assert.Contains(t, c.pendingDel, "gone")
+	assert.True(t, c.now().Before(c.downUntil), "a failed replay backs off again")
+	client.AssertExpectations(t)
+}
+
+func TestCache_Replay_DoesNotBlockOthers(t *testing.T) {
+	client := new(mocks.RedisClient)
+	ctx := context.Background()
+	c, now := newTestCache(client)
+
+	client.On("Del", ctx, "gone").Return(redis.NewIntResult(0, errConnection)).Once()
+	c.Del(ctx, "gone")
+	*now = now.Add(testCacheConfig.RetryInterval)
+
+	client.On("Del", ctx, "gone").
+		Run(func(args mock.Arguments) {
+			// Another request while the replay is in flight takes the lock
+			// and, with redis not caught up yet, stays in memory.
+			c.Bump(ctx, "counter")
+			assert.Equal(t, int64(1), c.Version(ctx, "counter"))
+		}).
+		Return(redis.NewIntResult(1, nil)).Once()
+
+	assert.True(t, c.redisAvailable(ctx))
+	// The bump made during the replay is delivered next time.
+	assert.Contains(t, c.pendingBump, "counter")
+	client.AssertExpectations(t)
+}
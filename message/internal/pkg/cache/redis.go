@@ -1,23 +1,37 @@
 package cache
 
 import (
+	"errors"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
-	"os"
-	"time"
 )
 
 const (
-	TimeToLive = time.Hour
+	// IdempotencyTTL is how long a client may keep retrying a create with
+	// the same idempotency key and still get the original message back.
+	IdempotencyTTL = 24 * time.Hour
+	// IdempotencyPendingTTL is how long a create holds its idempotency key
+	// before it has a message to show for it. It only has to outlast one
+	// request; if the create dies halfway, the key is free again after it.
+	IdempotencyPendingTTL = 30 * time.Second
 )
 
 var RedisClient *redis.Client
 
-func InitRedis(logger *logrus.Logger) {
+var errRedisUnavailable = errors.New("redis is unavailable")
+
+// InitRedis creates the redis client. Redis being down at startup is not
+// fatal: the client reconnects on its own and the cache serves from memory
+// until it does.
+func InitRedis(cfg config.RedisConfig, logger *logrus.Logger) {
 	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     os.Getenv("REDIS_HOST"),
-		Password: os.Getenv("REDIS_PASSWORD"),
-		DB:       0,
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
 	})
 
 	_, err := RedisClient.Ping(RedisClient.Context()).Result()
@@ -26,6 +40,6 @@ func InitRedis(logger *logrus.Logger) {
 			"module": "cache",
 			"func":   "InitRedis",
 			"error":  err.Error(),
-		}).Fatalf("failed to ping redis: %v", err)
+		}).Warnf("failed to ping redis, starting with the in-memory cache: %v", err)
 	}
 }
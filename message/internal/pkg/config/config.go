@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/configload"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	Server    ServerConfig
+	Log       LogConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Cache     CacheConfig
+	RateLimit RateLimitConfig
+	Users     UserServiceConfig
+	Outbox    OutboxConfig
+	Broker    BrokerConfig
+	Consumer  ConsumerConfig
+	Shutdown  ShutdownConfig
+	Health    HealthConfig
+}
+
+type ServerConfig struct {
+	Port string `env:"SERVER_PORT" flag:"port" default:"8080" required:"true" usage:"HTTP listen port"`
+}
+
+type LogConfig struct {
+	Level string `env:"LOG_LEVEL" flag:"log-level" default:"info" usage:"log level (debug, info, warn, error)"`
+}
+
+type DatabaseConfig struct {
+	Host     string `env:"DB_HOST" flag:"db-host" required:"true" usage:"PostgreSQL host"`
+	Port     string `env:"DB_PORT" flag:"db-port" default:"5432" usage:"PostgreSQL port"`
+	User     string `env:"DB_USER" flag:"db-user" required:"true" usage:"PostgreSQL user"`
+	Password string `env:"DB_PASSWORD" flag:"db-password" secret:"true" usage:"PostgreSQL password"`
+	Name     string `env:"DB_NAME" flag:"db-name" required:"true" usage:"PostgreSQL database name"`
+	SSLMode  string `env:"DB_SSLMODE" flag:"db-sslmode" default:"disable" usage:"PostgreSQL sslmode"`
+}
+
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s", c.Host, c.User, c.Password, c.Name, c.Port, c.SSLMode)
+}
+
+type RedisConfig struct {
+	Addr     string `env:"REDIS_HOST" flag:"redis-host" required:"true" usage:"Redis address, host:port"`
+	Password string `env:"REDIS_PASSWORD" flag:"redis-password" secret:"true" usage:"Redis password"`
+	DB       int    `env:"REDIS_DB" flag:"redis-db" default:"0" usage:"Redis database number"`
+}
+
+type CacheConfig struct {
+	TTL           time.Duration `env:"CACHE_TTL" flag:"cache-ttl" default:"1h" usage:"base lifetime of cached entries"`
+	NegativeTTL   time.Duration `env:"CACHE_NEGATIVE_TTL" flag:"cache-negative-ttl" default:"1m" usage:"lifetime of cached lookups that found nothing"`
+	TTLJitter     float64       `env:"CACHE_TTL_JITTER" flag:"cache-ttl-jitter" default:"0.1" usage:"fraction of the TTL by which entry lifetimes are randomly spread"`
+	LocalSize     int           `env:"CACHE_LOCAL_SIZE" flag:"cache-local-size" default:"1024" usage:"number of entries kept in memory while redis is unavailable"`
+	LocalTTL      time.Duration `env:"CACHE_LOCAL_TTL" flag:"cache-local-ttl" default:"30s" usage:"lifetime of in-memory entries"`
+	RetryInterval time.Duration `env:"CACHE_REDIS_RETRY_INTERVAL" flag:"cache-redis-retry-interval" default:"5s" usage:"how long to bypass redis after it fails"`
+	LoadTimeout   time.Duration `env:"CACHE_LOAD_TIMEOUT" flag:"cache-load-timeout" default:"5s" usage:"timeout of a load shared by concurrent cache misses"`
+}
+
+type RateLimitConfig struct {
+	Enabled        bool     `env:"RATE_LIMIT_ENABLED" flag:"rate-limit-enabled" default:"true" usage:"enable rate limiting"`
+	IP             string   `env:"RATE_LIMIT_IP" flag:"rate-limit-ip" default:"300/1m" usage:"default per-IP limit on each route, N/period"`
+	IPRoutes       []string `env:"RATE_LIMIT_IP_ROUTES" flag:"rate-limit-ip-routes" default:"POST /messages=60/1m" usage:"comma separated per-route overrides of the per-IP limit, METHOD /path=N/period"`
+	TrustForwarded bool     `env:"RATE_LIMIT_TRUST_FORWARDED" flag:"rate-limit-trust-forwarded" default:"false" usage:"take the client IP from X-Forwarded-For"`
+}
+
+type UserServiceConfig struct {
+	Addr             string        `env:"USER_SERVICE_ADDR" flag:"user-service-addr" default:"localhost:9091" usage:"gRPC address of the user service, host:port"`
+	Token            string        `env:"USER_SERVICE_TOKEN" flag:"user-service-token" required:"true" secret:"true" usage:"service token sent to the user service, one of its SERVICE_TOKENS"`
+	Timeout          time.Duration `env:"USER_SERVICE_TIMEOUT" flag:"user-service-timeout" default:"2s" usage:"timeout of each call to the user service"`
+	CacheTTL         time.Duration `env:"AUTHOR_CACHE_TTL" flag:"author-cache-ttl" default:"5m" usage:"how long authors looked up in the user service stay cached"`
+	FailOpen         bool          `env:"AUTHOR_CHECK_FAIL_OPEN" flag:"author-check-fail-open" default:"true" usage:"accept messages whose author can't be checked because the user service is unavailable"`
+	BreakerThreshold int           `env:"USER_SERVICE_BREAKER_THRESHOLD" flag:"user-service-breaker-threshold" default:"5" usage:"failed calls in a row that stop calls to the user service"`
+	BreakerCooldown  time.Duration `env:"USER_SERVICE_BREAKER_COOLDOWN" flag:"user-service-breaker-cooldown" default:"30s" usage:"how long calls to the user service stay stopped before one is tried"`
+	DeletedUsers     string        `env:"DELETED_USER_MESSAGES" flag:"deleted-user-messages" default:"anonymise" usage:"what happens to the messages of users who delete their account: anonymise, soft-delete or keep"`
+	DeletedUserBatch int           `env:"DELETED_USER_BATCH_SIZE" flag:"deleted-user-batch-size" default:"500" usage:"messages of a deleted user changed in each transaction"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" flag:"outbox-poll-interval" default:"1s" usage:"how often the outbox is checked for events to publish"`
+	BatchSize    int           `env:"OUTBOX_BATCH_SIZE" flag:"outbox-batch-size" default:"100" usage:"most events published from the outbox in one go"`
+	Retention    time.Duration `env:"OUTBOX_RETENTION" flag:"outbox-retention" default:"168h" usage:"how long published events are kept in the outbox"`
+	MaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" flag:"outbox-max-attempts" default:"20" usage:"how often publishing an event is tried before it is parked in the outbox"`
+	RetryMax     time.Duration `env:"OUTBOX_RETRY_MAX" flag:"outbox-retry-max" default:"10m" usage:"longest wait before publishing a failed event is tried again"`
+}
+
+type BrokerConfig struct {
+	Driver       string `env:"BROKER_DRIVER" flag:"broker-driver" default:"redis" usage:"where events are published: redis (Redis Streams) or memory (in-process, for local use)"`
+	StreamPrefix string `env:"BROKER_STREAM_PREFIX" flag:"broker-stream-prefix" default:"events:" usage:"prefix of the Redis stream each topic is published to"`
+	StreamMaxLen int    `env:"BROKER_STREAM_MAX_LEN" flag:"broker-stream-max-len" default:"100000" usage:"approximate number of events kept in each stream"`
+}
+
+type ConsumerConfig struct {
+	Group         string        `env:"EVENT_CONSUMER_GROUP" flag:"event-consumer-group" default:"message" usage:"Redis Streams consumer group events from other services are read in"`
+	Name          string        `env:"EVENT_CONSUMER_NAME" flag:"event-consumer-name" usage:"name of this instance in the consumer group, the hostname when empty"`
+	BatchSize     int           `env:"EVENT_CONSUMER_BATCH_SIZE" flag:"event-consumer-batch-size" default:"16" usage:"most events read in one go"`
+	Block         time.Duration `env:"EVENT_CONSUMER_BLOCK" flag:"event-consumer-block" default:"5s" usage:"how long a read waits for new events"`
+	ClaimIdle     time.Duration `env:"EVENT_CONSUMER_CLAIM_IDLE" flag:"event-consumer-claim-idle" default:"1m" usage:"how long an event may go unacknowledged before it is retried, by this or another instance"`
+	MaxDeliveries int           `env:"EVENT_CONSUMER_MAX_DELIVERIES" flag:"event-consumer-max-deliveries" default:"10" usage:"how often an event is handed out before it is parked in the dead-letter stream"`
+}
+
+type ShutdownConfig struct {
+	Timeout    time.Duration `env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" default:"15s" usage:"how long in-flight requests may take to drain"`
+	DrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" flag:"shutdown-drain-delay" default:"5s" usage:"how long to report not ready before draining"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" flag:"health-check-timeout" default:"2s" usage:"timeout of each readiness dependency check"`
+}
+
+// Load builds the message service configuration from command line flags, the
+// environment and an optional config file, in that order of precedence.
+// args are the command line arguments without the program name; whatever
+// follows the flags, such as a subcommand, is returned.
+func Load(args []string) (*Config, []string, error) {
+	cfg := &Config{}
+	rest, err := configload.Load(cfg, "message", args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, rest, nil
+}
+
+// Fields returns the effective configuration for logging, with secrets
+// redacted.
+func (c *Config) Fields() logrus.Fields {
+	return configload.Fields(c)
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/dmitriysta/messenger/pkg/configload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Fields_RedactsSecrets(t *testing.T) {
+	cfg := &Config{}
+	cfg.Database.Host = "db"
+	cfg.Database.Password = "db-password"
+	cfg.Redis.Password = "redis-password"
+	cfg.Users.Token = "service-token"
+
+	got := cfg.Fields()
+	assert.Equal(t, "db", got["DB_HOST"])
+	assert.Equal(t, configload.Redacted, got["DB_PASSWORD"])
+	assert.Equal(t, configload.Redacted, got["REDIS_PASSWORD"])
+	assert.Equal(t, configload.Redacted, got["USER_SERVICE_TOKEN"])
+	for _, value := range got {
+		assert.NotContains(t, value, "password")
+		assert.NotContains(t, value, "token")
+	}
+}
@@ -0,0 +1,278 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/message/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler acts on an event. An error leaves the event unacknowledged, to
+// be delivered again.
+type Handler func(ctx context.Context, event *events.Envelope) error
+
+// RedisConsumer reads a Redis stream as a member of a consumer group and
+// acknowledges each event once its handler succeeded. Nothing is lost when
+// an instance stops: events it was handed and hadn't acknowledged are
+// handled again when it comes back, or claimed by another instance once
+// they have been idle for ClaimIdle. The same goes for events whose handler
+// failed, so handlers must tolerate seeing an event more than once. An
+// event handed out MaxDeliveries times without success is parked: copied to
+// the dead-letter stream, see DeadLetterStream, and acknowledged.
+type RedisConsumer struct {
+	client  redis.Cmdable
+	stream  string
+	handler Handler
+	cfg     config.ConsumerConfig
+	logger  *logrus.Logger
+}
+
+func NewRedisConsumer(client redis.Cmdable, stream string, handler Handler, cfg config.ConsumerConfig, logger *logrus.Logger) *RedisConsumer {
+	return &RedisConsumer{
+		client:  client,
+		stream:  stream,
+		handler: handler,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// DeadLetterStream names the stream the events of stream are parked in once
+// they ran out of deliveries. Its entries have the fields of the original
+// ones, so they can be added back to stream once the cause is fixed.
+func DeadLetterStream(stream string) string {
+	return stream + ":dead"
+}
+
+// Run consumes the stream until ctx is done.
+func (c *RedisConsumer) Run(ctx context.Context) {
+	for !c.createGroup(ctx) {
+		if !c.wait(ctx) {
+			return
+		}
+	}
+
+	// Events handed to this consumer before it last stopped come first.
+	pending := "0"
+	for pending != "" && ctx.Err() == nil {
+		messages, err := c.read(ctx, pending)
+		if err != nil {
+			c.failed("read", err)
+			if !c.wait(ctx) {
+				return
+			}
+
+			continue
+		}
+
+		pending = ""
+		if len(messages) > 0 {
+			pending = messages[len(messages)-1].ID
+			c.handle(ctx, messages)
+		}
+	}
+
+	for ctx.Err() == nil {
+		if err := c.claim(ctx); err != nil {
+			c.failed("claim", err)
+		}
+
+		messages, err := c.read(ctx, ">")
+		if err != nil {
+			c.failed("read", err)
+			// The stream or the group was removed behind our back.
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				c.createGroup(ctx)
+			}
+			if !c.wait(ctx) {
+				return
+			}
+
+			continue
+		}
+
+		c.handle(ctx, messages)
+	}
+}
+
+// createGroup creates the consumer group, and the stream if nothing was
+// published yet. A new group starts at the beginning of the stream, so
+// events published before this service first ran are consumed too.
+func (c *RedisConsumer) createGroup(ctx context.Context) bool {
+	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		c.failed("create group", err)
+		return false
+	}
+
+	return true
+}
+
+// read returns new events for ">", and otherwise the events after id that
+// were handed to this consumer and not acknowledged.
+func (c *RedisConsumer) read(ctx context.Context, id string) ([]redis.XMessage, error) {
+	block := c.cfg.Block
+	if id != ">" {
+		block = -1
+	}
+
+	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.cfg.Group,
+		Consumer: c.cfg.Name,
+		Streams:  []string{c.stream, id},
+		Count:    int64(c.cfg.BatchSize),
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []redis.XMessage
+	for _, stream := range streams {
+		messages = append(messages, stream.Messages...)
+	}
+
+	return messages, nil
+}
+
+// claim takes over events that stayed unacknowledged for ClaimIdle, left
+// behind by a failed handler or by an instance that went away, and handles
+// them. Events that were already handed out MaxDeliveries times are parked
+// instead.
+func (c *RedisConsumer) claim(ctx context.Context) error {
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: c.stream,
+		Group:  c.cfg.Group,
+		Idle:   c.cfg.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(c.cfg.BatchSize),
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for _, entry := range pending {
+		ids = append(ids, entry.ID)
+		deliveries[entry.ID] = entry.RetryCount
+	}
+
+	// XCLAIM checks the idle time again, so an event another instance
+	// claimed in the meantime stays with it.
+	messages, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.cfg.Group,
+		Consumer: c.cfg.Name,
+		MinIdle:  c.cfg.ClaimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	retries := make([]redis.XMessage, 0, len(messages))
+	for _, message := range messages {
+		if deliveries[message.ID] >= int64(c.cfg.MaxDeliveries) {
+			c.park(ctx, message, deliveries[message.ID])
+		} else {
+			retries = append(retries, message)
+		}
+	}
+
+	c.handle(ctx, retries)
+
+	return nil
+}
+
+// park copies message to the dead-letter stream and acknowledges it, in one
+// transaction so that it is neither lost nor parked twice.
+func (c *RedisConsumer) park(ctx context.Context, message redis.XMessage, deliveries int64) {
+	deadLetters := DeadLetterStream(c.stream)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: deadLetters, Values: message.Values})
+		pipe.XAck(ctx, c.stream, c.cfg.Group, message.ID)
+
+		return nil
+	})
+	if err != nil {
+		c.failed("park", err)
+		return
+	}
+
+	metrics.EventsDeadLettered.WithLabelValues(c.stream).Inc()
+	c.logger.WithFields(logrus.Fields{
+		"module":      "consumer",
+		"func":        "park",
+		"stream":      c.stream,
+		"entryId":     message.ID,
+		"deadLetters": deadLetters,
+	}).Errorf("parked event after %d failed deliveries", deliveries)
+}
+
+func (c *RedisConsumer) handle(ctx context.Context, messages []redis.XMessage) {
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+
+		event, err := events.FromValues(message.Values)
+		if err != nil {
+			c.logger.WithFields(logrus.Fields{
+				"module":  "consumer",
+				"func":    "handle",
+				"stream":  c.stream,
+				"entryId": message.ID,
+			}).Error("dropping malformed event")
+		} else if err := c.handler(ctx, event); err != nil {
+			c.logger.WithFields(logrus.Fields{
+				"module":  "consumer",
+				"func":    "handle",
+				"stream":  c.stream,
+				"eventId": event.Id,
+				"error":   err.Error(),
+			}).Errorf("failed to handle event, retrying in %s: %v", c.cfg.ClaimIdle, err)
+
+			continue
+		}
+
+		if err := c.client.XAck(ctx, c.stream, c.cfg.Group, message.ID).Err(); err != nil {
+			c.failed("ack", err)
+		}
+	}
+}
+
+func (c *RedisConsumer) failed(operation string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"module":    "consumer",
+		"func":      "Run",
+		"stream":    c.stream,
+		"operation": operation,
+		"error":     err.Error(),
+	}).Warnf("failed to %s: %v", operation, err)
+}
+
+// wait pauses before a retry and reports whether to go on.
+func (c *RedisConsumer) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(c.cfg.Block):
+		return true
+	}
+}
@@ -0,0 +1,100 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envRedisURL names the redis database these tests use, e.g.
+// redis://localhost:6379/15. The database is flushed before and after each
+// test, so it mustn't be one anything else uses.
+const envRedisURL = "TEST_REDIS_URL"
+
+func testClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	url := os.Getenv(envRedisURL)
+	if url == "" {
+		t.Skipf("%s is not set", envRedisURL)
+	}
+
+	options, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	client := redis.NewClient(options)
+	require.NoError(t, client.FlushDB(context.Background()).Err())
+
+	t.Cleanup(func() {
+		client.FlushDB(context.Background())
+		client.Close()
+	})
+
+	return client
+}
+
+func TestRedisConsumer_ParksFailingEvent(t *testing.T) {
+	client := testClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream := events.Stream("events:", events.TopicUserDeleted)
+	event := &events.Envelope{Id: "1", Topic: events.TopicUserDeleted, Key: "7", Payload: []byte(`{}`), OccurredAt: time.Now()}
+	require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: event.Values()}).Err())
+
+	var calls atomic.Int32
+	handler := func(ctx context.Context, event *events.Envelope) error {
+		calls.Add(1)
+		return errors.New("always fails")
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	cfg := config.ConsumerConfig{
+		Group:         "message",
+		Name:          "test",
+		BatchSize:     16,
+		Block:         10 * time.Millisecond,
+		ClaimIdle:     10 * time.Millisecond,
+		MaxDeliveries: 2,
+	}
+	consumer := NewRedisConsumer(client, stream, handler, cfg, logger)
+
+	done := make(chan struct{})
+	go func() {
+		consumer.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return client.XLen(ctx, DeadLetterStream(stream)).Val() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, int32(2), calls.Load())
+
+	parked, err := client.XRange(context.Background(), DeadLetterStream(stream), "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, parked, 1)
+	parkedEvent, err := events.FromValues(parked[0].Values)
+	require.NoError(t, err)
+	assert.Equal(t, event.Id, parkedEvent.Id)
+
+	pending, err := client.XPending(context.Background(), stream, cfg.Group).Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
@@ -0,0 +1,22 @@
+-- user_id and channel_id point at rows owned by other services, so they are
+-- indexed but carry no foreign keys.
+CREATE TABLE IF NOT EXISTS "message" (
+    id SERIAL PRIMARY KEY UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL,
+    channel_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    ref_kind VARCHAR(16) DEFAULT NULL,
+    ref_message_id INTEGER DEFAULT NULL,
+    ref_channel_id INTEGER DEFAULT NULL,
+    ref_user_id INTEGER DEFAULT NULL,
+    ref_content TEXT DEFAULT NULL,
+    ref_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_id ON "message" (user_id);
+CREATE INDEX IF NOT EXISTS idx_channel_id ON "message" (channel_id);
+CREATE INDEX IF NOT EXISTS idx_ref_message_id ON "message" (ref_message_id) WHERE ref_message_id IS NOT NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "channel_member" (
+    channel_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_member_user_id ON "channel_member" (user_id);
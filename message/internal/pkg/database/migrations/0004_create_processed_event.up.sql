@@ -0,0 +1,8 @@
+-- Events consumed from other services, so redeliveries are recognised and
+-- skipped.
+CREATE TABLE IF NOT EXISTS "processed_event" (
+    topic VARCHAR(64) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (topic, event_id)
+);
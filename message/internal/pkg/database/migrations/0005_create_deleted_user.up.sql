@@ -0,0 +1,6 @@
+-- Users whose account was deleted in the user service. Messages are no
+-- longer accepted from them.
+CREATE TABLE IF NOT EXISTS "deleted_user" (
+    user_id INTEGER PRIMARY KEY,
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_ref_user_id;
@@ -0,0 +1,3 @@
+-- Forwards and quotes are looked up by the author of their source when the
+-- author's account is deleted.
+CREATE INDEX IF NOT EXISTS idx_ref_user_id ON "message" (ref_user_id) WHERE ref_user_id IS NOT NULL;
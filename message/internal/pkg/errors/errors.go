@@ -9,6 +9,23 @@ const (
 	ErrorBindingRequestBody = "Error binding request body"
 	ErrorUpdatingMessage    = "Error updating message"
 	ErrorDeletingMessage    = "Error deleting message"
+	ErrorForwardingMessage  = "Error forwarding message"
+	ErrorQuotingMessage     = "Error quoting message"
+	ErrorMessageNotFound    = "Message not found"
+	ErrorChannelAccess      = "No access to the source channel"
+	ErrorIdempotencyKey     = "Invalid idempotency key"
+	ErrorRequestInProgress  = "A request with this idempotency key is still in progress"
+	ErrorIdempotencyReused  = "Idempotency key was already used for a different request"
+	ErrorIdempotentGone     = "The message created with this idempotency key no longer exists"
+	ErrorInvalidIfMatch     = "Invalid If-Match header"
+	ErrorPreconditionFailed = "Message has been modified since it was read"
+	ErrorVersionConflict    = "Message was modified concurrently"
+	ErrorInvalidPatch       = "Invalid merge patch"
+	ErrorUnsupportedPatch   = "Content-Type must be application/merge-patch+json"
+	ErrorRateLimited        = "Too many requests"
 	ErrorInternalServer     = "Internal server error"
+	ErrorUnknownAuthor      = "User does not exist"
+	ErrorUserService        = "User service is unavailable"
+	ErrorInvalidExpand      = "Invalid expand, only author is supported"
 	ErrorEncodingResponse   = "Failed to encode response: %v"
 )
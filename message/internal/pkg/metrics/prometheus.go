@@ -29,4 +29,100 @@ var (
 		},
 		[]string{"method", "endpoint", "http_status"},
 	)
+
+	DependencyUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "message_dependency_up",
+			Help: "Whether the last readiness check of a dependency of message service succeeded",
+		},
+		[]string{"dependency"},
+	)
+
+	DependencyLatency = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "message_dependency_check_latency_seconds",
+			Help: "Latency of the last readiness check of a dependency of message service",
+		},
+		[]string{"dependency"},
+	)
+
+	BreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "message_breaker_state",
+			Help: "State of the circuit breaker in front of a dependency of message service: 0 closed, 1 open, 2 half-open",
+		},
+		[]string{"dependency"},
+	)
+
+	CacheHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_cache_hits_total",
+			Help: "Cache hits of message service",
+		},
+		[]string{"backend"},
+	)
+
+	CacheMisses = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_cache_misses_total",
+			Help: "Cache misses of message service",
+		},
+		[]string{"backend"},
+	)
+
+	CacheErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_cache_errors_total",
+			Help: "Failed redis operations of message service cache",
+		},
+		[]string{"operation"},
+	)
+
+	RateLimited = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_rate_limited_total",
+			Help: "Requests to message service rejected by rate limiting",
+		},
+		[]string{"scope", "route"},
+	)
+
+	OutboxPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_outbox_published_total",
+			Help: "Events published from the outbox of message service by topic",
+		},
+		[]string{"topic"},
+	)
+
+	OutboxPublishFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_outbox_publish_failures_total",
+			Help: "Failed attempts to publish an event from the outbox of message service by topic",
+		},
+		[]string{"topic"},
+	)
+
+	OutboxParked = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_outbox_parked_total",
+			Help: "Events of message service parked in the outbox after running out of publish attempts by topic",
+		},
+		[]string{"topic"},
+	)
+
+	EventsConsumed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_events_consumed_total",
+			Help: "Events from other services consumed by message service by topic and result",
+		},
+		[]string{"topic", "result"},
+	)
+
+	EventsDeadLettered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_events_dead_lettered_total",
+			Help: "Events from other services parked in a dead-letter stream by message service after running out of deliveries by stream",
+		},
+		[]string{"stream"},
+	)
 )
@@ -0,0 +1,178 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces"
+	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/message/internal/pkg/breaker"
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/pkg/userpb"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errUserServiceCall is a call the user service rejected as invalid.
+var errUserServiceCall = errors.New("user service rejected the call")
+
+const (
+	CheAuthorPrefix = "messages:author:"
+
+	// maxBatch stays under the most ids the user service takes in one
+	// call.
+	maxBatch = 500
+)
+
+// Client looks up authors in the user service. Authors are cached, and so
+// are ids the user service doesn't know, for the cache's negative TTL.
+// Calls go through a circuit breaker, so an unavailable user service fails
+// fast instead of holding up every request for the timeout.
+type Client struct {
+	rpc     userpb.UserServiceClient
+	cache   interfaces.Cache
+	breaker *breaker.Breaker
+	cfg     config.UserServiceConfig
+	logger  *logrus.Logger
+}
+
+func NewClient(rpc userpb.UserServiceClient, cache interfaces.Cache, breaker *breaker.Breaker, cfg config.UserServiceConfig, logger *logrus.Logger) *Client {
+	return &Client{
+		rpc:     rpc,
+		cache:   cache,
+		breaker: breaker,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+func (c *Client) GetAuthors(ctx context.Context, userIds []int) (map[int]models.Author, error) {
+	authors := make(map[int]models.Author, len(userIds))
+
+	var ids []int
+	var keys []string
+	seen := make(map[int]bool, len(userIds))
+	for _, userId := range userIds {
+		// The user service has no users without a positive id, so there is
+		// no point asking.
+		if userId <= 0 || seen[userId] {
+			continue
+		}
+
+		seen[userId] = true
+		ids = append(ids, userId)
+		keys = append(keys, AuthorKey(userId))
+	}
+
+	cached := c.cache.GetMany(ctx, keys)
+
+	var misses []int
+	for i, userId := range ids {
+		value, ok := cached[keys[i]]
+		if !ok {
+			misses = append(misses, userId)
+			continue
+		}
+
+		if value == nil {
+			continue
+		}
+
+		var author models.Author
+		if err := json.Unmarshal(value, &author); err != nil {
+			misses = append(misses, userId)
+			continue
+		}
+
+		authors[userId] = author
+	}
+
+	for start := 0; start < len(misses); start += maxBatch {
+		batch := misses[start:min(start+maxBatch, len(misses))]
+		if err := c.load(ctx, batch, authors); err != nil {
+			return nil, err
+		}
+	}
+
+	return authors, nil
+}
+
+// load asks the user service for userIds and caches the answer.
+func (c *Client) load(ctx context.Context, userIds []int, authors map[int]models.Author) error {
+	if err := c.breaker.Allow(); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrUserServiceUnavailable, err)
+	}
+
+	ids := make([]int64, len(userIds))
+	for i, userId := range userIds {
+		ids[i] = int64(userId)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	resp, err := c.rpc.GetUsers(ctx, &userpb.GetUsersRequest{Ids: ids})
+	if err != nil {
+		outcome := classify(err)
+		if outcome == models.ErrUserServiceUnavailable {
+			c.breaker.Failure()
+		} else {
+			// The user service answered, it just didn't like the call.
+			c.breaker.Success()
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"module":  "users",
+			"func":    "load",
+			"userIds": userIds,
+			"error":   err.Error(),
+		}).Errorf("failed to get users: %v", err)
+
+		return fmt.Errorf("%w: %v", outcome, err)
+	}
+
+	c.breaker.Success()
+
+	for _, user := range resp.GetUsers() {
+		author := models.Author{
+			Id:          int(user.GetId()),
+			Username:    user.GetUsername(),
+			DisplayName: user.GetDisplayName(),
+			AvatarURL:   user.GetAvatarUrl(),
+		}
+		authors[author.Id] = author
+
+		if jsonData, err := json.Marshal(author); err == nil {
+			c.cache.Set(ctx, AuthorKey(author.Id), jsonData, c.cfg.CacheTTL)
+		}
+	}
+
+	for _, userId := range resp.GetMissing() {
+		c.cache.SetMissing(ctx, AuthorKey(int(userId)))
+	}
+
+	return nil
+}
+
+// classify tells why a call to the user service failed: it couldn't serve
+// the call at all, it refused the credentials of the message service, or
+// it rejected the call itself, which is a bug on this side.
+func classify(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return models.ErrUserServiceUnavailable
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return models.ErrUserServiceRejected
+	default:
+		return errUserServiceCall
+	}
+}
+
+// AuthorKey is the cache key of the author userId.
+func AuthorKey(userId int) string {
+	return fmt.Sprintf(CheAuthorPrefix+"%d", userId)
+}
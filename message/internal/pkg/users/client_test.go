@@ -0,0 +1,223 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/message/internal/pkg/breaker"
+	"github.com/dmitriysta/messenger/message/internal/pkg/cache"
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
+	"github.com/dmitriysta/messenger/pkg/userpb"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testCacheConfig = config.CacheConfig{
+	TTL:           time.Hour,
+	NegativeTTL:   time.Minute,
+	LocalSize:     16,
+	LocalTTL:      time.Second,
+	RetryInterval: time.Second,
+	LoadTimeout:   time.Second,
+}
+
+var testUserServiceConfig = config.UserServiceConfig{
+	Timeout:  time.Second,
+	CacheTTL: 5 * time.Minute,
+}
+
+// fakeUsers answers GetUsers with a profile for every id it is asked
+// about, except those in missing, and records the ids of each call. With
+// err set it fails every call instead.
+type fakeUsers struct {
+	userpb.UserServiceClient
+
+	missing map[int64]bool
+	err     error
+	calls   [][]int64
+}
+
+func (f *fakeUsers) GetUsers(ctx context.Context, in *userpb.GetUsersRequest, opts ...grpc.CallOption) (*userpb.GetUsersResponse, error) {
+	f.calls = append(f.calls, in.GetIds())
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	resp := &userpb.GetUsersResponse{}
+	for _, id := range in.GetIds() {
+		if f.missing[id] {
+			resp.Missing = append(resp.Missing, id)
+			continue
+		}
+
+		resp.Users = append(resp.Users, &userpb.Profile{Id: id, Username: fmt.Sprintf("user%d", id)})
+	}
+
+	return resp, nil
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func testClient(rpc userpb.UserServiceClient, redisClient *mocks.RedisClient, b *breaker.Breaker) *Client {
+	logger := testLogger()
+	return NewClient(rpc, cache.NewCache(redisClient, testCacheConfig, logger), b, testUserServiceConfig, logger)
+}
+
+// expectMGet makes the cache miss on the author keys of userIds, or return
+// values for them when given.
+func expectMGet(redisClient *mocks.RedisClient, userIds []int, values []interface{}) {
+	args := []interface{}{mock.Anything}
+	for _, userId := range userIds {
+		args = append(args, AuthorKey(userId))
+	}
+
+	if values == nil {
+		values = make([]interface{}, len(userIds))
+	}
+
+	redisClient.On("MGet", args...).Return(redis.NewSliceResult(values, nil)).Once()
+}
+
+func TestClient_GetAuthors_Batches(t *testing.T) {
+	redisClient := new(mocks.RedisClient)
+	rpc := &fakeUsers{}
+	client := testClient(rpc, redisClient, breaker.New(5, time.Minute, nil))
+
+	userIds := make([]int, maxBatch+1)
+	for i := range userIds {
+		userIds[i] = i + 1
+	}
+
+	expectMGet(redisClient, userIds, nil)
+	redisClient.On("Set", mock.Anything, mock.Anything, mock.Anything, testUserServiceConfig.CacheTTL).
+		Return(redis.NewStatusResult("", nil))
+
+	authors, err := client.GetAuthors(context.Background(), userIds)
+
+	assert.NoError(t, err)
+	assert.Len(t, authors, maxBatch+1)
+	if assert.Len(t, rpc.calls, 2) {
+		assert.Len(t, rpc.calls[0], maxBatch)
+		assert.Equal(t, []int64{maxBatch + 1}, rpc.calls[1])
+	}
+	redisClient.AssertNumberOfCalls(t, "Set", maxBatch+1)
+}
+
+func TestClient_GetAuthors_SkipsInvalidAndDuplicateIds(t *testing.T) {
+	redisClient := new(mocks.RedisClient)
+	rpc := &fakeUsers{}
+	client := testClient(rpc, redisClient, breaker.New(5, time.Minute, nil))
+
+	expectMGet(redisClient, []int{2, 1}, nil)
+	redisClient.On("Set", mock.Anything, mock.Anything, mock.Anything, testUserServiceConfig.CacheTTL).
+		Return(redis.NewStatusResult("", nil))
+
+	authors, err := client.GetAuthors(context.Background(), []int{2, 0, 1, -3, 2, 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, authors, 2)
+	assert.Equal(t, [][]int64{{2, 1}}, rpc.calls)
+}
+
+func TestClient_GetAuthors_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	redisClient := new(mocks.RedisClient)
+	rpc := &fakeUsers{missing: map[int64]bool{2: true}}
+	client := testClient(rpc, redisClient, breaker.New(5, time.Minute, nil))
+
+	author, err := json.Marshal(models.Author{Id: 1, Username: "user1"})
+	assert.NoError(t, err)
+
+	// Author 2 is unknown to the user service and gets a tombstone.
+	expectMGet(redisClient, []int{1, 2}, nil)
+	redisClient.On("Set", mock.Anything, AuthorKey(1), author, testUserServiceConfig.CacheTTL).
+		Return(redis.NewStatusResult("", nil)).Once()
+	redisClient.On("Set", mock.Anything, AuthorKey(2), []byte("\x00"), testCacheConfig.NegativeTTL).
+		Return(redis.NewStatusResult("", nil)).Once()
+
+	authors, err := client.GetAuthors(ctx, []int{1, 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]models.Author{1: {Id: 1, Username: "user1"}}, authors)
+
+	// The next lookup finds both in the cache and doesn't call the user
+	// service again.
+	expectMGet(redisClient, []int{1, 2}, []interface{}{string(author), "\x00"})
+
+	authors, err = client.GetAuthors(ctx, []int{1, 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]models.Author{1: {Id: 1, Username: "user1"}}, authors)
+	assert.Len(t, rpc.calls, 1)
+	redisClient.AssertExpectations(t)
+}
+
+func TestClient_GetAuthors_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     codes.Code
+		wantErr  error
+		wantOpen bool
+	}{
+		{name: "unavailable", code: codes.Unavailable, wantErr: models.ErrUserServiceUnavailable, wantOpen: true},
+		{name: "deadline exceeded", code: codes.DeadlineExceeded, wantErr: models.ErrUserServiceUnavailable, wantOpen: true},
+		{name: "resource exhausted", code: codes.ResourceExhausted, wantErr: models.ErrUserServiceUnavailable, wantOpen: true},
+		{name: "internal", code: codes.Internal, wantErr: models.ErrUserServiceUnavailable, wantOpen: true},
+		{name: "unknown", code: codes.Unknown, wantErr: models.ErrUserServiceUnavailable, wantOpen: true},
+		{name: "unauthenticated", code: codes.Unauthenticated, wantErr: models.ErrUserServiceRejected},
+		{name: "permission denied", code: codes.PermissionDenied, wantErr: models.ErrUserServiceRejected},
+		{name: "invalid argument", code: codes.InvalidArgument, wantErr: errUserServiceCall},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisClient := new(mocks.RedisClient)
+			rpc := &fakeUsers{err: status.Error(tt.code, "failed")}
+			b := breaker.New(1, time.Minute, nil)
+			client := testClient(rpc, redisClient, b)
+
+			expectMGet(redisClient, []int{1}, nil)
+
+			_, err := client.GetAuthors(context.Background(), []int{1})
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantOpen {
+				assert.Equal(t, breaker.StateOpen, b.State())
+			} else {
+				assert.Equal(t, breaker.StateClosed, b.State())
+				assert.NotErrorIs(t, err, models.ErrUserServiceUnavailable)
+			}
+		})
+	}
+}
+
+func TestClient_GetAuthors_BreakerOpen(t *testing.T) {
+	redisClient := new(mocks.RedisClient)
+	rpc := &fakeUsers{}
+	b := breaker.New(1, time.Minute, nil)
+	b.Failure()
+	client := testClient(rpc, redisClient, b)
+
+	expectMGet(redisClient, []int{1}, nil)
+
+	_, err := client.GetAuthors(context.Background(), []int{1})
+
+	assert.ErrorIs(t, err, models.ErrUserServiceUnavailable)
+	assert.Empty(t, rpc.calls)
+}
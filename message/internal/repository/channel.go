@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ChannelRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewChannelRepository(db *sql.DB, logger *logrus.Logger) *ChannelRepository {
+	return &ChannelRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *ChannelRepository) IsChannelMember(ctx context.Context, channelId, userId int) (bool, error) {
+	var isMember bool
+	query := `SELECT EXISTS (SELECT 1 FROM channel_member WHERE channel_id = $1 AND user_id = $2)`
+	err := r.db.QueryRowContext(ctx, query, channelId, userId).Scan(&isMember)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "channel",
+			"func":      "IsChannelMember",
+			"error":     err.Error(),
+			"channelId": channelId,
+			"userId":    userId,
+		}).Errorf("failed to check channel membership: %v", err)
+
+		return false, err
+	}
+
+	return isMember, nil
+}
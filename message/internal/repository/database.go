@@ -2,24 +2,15 @@ package repository
 
 import (
 	"database/sql"
-	"fmt"
-	"os"
+
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
 
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
-func DatabaseConnect(logger *logrus.Logger) *sql.DB {
-
-	host := os.Getenv("DB_HOST")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	port := os.Getenv("DB_PORT")
-
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, user, password, dbname, port)
-
-	db, err := sql.Open("postgres", dsn)
+func DatabaseConnect(cfg config.DatabaseConfig, logger *logrus.Logger) *sql.DB {
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"module": "repository",
@@ -3,12 +3,22 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/dmitriysta/messenger/message/internal/models"
 
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+const messageColumns = `id, user_id, channel_id, content, ref_kind, ref_message_id, ref_channel_id, ref_user_id, ref_content, ref_deleted, version, created_at, updated_at, deleted_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Messages with deleted_at set were hidden when their author's account was
+// deleted. They stay in the table but no read or write sees them.
 type MessageRepository struct {
 	db     *sql.DB
 	logger *logrus.Logger
@@ -32,8 +42,46 @@ func (r *MessageRepository) CreateMessage(ctx context.Context, message *models.M
 		return err
 	}
 
-	query := `INSERT INTO message (user_id, channel_id, content, created_at) VALUES ($1, $2, $3, $4) RETURNING id`
-	err := r.db.QueryRowContext(ctx, query, message.UserID, message.ChannelID, message.Content, message.CreatedAt).Scan(&message.Id)
+	var refKind sql.NullString
+	var refMessageId, refChannelId, refUserId sql.NullInt64
+	var refContent sql.NullString
+	if ref := message.Reference; ref != nil {
+		refKind = sql.NullString{String: ref.Kind, Valid: true}
+		refMessageId = sql.NullInt64{Int64: int64(ref.MessageID), Valid: true}
+		refChannelId = sql.NullInt64{Int64: int64(ref.ChannelID), Valid: true}
+		refUserId = sql.NullInt64{Int64: int64(ref.UserID), Valid: true}
+		refContent = sql.NullString{String: ref.Content, Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   "CreateMessage",
+			"error":  err.Error(),
+		}).Errorf("failed to begin transaction: %v", err)
+
+		return err
+	}
+	defer tx.Rollback()
+
+	deleted, err := lockUser(ctx, tx, message.UserID)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   "CreateMessage",
+			"error":  err.Error(),
+			"userId": message.UserID,
+		}).Errorf("failed to check author: %v", err)
+
+		return err
+	}
+	if deleted {
+		return models.ErrUnknownAuthor
+	}
+
+	query := `INSERT INTO message (user_id, channel_id, content, ref_kind, ref_message_id, ref_channel_id, ref_user_id, ref_content, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, version`
+	err = tx.QueryRowContext(ctx, query, message.UserID, message.ChannelID, message.Content, refKind, refMessageId, refChannelId, refUserId, refContent, message.CreatedAt).Scan(&message.Id, &message.Version)
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module": "message",
@@ -44,12 +92,33 @@ func (r *MessageRepository) CreateMessage(ctx context.Context, message *models.M
 		return err
 	}
 
+	if err := insertOutboxEvent(ctx, tx, models.TopicMessageCreated, message.Id, models.NewMessageEvent(message)); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "CreateMessage",
+			"error":     err.Error(),
+			"messageId": message.Id,
+		}).Errorf("failed to record message event: %v", err)
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   "CreateMessage",
+			"error":  err.Error(),
+		}).Errorf("failed to commit transaction: %v", err)
+
+		return err
+	}
+
 	return nil
 }
 
 func (r *MessageRepository) GetMessagesByChannelId(ctx context.Context, channelId int) ([]models.Message, error) {
 	var messages []models.Message
-	query := `SELECT * FROM message WHERE channel_id = $1`
+	query := `SELECT ` + messageColumns + ` FROM message WHERE channel_id = $1 AND deleted_at IS NULL`
 	rows, err := r.db.QueryContext(ctx, query, channelId)
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
@@ -63,8 +132,7 @@ func (r *MessageRepository) GetMessagesByChannelId(ctx context.Context, channelI
 	defer rows.Close()
 
 	for rows.Next() {
-		var message models.Message
-		err := rows.Scan(&message.Id, &message.UserID, &message.ChannelID, &message.Content, &message.CreatedAt, &message.UpdatedAt, &message.DeletedAt)
+		message, err := scanMessage(rows)
 		if err != nil {
 			r.logger.WithFields(logrus.Fields{
 				"module":    "message",
@@ -75,7 +143,7 @@ func (r *MessageRepository) GetMessagesByChannelId(ctx context.Context, channelI
 			return nil, err
 		}
 
-		messages = append(messages, message)
+		messages = append(messages, *message)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -92,9 +160,8 @@ func (r *MessageRepository) GetMessagesByChannelId(ctx context.Context, channelI
 }
 
 func (r *MessageRepository) GetMessageById(ctx context.Context, messageId int) (*models.Message, error) {
-	var message models.Message
-	query := `SELECT * FROM message WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, messageId).Scan(&message.Id, &message.UserID, &message.ChannelID, &message.Content, &message.CreatedAt, &message.UpdatedAt, &message.DeletedAt)
+	query := `SELECT ` + messageColumns + ` FROM message WHERE id = $1 AND deleted_at IS NULL`
+	message, err := scanMessage(r.db.QueryRowContext(ctx, query, messageId))
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module":    "message",
@@ -106,7 +173,7 @@ func (r *MessageRepository) GetMessageById(ctx context.Context, messageId int) (
 		return nil, err
 	}
 
-	return &message, nil
+	return message, nil
 }
 
 func (r *MessageRepository) UpdateMessage(ctx context.Context, message *models.Message) error {
@@ -120,8 +187,33 @@ func (r *MessageRepository) UpdateMessage(ctx context.Context, message *models.M
 		return err
 	}
 
-	query := `UPDATE message SET user_id = $1, channel_id = $2, content = $3, updated_at = $4 WHERE id = $5`
-	_, err := r.db.ExecContext(ctx, query, message.UserID, message.ChannelID, message.Content, message.UpdatedAt, message.Id)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "UpdateMessage",
+			"error":     err.Error(),
+			"messageId": message.Id,
+		}).Errorf("failed to begin transaction: %v", err)
+
+		return err
+	}
+	defer tx.Rollback()
+
+	// The version check turns a lost update into an explicit conflict: if
+	// someone else wrote the row since the caller read it, nothing matches.
+	query := `UPDATE message SET user_id = $1, channel_id = $2, content = $3, updated_at = $4, version = version + 1 WHERE id = $5 AND version = $6 AND deleted_at IS NULL RETURNING version`
+	err = tx.QueryRowContext(ctx, query, message.UserID, message.ChannelID, message.Content, message.UpdatedAt, message.Id, message.Version).Scan(&message.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "UpdateMessage",
+			"messageId": message.Id,
+			"version":   message.Version,
+		}).Warn("message version conflict")
+
+		return models.ErrVersionConflict
+	}
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module": "message",
@@ -132,12 +224,55 @@ func (r *MessageRepository) UpdateMessage(ctx context.Context, message *models.M
 		return err
 	}
 
+	if err := insertOutboxEvent(ctx, tx, models.TopicMessageUpdated, message.Id, models.NewMessageEvent(message)); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "UpdateMessage",
+			"error":     err.Error(),
+			"messageId": message.Id,
+		}).Errorf("failed to record message event: %v", err)
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "UpdateMessage",
+			"error":     err.Error(),
+			"messageId": message.Id,
+		}).Errorf("failed to commit transaction: %v", err)
+
+		return err
+	}
+
 	return nil
 }
 
-func (r *MessageRepository) DeleteMessage(ctx context.Context, messageId int) error {
-	query := `DELETE FROM message WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, messageId)
+// DeleteMessage deletes messageId and marks its forwards and quotes as
+// referring to a deleted message. It returns those forwards and quotes as
+// they are now, with their version bumped, so that an If-Match taken
+// before the delete no longer holds for them.
+func (r *MessageRepository) DeleteMessage(ctx context.Context, messageId int) ([]models.Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "DeleteMessage",
+			"error":     err.Error(),
+			"messageId": messageId,
+		}).Errorf("failed to begin transaction: %v", err)
+
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := models.MessageDeletedEvent{MessageId: messageId}
+	query := `DELETE FROM message WHERE id = $1 RETURNING channel_id, user_id`
+	err = tx.QueryRowContext(ctx, query, messageId).Scan(&deleted.ChannelId, &deleted.UserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module":    "message",
@@ -146,8 +281,140 @@ func (r *MessageRepository) DeleteMessage(ctx context.Context, messageId int) er
 			"messageId": messageId,
 		}).Errorf("failed to delete message: %v", err)
 
+		return nil, err
+	}
+
+	query = `UPDATE message SET ref_content = '', ref_deleted = TRUE, version = version + 1 WHERE ref_message_id = $1 RETURNING ` + messageColumns
+	rows, err := tx.QueryContext(ctx, query, messageId)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "DeleteMessage",
+			"error":     err.Error(),
+			"messageId": messageId,
+		}).Errorf("failed to mark references as deleted: %v", err)
+
+		return nil, err
+	}
+
+	references, err := r.scanMessages(rows, "DeleteMessage")
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertOutboxEvent(ctx, tx, models.TopicMessageDeleted, messageId, deleted); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "DeleteMessage",
+			"error":     err.Error(),
+			"messageId": messageId,
+		}).Errorf("failed to record message event: %v", err)
+
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "DeleteMessage",
+			"error":     err.Error(),
+			"messageId": messageId,
+		}).Errorf("failed to commit transaction: %v", err)
+
+		return nil, err
+	}
+
+	return references, nil
+}
+
+// GetMessagesByIds returns the messages with the given ids in one query. Ids
+// that don't exist are left out, and the order of the result is undefined.
+func (r *MessageRepository) GetMessagesByIds(ctx context.Context, messageIds []int) ([]models.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM message WHERE id = ANY($1) AND deleted_at IS NULL`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(messageIds))
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module":     "message",
+			"func":       "GetMessagesByIds",
+			"error":      err.Error(),
+			"messageIds": messageIds,
+		}).Errorf("failed to get messages by ids: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanMessages(rows, "GetMessagesByIds")
+}
+
+func (r *MessageRepository) scanMessages(rows *sql.Rows, caller string) ([]models.Message, error) {
+	var messages []models.Message
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			r.logger.WithFields(logrus.Fields{
+				"module": "message",
+				"func":   caller,
+				"error":  err.Error(),
+			}).Errorf("failed to scan message: %v", err)
+			return nil, err
+		}
+
+		messages = append(messages, *message)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   caller,
+			"error":  err.Error(),
+		}).Errorf("failed to read messages: %v", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// insertOutboxEvent records an event about messageId in the outbox as part
+// of tx, the transaction that made the change.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, topic string, messageId int, payload interface{}) error {
+	event, err := models.NewOutboxEvent(topic, messageId, payload)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	query := `INSERT INTO message_outbox (topic, key, payload) VALUES ($1, $2, $3)`
+	_, err = tx.ExecContext(ctx, query, event.Topic, event.Key, string(event.Payload))
+	return err
+}
+
+func scanMessage(row rowScanner) (*models.Message, error) {
+	var message models.Message
+	var refKind, refContent sql.NullString
+	var refMessageId, refChannelId, refUserId sql.NullInt64
+	var refDeleted sql.NullBool
+	var updatedAt, deletedAt sql.NullTime
+
+	err := row.Scan(&message.Id, &message.UserID, &message.ChannelID, &message.Content,
+		&refKind, &refMessageId, &refChannelId, &refUserId, &refContent, &refDeleted,
+		&message.Version, &message.CreatedAt, &updatedAt, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if refKind.Valid {
+		message.Reference = &models.MessageReference{
+			Kind:      refKind.String,
+			MessageID: int(refMessageId.Int64),
+			ChannelID: int(refChannelId.Int64),
+			UserID:    int(refUserId.Int64),
+			Content:   refContent.String,
+			Deleted:   refDeleted.Bool,
+		}
+	}
+
+	message.UpdatedAt = updatedAt.Time
+	message.DeletedAt = deletedAt.Time
+
+	return &message, nil
 }
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dmitriysta/messenger/message/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRepository_DeleteMessage_BumpsReferences(t *testing.T) {
+	f := newUserDataFixture(t)
+	ctx := context.Background()
+	source := f.own[0]
+
+	references, err := f.messages.DeleteMessage(ctx, source.Id)
+	require.NoError(t, err)
+
+	require.Len(t, references, 1)
+	quote := references[0]
+	assert.Equal(t, f.quote.Id, quote.Id)
+	assert.Equal(t, f.quote.ChannelID, quote.ChannelID)
+	// An If-Match taken on the quote before the delete no longer holds.
+	assert.Equal(t, f.quote.Version+1, quote.Version)
+	assert.True(t, quote.Reference.Deleted)
+	assert.Empty(t, quote.Reference.Content)
+
+	stale := *f.quote
+	stale.Content = "edited"
+	assert.ErrorIs(t, f.messages.UpdateMessage(ctx, &stale), models.ErrVersionConflict)
+
+	// Deleting it again changes nothing.
+	references, err = f.messages.DeleteMessage(ctx, source.Id)
+	require.NoError(t, err)
+	assert.Empty(t, references)
+}
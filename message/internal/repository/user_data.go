@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dmitriysta/messenger/message/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// UserDataRepository removes what the message service keeps about users
+// whose account was deleted in the user service.
+type UserDataRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewUserDataRepository(db *sql.DB, logger *logrus.Logger) *UserDataRepository {
+	return &UserDataRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// userLockClass is the class of the advisory locks taken on user ids, which
+// keeps them apart from other advisory locks on the database.
+const userLockClass = 1
+
+// Deleting the data of a user takes several calls, each in a transaction
+// of its own, so that no transaction grows with the number of messages of
+// the user. MarkUserDeleted comes first, DeleteUserMessages is repeated
+// until it changes nothing, and FinishUserDeletion records the event as
+// processed. Every step can be repeated, so an event that fails half way
+// is simply run again on redelivery, and two instances working on the same
+// event don't get in each other's way.
+
+// MarkUserDeleted records userId as deleted. From then on CreateMessage
+// refuses messages from the user, and every message that got in before is
+// committed, so DeleteUserMessages finds it.
+func (r *UserDataRepository) MarkUserDeleted(ctx context.Context, userId int) error {
+	return r.inTx(ctx, "MarkUserDeleted", userId, func(tx *sql.Tx) error {
+		// CreateMessage holds the lock shared while it writes a message,
+		// so taking it exclusively waits for messages in flight.
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, userLockClass, userId); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO deleted_user (user_id) VALUES ($1) ON CONFLICT DO NOTHING`, userId)
+		return err
+	})
+}
+
+// DeleteUserMessages applies policy to at most limit messages of userId and
+// to at most limit forwards and quotes of them. Changed messages get their
+// events in the outbox like any other change. It returns what it changed,
+// and nothing once no message of the user is left to change.
+func (r *UserDataRepository) DeleteUserMessages(ctx context.Context, userId int, policy string, limit int) (*models.UserDataDeletion, error) {
+	deletion := &models.UserDataDeletion{}
+	err := r.inTx(ctx, "DeleteUserMessages", userId, func(tx *sql.Tx) error {
+		switch policy {
+		case models.DeletedUserAnonymise:
+			return anonymiseMessages(ctx, tx, userId, limit, deletion)
+		case models.DeletedUserSoftDelete:
+			return hideMessages(ctx, tx, userId, limit, deletion)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+// FinishUserDeletion removes userId from every channel and records the event
+// eventId of topic as processed, and returns the channels the user left. A
+// redelivered event fails with ErrEventProcessed.
+func (r *UserDataRepository) FinishUserDeletion(ctx context.Context, topic, eventId string, userId int) ([]int, error) {
+	var channelIds []int
+	err := r.inTx(ctx, "FinishUserDeletion", userId, func(tx *sql.Tx) error {
+		query := `INSERT INTO processed_event (topic, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		result, err := tx.ExecContext(ctx, query, topic, eventId)
+		if err != nil {
+			return err
+		}
+		if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+			return models.ErrEventProcessed
+		}
+
+		channelIds, err = queryIds(ctx, tx, `DELETE FROM channel_member WHERE user_id = $1 RETURNING channel_id`, userId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return channelIds, nil
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func (r *UserDataRepository) inTx(ctx context.Context, caller string, userId int, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "userdata",
+			"func":   caller,
+			"error":  err.Error(),
+			"userId": userId,
+		}).Errorf("failed to begin transaction: %v", err)
+
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		if !errors.Is(err, models.ErrEventProcessed) {
+			r.logger.WithFields(logrus.Fields{
+				"module": "userdata",
+				"func":   caller,
+				"error":  err.Error(),
+				"userId": userId,
+			}).Errorf("failed to delete user data: %v", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "userdata",
+			"func":   caller,
+			"error":  err.Error(),
+			"userId": userId,
+		}).Errorf("failed to commit transaction: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// lockUser tells whether userId was deleted, holding a shared lock on it
+// until tx ends so MarkUserDeleted waits for tx to write its messages.
+func lockUser(ctx context.Context, tx *sql.Tx, userId int) (bool, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock_shared($1, $2)`, userLockClass, userId); err != nil {
+		return false, err
+	}
+
+	// The lock may have waited for a deletion to commit, so the check
+	// needs a statement, and a snapshot, of its own.
+	var deleted bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM deleted_user WHERE user_id = $1)`, userId).Scan(&deleted)
+	return deleted, err
+}
+
+// anonymiseMessages hands up to limit messages of userId over to
+// DeletedUserId, and the snapshots of them in up to limit forwards and
+// quotes too.
+func anonymiseMessages(ctx context.Context, tx *sql.Tx, userId, limit int, deletion *models.UserDataDeletion) error {
+	query := `UPDATE message SET user_id = $1, updated_at = $2, version = version + 1
+		WHERE id IN (SELECT id FROM message WHERE user_id = $3 AND deleted_at IS NULL ORDER BY id LIMIT $4 FOR UPDATE)
+		RETURNING ` + messageColumns
+	rows, err := tx.QueryContext(ctx, query, models.DeletedUserId, time.Now(), userId, limit)
+	if err != nil {
+		return err
+	}
+
+	var messages []models.Message
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		messages = append(messages, *message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range messages {
+		if err := insertOutboxEvent(ctx, tx, models.TopicMessageUpdated, messages[i].Id, models.NewMessageEvent(&messages[i])); err != nil {
+			return err
+		}
+
+		deletion.MessageIds = append(deletion.MessageIds, messages[i].Id)
+		deletion.ChannelIds = append(deletion.ChannelIds, messages[i].ChannelID)
+	}
+
+	query = `UPDATE message SET ref_user_id = $1, version = version + 1
+		WHERE id IN (SELECT id FROM message WHERE ref_user_id = $2 AND deleted_at IS NULL ORDER BY id LIMIT $3 FOR UPDATE)
+		RETURNING id, channel_id`
+	return collectReferences(ctx, tx, deletion, query, models.DeletedUserId, userId, limit)
+}
+
+// hideMessages soft-deletes up to limit messages of userId. Their forwards
+// and quotes render as deleted, as they do after a regular delete.
+func hideMessages(ctx context.Context, tx *sql.Tx, userId, limit int, deletion *models.UserDataDeletion) error {
+	query := `UPDATE message SET deleted_at = $1
+		WHERE id IN (SELECT id FROM message WHERE user_id = $2 AND deleted_at IS NULL ORDER BY id LIMIT $3 FOR UPDATE)
+		RETURNING id, channel_id`
+	rows, err := tx.QueryContext(ctx, query, time.Now(), userId, limit)
+	if err != nil {
+		return err
+	}
+
+	var hidden []models.MessageDeletedEvent
+	for rows.Next() {
+		event := models.MessageDeletedEvent{UserId: userId}
+		if err := rows.Scan(&event.MessageId, &event.ChannelId); err != nil {
+			rows.Close()
+			return err
+		}
+
+		hidden = append(hidden, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, event := range hidden {
+		if err := insertOutboxEvent(ctx, tx, models.TopicMessageDeleted, event.MessageId, event); err != nil {
+			return err
+		}
+
+		deletion.MessageIds = append(deletion.MessageIds, event.MessageId)
+		deletion.ChannelIds = append(deletion.ChannelIds, event.ChannelId)
+	}
+
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	query = `UPDATE message SET ref_content = '', ref_deleted = TRUE, version = version + 1 WHERE ref_message_id = ANY($1) AND deleted_at IS NULL RETURNING id, channel_id`
+	return collectReferences(ctx, tx, deletion, query, pq.Array(deletion.MessageIds))
+}
+
+// collectReferences runs query, which returns the id and channel id of
+// the forwards and quotes it changed, and adds them to deletion. A changed
+// snapshot is a new version of the forward or quote, so query has to bump
+// it.
+func collectReferences(ctx context.Context, tx *sql.Tx, deletion *models.UserDataDeletion, query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var referenceId, channelId int
+		if err := rows.Scan(&referenceId, &channelId); err != nil {
+			return err
+		}
+
+		deletion.ReferenceIds = append(deletion.ReferenceIds, referenceId)
+		deletion.ChannelIds = append(deletion.ChannelIds, channelId)
+	}
+
+	return rows.Err()
+}
+
+func queryIds(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/message/internal/pkg/database"
+	"github.com/dmitriysta/messenger/pkg/migrate"
+	"github.com/dmitriysta/messenger/pkg/pgtest"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userDataFixture is user 7 with three messages in channels 10 and 11, one
+// of them quoted by user 8, and memberships of channels 10 and 11.
+type userDataFixture struct {
+	db       *sql.DB
+	repo     *UserDataRepository
+	messages *MessageRepository
+	own      []*models.Message
+	quote    *models.Message
+}
+
+func newUserDataFixture(t *testing.T) *userDataFixture {
+	ctx := context.Background()
+	logger, _ := test.NewNullLogger()
+
+	db := pgtest.DB(t, "postgres")
+	migrator, err := migrate.NewMigrator(db, database.Migrations(), database.MigrationsTable, database.MigrationsLockId, logger)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(ctx))
+
+	f := &userDataFixture{
+		db:       db,
+		repo:     NewUserDataRepository(db, logger),
+		messages: NewMessageRepository(db, logger),
+	}
+
+	for _, channelId := range []int{10, 10, 11} {
+		message := models.NewMessage(7, channelId, "hello")
+		require.NoError(t, f.messages.CreateMessage(ctx, message))
+		f.own = append(f.own, message)
+	}
+
+	f.quote = models.NewReferenceMessage(models.ReferenceKindQuote, 8, 12, "quoting", f.own[0])
+	require.NoError(t, f.messages.CreateMessage(ctx, f.quote))
+
+	_, err = db.Exec(`INSERT INTO channel_member (channel_id, user_id) VALUES (10, 7), (11, 7), (10, 8)`)
+	require.NoError(t, err)
+
+	return f
+}
+
+// deleteAll runs DeleteUserMessages until it changes nothing and returns
+// what each batch changed.
+func (f *userDataFixture) deleteAll(t *testing.T, policy string, limit int) []*models.UserDataDeletion {
+	var batches []*models.UserDataDeletion
+	for {
+		deletion, err := f.repo.DeleteUserMessages(context.Background(), 7, policy, limit)
+		require.NoError(t, err)
+		if len(deletion.MessageIds) == 0 && len(deletion.ReferenceIds) == 0 {
+			return batches
+		}
+
+		batches = append(batches, deletion)
+		require.Less(t, len(batches), 10, "DeleteUserMessages doesn't make progress")
+	}
+}
+
+func (f *userDataFixture) outboxTopics(t *testing.T) map[string]int {
+	rows, err := f.db.Query(`SELECT topic, COUNT(*) FROM message_outbox GROUP BY topic`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	topics := map[string]int{}
+	for rows.Next() {
+		var topic string
+		var count int
+		require.NoError(t, rows.Scan(&topic, &count))
+		topics[topic] = count
+	}
+	require.NoError(t, rows.Err())
+
+	return topics
+}
+
+func TestUserDataRepository_Anonymise(t *testing.T) {
+	f := newUserDataFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.repo.MarkUserDeleted(ctx, 7))
+	batches := f.deleteAll(t, models.DeletedUserAnonymise, 2)
+
+	// Two messages and the quote, then the last message.
+	require.Len(t, batches, 2)
+	assert.ElementsMatch(t, []int{f.own[0].Id, f.own[1].Id}, batches[0].MessageIds)
+	assert.Equal(t, []int{f.quote.Id}, batches[0].ReferenceIds)
+	assert.Equal(t, []int{f.own[2].Id}, batches[1].MessageIds)
+	assert.Empty(t, batches[1].ReferenceIds)
+
+	for _, own := range f.own {
+		message, err := f.messages.GetMessageById(ctx, own.Id)
+		require.NoError(t, err)
+		assert.Equal(t, models.DeletedUserId, message.UserID)
+		assert.Equal(t, own.Version+1, message.Version)
+	}
+
+	quote, err := f.messages.GetMessageById(ctx, f.quote.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeletedUserId, quote.Reference.UserID)
+	assert.Equal(t, "hello", quote.Reference.Content)
+	assert.Equal(t, f.quote.Version+1, quote.Version)
+
+	assert.Equal(t, map[string]int{models.TopicMessageCreated: 4, models.TopicMessageUpdated: 3}, f.outboxTopics(t))
+}
+
+func TestUserDataRepository_SoftDelete(t *testing.T) {
+	f := newUserDataFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.repo.MarkUserDeleted(ctx, 7))
+	batches := f.deleteAll(t, models.DeletedUserSoftDelete, 2)
+
+	require.Len(t, batches, 2)
+	assert.ElementsMatch(t, []int{f.own[0].Id, f.own[1].Id}, batches[0].MessageIds)
+	assert.Equal(t, []int{f.quote.Id}, batches[0].ReferenceIds)
+	assert.Equal(t, []int{f.own[2].Id}, batches[1].MessageIds)
+
+	for _, own := range f.own {
+		_, err := f.messages.GetMessageById(ctx, own.Id)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	}
+
+	quote, err := f.messages.GetMessageById(ctx, f.quote.Id)
+	require.NoError(t, err)
+	assert.True(t, quote.Reference.Deleted)
+	assert.Empty(t, quote.Reference.Content)
+	assert.Equal(t, f.quote.Version+1, quote.Version)
+
+	assert.Equal(t, map[string]int{models.TopicMessageCreated: 4, models.TopicMessageDeleted: 3}, f.outboxTopics(t))
+}
+
+func TestUserDataRepository_Keep(t *testing.T) {
+	f := newUserDataFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.repo.MarkUserDeleted(ctx, 7))
+	assert.Empty(t, f.deleteAll(t, models.DeletedUserKeep, 2))
+
+	message, err := f.messages.GetMessageById(ctx, f.own[0].Id)
+	require.NoError(t, err)
+	assert.Equal(t, 7, message.UserID)
+}
+
+func TestUserDataRepository_RefusesMessagesOfDeletedUsers(t *testing.T) {
+	f := newUserDataFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.repo.MarkUserDeleted(ctx, 7))
+	// Marking twice, as a redelivered event does, is fine.
+	require.NoError(t, f.repo.MarkUserDeleted(ctx, 7))
+
+	err := f.messages.CreateMessage(ctx, models.NewMessage(7, 10, "too late"))
+	assert.ErrorIs(t, err, models.ErrUnknownAuthor)
+
+	assert.NoError(t, f.messages.CreateMessage(ctx, models.NewMessage(8, 10, "still here")))
+}
+
+func TestUserDataRepository_FinishUserDeletion(t *testing.T) {
+	f := newUserDataFixture(t)
+	ctx := context.Background()
+
+	channelIds, err := f.repo.FinishUserDeletion(ctx, "user.deleted", "42", 7)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{10, 11}, channelIds)
+
+	var members int
+	require.NoError(t, f.db.QueryRow(`SELECT COUNT(*) FROM channel_member WHERE user_id = 7`).Scan(&members))
+	assert.Zero(t, members)
+
+	_, err = f.repo.FinishUserDeletion(ctx, "user.deleted", "42", 7)
+	assert.ErrorIs(t, err, models.ErrEventProcessed)
+}
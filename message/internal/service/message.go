@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+
 	"github.com/dmitriysta/messenger/message/internal/interfaces"
 	"github.com/dmitriysta/messenger/message/internal/models"
 	"github.com/dmitriysta/messenger/message/internal/pkg/cache"
@@ -13,23 +19,114 @@ import (
 
 const (
 	CheMessageChannelPrefix = "messages:channel:"
+	CheMessagePrefix        = "messages:message:"
+	CheIdempotencyPrefix    = "messages:idempotency:"
+
+	cheVersionSuffix = ":version"
+
+	idempotencyPending = "pending"
 )
 
 type MessageService struct {
-	repo   interfaces.MessageRepository
-	logger *logrus.Logger
-	cache  interfaces.RedisClient
+	repo     interfaces.MessageRepository
+	channels interfaces.ChannelRepository
+	users    interfaces.UserClient
+	logger   *logrus.Logger
+	cache    interfaces.Cache
+	failOpen bool
 }
 
-func NewMessageService(repo interfaces.MessageRepository, logger *logrus.Logger, cache interfaces.RedisClient) *MessageService {
+// NewMessageService returns the message service. failOpen lets messages
+// through when the user service can't confirm their author exists.
+func NewMessageService(repo interfaces.MessageRepository, channels interfaces.ChannelRepository, users interfaces.UserClient, logger *logrus.Logger, cache interfaces.Cache, failOpen bool) *MessageService {
 	return &MessageService{
-		repo:   repo,
-		logger: logger,
-		cache:  cache,
+		repo:     repo,
+		channels: channels,
+		users:    users,
+		logger:   logger,
+		cache:    cache,
+		failOpen: failOpen,
+	}
+}
+
+// CreateMessage stores a new message by userId, who has to exist in the
+// user service. When idempotencyKey is set, retries of the same request by
+// the same user return the originally created message instead of inserting
+// a duplicate.
+func (s *MessageService) CreateMessage(ctx context.Context, userId, channelId int, content, idempotencyKey string) (*models.Message, error) {
+	if err := s.checkAuthor(ctx, "CreateMessage", userId); err != nil {
+		return nil, err
+	}
+
+	if idempotencyKey == "" {
+		return s.createMessage(ctx, userId, channelId, content)
+	}
+
+	key := fmt.Sprintf(CheIdempotencyPrefix+"%d:%s", userId, idempotencyKey)
+	request := idempotencyRequest(channelId, content)
+
+	// The reservation only has to last as long as this request. Should the
+	// request die before it is settled, retries get through again soon
+	// rather than being told it is in progress for a day.
+	reserved, err := s.cache.SetNX(ctx, key, []byte(idempotencyPending), cache.IdempotencyPendingTTL)
+	if err != nil {
+		// Without the cache we can't deduplicate, but refusing to create the
+		// message would be worse than a possible duplicate.
+		s.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   "CreateMessage",
+			"error":  err.Error(),
+			"userId": userId,
+		}).Warnf("failed to reserve idempotency key: %v", err)
+
+		return s.createMessage(ctx, userId, channelId, content)
+	}
+
+	if !reserved {
+		return s.replayMessage(ctx, key, request)
+	}
+
+	message, err := s.createMessage(ctx, userId, channelId, content)
+	if err != nil {
+		s.cache.Del(ctx, key)
+		return nil, err
+	}
+
+	value, err := json.Marshal(idempotencyRecord{MessageId: message.Id, Request: request})
+	if err == nil {
+		err = s.cache.SetShared(ctx, key, value, cache.IdempotencyTTL)
+	}
+	if err != nil {
+		// The message exists, so the request succeeded; only a retry of it
+		// may now create a duplicate once the reservation runs out.
+		s.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "CreateMessage",
+			"error":     err.Error(),
+			"userId":    userId,
+			"messageId": message.Id,
+		}).Warnf("failed to record idempotency key: %v", err)
 	}
+
+	return message, nil
+}
+
+// idempotencyRecord is what an idempotency key maps to once its request
+// created a message: the message, and a digest of the request that created
+// it, to tell retries from a different request under the same key.
+type idempotencyRecord struct {
+	MessageId int    `json:"messageId"`
+	Request   string `json:"request"`
+}
+
+// idempotencyRequest digests the parts of a create request that make it the
+// same request.
+func idempotencyRequest(channelId int, content string) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(channelId) + "\x00" + content))
+	return hex.EncodeToString(sum[:])
 }
 
-func (s *MessageService) CreateMessage(ctx context.Context, userId, channelId int, content string) (*models.Message, error) {
+func (s *MessageService) createMessage(ctx context.Context, userId, channelId int, content string) (*models.Message, error) {
 	message := models.NewMessage(userId, channelId, content)
 	if err := s.repo.CreateMessage(ctx, message); err != nil {
 		s.logger.WithFields(logrus.Fields{
@@ -41,73 +138,328 @@ func (s *MessageService) CreateMessage(ctx context.Context, userId, channelId in
 		return nil, err
 	}
 
-	key := fmt.Sprintf(CheMessageChannelPrefix+"%d", channelId)
-	s.cache.Del(ctx, key)
+	s.invalidateMessages(ctx, message.Id)
+	s.invalidateChannels(ctx, channelId)
+
+	return message, nil
+}
+
+// replayMessage returns the message created by an earlier request that used
+// the same idempotency key, as it is now.
+func (s *MessageService) replayMessage(ctx context.Context, key, request string) (*models.Message, error) {
+	value, ok := s.cache.Get(ctx, key)
+	if !ok {
+		// The key expired or redis went away between SetNX and Get; either
+		// way the original request can't be found, so treat it as running.
+		return nil, models.ErrIdempotencyInProgress
+	}
+
+	if string(value) == idempotencyPending {
+		return nil, models.ErrIdempotencyInProgress
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   "replayMessage",
+			"error":  err.Error(),
+			"key":    key,
+		}).Errorf("failed to parse idempotency key value: %v", err)
+
+		return nil, err
+	}
+
+	if record.Request != request {
+		return nil, models.ErrIdempotencyKeyReused
+	}
+
+	message, err := s.repo.GetMessageById(ctx, record.MessageId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrIdempotentMessageGone
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "replayMessage",
+			"error":     err.Error(),
+			"messageId": record.MessageId,
+		}).Errorf("failed to get message by id: %v", err)
+
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func (s *MessageService) ForwardMessage(ctx context.Context, userId, sourceId, channelId int, content string) (*models.Message, error) {
+	return s.referenceMessage(ctx, models.ReferenceKindForward, userId, sourceId, channelId, content)
+}
+
+func (s *MessageService) QuoteMessage(ctx context.Context, userId, sourceId, channelId int, content string) (*models.Message, error) {
+	return s.referenceMessage(ctx, models.ReferenceKindQuote, userId, sourceId, channelId, content)
+}
+
+// referenceMessage creates a forward or a quote of sourceId in channelId. The
+// caller has to be able to read the source channel, otherwise the forward
+// would leak content out of a channel they are not a member of.
+func (s *MessageService) referenceMessage(ctx context.Context, kind string, userId, sourceId, channelId int, content string) (*models.Message, error) {
+	if err := s.checkAuthor(ctx, "referenceMessage", userId); err != nil {
+		return nil, err
+	}
+
+	source, err := s.repo.GetMessageById(ctx, sourceId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "message",
+			"func":     "referenceMessage",
+			"kind":     kind,
+			"error":    err.Error(),
+			"sourceId": sourceId,
+		}).Errorf("failed to get source message: %v", err)
+
+		return nil, err
+	}
+
+	canRead, err := s.channels.IsChannelMember(ctx, source.ChannelID, userId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "referenceMessage",
+			"kind":      kind,
+			"error":     err.Error(),
+			"channelId": source.ChannelID,
+			"userId":    userId,
+		}).Errorf("failed to check source channel access: %v", err)
+
+		return nil, err
+	}
+
+	if !canRead {
+		return nil, models.ErrChannelAccessDenied
+	}
+
+	message := models.NewReferenceMessage(kind, userId, channelId, content, source)
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "message",
+			"func":     "referenceMessage",
+			"kind":     kind,
+			"error":    err.Error(),
+			"sourceId": sourceId,
+		}).Errorf("failed to create message: %v", err)
+
+		return nil, err
+	}
+
+	s.invalidateMessages(ctx, message.Id)
+	s.invalidateChannels(ctx, channelId)
 
 	return message, nil
 }
 
 func (s *MessageService) GetMessagesByChannelId(ctx context.Context, channelId int) ([]models.Message, error) {
-	key := fmt.Sprintf(CheMessageChannelPrefix+"%d", channelId)
+	key := s.channelKey(ctx, channelId)
 
-	cachedMessages, err := s.cache.Get(ctx, key).Result()
-	if err == nil {
-		var messages []models.Message
-		if err := json.Unmarshal([]byte(cachedMessages), &messages); err != nil {
+	cachedMessages, err := s.cache.GetOrLoad(ctx, key, func(ctx context.Context) ([]byte, error) {
+		messages, err := s.repo.GetMessagesByChannelId(ctx, channelId)
+		if err != nil {
 			s.logger.WithFields(logrus.Fields{
 				"module":    "message",
 				"func":      "GetMessagesByChannelId",
 				"error":     err.Error(),
 				"channelId": channelId,
-			}).Errorf("failed to unmarshal cached messages: %v", err)
-		} else {
-			return messages, nil
+			}).Errorf("failed to get messages by channel id: %v", err)
+
+			return nil, err
+		}
+
+		jsonData, err := json.Marshal(messages)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"module":    "message",
+				"func":      "GetMessagesByChannelId",
+				"error":     err.Error(),
+				"channelId": channelId,
+			}).Errorf("failed to marshal messages: %v", err)
+
+			return nil, err
 		}
-	}
 
-	messages, err := s.repo.GetMessagesByChannelId(ctx, channelId)
+		return jsonData, nil
+	})
 	if err != nil {
+		return nil, err
+	}
+
+	var messages []models.Message
+	if err := json.Unmarshal(cachedMessages, &messages); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"module":    "message",
 			"func":      "GetMessagesByChannelId",
 			"error":     err.Error(),
 			"channelId": channelId,
-		}).Errorf("failed to get messages by channel id: %v", err)
+		}).Errorf("failed to unmarshal cached messages: %v", err)
 
-		return nil, err
+		s.cache.Del(ctx, key)
+
+		return s.repo.GetMessagesByChannelId(ctx, channelId)
 	}
 
-	jsonData, err := json.Marshal(messages)
+	return messages, nil
+}
+
+// GetMessageById serves from the per-message cache. Ids that don't exist are
+// cached too, for a shorter time, so repeated lookups of a bad id stay off
+// PostgreSQL. The entry can still be stale when redis is down and replicas
+// fall back to their own memory, so writes must not rely on it; see
+// GetMessageForUpdate.
+func (s *MessageService) GetMessageById(ctx context.Context, messageId int) (*models.Message, error) {
+	key := s.messageKey(ctx, messageId)
+
+	cachedMessage, err := s.cache.GetOrLoad(ctx, key, func(ctx context.Context) ([]byte, error) {
+		message, err := s.repo.GetMessageById(ctx, messageId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, cache.ErrNotFound
+		}
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"module":    "message",
+				"func":      "GetMessageById",
+				"error":     err.Error(),
+				"messageId": messageId,
+			}).Errorf("failed to get message by id: %v", err)
+
+			return nil, err
+		}
+
+		return json.Marshal(message)
+	})
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, sql.ErrNoRows
+	}
 	if err != nil {
+		return nil, err
+	}
+
+	var message models.Message
+	if err := json.Unmarshal(cachedMessage, &message); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"module":    "message",
-			"func":      "GetMessagesByChannelId",
+			"func":      "GetMessageById",
 			"error":     err.Error(),
-			"channelId": channelId,
-		}).Errorf("failed to marshal messages: %v", err)
+			"messageId": messageId,
+		}).Errorf("failed to unmarshal cached message: %v", err)
 
-		return nil, err
+		s.cache.Del(ctx, key)
+
+		return s.repo.GetMessageById(ctx, messageId)
 	}
 
-	s.cache.Set(ctx, key, jsonData, cache.TimeToLive)
+	return &message, nil
+}
 
-	return messages, nil
+// GetMessagesByIds returns the messages with the given ids, in the order
+// asked for and without the ones that don't exist. Cached messages come from
+// one multi-key lookup and the rest from one query.
+func (s *MessageService) GetMessagesByIds(ctx context.Context, messageIds []int) ([]models.Message, error) {
+	var ids []int
+	seen := make(map[int]bool, len(messageIds))
+	for _, messageId := range messageIds {
+		if seen[messageId] {
+			continue
+		}
+
+		seen[messageId] = true
+		ids = append(ids, messageId)
+	}
+	keys := s.messageKeys(ctx, ids)
+
+	cached := s.cache.GetMany(ctx, keys)
+
+	found := make(map[int]models.Message, len(ids))
+	var misses []int
+	missKeys := make(map[int]string)
+	for i, messageId := range ids {
+		value, ok := cached[keys[i]]
+		if !ok {
+			misses = append(misses, messageId)
+			missKeys[messageId] = keys[i]
+			continue
+		}
+
+		if value == nil {
+			continue
+		}
+
+		var message models.Message
+		if err := json.Unmarshal(value, &message); err != nil {
+			misses = append(misses, messageId)
+			missKeys[messageId] = keys[i]
+			continue
+		}
+
+		found[messageId] = message
+	}
+
+	if len(misses) > 0 {
+		messages, err := s.repo.GetMessagesByIds(ctx, misses)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"module":     "message",
+				"func":       "GetMessagesByIds",
+				"error":      err.Error(),
+				"messageIds": misses,
+			}).Errorf("failed to get messages by ids: %v", err)
+
+			return nil, err
+		}
+
+		for _, message := range messages {
+			found[message.Id] = message
+		}
+
+		// Entries go under the keys looked up before the query, so a write
+		// that happened in between leaves them where nobody reads them.
+		for _, messageId := range misses {
+			message, ok := found[messageId]
+			if !ok {
+				s.cache.SetMissing(ctx, missKeys[messageId])
+				continue
+			}
+
+			if jsonData, err := json.Marshal(message); err == nil {
+				s.cache.Set(ctx, missKeys[messageId], jsonData, s.cache.TTL())
+			}
+		}
+	}
+
+	result := make([]models.Message, 0, len(found))
+	for _, messageId := range ids {
+		if message, ok := found[messageId]; ok {
+			result = append(result, message)
+		}
+	}
+
+	return result, nil
 }
 
-func (s *MessageService) GetMessageById(ctx context.Context, messageId int) (*models.Message, error) {
+// GetMessageForUpdate reads messageId from the database, bypassing the
+// cache, for a read-modify-write. If-Match and the version UpdateMessage
+// checks then compare against the stored version, so a stale cache entry
+// can't fail the update over and over.
+func (s *MessageService) GetMessageForUpdate(ctx context.Context, messageId int) (*models.Message, error) {
 	message, err := s.repo.GetMessageById(ctx, messageId)
-	if err != nil {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		s.logger.WithFields(logrus.Fields{
 			"module":    "message",
-			"func":      "GetMessageById",
+			"func":      "GetMessageForUpdate",
 			"error":     err.Error(),
 			"messageId": messageId,
 		}).Errorf("failed to get message by id: %v", err)
-
-		return nil, err
 	}
 
-	return message, nil
+	return message, err
 }
 
 func (s *MessageService) UpdateMessage(ctx context.Context, message *models.Message) error {
@@ -118,17 +470,40 @@ func (s *MessageService) UpdateMessage(ctx context.Context, message *models.Mess
 			"error":  err.Error(),
 		}).Errorf("failed to update message: %v", err)
 
+		// Whoever wrote first may have left a cached copy behind that
+		// readers still see; make them refetch.
+		if errors.Is(err, models.ErrVersionConflict) {
+			s.invalidateMessages(ctx, message.Id)
+		}
+
 		return err
 	}
 
-	key := fmt.Sprintf(CheMessageChannelPrefix+"%d", message.ChannelID)
-	s.cache.Del(ctx, key)
+	// Forwards and quotes keep their own snapshot of the content, so only
+	// the message itself and its own channel change.
+	s.invalidateMessages(ctx, message.Id)
+	s.invalidateChannels(ctx, message.ChannelID)
 
 	return nil
 }
 
 func (s *MessageService) DeleteMessage(ctx context.Context, messageId int) error {
-	if err := s.repo.DeleteMessage(ctx, messageId); err != nil {
+	// The message has to be read before it is gone to know which channel's
+	// cached list it sits in.
+	message, err := s.repo.GetMessageById(ctx, messageId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":    "message",
+			"func":      "DeleteMessage",
+			"error":     err.Error(),
+			"messageId": messageId,
+		}).Errorf("failed to get message by id: %v", err)
+
+		return err
+	}
+
+	references, err := s.repo.DeleteMessage(ctx, messageId)
+	if err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"module": "message",
 			"func":   "DeleteMessage",
@@ -138,8 +513,169 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageId int) error
 		return err
 	}
 
-	key := fmt.Sprintf(CheMessageChannelPrefix+"%d", messageId)
-	s.cache.Del(ctx, key)
+	// Forwards and quotes of the deleted message now render as deleted, at
+	// a new version, so they and the channels holding them have to be
+	// refetched as well.
+	messageIds := []int{messageId}
+	channelIds := []int{message.ChannelID}
+	for _, reference := range references {
+		messageIds = append(messageIds, reference.Id)
+		channelIds = append(channelIds, reference.ChannelID)
+	}
+
+	s.invalidateMessages(ctx, messageIds...)
+	s.invalidateChannels(ctx, channelIds...)
+	s.cache.SetMissing(ctx, s.messageKey(ctx, messageId))
 
 	return nil
 }
+
+// GetAuthors returns the authors among userIds, for embedding them in
+// responses. When the user service is unavailable it returns none rather
+// than failing, and the messages go out without their authors. When it
+// rejects the message service the call fails, so that a wrong token shows
+// up at once instead of as authors that quietly went missing.
+func (s *MessageService) GetAuthors(ctx context.Context, userIds []int) (map[int]models.Author, error) {
+	// Anonymised messages have no author to look up.
+	anonymised := false
+	lookup := make([]int, 0, len(userIds))
+	for _, userId := range userIds {
+		if userId == models.DeletedUserId {
+			anonymised = true
+			continue
+		}
+
+		lookup = append(lookup, userId)
+	}
+
+	authors, err := s.users.GetAuthors(ctx, lookup)
+	if errors.Is(err, models.ErrUserServiceUnavailable) {
+		s.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   "GetAuthors",
+			"error":  err.Error(),
+		}).Warnf("failed to get authors, leaving them out: %v", err)
+
+		authors = map[int]models.Author{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if anonymised {
+		authors[models.DeletedUserId] = models.DeletedAuthor
+	}
+
+	return authors, nil
+}
+
+// checkAuthor fails with ErrUnknownAuthor unless userId exists in the user
+// service. If the user service can't be asked, the message is let through
+// when the service fails open and refused otherwise. A user service that
+// rejects the message service never lets a message through: that is a
+// configuration error, and failing open would hide it for good.
+func (s *MessageService) checkAuthor(ctx context.Context, fn string, userId int) error {
+	authors, err := s.users.GetAuthors(ctx, []int{userId})
+	if errors.Is(err, models.ErrUserServiceUnavailable) && s.failOpen {
+		s.logger.WithFields(logrus.Fields{
+			"module": "message",
+			"func":   fn,
+			"userId": userId,
+			"error":  err.Error(),
+		}).Warnf("failed to check author, accepting the message: %v", err)
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, ok := authors[userId]; !ok {
+		return models.ErrUnknownAuthor
+	}
+
+	return nil
+}
+
+// channelKey is the cache key of a channel's message list. It embeds the
+// channel's version stamp, so a list loaded before a write can't be cached
+// under the key readers use after it.
+func (s *MessageService) channelKey(ctx context.Context, channelId int) string {
+	version := s.cache.Version(ctx, channelVersionKey(channelId))
+	return fmt.Sprintf(CheMessageChannelPrefix+"%d:v%d", channelId, version)
+}
+
+// invalidateChannels must be called by every write that changes what a
+// channel's message list renders.
+func (s *MessageService) invalidateChannels(ctx context.Context, channelIds ...int) {
+	seen := make(map[int]bool, len(channelIds))
+	keys := make([]string, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		if seen[channelId] {
+			continue
+		}
+
+		seen[channelId] = true
+		keys = append(keys, channelVersionKey(channelId))
+	}
+
+	s.cache.Bump(ctx, keys...)
+}
+
+// invalidateMessages must be called by every write that changes how one of
+// messageIds renders on its own. Cached misses of them are dropped too.
+func (s *MessageService) invalidateMessages(ctx context.Context, messageIds ...int) {
+	invalidateMessages(ctx, s.cache, messageIds...)
+}
+
+// messageKey is the cache key of a message. Like channelKey it embeds a
+// version stamp, bumped by every write to the message.
+func (s *MessageService) messageKey(ctx context.Context, messageId int) string {
+	return s.messageKeys(ctx, []int{messageId})[0]
+}
+
+// messageKeys is messageKey for many messages, in one round trip.
+func (s *MessageService) messageKeys(ctx context.Context, messageIds []int) []string {
+	return messageKeys(ctx, s.cache, messageIds)
+}
+
+func messageKeys(ctx context.Context, c interfaces.Cache, messageIds []int) []string {
+	versionKeys := make([]string, len(messageIds))
+	for i, messageId := range messageIds {
+		versionKeys[i] = messageVersionKey(messageId)
+	}
+
+	versions := c.Versions(ctx, versionKeys)
+	keys := make([]string, len(messageIds))
+	for i, messageId := range messageIds {
+		keys[i] = fmt.Sprintf(CheMessagePrefix+"%d:v%d", messageId, versions[i])
+	}
+
+	return keys
+}
+
+func invalidateMessages(ctx context.Context, c interfaces.Cache, messageIds ...int) {
+	if len(messageIds) == 0 {
+		return
+	}
+
+	seen := make(map[int]bool, len(messageIds))
+	keys := make([]string, 0, len(messageIds))
+	for _, messageId := range messageIds {
+		if seen[messageId] {
+			continue
+		}
+
+		seen[messageId] = true
+		keys = append(keys, messageVersionKey(messageId))
+	}
+
+	c.Bump(ctx, keys...)
+}
+
+func messageVersionKey(messageId int) string {
+	return fmt.Sprintf(CheMessagePrefix+"%d"+cheVersionSuffix, messageId)
+}
+
+func channelVersionKey(channelId int) string {
+	return fmt.Sprintf(CheMessageChannelPrefix+"%d"+cheVersionSuffix, channelId)
+}
@@ -2,21 +2,69 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/dmitriysta/messenger/message/internal/pkg/cache"
 	"github.com/go-redis/redis/v8"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/dmitriysta/messenger/message/internal/interfaces"
 	"github.com/dmitriysta/messenger/message/internal/interfaces/mocks"
 	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/message/internal/pkg/config"
 
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// Loads behind the cache run on a context detached from the request, so
+// the repository reads in these tests match any context.
+var testCacheConfig = config.CacheConfig{
+	TTL:           time.Hour,
+	LocalSize:     16,
+	LocalTTL:      time.Minute,
+	NegativeTTL:   time.Minute,
+	RetryInterval: time.Minute,
+	LoadTimeout:   time.Second,
+}
+
+func newTestCache(client interfaces.RedisClient) *cache.Cache {
+	logger, _ := test.NewNullLogger()
+	return cache.NewCache(client, testCacheConfig, logger)
+}
+
+// newTestUserClient returns a user client that knows every user asked for,
+// for tests that aren't about authors.
+func newTestUserClient() *mocks.UserClient {
+	users := new(mocks.UserClient)
+	users.On("GetAuthors", mock.Anything, mock.Anything).Return(func(ctx context.Context, userIds []int) (map[int]models.Author, error) {
+		authors := make(map[int]models.Author, len(userIds))
+		for _, userId := range userIds {
+			authors[userId] = models.Author{Id: userId, Username: fmt.Sprintf("user%d", userId)}
+		}
+
+		return authors, nil
+	}).Maybe()
+
+	return users
+}
+
+// onMessageVersions has the version stamps of messageIds read as never
+// bumped, so their entries are at v0.
+func onMessageVersions(client *mocks.RedisClient, ctx context.Context, messageIds ...int) *mock.Call {
+	args := []interface{}{ctx}
+	for _, messageId := range messageIds {
+		args = append(args, fmt.Sprintf("messages:message:%d:version", messageId))
+	}
+
+	return client.On("MGet", args...).Return(redis.NewSliceResult(make([]interface{}, len(messageIds)), nil))
+}
+
 func TestMessageService_CreateMessage(t *testing.T) {
 	mockRepo := new(mocks.MessageRepository)
 	mockCache := new(mocks.RedisClient)
@@ -31,13 +79,14 @@ func TestMessageService_CreateMessage(t *testing.T) {
 	}
 
 	mockRepo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
-	mockCache.On("Del", ctx, fmt.Sprintf("messages:channel:%d", testMessage.ChannelID)).Return(nil)
+	mockCache.On("Incr", ctx, "messages:message:0:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, fmt.Sprintf("messages:channel:%d:version", testMessage.ChannelID)).Return(redis.NewIntResult(1, nil))
 
 	logger, _ := test.NewNullLogger()
 
-	service := NewMessageService(mockRepo, logger, mockCache)
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
 
-	result, err := service.CreateMessage(ctx, testMessage.UserID, testMessage.ChannelID, testMessage.Content)
+	result, err := service.CreateMessage(ctx, testMessage.UserID, testMessage.ChannelID, testMessage.Content, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -50,6 +99,113 @@ func TestMessageService_CreateMessage(t *testing.T) {
 	mockCache.AssertExpectations(t)
 }
 
+func TestMessageService_CreateMessage_Idempotent(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	key := "messages:idempotency:1:retry-key"
+	versionKey := fmt.Sprintf("messages:channel:%d:version", 1)
+
+	record := fmt.Sprintf(`{"messageId":42,"request":%q}`, idempotencyRequest(1, "test content"))
+
+	mockCache.On("SetNX", ctx, key, []byte("pending"), cache.IdempotencyPendingTTL).Return(redis.NewBoolResult(true, nil)).Once()
+	mockRepo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Message).Id = 42
+	}).Return(nil).Once()
+	mockCache.On("Incr", ctx, "messages:message:42:version").Return(redis.NewIntResult(1, nil)).Once()
+	mockCache.On("Incr", ctx, versionKey).Return(redis.NewIntResult(1, nil)).Once()
+	mockCache.On("Set", ctx, key, []byte(record), cache.IdempotencyTTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	first, err := service.CreateMessage(ctx, 1, 1, "test content", "retry-key")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, first.Id)
+
+	mockCache.On("SetNX", ctx, key, []byte("pending"), cache.IdempotencyPendingTTL).Return(redis.NewBoolResult(false, nil))
+	mockCache.On("Get", ctx, key).Return(redis.NewStringResult(record, nil))
+	mockRepo.On("GetMessageById", mock.Anything, 42).Return(first, nil).Once()
+
+	second, err := service.CreateMessage(ctx, 1, 1, "test content", "retry-key")
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	_, err = service.CreateMessage(ctx, 1, 1, "other content", "retry-key")
+	assert.ErrorIs(t, err, models.ErrIdempotencyKeyReused)
+
+	_, err = service.CreateMessage(ctx, 1, 2, "test content", "retry-key")
+	assert.ErrorIs(t, err, models.ErrIdempotencyKeyReused)
+
+	// A retry is compared with the request that created the message, not
+	// with the message, which may have been edited since.
+	edited := &models.Message{Id: 42, UserID: 1, ChannelID: 1, Content: "edited content", Version: 2}
+	mockRepo.On("GetMessageById", mock.Anything, 42).Return(edited, nil).Once()
+
+	third, err := service.CreateMessage(ctx, 1, 1, "test content", "retry-key")
+	assert.NoError(t, err)
+	assert.Equal(t, edited, third)
+
+	mockRepo.On("GetMessageById", mock.Anything, 42).Return(nil, sql.ErrNoRows).Once()
+
+	_, err = service.CreateMessage(ctx, 1, 1, "test content", "retry-key")
+	assert.ErrorIs(t, err, models.ErrIdempotentMessageGone)
+
+	mockRepo.AssertNumberOfCalls(t, "CreateMessage", 1)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_CreateMessage_IdempotencyInProgress(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	key := "messages:idempotency:1:retry-key"
+
+	mockCache.On("SetNX", ctx, key, []byte("pending"), cache.IdempotencyPendingTTL).Return(redis.NewBoolResult(false, nil))
+	mockCache.On("Get", ctx, key).Return(redis.NewStringResult("pending", nil))
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.CreateMessage(ctx, 1, 1, "test content", "retry-key")
+	assert.ErrorIs(t, err, models.ErrIdempotencyInProgress)
+	assert.Nil(t, result)
+
+	mockRepo.AssertNotCalled(t, "CreateMessage", mock.Anything, mock.Anything)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_CreateMessage_IdempotencyRecordFails(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	key := "messages:idempotency:1:retry-key"
+
+	mockCache.On("SetNX", ctx, key, []byte("pending"), cache.IdempotencyPendingTTL).Return(redis.NewBoolResult(true, nil))
+	mockRepo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Message).Id = 42
+	}).Return(nil)
+	mockCache.On("Incr", ctx, "messages:message:42:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, "messages:channel:1:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("Set", ctx, key, mock.Anything, cache.IdempotencyTTL).Return(redis.NewStatusResult("", errors.New("connection reset")))
+
+	logger, hook := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	// The message was created, so the request still succeeds; the pending
+	// reservation runs out on its own shortly.
+	result, err := service.CreateMessage(ctx, 1, 1, "test content", "retry-key")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result.Id)
+	assert.Contains(t, hook.LastEntry().Message, "failed to record idempotency key")
+
+	mockCache.AssertExpectations(t)
+}
+
 func TestMessageService_GetMessagesByChannelId(t *testing.T) {
 	mockRepo := new(mocks.MessageRepository)
 	mockCache := new(mocks.RedisClient)
@@ -71,55 +227,200 @@ func TestMessageService_GetMessagesByChannelId(t *testing.T) {
 		},
 	}
 
-	key := fmt.Sprintf("messages:channel:%d", 1)
+	key := fmt.Sprintf("messages:channel:%d:v0", 1)
 	jsonData, _ := json.Marshal(testMessages)
 
 	logger, _ := test.NewNullLogger()
-	service := NewMessageService(mockRepo, logger, mockCache)
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+	mockCache.On("Get", ctx, "messages:channel:1:version").Return(redis.NewStringResult("", redis.Nil))
 	mockCache.On("Get", ctx, key).Return(redis.NewStringResult(string(jsonData), nil)).Once()
 	result, err := service.GetMessagesByChannelId(ctx, 1)
 	assert.NoError(t, err)
 	assert.Equal(t, testMessages, result)
 	mockCache.AssertCalled(t, "Get", ctx, key)
-	mockRepo.AssertNotCalled(t, "GetMessagesByChannelId", ctx, 1)
+	mockRepo.AssertNotCalled(t, "GetMessagesByChannelId", mock.Anything, 1)
 
 	mockCache.On("Get", ctx, key).Return(redis.NewStringResult("", redis.Nil)).Once()
-	mockRepo.On("GetMessagesByChannelId", ctx, 1).Return(testMessages, nil).Once()
-	mockCache.On("Set", ctx, key, jsonData, cache.TimeToLive).Return(redis.NewStatusResult("", nil)).Once()
+	mockRepo.On("GetMessagesByChannelId", mock.Anything, 1).Return(testMessages, nil).Once()
+	mockCache.On("Set", mock.Anything, key, jsonData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil)).Once()
+	result, err = service.GetMessagesByChannelId(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessages, result)
+	mockRepo.AssertCalled(t, "GetMessagesByChannelId", mock.Anything, 1)
+	mockCache.AssertCalled(t, "Set", mock.Anything, key, jsonData, testCacheConfig.TTL)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_GetMessagesByChannelId_RedisDown(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	testMessages := []models.Message{{Id: 1, UserID: 1, ChannelID: 1, Content: "test content"}}
+	mockCache.On("Get", ctx, "messages:channel:1:version").Return(redis.NewStringResult("", errors.New("connection refused"))).Once()
+	mockRepo.On("GetMessagesByChannelId", mock.Anything, 1).Return(testMessages, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.GetMessagesByChannelId(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessages, result)
+
+	// Redis is skipped until the retry interval passes, and the second read
+	// is served from memory instead of PostgreSQL.
 	result, err = service.GetMessagesByChannelId(ctx, 1)
 	assert.NoError(t, err)
 	assert.Equal(t, testMessages, result)
-	mockRepo.AssertCalled(t, "GetMessagesByChannelId", ctx, 1)
-	mockCache.AssertCalled(t, "Set", ctx, key, jsonData, cache.TimeToLive)
 
+	mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockCache.AssertNumberOfCalls(t, "Get", 1)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_GetMessagesByChannelId_CoalescesMisses(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	testMessages := []models.Message{{Id: 1, UserID: 1, ChannelID: 1, Content: "test content"}}
+	key := fmt.Sprintf("messages:channel:%d:v0", 1)
+	jsonData, _ := json.Marshal(testMessages)
+
+	release := make(chan struct{})
+	mockCache.On("Get", ctx, "messages:channel:1:version").Return(redis.NewStringResult("", redis.Nil))
+	mockCache.On("Get", ctx, key).Return(redis.NewStringResult("", redis.Nil))
+	mockRepo.On("GetMessagesByChannelId", mock.Anything, 1).Run(func(args mock.Arguments) {
+		<-release
+	}).Return(testMessages, nil).Once()
+	mockCache.On("Set", mock.Anything, key, jsonData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := service.GetMessagesByChannelId(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, testMessages, result)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	mockRepo.AssertNumberOfCalls(t, "GetMessagesByChannelId", 1)
 	mockRepo.AssertExpectations(t)
 	mockCache.AssertExpectations(t)
 }
 
 func TestMessageService_GetMessageById(t *testing.T) {
 	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
 	ctx := context.Background()
-	currentTime := time.Now().Truncate(time.Second)
+	currentTime := time.Now().UTC().Truncate(time.Second)
 
 	testMessage := &models.Message{
+		Id:        1,
 		UserID:    1,
 		ChannelID: 1,
 		Content:   "test content",
 		CreatedAt: currentTime,
 	}
+	jsonData, _ := json.Marshal(testMessage)
 
-	mockRepo.On("GetMessageById", ctx, 1).Return(testMessage, nil)
+	onMessageVersions(mockCache, ctx, 1)
+	mockCache.On("Get", ctx, "messages:message:1:v0").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockRepo.On("GetMessageById", mock.Anything, 1).Return(testMessage, nil).Once()
+	mockCache.On("Set", mock.Anything, "messages:message:1:v0", jsonData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil)).Once()
 
 	logger, _ := test.NewNullLogger()
 
-	service := NewMessageService(mockRepo, logger, nil)
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
 
 	result, err := service.GetMessageById(ctx, 1)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, testMessage, result)
+
+	mockCache.On("Get", ctx, "messages:message:1:v0").Return(redis.NewStringResult(string(jsonData), nil)).Once()
+
+	result, err = service.GetMessageById(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage, result)
+	mockRepo.AssertNumberOfCalls(t, "GetMessageById", 1)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_GetMessageById_NegativeCache(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	onMessageVersions(mockCache, ctx, 99)
+	mockCache.On("Get", ctx, "messages:message:99:v0").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockRepo.On("GetMessageById", mock.Anything, 99).Return(nil, sql.ErrNoRows).Once()
+	mockCache.On("Set", mock.Anything, "messages:message:99:v0", []byte("\x00"), testCacheConfig.NegativeTTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.GetMessageById(ctx, 99)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Nil(t, result)
+
+	mockCache.On("Get", ctx, "messages:message:99:v0").Return(redis.NewStringResult("\x00", nil)).Once()
+
+	result, err = service.GetMessageById(ctx, 99)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Nil(t, result)
+
+	mockRepo.AssertNumberOfCalls(t, "GetMessageById", 1)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_GetMessagesByIds(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	cachedMessage := models.Message{Id: 1, UserID: 1, ChannelID: 1, Content: "cached"}
+	loadedMessage := models.Message{Id: 3, UserID: 2, ChannelID: 1, Content: "loaded"}
+	cachedData, _ := json.Marshal(cachedMessage)
+	loadedData, _ := json.Marshal(loadedMessage)
+
+	// 1 is cached, 2 is cached as missing, 3 and 4 are misses of which only
+	// 3 exists.
+	// 4 was edited once, so its entry moved on to v1.
+	mockCache.On("MGet", ctx, "messages:message:3:version", "messages:message:1:version", "messages:message:2:version", "messages:message:4:version").
+		Return(redis.NewSliceResult([]interface{}{nil, nil, nil, "1"}, nil))
+	mockCache.On("MGet", ctx, "messages:message:3:v0", "messages:message:1:v0", "messages:message:2:v0", "messages:message:4:v1").
+		Return(redis.NewSliceResult([]interface{}{nil, string(cachedData), "\x00", nil}, nil))
+	mockRepo.On("GetMessagesByIds", ctx, []int{3, 4}).Return([]models.Message{loadedMessage}, nil)
+	mockCache.On("Set", mock.Anything, "messages:message:3:v0", loadedData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil))
+	mockCache.On("Set", mock.Anything, "messages:message:4:v1", []byte("\x00"), testCacheConfig.NegativeTTL).Return(redis.NewStatusResult("", nil))
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.GetMessagesByIds(ctx, []int{3, 1, 2, 4, 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{loadedMessage, cachedMessage}, result)
 	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
 }
 
 func TestMessageService_UpdateMessage(t *testing.T) {
@@ -134,10 +435,11 @@ func TestMessageService_UpdateMessage(t *testing.T) {
 	}
 
 	mockRepo.On("UpdateMessage", ctx, testMessage).Return(nil)
-	mockCache.On("Del", ctx, fmt.Sprintf("messages:channel:%d", testMessage.ChannelID)).Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, "messages:message:0:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, fmt.Sprintf("messages:channel:%d:version", testMessage.ChannelID)).Return(redis.NewIntResult(1, nil))
 
 	logger, _ := test.NewNullLogger()
-	service := NewMessageService(mockRepo, logger, mockCache)
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
 
 	err := service.UpdateMessage(ctx, testMessage)
 
@@ -146,17 +448,130 @@ func TestMessageService_UpdateMessage(t *testing.T) {
 	mockCache.AssertExpectations(t)
 }
 
+func TestMessageService_UpdateMessage_VersionConflict(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	testMessage := &models.Message{
+		Id:        1,
+		UserID:    1,
+		ChannelID: 1,
+		Content:   "updated content",
+		Version:   3,
+	}
+
+	mockRepo.On("UpdateMessage", ctx, testMessage).Return(models.ErrVersionConflict)
+	// The cached copy may be what the caller based the update on.
+	mockCache.On("Incr", ctx, "messages:message:1:version").Return(redis.NewIntResult(1, nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	err := service.UpdateMessage(ctx, testMessage)
+
+	assert.ErrorIs(t, err, models.ErrVersionConflict)
+	mockCache.AssertNotCalled(t, "Incr", mock.Anything, "messages:channel:1:version")
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_UpdateMessage_InvalidatesMessage(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	original := &models.Message{Id: 1, UserID: 1, ChannelID: 1, Content: "original", Version: 1}
+	updated := &models.Message{Id: 1, UserID: 1, ChannelID: 1, Content: "updated", Version: 2}
+	originalData, _ := json.Marshal(original)
+	updatedData, _ := json.Marshal(updated)
+
+	// The original is cached at v0 before the update.
+	mockCache.On("MGet", ctx, "messages:message:1:version").Return(redis.NewSliceResult([]interface{}{nil}, nil)).Once()
+	mockCache.On("Get", ctx, "messages:message:1:v0").Return(redis.NewStringResult(string(originalData), nil)).Once()
+
+	mockRepo.On("UpdateMessage", ctx, updated).Return(nil).Once()
+	mockCache.On("Incr", ctx, "messages:message:1:version").Return(redis.NewIntResult(1, nil)).Once()
+	mockCache.On("Incr", ctx, "messages:channel:1:version").Return(redis.NewIntResult(1, nil)).Once()
+
+	// Afterwards the v0 entry is never read again.
+	mockCache.On("MGet", ctx, "messages:message:1:version").Return(redis.NewSliceResult([]interface{}{"1"}, nil)).Once()
+	mockCache.On("Get", ctx, "messages:message:1:v1").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockRepo.On("GetMessageById", mock.Anything, 1).Return(updated, nil).Once()
+	mockCache.On("Set", mock.Anything, "messages:message:1:v1", updatedData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.GetMessageById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, original, result)
+
+	assert.NoError(t, service.UpdateMessage(ctx, updated))
+
+	result, err = service.GetMessageById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, result)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_DeleteMessage_InvalidatesMessage(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	message := &models.Message{Id: 1, UserID: 1, ChannelID: 5, Content: "test content"}
+	messageData, _ := json.Marshal(message)
+
+	mockCache.On("MGet", ctx, "messages:message:1:version").Return(redis.NewSliceResult([]interface{}{nil}, nil)).Once()
+	mockCache.On("Get", ctx, "messages:message:1:v0").Return(redis.NewStringResult(string(messageData), nil)).Once()
+
+	mockRepo.On("GetMessageById", mock.Anything, 1).Return(message, nil).Once()
+	mockRepo.On("DeleteMessage", ctx, 1).Return([]models.Message{}, nil)
+	mockCache.On("Incr", ctx, "messages:message:1:version").Return(redis.NewIntResult(1, nil)).Once()
+	mockCache.On("Incr", ctx, "messages:channel:5:version").Return(redis.NewIntResult(1, nil)).Once()
+	mockCache.On("MGet", ctx, "messages:message:1:version").Return(redis.NewSliceResult([]interface{}{"1"}, nil)).Once()
+	mockCache.On("Set", ctx, "messages:message:1:v1", []byte("\x00"), testCacheConfig.NegativeTTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	// The tombstone is at the version readers look up now.
+	mockCache.On("MGet", ctx, "messages:message:1:version").Return(redis.NewSliceResult([]interface{}{"1"}, nil)).Once()
+	mockCache.On("Get", ctx, "messages:message:1:v1").Return(redis.NewStringResult("\x00", nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.GetMessageById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, message, result)
+
+	assert.NoError(t, service.DeleteMessage(ctx, 1))
+
+	result, err = service.GetMessageById(ctx, 1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Nil(t, result)
+	mockRepo.AssertNumberOfCalls(t, "GetMessageById", 1)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
 func TestMessageService_DeleteMessage(t *testing.T) {
 	mockRepo := new(mocks.MessageRepository)
 	mockCache := new(mocks.RedisClient)
 	ctx := context.Background()
 
-	mockRepo.On("DeleteMessage", ctx, 1).Return(nil)
-	mockCache.On("Del", ctx, fmt.Sprintf("messages:channel:%d", 1)).Return(redis.NewIntResult(1, nil))
+	mockRepo.On("GetMessageById", mock.Anything, 1).Return(&models.Message{Id: 1, UserID: 1, ChannelID: 5, Content: "test content"}, nil)
+	mockRepo.On("DeleteMessage", ctx, 1).Return([]models.Message{{Id: 7, UserID: 2, ChannelID: 2}}, nil)
+	mockCache.On("Incr", ctx, "messages:message:1:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, "messages:message:7:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("MGet", ctx, "messages:message:1:version").Return(redis.NewSliceResult([]interface{}{"1"}, nil))
+	mockCache.On("Set", ctx, "messages:message:1:v1", []byte("\x00"), testCacheConfig.NegativeTTL).Return(redis.NewStatusResult("", nil))
+	mockCache.On("Incr", ctx, fmt.Sprintf("messages:channel:%d:version", 5)).Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, fmt.Sprintf("messages:channel:%d:version", 2)).Return(redis.NewIntResult(1, nil))
 
 	logger, _ := test.NewNullLogger()
 
-	service := NewMessageService(mockRepo, logger, mockCache)
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
 
 	err := service.DeleteMessage(ctx, 1)
 
@@ -165,3 +580,391 @@ func TestMessageService_DeleteMessage(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 	mockCache.AssertExpectations(t)
 }
+
+func TestMessageService_ForwardMessage(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockChannels := new(mocks.ChannelRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	source := &models.Message{
+		Id:        10,
+		UserID:    2,
+		ChannelID: 1,
+		Content:   "source content",
+	}
+
+	mockRepo.On("GetMessageById", mock.Anything, source.Id).Return(source, nil)
+	mockChannels.On("IsChannelMember", ctx, source.ChannelID, 1).Return(true, nil)
+	mockRepo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+	mockCache.On("Incr", ctx, "messages:message:0:version").Return(redis.NewIntResult(1, nil))
+	mockCache.On("Incr", ctx, fmt.Sprintf("messages:channel:%d:version", 3)).Return(redis.NewIntResult(1, nil))
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, mockChannels, newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.ForwardMessage(ctx, 1, source.Id, 3, "")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, 1, result.UserID)
+	assert.Equal(t, 3, result.ChannelID)
+	assert.Equal(t, &models.MessageReference{
+		Kind:      models.ReferenceKindForward,
+		MessageID: source.Id,
+		ChannelID: source.ChannelID,
+		UserID:    source.UserID,
+		Content:   source.Content,
+	}, result.Reference)
+
+	mockRepo.AssertExpectations(t)
+	mockChannels.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_QuoteMessage_AccessDenied(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockChannels := new(mocks.ChannelRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	source := &models.Message{
+		Id:        10,
+		UserID:    2,
+		ChannelID: 1,
+		Content:   "source content",
+	}
+
+	mockRepo.On("GetMessageById", mock.Anything, source.Id).Return(source, nil)
+	mockChannels.On("IsChannelMember", ctx, source.ChannelID, 1).Return(false, nil)
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, mockChannels, newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.QuoteMessage(ctx, 1, source.Id, 3, "reply")
+
+	assert.ErrorIs(t, err, models.ErrChannelAccessDenied)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "CreateMessage", mock.Anything, mock.Anything)
+
+	mockRepo.AssertExpectations(t)
+	mockChannels.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_CacheInvalidation(t *testing.T) {
+	source := &models.Message{Id: 10, UserID: 2, ChannelID: 1, Content: "source content", Version: 1}
+	edited := &models.Message{Id: 10, UserID: 2, ChannelID: 1, Content: "edited content", Version: 1}
+
+	tests := []struct {
+		name     string
+		setup    func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient)
+		mutate   func(ctx context.Context, service *MessageService) error
+		wantErr  error
+		messages []int
+		missing  []int
+		channels []int
+	}{
+		{
+			name: "create",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				_, err := service.CreateMessage(ctx, 1, 4, "test content", "")
+				return err
+			},
+			messages: []int{0},
+			channels: []int{4},
+		},
+		{
+			name: "create with idempotency key",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				redisClient.On("SetNX", ctx, "messages:idempotency:1:key", []byte("pending"), cache.IdempotencyPendingTTL).Return(redis.NewBoolResult(true, nil))
+				repo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+				redisClient.On("Set", ctx, "messages:idempotency:1:key", mock.Anything, cache.IdempotencyTTL).Return(redis.NewStatusResult("", nil))
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				_, err := service.CreateMessage(ctx, 1, 4, "test content", "key")
+				return err
+			},
+			messages: []int{0},
+			channels: []int{4},
+		},
+		{
+			name: "forward",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("GetMessageById", mock.Anything, source.Id).Return(source, nil)
+				channels.On("IsChannelMember", ctx, source.ChannelID, 1).Return(true, nil)
+				repo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				_, err := service.ForwardMessage(ctx, 1, source.Id, 4, "")
+				return err
+			},
+			messages: []int{0},
+			channels: []int{4},
+		},
+		{
+			name: "quote",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("GetMessageById", mock.Anything, source.Id).Return(source, nil)
+				channels.On("IsChannelMember", ctx, source.ChannelID, 1).Return(true, nil)
+				repo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				_, err := service.QuoteMessage(ctx, 1, source.Id, 4, "reply")
+				return err
+			},
+			messages: []int{0},
+			channels: []int{4},
+		},
+		{
+			name: "update",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("UpdateMessage", ctx, edited).Return(nil)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				return service.UpdateMessage(ctx, edited)
+			},
+			messages: []int{edited.Id},
+			channels: []int{1},
+		},
+		{
+			name: "update with version conflict",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("UpdateMessage", ctx, edited).Return(models.ErrVersionConflict)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				return service.UpdateMessage(ctx, edited)
+			},
+			wantErr:  models.ErrVersionConflict,
+			messages: []int{edited.Id},
+		},
+		{
+			name: "delete with forwards and quotes",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("GetMessageById", mock.Anything, source.Id).Return(source, nil)
+				repo.On("DeleteMessage", ctx, source.Id).Return([]models.Message{
+					{Id: 11, ChannelID: 4},
+					{Id: 12, ChannelID: 1},
+					{Id: 13, ChannelID: 6},
+				}, nil)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				return service.DeleteMessage(ctx, source.Id)
+			},
+			messages: []int{source.Id, 11, 12, 13},
+			missing:  []int{source.Id},
+			channels: []int{1, 4, 6},
+		},
+		{
+			name: "delete missing message",
+			setup: func(ctx context.Context, repo *mocks.MessageRepository, channels *mocks.ChannelRepository, redisClient *mocks.RedisClient) {
+				repo.On("GetMessageById", mock.Anything, 99).Return(nil, sql.ErrNoRows)
+			},
+			mutate: func(ctx context.Context, service *MessageService) error {
+				return service.DeleteMessage(ctx, 99)
+			},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MessageRepository)
+			mockChannels := new(mocks.ChannelRepository)
+			mockCache := new(mocks.RedisClient)
+			ctx := context.Background()
+
+			tt.setup(ctx, mockRepo, mockChannels, mockCache)
+			for _, messageId := range tt.messages {
+				mockCache.On("Incr", ctx, fmt.Sprintf("messages:message:%d:version", messageId)).Return(redis.NewIntResult(1, nil)).Once()
+			}
+			for _, messageId := range tt.missing {
+				mockCache.On("MGet", ctx, fmt.Sprintf("messages:message:%d:version", messageId)).Return(redis.NewSliceResult([]interface{}{"1"}, nil)).Once()
+				mockCache.On("Set", ctx, fmt.Sprintf("messages:message:%d:v1", messageId), []byte("\x00"), testCacheConfig.NegativeTTL).Return(redis.NewStatusResult("", nil)).Once()
+			}
+			for _, channelId := range tt.channels {
+				mockCache.On("Incr", ctx, fmt.Sprintf("messages:channel:%d:version", channelId)).Return(redis.NewIntResult(1, nil)).Once()
+			}
+
+			logger, _ := test.NewNullLogger()
+			service := NewMessageService(mockRepo, mockChannels, newTestUserClient(), logger, newTestCache(mockCache), false)
+
+			err := tt.mutate(ctx, service)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockCache.AssertNumberOfCalls(t, "Incr", len(tt.messages)+len(tt.channels))
+			mockCache.AssertNotCalled(t, "Del", mock.Anything)
+			mockRepo.AssertExpectations(t)
+			mockChannels.AssertExpectations(t)
+			mockCache.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_GetMessagesByChannelId_StaleFillAfterWrite(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockCache := new(mocks.RedisClient)
+	ctx := context.Background()
+
+	before := []models.Message{{Id: 1, UserID: 1, ChannelID: 1, Content: "before"}}
+	after := []models.Message{{Id: 1, UserID: 1, ChannelID: 1, Content: "after", Version: 2}}
+	beforeData, _ := json.Marshal(before)
+	afterData, _ := json.Marshal(after)
+
+	// A reader that loaded the list before the edit stores it under v0; the
+	// edit moves readers on to v1, so the stale list is never served.
+	mockCache.On("Get", ctx, "messages:channel:1:version").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockCache.On("Get", ctx, "messages:channel:1:v0").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockRepo.On("GetMessagesByChannelId", mock.Anything, 1).Return(before, nil).Once()
+	mockCache.On("Set", mock.Anything, "messages:channel:1:v0", beforeData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	mockRepo.On("UpdateMessage", ctx, &after[0]).Return(nil).Once()
+	mockCache.On("Incr", ctx, "messages:message:1:version").Return(redis.NewIntResult(1, nil)).Once()
+	mockCache.On("Incr", ctx, "messages:channel:1:version").Return(redis.NewIntResult(1, nil)).Once()
+
+	mockCache.On("Get", ctx, "messages:channel:1:version").Return(redis.NewStringResult("1", nil)).Once()
+	mockCache.On("Get", ctx, "messages:channel:1:v1").Return(redis.NewStringResult("", redis.Nil)).Once()
+	mockRepo.On("GetMessagesByChannelId", mock.Anything, 1).Return(after, nil).Once()
+	mockCache.On("Set", mock.Anything, "messages:channel:1:v1", afterData, testCacheConfig.TTL).Return(redis.NewStatusResult("", nil)).Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), newTestUserClient(), logger, newTestCache(mockCache), false)
+
+	result, err := service.GetMessagesByChannelId(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, before, result)
+
+	assert.NoError(t, service.UpdateMessage(ctx, &after[0]))
+
+	result, err = service.GetMessagesByChannelId(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, after, result)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMessageService_CreateMessage_UnknownAuthor(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockUsers := new(mocks.UserClient)
+	ctx := context.Background()
+
+	mockUsers.On("GetAuthors", ctx, []int{7}).Return(map[int]models.Author{}, nil)
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), mockUsers, logger, newTestCache(new(mocks.RedisClient)), true)
+
+	result, err := service.CreateMessage(ctx, 7, 1, "test content", "")
+	assert.ErrorIs(t, err, models.ErrUnknownAuthor)
+	assert.Nil(t, result)
+
+	mockRepo.AssertNotCalled(t, "CreateMessage", mock.Anything, mock.Anything)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestMessageService_CreateMessage_UserServiceUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		usersErr error
+		failOpen bool
+		wantErr  error
+	}{
+		{name: "fail open", usersErr: models.ErrUserServiceUnavailable, failOpen: true},
+		{name: "fail closed", usersErr: models.ErrUserServiceUnavailable, failOpen: false, wantErr: models.ErrUserServiceUnavailable},
+		{name: "rejected never fails open", usersErr: models.ErrUserServiceRejected, failOpen: true, wantErr: models.ErrUserServiceRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MessageRepository)
+			mockCache := new(mocks.RedisClient)
+			mockUsers := new(mocks.UserClient)
+			ctx := context.Background()
+
+			mockUsers.On("GetAuthors", ctx, []int{1}).Return(nil, fmt.Errorf("%w: rpc error", tt.usersErr))
+			mockRepo.On("CreateMessage", ctx, mock.AnythingOfType("*models.Message")).Return(nil).Maybe()
+			mockCache.On("Incr", ctx, "messages:message:0:version").Return(redis.NewIntResult(1, nil)).Maybe()
+			mockCache.On("Incr", ctx, "messages:channel:1:version").Return(redis.NewIntResult(1, nil)).Maybe()
+
+			logger, _ := test.NewNullLogger()
+			service := NewMessageService(mockRepo, new(mocks.ChannelRepository), mockUsers, logger, newTestCache(mockCache), tt.failOpen)
+
+			result, err := service.CreateMessage(ctx, 1, 1, "test content", "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "CreateMessage", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, result)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageService_ForwardMessage_UnknownAuthor(t *testing.T) {
+	mockRepo := new(mocks.MessageRepository)
+	mockUsers := new(mocks.UserClient)
+	ctx := context.Background()
+
+	mockUsers.On("GetAuthors", ctx, []int{7}).Return(map[int]models.Author{}, nil)
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(mockRepo, new(mocks.ChannelRepository), mockUsers, logger, newTestCache(new(mocks.RedisClient)), true)
+
+	_, err := service.ForwardMessage(ctx, 7, 1, 2, "")
+	assert.ErrorIs(t, err, models.ErrUnknownAuthor)
+
+	mockRepo.AssertNotCalled(t, "GetMessageById", mock.Anything, mock.Anything)
+}
+
+func TestMessageService_GetAuthors_UserServiceUnavailable(t *testing.T) {
+	mockUsers := new(mocks.UserClient)
+	ctx := context.Background()
+
+	mockUsers.On("GetAuthors", ctx, []int{1, 2}).Return(nil, fmt.Errorf("%w: circuit breaker is open", models.ErrUserServiceUnavailable))
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(new(mocks.MessageRepository), new(mocks.ChannelRepository), mockUsers, logger, newTestCache(new(mocks.RedisClient)), false)
+
+	authors, err := service.GetAuthors(ctx, []int{1, 2})
+	assert.NoError(t, err)
+	assert.Empty(t, authors)
+}
+
+func TestMessageService_GetAuthors_UserServiceRejected(t *testing.T) {
+	mockUsers := new(mocks.UserClient)
+	ctx := context.Background()
+
+	mockUsers.On("GetAuthors", ctx, []int{1}).Return(nil, fmt.Errorf("%w: rpc error: code = Unauthenticated", models.ErrUserServiceRejected))
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(new(mocks.MessageRepository), new(mocks.ChannelRepository), mockUsers, logger, newTestCache(new(mocks.RedisClient)), true)
+
+	authors, err := service.GetAuthors(ctx, []int{1})
+	assert.ErrorIs(t, err, models.ErrUserServiceRejected)
+	assert.Nil(t, authors)
+}
+
+func TestMessageService_GetAuthors_DeletedUser(t *testing.T) {
+	mockUsers := new(mocks.UserClient)
+	ctx := context.Background()
+
+	mockUsers.On("GetAuthors", ctx, []int{1}).Return(map[int]models.Author{1: {Id: 1, Username: "user1"}}, nil)
+
+	logger, _ := test.NewNullLogger()
+	service := NewMessageService(new(mocks.MessageRepository), new(mocks.ChannelRepository), mockUsers, logger, newTestCache(new(mocks.RedisClient)), false)
+
+	authors, err := service.GetAuthors(ctx, []int{1, models.DeletedUserId})
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", authors[1].Username)
+	assert.Equal(t, models.DeletedAuthor, authors[models.DeletedUserId])
+}
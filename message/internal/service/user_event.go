@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces"
+	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/message/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/message/internal/pkg/users"
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UserEventService keeps the message service in step with the events the
+// user service publishes.
+type UserEventService struct {
+	repo      interfaces.UserDataRepository
+	cache     interfaces.Cache
+	policy    string
+	batchSize int
+	logger    *logrus.Logger
+}
+
+// NewUserEventService returns the service. policy says what happens to the
+// messages of deleted users, one of the DeletedUser constants, and
+// batchSize how many of them are changed in each transaction.
+func NewUserEventService(repo interfaces.UserDataRepository, cache interfaces.Cache, policy string, batchSize int, logger *logrus.Logger) *UserEventService {
+	return &UserEventService{
+		repo:      repo,
+		cache:     cache,
+		policy:    policy,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// HandleEvent acts on event. It only fails when the event should be
+// delivered again; events it has no use for and events it can't read are
+// dropped.
+func (s *UserEventService) HandleEvent(ctx context.Context, event *events.Envelope) error {
+	switch event.Topic {
+	case events.TopicUserDeleted:
+		return s.userDeleted(ctx, event)
+	default:
+		metrics.EventsConsumed.WithLabelValues(event.Topic, "ignored").Inc()
+		return nil
+	}
+}
+
+// userDeleted applies the deleted user policy to the messages of the user
+// and takes them out of their channels. The user is cached as missing
+// first, so the author check stops accepting their messages without
+// waiting for the cached author to expire. Redeliveries of an event
+// already acted on change nothing.
+func (s *UserEventService) userDeleted(ctx context.Context, event *events.Envelope) error {
+	var payload events.UserDeleted
+	if err := json.Unmarshal(event.Payload, &payload); err != nil || payload.UserId <= 0 {
+		s.logger.WithFields(logrus.Fields{
+			"module":  "userevent",
+			"func":    "userDeleted",
+			"eventId": event.Id,
+		}).Error("dropping malformed user.deleted event")
+
+		metrics.EventsConsumed.WithLabelValues(event.Topic, "malformed").Inc()
+		return nil
+	}
+
+	if err := s.repo.MarkUserDeleted(ctx, payload.UserId); err != nil {
+		metrics.EventsConsumed.WithLabelValues(event.Topic, "failed").Inc()
+		return err
+	}
+	s.cache.SetMissing(ctx, users.AuthorKey(payload.UserId))
+
+	// Each batch is dropped from the cache as soon as it is committed. If
+	// one fails, the redelivered event carries on where this one stopped.
+	var messages, references int
+	for s.policy != models.DeletedUserKeep {
+		deletion, err := s.repo.DeleteUserMessages(ctx, payload.UserId, s.policy, s.batchSize)
+		if err != nil {
+			metrics.EventsConsumed.WithLabelValues(event.Topic, "failed").Inc()
+			return err
+		}
+		if len(deletion.MessageIds) == 0 && len(deletion.ReferenceIds) == 0 {
+			break
+		}
+
+		s.invalidate(ctx, deletion)
+		messages += len(deletion.MessageIds)
+		references += len(deletion.ReferenceIds)
+	}
+
+	channelIds, err := s.repo.FinishUserDeletion(ctx, event.Topic, event.Id, payload.UserId)
+	if errors.Is(err, models.ErrEventProcessed) {
+		s.logger.WithFields(logrus.Fields{
+			"module":  "userevent",
+			"func":    "userDeleted",
+			"eventId": event.Id,
+			"userId":  payload.UserId,
+		}).Debug("user.deleted event already processed")
+
+		metrics.EventsConsumed.WithLabelValues(event.Topic, "duplicate").Inc()
+		return nil
+	}
+	if err != nil {
+		metrics.EventsConsumed.WithLabelValues(event.Topic, "failed").Inc()
+		return err
+	}
+
+	s.invalidate(ctx, &models.UserDataDeletion{ChannelIds: channelIds})
+
+	s.logger.WithFields(logrus.Fields{
+		"module":     "userevent",
+		"func":       "userDeleted",
+		"eventId":    event.Id,
+		"userId":     payload.UserId,
+		"policy":     s.policy,
+		"messages":   messages,
+		"references": references,
+	}).Info("deleted user data")
+
+	metrics.EventsConsumed.WithLabelValues(event.Topic, "processed").Inc()
+	return nil
+}
+
+// invalidate drops whatever deletion changed from the cache. Hidden
+// messages are cached as missing straight away, like deleted ones.
+func (s *UserEventService) invalidate(ctx context.Context, deletion *models.UserDataDeletion) {
+	invalidateMessages(ctx, s.cache, append(append([]int{}, deletion.MessageIds...), deletion.ReferenceIds...)...)
+	if s.policy == models.DeletedUserSoftDelete && len(deletion.MessageIds) > 0 {
+		for _, key := range messageKeys(ctx, s.cache, deletion.MessageIds) {
+			s.cache.SetMissing(ctx, key)
+		}
+	}
+
+	seen := make(map[int]bool, len(deletion.ChannelIds))
+	versionKeys := make([]string, 0, len(deletion.ChannelIds))
+	for _, channelId := range deletion.ChannelIds {
+		if seen[channelId] {
+			continue
+		}
+
+		seen[channelId] = true
+		versionKeys = append(versionKeys, channelVersionKey(channelId))
+	}
+	if len(versionKeys) > 0 {
+		s.cache.Bump(ctx, versionKeys...)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dmitriysta/messenger/message/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/message/internal/models"
+	"github.com/dmitriysta/messenger/pkg/events"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func userDeletedEvent(t *testing.T, id string, userId int) *events.Envelope {
+	payload, err := json.Marshal(events.UserDeleted{UserId: userId})
+	require.NoError(t, err)
+
+	return &events.Envelope{
+		Id:      id,
+		Topic:   events.TopicUserDeleted,
+		Key:     "7",
+		Payload: payload,
+	}
+}
+
+// testBatchSize is the batch size of the services under test. The mocked
+// repository decides what each batch returns, so the value only has to
+// reach it.
+const testBatchSize = 2
+
+// expectAuthorMissing expects user 7 to be cached as missing, so the author
+// check stops accepting their messages.
+func expectAuthorMissing(ctx context.Context, mockCache *mocks.Cache) {
+	mockCache.On("SetMissing", ctx, "messages:author:7").Once()
+}
+
+func TestUserEventService_UserDeleted_Anonymise(t *testing.T) {
+	mockRepo := new(mocks.UserDataRepository)
+	mockCache := new(mocks.Cache)
+	ctx := context.Background()
+
+	mockRepo.On("MarkUserDeleted", ctx, 7).Return(nil).Once()
+	mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(&models.UserDataDeletion{
+		MessageIds:   []int{1, 2},
+		ReferenceIds: []int{3},
+		ChannelIds:   []int{10, 10, 11},
+	}, nil).Once()
+	mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(&models.UserDataDeletion{
+		MessageIds: []int{4},
+		ChannelIds: []int{11},
+	}, nil).Once()
+	mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(&models.UserDataDeletion{}, nil).Once()
+	mockRepo.On("FinishUserDeletion", ctx, events.TopicUserDeleted, "42", 7).Return([]int{12}, nil).Once()
+
+	expectAuthorMissing(ctx, mockCache)
+	mockCache.On("Bump", ctx, "messages:message:1:version", "messages:message:2:version", "messages:message:3:version").Once()
+	mockCache.On("Bump", ctx, "messages:message:4:version").Once()
+	// Every batch drops the channels it touched.
+	mockCache.On("Bump", ctx, "messages:channel:10:version", "messages:channel:11:version").Once()
+	mockCache.On("Bump", ctx, "messages:channel:11:version").Once()
+	mockCache.On("Bump", ctx, "messages:channel:12:version").Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewUserEventService(mockRepo, mockCache, models.DeletedUserAnonymise, testBatchSize, logger)
+
+	err := service.HandleEvent(ctx, userDeletedEvent(t, "42", 7))
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestUserEventService_UserDeleted_SoftDelete(t *testing.T) {
+	mockRepo := new(mocks.UserDataRepository)
+	mockCache := new(mocks.Cache)
+	ctx := context.Background()
+
+	mockRepo.On("MarkUserDeleted", ctx, 7).Return(nil).Once()
+	mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserSoftDelete, testBatchSize).Return(&models.UserDataDeletion{
+		MessageIds:   []int{1},
+		ReferenceIds: []int{3},
+		ChannelIds:   []int{10, 12},
+	}, nil).Once()
+	mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserSoftDelete, testBatchSize).Return(&models.UserDataDeletion{}, nil).Once()
+	mockRepo.On("FinishUserDeletion", ctx, events.TopicUserDeleted, "42", 7).Return(nil, nil).Once()
+
+	expectAuthorMissing(ctx, mockCache)
+	// Hidden messages are cached as missing, their quotes are refetched.
+	mockCache.On("Bump", ctx, "messages:message:1:version", "messages:message:3:version").Once()
+	mockCache.On("Versions", ctx, []string{"messages:message:1:version"}).Return([]int64{1}).Once()
+	mockCache.On("SetMissing", ctx, "messages:message:1:v1").Once()
+	mockCache.On("Bump", ctx, "messages:channel:10:version", "messages:channel:12:version").Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewUserEventService(mockRepo, mockCache, models.DeletedUserSoftDelete, testBatchSize, logger)
+
+	err := service.HandleEvent(ctx, userDeletedEvent(t, "42", 7))
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestUserEventService_UserDeleted_Keep(t *testing.T) {
+	mockRepo := new(mocks.UserDataRepository)
+	mockCache := new(mocks.Cache)
+	ctx := context.Background()
+
+	// Only the memberships go.
+	mockRepo.On("MarkUserDeleted", ctx, 7).Return(nil).Once()
+	mockRepo.On("FinishUserDeletion", ctx, events.TopicUserDeleted, "42", 7).Return([]int{10}, nil).Once()
+	expectAuthorMissing(ctx, mockCache)
+	mockCache.On("Bump", ctx, "messages:channel:10:version").Once()
+
+	logger, _ := test.NewNullLogger()
+	service := NewUserEventService(mockRepo, mockCache, models.DeletedUserKeep, testBatchSize, logger)
+
+	err := service.HandleEvent(ctx, userDeletedEvent(t, "42", 7))
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "DeleteUserMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockCache.AssertExpectations(t)
+}
+
+func TestUserEventService_UserDeleted_Redelivered(t *testing.T) {
+	mockRepo := new(mocks.UserDataRepository)
+	mockCache := new(mocks.Cache)
+	ctx := context.Background()
+
+	// Everything was done before, so there is nothing left to change.
+	mockRepo.On("MarkUserDeleted", ctx, 7).Return(nil).Once()
+	mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(&models.UserDataDeletion{}, nil).Once()
+	mockRepo.On("FinishUserDeletion", ctx, events.TopicUserDeleted, "42", 7).Return(nil, models.ErrEventProcessed).Once()
+	expectAuthorMissing(ctx, mockCache)
+
+	logger, _ := test.NewNullLogger()
+	service := NewUserEventService(mockRepo, mockCache, models.DeletedUserAnonymise, testBatchSize, logger)
+
+	err := service.HandleEvent(ctx, userDeletedEvent(t, "42", 7))
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "Bump", mock.Anything, mock.Anything)
+}
+
+func TestUserEventService_UserDeleted_Failure(t *testing.T) {
+	dbErr := errors.New("connection reset")
+
+	tests := []struct {
+		name   string
+		expect func(ctx context.Context, mockRepo *mocks.UserDataRepository, mockCache *mocks.Cache)
+	}{
+		{
+			name: "mark",
+			expect: func(ctx context.Context, mockRepo *mocks.UserDataRepository, mockCache *mocks.Cache) {
+				mockRepo.On("MarkUserDeleted", ctx, 7).Return(dbErr).Once()
+			},
+		},
+		{
+			name: "second batch",
+			expect: func(ctx context.Context, mockRepo *mocks.UserDataRepository, mockCache *mocks.Cache) {
+				mockRepo.On("MarkUserDeleted", ctx, 7).Return(nil).Once()
+				mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(&models.UserDataDeletion{
+					MessageIds: []int{1},
+					ChannelIds: []int{10},
+				}, nil).Once()
+				mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(nil, dbErr).Once()
+				expectAuthorMissing(ctx, mockCache)
+				// The first batch is committed, so it is dropped already.
+				mockCache.On("Bump", ctx, "messages:message:1:version").Once()
+				mockCache.On("Bump", ctx, "messages:channel:10:version").Once()
+			},
+		},
+		{
+			name: "finish",
+			expect: func(ctx context.Context, mockRepo *mocks.UserDataRepository, mockCache *mocks.Cache) {
+				mockRepo.On("MarkUserDeleted", ctx, 7).Return(nil).Once()
+				mockRepo.On("DeleteUserMessages", ctx, 7, models.DeletedUserAnonymise, testBatchSize).Return(&models.UserDataDeletion{}, nil).Once()
+				mockRepo.On("FinishUserDeletion", ctx, events.TopicUserDeleted, "42", 7).Return(nil, dbErr).Once()
+				expectAuthorMissing(ctx, mockCache)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserDataRepository)
+			mockCache := new(mocks.Cache)
+			ctx := context.Background()
+			tt.expect(ctx, mockRepo, mockCache)
+
+			logger, _ := test.NewNullLogger()
+			service := NewUserEventService(mockRepo, mockCache, models.DeletedUserAnonymise, testBatchSize, logger)
+
+			// The error leaves the event to be delivered again.
+			err := service.HandleEvent(ctx, userDeletedEvent(t, "42", 7))
+			assert.ErrorIs(t, err, dbErr)
+			mockRepo.AssertExpectations(t)
+			mockCache.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserEventService_HandleEvent_Dropped(t *testing.T) {
+	mockRepo := new(mocks.UserDataRepository)
+	ctx := context.Background()
+
+	logger, _ := test.NewNullLogger()
+	service := NewUserEventService(mockRepo, new(mocks.Cache), models.DeletedUserAnonymise, testBatchSize, logger)
+
+	malformed := userDeletedEvent(t, "42", 7)
+	malformed.Payload = json.RawMessage(`{"userId":"seven"}`)
+	assert.NoError(t, service.HandleEvent(ctx, malformed))
+
+	assert.NoError(t, service.HandleEvent(ctx, &events.Envelope{Id: "1", Topic: events.TopicUserCreated, Payload: json.RawMessage(`{}`)}))
+
+	mockRepo.AssertNotCalled(t, "MarkUserDeleted", mock.Anything, mock.Anything)
+}
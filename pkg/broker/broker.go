@@ -0,0 +1,40 @@
+// Package broker publishes the domain events of the messenger services.
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
+// Broker delivers published events to whoever consumes them.
+type Broker interface {
+	Publish(ctx context.Context, event *events.Envelope) error
+}
+
+// New returns the broker selected by driver. The redis driver publishes to
+// streams named by streamPrefix and trims each to about streamMaxLen
+// events.
+func New(driver, streamPrefix string, streamMaxLen int, client redis.Cmdable, logger *logrus.Logger) (Broker, error) {
+	switch driver {
+	case DriverRedis:
+		if streamPrefix == "" {
+			return nil, fmt.Errorf("broker driver %q needs BROKER_STREAM_PREFIX", driver)
+		}
+
+		return NewRedisBroker(client, streamPrefix, int64(streamMaxLen)), nil
+	case DriverMemory:
+		return NewMemoryBroker(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown broker driver %q", driver)
+	}
+}
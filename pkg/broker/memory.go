@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handler consumes an event delivered by MemoryBroker.
+type Handler func(ctx context.Context, event *events.Envelope) error
+
+// MemoryBroker hands events straight to handlers subscribed in the same
+// process, for local development and tests. Events nobody subscribed to are
+// logged and dropped.
+type MemoryBroker struct {
+	logger *logrus.Logger
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewMemoryBroker(logger *logrus.Logger) *MemoryBroker {
+	return &MemoryBroker{
+		logger:   logger,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe has handler called with every event published to topic.
+func (b *MemoryBroker) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// Publish calls the handlers of the event's topic in turn. The first error
+// fails the publish, so the event is published again later and handlers
+// must tolerate seeing it twice.
+func (b *MemoryBroker) Publish(ctx context.Context, event *events.Envelope) error {
+	b.mu.RLock()
+	handlers := b.handlers[event.Topic]
+	b.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		b.logger.WithFields(logrus.Fields{
+			"module": "broker",
+			"func":   "Publish",
+			"topic":  event.Topic,
+			"id":     event.Id,
+			"key":    event.Key,
+		}).Debug("event published with no subscribers")
+
+		return nil
+	}
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisBroker appends events to Redis Streams, one stream per topic.
+// Streams are trimmed to roughly maxLen entries, so consumers that fall
+// further behind than that lose events.
+type RedisBroker struct {
+	client redis.Cmdable
+	prefix string
+	maxLen int64
+}
+
+func NewRedisBroker(client redis.Cmdable, prefix string, maxLen int64) *RedisBroker {
+	return &RedisBroker{
+		client: client,
+		prefix: prefix,
+		maxLen: maxLen,
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, event *events.Envelope) error {
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: events.Stream(b.prefix, event.Topic),
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: event.Values(),
+	}).Err()
+}
@@ -0,0 +1,229 @@
+// Package configload fills a service's config struct from command line
+// flags, the environment and an optional config file, as described by the
+// struct tags of its fields.
+package configload
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted stands in for the value of a secret field in Fields.
+const Redacted = "[REDACTED]"
+
+const (
+	configFileEnv  = "CONFIG_FILE"
+	configFileFlag = "config"
+	// defaultConfigFile is read when present; running without it is fine.
+	defaultConfigFile = ".env"
+)
+
+// field is one leaf of the config struct together with its struct tags:
+//
+//	env      - environment variable, also the key used in the config file
+//	flag     - command line flag name
+//	default  - value used when no source sets the field
+//	required - the field must end up non-empty
+//	secret   - the value is redacted when the config is logged
+type field struct {
+	value    reflect.Value
+	env      string
+	flag     string
+	usage    string
+	def      string
+	required bool
+	secret   bool
+}
+
+// Load fills cfg, a pointer to a struct, from, in order of precedence,
+// command line flags, the environment and the config file, falling back to
+// the defaults in the struct tags. A flag that is given wins even when it
+// is empty. name is the program name used in flag errors. Load returns the
+// arguments left over after flag parsing, and a single error listing every
+// field that is missing or malformed.
+func Load(cfg interface{}, name string, args []string) ([]string, error) {
+	fields := collect(reflect.ValueOf(cfg).Elem())
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := flags.String(configFileFlag, "", "path to an optional KEY=VALUE config file (default "+defaultConfigFile+")")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		flagValues[f.flag] = flags.String(f.flag, "", f.usage)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	fileValues, err := readConfigFile(*configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		value := *flagValues[f.flag]
+		if !given[f.flag] {
+			value = os.Getenv(f.env)
+			if value == "" {
+				value = fileValues[f.env]
+			}
+			if value == "" {
+				value = f.def
+			}
+		}
+
+		if value == "" {
+			if f.required {
+				errs = append(errs, fmt.Errorf("%s is required", f.env))
+			}
+			continue
+		}
+
+		if err := set(f.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return flags.Args(), nil
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(configFileEnv)
+		explicit = path != ""
+	}
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	values, err := godotenv.Read(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// Fields returns cfg, a pointer to a struct filled by Load, as log fields
+// keyed by environment variable, with secrets redacted.
+func Fields(cfg interface{}) logrus.Fields {
+	result := logrus.Fields{}
+	for _, f := range collect(reflect.ValueOf(cfg).Elem()) {
+		value := fmt.Sprint(f.value.Interface())
+		if f.secret && value != "" {
+			value = Redacted
+		}
+
+		result[f.env] = value
+	}
+
+	return result
+}
+
+func collect(v reflect.Value) []field {
+	var result []field
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		value := v.Field(i)
+
+		if structField.Type.Kind() == reflect.Struct && structField.Type != reflect.TypeOf(time.Duration(0)) {
+			result = append(result, collect(value)...)
+			continue
+		}
+
+		env := structField.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+
+		result = append(result, field{
+			value:    value,
+			env:      env,
+			flag:     structField.Tag.Get("flag"),
+			usage:    structField.Tag.Get("usage"),
+			def:      structField.Tag.Get("default"),
+			required: structField.Tag.Get("required") == "true",
+			secret:   structField.Tag.Get("secret") == "true",
+		})
+	}
+
+	return result
+}
+
+func set(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(duration))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(number))
+	case reflect.Float64:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(number)
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(boolean)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
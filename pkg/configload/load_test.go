@@ -0,0 +1,143 @@
+package configload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Name   string `env:"TEST_NAME" flag:"name" default:"default"`
+	Server struct {
+		Port    int           `env:"TEST_PORT" flag:"port" required:"true"`
+		Timeout time.Duration `env:"TEST_TIMEOUT" flag:"timeout" default:"1s"`
+		Debug   bool          `env:"TEST_DEBUG" flag:"debug"`
+		Ratio   float64       `env:"TEST_RATIO" flag:"ratio" default:"0.5"`
+	}
+	Token string `env:"TEST_TOKEN" flag:"token" required:"true" secret:"true"`
+}
+
+// clearEnv unsets every variable testConfig reads for the duration of the
+// test, including where to find the config file.
+func clearEnv(t *testing.T) {
+	for _, env := range []string{"TEST_NAME", "TEST_PORT", "TEST_TIMEOUT", "TEST_DEBUG", "TEST_RATIO", "TEST_TOKEN", configFileEnv} {
+		t.Setenv(env, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      string
+		flagGiven bool
+		env       string
+		file      string
+		want      string
+	}{
+		{name: "default", want: "default"},
+		{name: "file over default", file: "file", want: "file"},
+		{name: "env over file", env: "env", file: "file", want: "env"},
+		{name: "flag over env", flag: "flag", env: "env", file: "file", want: "flag"},
+		{name: "flag over default", flag: "flag", want: "flag"},
+		{name: "empty flag over env", flag: "", flagGiven: true, env: "env", file: "file", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("TEST_NAME", tt.env)
+
+			args := []string{"-port", "80", "-token", "token"}
+			if tt.file != "" {
+				args = append(args, "-config", writeConfigFile(t, "TEST_NAME="+tt.file+"\n"))
+			}
+			if tt.flag != "" || tt.flagGiven {
+				args = append(args, "-name="+tt.flag)
+			}
+
+			cfg := &testConfig{}
+			_, err := Load(cfg, "test", args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.Name)
+		})
+	}
+}
+
+func TestLoad_Types(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TEST_PORT", "8081")
+	t.Setenv("TEST_DEBUG", "true")
+
+	cfg := &testConfig{}
+	rest, err := Load(cfg, "test", []string{"-timeout", "5s", "-token", "token", "migrate", "up"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 8081, cfg.Server.Port)
+	assert.Equal(t, 5*time.Second, cfg.Server.Timeout)
+	assert.True(t, cfg.Server.Debug)
+	assert.Equal(t, 0.5, cfg.Server.Ratio)
+	assert.Equal(t, "token", cfg.Token)
+	// What follows the flags is left to the caller.
+	assert.Equal(t, []string{"migrate", "up"}, rest)
+}
+
+func TestLoad_CollectsErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TEST_TIMEOUT", "soon")
+
+	_, err := Load(&testConfig{}, "test", nil)
+	require.Error(t, err)
+
+	// One report lists every problem, not just the first.
+	assert.ErrorContains(t, err, "TEST_PORT is required")
+	assert.ErrorContains(t, err, "TEST_TIMEOUT: time: invalid duration")
+	assert.ErrorContains(t, err, "TEST_TOKEN is required")
+	assert.NotContains(t, err.Error(), "TEST_NAME")
+}
+
+func TestLoad_MissingConfigFile(t *testing.T) {
+	clearEnv(t)
+	args := []string{"-port", "80", "-token", "token"}
+
+	// Without a .env in the working directory the defaults are used.
+	_, err := Load(&testConfig{}, "test", args)
+	assert.NoError(t, err)
+
+	// A file that was asked for has to exist.
+	_, err = Load(&testConfig{}, "test", append(args, "-config", filepath.Join(t.TempDir(), "missing.env")))
+	assert.ErrorContains(t, err, "failed to read config file")
+}
+
+func TestFields_RedactsSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "set", token: "token", want: Redacted},
+		{name: "empty", token: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &testConfig{Name: "name", Token: tt.token}
+			cfg.Server.Port = 80
+
+			got := Fields(cfg)
+			assert.Equal(t, tt.want, got["TEST_TOKEN"])
+			assert.Equal(t, "name", got["TEST_NAME"])
+			assert.Equal(t, "80", got["TEST_PORT"])
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package events defines how the messenger services publish domain events,
+// and the events the user service publishes.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	TopicUserCreated = "user.created"
+	TopicUserDeleted = "user.deleted"
+)
+
+// Fields of an event in a Redis stream.
+const (
+	FieldId         = "id"
+	FieldTopic      = "topic"
+	FieldKey        = "key"
+	FieldPayload    = "payload"
+	FieldOccurredAt = "occurredAt"
+)
+
+var ErrMalformedEvent = errors.New("malformed event")
+
+// Envelope is an event on the wire. Id is unique within the topic and the
+// same on every delivery of the event, so consumers can tell redeliveries
+// apart from new events. Key names what the event is about, such as the id
+// of the user, and events with the same key are published in order.
+type Envelope struct {
+	Id         string          `json:"id"`
+	Topic      string          `json:"topic"`
+	Key        string          `json:"key"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurredAt"`
+}
+
+// UserCreated is the payload of user.created.
+type UserCreated struct {
+	UserId    int       `json:"userId"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserDeleted is the payload of user.deleted. The account is gone by the
+// time it is published; whatever other services keep about the user is
+// theirs to clean up.
+type UserDeleted struct {
+	UserId    int       `json:"userId"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Stream returns the Redis stream that events of topic go to.
+func Stream(prefix, topic string) string {
+	return prefix + topic
+}
+
+// Values returns e as the fields of a Redis stream entry.
+func (e *Envelope) Values() map[string]interface{} {
+	return map[string]interface{}{
+		FieldId:         e.Id,
+		FieldTopic:      e.Topic,
+		FieldKey:        e.Key,
+		FieldPayload:    string(e.Payload),
+		FieldOccurredAt: strconv.FormatInt(e.OccurredAt.UnixMilli(), 10),
+	}
+}
+
+// FromValues reads an envelope from the fields of a Redis stream entry.
+func FromValues(values map[string]interface{}) (*Envelope, error) {
+	field := func(name string) string {
+		value, _ := values[name].(string)
+		return value
+	}
+
+	occurredAt, err := strconv.ParseInt(field(FieldOccurredAt), 10, 64)
+	if err != nil || field(FieldId) == "" || field(FieldTopic) == "" {
+		return nil, ErrMalformedEvent
+	}
+
+	return &Envelope{
+		Id:         field(FieldId),
+		Topic:      field(FieldTopic),
+		Key:        field(FieldKey),
+		Payload:    json.RawMessage(field(FieldPayload)),
+		OccurredAt: time.UnixMilli(occurredAt).UTC(),
+	}, nil
+}
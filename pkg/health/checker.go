@@ -0,0 +1,167 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+	StatusDegraded    = "degraded"
+)
+
+// Check probes one dependency. Run may return a short detail string, such as
+// the schema version, that is included in the readiness report. A failing
+// Optional check marks the report degraded but keeps the instance ready,
+// for dependencies the service can work around.
+type Check struct {
+	Name     string
+	Timeout  time.Duration
+	Optional bool
+	Run      func(ctx context.Context) (string, error)
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Metrics are the gauges a Checker exports, labelled by dependency. Each
+// service registers its own.
+type Metrics struct {
+	Up      *prometheus.GaugeVec
+	Latency *prometheus.GaugeVec
+}
+
+// Checker serves /livez and /readyz. Liveness only says the process is up;
+// readiness also requires every dependency check to pass and the instance
+// not to be draining.
+type Checker struct {
+	readiness *Readiness
+	checks    []Check
+	metrics   Metrics
+	logger    *logrus.Logger
+}
+
+func NewChecker(readiness *Readiness, metrics Metrics, logger *logrus.Logger, checks ...Check) *Checker {
+	return &Checker{
+		readiness: readiness,
+		checks:    checks,
+		metrics:   metrics,
+		logger:    logger,
+	}
+}
+
+// Run executes all checks concurrently, each bounded by its own timeout, and
+// exports the results as Prometheus gauges.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			result := c.runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			switch {
+			case result.Status == StatusOK:
+			case check.Optional:
+				if report.Status == StatusOK {
+					report.Status = StatusDegraded
+				}
+			default:
+				report.Status = StatusUnavailable
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	if !c.readiness.Ready() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check.Run(ctx)
+	latency := time.Since(start)
+
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		Detail:    detail,
+	}
+
+	up := 1.0
+	if err != nil {
+		up = 0
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+
+		c.logger.WithFields(logrus.Fields{
+			"module":     "health",
+			"func":       "runCheck",
+			"dependency": check.Name,
+			"error":      err.Error(),
+		}).Warnf("dependency check failed: %v", err)
+	}
+
+	c.metrics.Up.WithLabelValues(check.Name).Set(up)
+	c.metrics.Latency.WithLabelValues(check.Name).Set(latency.Seconds())
+
+	return result
+}
+
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	}
+}
+
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusOK && report.Status != StatusDegraded {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, report)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/migrate"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type redisPinger interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+func PostgresCheck(db *sql.DB, timeout time.Duration) Check {
+	return Check{
+		Name:    "postgres",
+		Timeout: timeout,
+		Run: func(ctx context.Context) (string, error) {
+			return "", db.PingContext(ctx)
+		},
+	}
+}
+
+// RedisCheck is optional: the services work around redis being down, with
+// rate limiting failing open and the cache falling back to memory, so an
+// outage degrades the instance rather than taking it out of rotation.
+func RedisCheck(client redisPinger, timeout time.Duration) Check {
+	return Check{
+		Name:     "redis",
+		Timeout:  timeout,
+		Optional: true,
+		Run: func(ctx context.Context) (string, error) {
+			return "", client.Ping(ctx).Err()
+		},
+	}
+}
+
+// MigrationsCheck reports the schema version and fails while migrations
+// are pending, so an instance never serves traffic against an old schema.
+// It only reads; a database that was never migrated has every migration
+// pending and isn't ready.
+func MigrationsCheck(migrator *migrate.Migrator, timeout time.Duration) Check {
+	return Check{
+		Name:    "migrations",
+		Timeout: timeout,
+		Run: func(ctx context.Context) (string, error) {
+			version, pending, err := migrator.Version(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			detail := fmt.Sprintf("schema version %d", version)
+			if pending > 0 {
+				return detail, fmt.Errorf("%d pending migrations", pending)
+			}
+
+			return detail, nil
+		},
+	}
+}
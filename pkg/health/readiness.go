@@ -0,0 +1,24 @@
+package health
+
+import (
+	"sync/atomic"
+)
+
+// Readiness tells load balancers whether this instance should receive
+// traffic. It is flipped off at the start of a shutdown so that new requests
+// go elsewhere while in-flight ones drain.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnknownCommand is returned by Run for anything but up, down and
+// status.
+var ErrUnknownCommand = errors.New("unknown migrate command")
+
+// Run carries out the migrate subcommand of a service binary. status
+// prints to w.
+func (m *Migrator) Run(ctx context.Context, command string, w io.Writer) error {
+	switch command {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "status":
+		return m.WriteStatus(ctx, w)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownCommand, command)
+	}
+}
+
+// WriteStatus prints the Status of every migration as a table.
+func (m *Migrator) WriteStatus(ctx context.Context, w io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return tw.Flush()
+}
+
+// LogVersion reports the schema version at startup and warns when the
+// database is behind the binary.
+func (m *Migrator) LogVersion(ctx context.Context) {
+	version, pending, err := m.Version(ctx)
+	if err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"module": "migrate",
+			"func":   "LogVersion",
+			"error":  err.Error(),
+		}).Errorf("failed to get schema version: %v", err)
+
+		return
+	}
+
+	entry := m.logger.WithFields(logrus.Fields{
+		"module":        "migrate",
+		"func":          "LogVersion",
+		"schemaVersion": version,
+		"pending":       pending,
+	})
+	if pending > 0 {
+		entry.Warn("database schema has pending migrations, run \"migrate up\"")
+		return
+	}
+
+	entry.Info("database schema is up to date")
+}
@@ -0,0 +1,341 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Migration is one versioned schema change. Files are named
+// NNNN_description.up.sql and NNNN_description.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies embedded migrations and records them in a per-service
+// table. Every run holds a PostgreSQL advisory lock so that replicas
+// starting at the same time don't apply the same migration twice.
+type Migrator struct {
+	db         *sql.DB
+	table      string
+	lockId     int64
+	migrations []Migration
+	logger     *logrus.Logger
+}
+
+func NewMigrator(db *sql.DB, migrations fs.FS, table string, lockId int64, logger *logrus.Logger) (*Migrator, error) {
+	loaded, err := load(migrations)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "migrate",
+			"func":   "NewMigrator",
+			"error":  err.Error(),
+		}).Errorf("failed to load migrations: %v", err)
+
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		table:      table,
+		lockId:     lockId,
+		migrations: loaded,
+		logger:     logger,
+	}, nil
+}
+
+// Up applies every migration that hasn't been applied yet, in order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			insert := fmt.Sprintf(`INSERT INTO %s (version, name) VALUES ($1, $2)`, m.table)
+			if err := m.run(ctx, conn, migration, migration.Up, insert, migration.Version, migration.Name); err != nil {
+				return err
+			}
+
+			m.logger.WithFields(logrus.Fields{
+				"module":  "migrate",
+				"func":    "Up",
+				"version": migration.Version,
+				"name":    migration.Name,
+			}).Info("migration applied")
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			remove := fmt.Sprintf(`DELETE FROM %s WHERE version = $1`, m.table)
+			if err := m.run(ctx, conn, migration, migration.Down, remove, migration.Version); err != nil {
+				return err
+			}
+
+			m.logger.WithFields(logrus.Fields{
+				"module":  "migrate",
+				"func":    "Down",
+				"version": migration.Version,
+				"name":    migration.Name,
+			}).Info("migration rolled back")
+
+			return nil
+		}
+
+		return nil
+	})
+}
+
+// Status lists every known migration and whether it has been applied. It
+// only reads: before the first Up there is no migrations table, and every
+// migration is reported pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, m.table).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time)
+	if exists {
+		applied, err = m.applied(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Version returns the highest applied migration version and how many known
+// migrations are still pending. Like Status it doesn't write.
+func (m *Migrator) Version(ctx context.Context) (version int, pending int, err error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, status := range statuses {
+		if status.Applied {
+			version = status.Version
+		} else {
+			pending++
+		}
+	}
+
+	return version, pending, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	// Session-level advisory locks belong to a connection, so the lock, the
+	// migrations and the unlock all have to run on the same one.
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, m.lockId); err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"module": "migrate",
+			"func":   "withLock",
+			"error":  err.Error(),
+		}).Errorf("failed to acquire migration lock: %v", err)
+
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, m.lockId)
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, m.table)
+
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"module": "migrate",
+			"func":   "ensureTable",
+			"error":  err.Error(),
+		}).Errorf("failed to create migrations table: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT version, applied_at FROM %s`, m.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// run executes one migration script and its bookkeeping statement in a
+// single transaction, so a failed migration leaves no trace.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, script, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"module":  "migrate",
+			"func":    "run",
+			"version": migration.Version,
+			"name":    migration.Name,
+			"error":   err.Error(),
+		}).Errorf("failed to run migration: %v", err)
+
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func load(migrations fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || path.Ext(fileName) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", fileName, err)
+		}
+
+		content, err := fs.ReadFile(migrations, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, name)
+		}
+
+		if direction == ".up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	loaded := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down script", migration.Version, migration.Name)
+		}
+
+		loaded = append(loaded, *migration)
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].Version < loaded[j].Version
+	})
+
+	return loaded, nil
+}
@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"context"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmitriysta/messenger/pkg/pgtest"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	migrations := fstest.MapFS{
+		"0002_add_name.up.sql":       {Data: []byte("ALTER TABLE thing ADD COLUMN name TEXT;")},
+		"0002_add_name.down.sql":     {Data: []byte("ALTER TABLE thing DROP COLUMN name;")},
+		"0001_create_thing.up.sql":   {Data: []byte("CREATE TABLE thing (id INTEGER);")},
+		"0001_create_thing.down.sql": {Data: []byte("DROP TABLE thing;")},
+		"README.md":                  {Data: []byte("not a migration")},
+	}
+
+	loaded, err := load(migrations)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+
+	assert.Equal(t, Migration{
+		Version: 1,
+		Name:    "create_thing",
+		Up:      "CREATE TABLE thing (id INTEGER);",
+		Down:    "DROP TABLE thing;",
+	}, loaded[0])
+	assert.Equal(t, 2, loaded[1].Version)
+	assert.Equal(t, "add_name", loaded[1].Name)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			name:  "missing down",
+			files: fstest.MapFS{"0001_create.up.sql": {Data: []byte("SELECT 1")}},
+			err:   "needs both an up and a down script",
+		},
+		{
+			name:  "no direction",
+			files: fstest.MapFS{"0001_create.sql": {Data: []byte("SELECT 1")}},
+			err:   "invalid migration file name",
+		},
+		{
+			name:  "no name",
+			files: fstest.MapFS{"0001.up.sql": {Data: []byte("SELECT 1")}},
+			err:   "invalid migration file name",
+		},
+		{
+			name:  "bad version",
+			files: fstest.MapFS{"first_create.up.sql": {Data: []byte("SELECT 1")}},
+			err:   "invalid migration version",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"0001_create.up.sql":  {Data: []byte("SELECT 1")},
+				"0001_other.down.sql": {Data: []byte("SELECT 1")},
+			},
+			err: "conflicting names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestMigrator_Run_UnknownCommand(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	migrator, err := NewMigrator(nil, fstest.MapFS{}, "test_schema_migrations", 72_999, logger)
+	require.NoError(t, err)
+
+	// Rejected before the database is touched.
+	err = migrator.Run(context.Background(), "sideways", io.Discard)
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+}
+
+func TestMigrator(t *testing.T) {
+	db := pgtest.DB(t, "pgx")
+	logger, _ := test.NewNullLogger()
+	ctx := context.Background()
+
+	migrations := fstest.MapFS{
+		"0001_create_thing.up.sql":   {Data: []byte("CREATE TABLE thing (id INTEGER);")},
+		"0001_create_thing.down.sql": {Data: []byte("DROP TABLE thing;")},
+		"0002_add_name.up.sql":       {Data: []byte("ALTER TABLE thing ADD COLUMN name TEXT;")},
+		"0002_add_name.down.sql":     {Data: []byte("ALTER TABLE thing DROP COLUMN name;")},
+	}
+	migrator, err := NewMigrator(db, migrations, "test_schema_migrations", 72_999, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.Up(ctx))
+	version, pending, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	assert.Equal(t, 0, pending)
+
+	_, err = db.ExecContext(ctx, `INSERT INTO thing (id, name) VALUES (1, 'one')`)
+	require.NoError(t, err)
+
+	// Up again is a no-op.
+	require.NoError(t, migrator.Up(ctx))
+
+	require.NoError(t, migrator.Down(ctx))
+	version, pending, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, 1, pending)
+
+	_, err = db.ExecContext(ctx, `SELECT name FROM thing`)
+	assert.Error(t, err)
+}
+
+func TestMigrator_VersionIsReadOnly(t *testing.T) {
+	db := pgtest.DB(t, "pgx")
+	logger, _ := test.NewNullLogger()
+	ctx := context.Background()
+
+	migrations := fstest.MapFS{
+		"0001_create_thing.up.sql":   {Data: []byte("CREATE TABLE thing (id INTEGER);")},
+		"0001_create_thing.down.sql": {Data: []byte("DROP TABLE thing;")},
+	}
+	migrator, err := NewMigrator(db, migrations, "test_schema_migrations", 72_999, logger)
+	require.NoError(t, err)
+
+	// Before the first Up everything is pending.
+	version, pending, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Equal(t, 1, pending)
+
+	var exists bool
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT to_regclass('test_schema_migrations') IS NOT NULL`).Scan(&exists))
+	assert.False(t, exists, "Version must not create the migrations table")
+}
+
+func TestMigrator_FailedMigrationLeavesNoTrace(t *testing.T) {
+	db := pgtest.DB(t, "pgx")
+	logger, _ := test.NewNullLogger()
+	ctx := context.Background()
+
+	migrations := fstest.MapFS{
+		"0001_create_thing.up.sql":   {Data: []byte("CREATE TABLE thing (id INTEGER);")},
+		"0001_create_thing.down.sql": {Data: []byte("DROP TABLE thing;")},
+		"0002_broken.up.sql":         {Data: []byte("CREATE TABLE other (id INTEGER); SELECT no_such_column FROM thing;")},
+		"0002_broken.down.sql":       {Data: []byte("DROP TABLE other;")},
+	}
+	migrator, err := NewMigrator(db, migrations, "test_schema_migrations", 72_999, logger)
+	require.NoError(t, err)
+
+	err = migrator.Up(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration 0002_broken")
+
+	version, pending, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, 1, pending)
+
+	_, err = db.ExecContext(ctx, `SELECT 1 FROM other`)
+	assert.Error(t, err, "the failed migration must be rolled back as a whole")
+}
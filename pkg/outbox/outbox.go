@@ -0,0 +1,84 @@
+// Package outbox relays the domain events the messenger services record in
+// their outbox tables to the broker.
+//
+// A service writes an event to its outbox in the same transaction as the
+// change the event describes, so the event exists if and only if the change
+// was committed. A Relay then publishes it, at least once: an event is only
+// marked published once the broker accepted it, so it is published again if
+// the relay stops in between.
+//
+// Events with the same key are published in the order they were recorded.
+// One that keeps failing holds back the later events of its key until it
+// runs out of attempts and is parked; events of other keys go ahead of it.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Event is an event read from an outbox table.
+type Event struct {
+	Id        int64
+	Topic     string
+	Key       string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+	// Attempts counts the times the event was handed to a relay, including
+	// the current one.
+	Attempts  int
+	LastError string
+}
+
+// Envelope returns the event as it is published.
+func (e *Event) Envelope() *events.Envelope {
+	return &events.Envelope{
+		Id:         strconv.FormatInt(e.Id, 10),
+		Topic:      e.Topic,
+		Key:        e.Key,
+		Payload:    e.Payload,
+		OccurredAt: e.CreatedAt,
+	}
+}
+
+// Store is an outbox table as a Relay sees it.
+type Store interface {
+	// Claim leases up to limit events that are due, oldest first, taking
+	// only the oldest unpublished event of each key. Claimed events are not
+	// claimed again until lease has passed, so a relay that stops before
+	// marking them lets another relay retry them.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+	// MarkFailed records why publishing id failed and makes it due again
+	// after retryIn. A parked event is never claimed again.
+	MarkFailed(ctx context.Context, id int64, reason string, retryIn time.Duration, park bool) error
+	// DeletePublished removes events published before before and returns
+	// how many there were. Parked events are kept.
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Config is how a Relay works through the outbox.
+type Config struct {
+	// PollInterval is how often the outbox is checked for events, and how
+	// long the first retry of a failed event waits. Every further retry
+	// waits twice as long as the one before, up to RetryMax.
+	PollInterval time.Duration
+	BatchSize    int
+	Retention    time.Duration
+	// MaxAttempts is how often an event is tried before it is parked.
+	MaxAttempts int
+	RetryMax    time.Duration
+}
+
+// Metrics are the counters a Relay reports to, each labelled by topic.
+type Metrics struct {
+	Published *prometheus.CounterVec
+	Failures  *prometheus.CounterVec
+	Parked    *prometheus.CounterVec
+}
@@ -0,0 +1,170 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/broker"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// cleanupInterval is how often published events past their retention
+	// are removed from the outbox.
+	cleanupInterval = time.Hour
+	// claimLease is how long claimed events are left to one relay. It only
+	// matters when a relay stops between claiming and marking events, and
+	// has to be well above the time a batch takes to publish.
+	claimLease = time.Minute
+)
+
+// Relay publishes the events recorded in an outbox to the broker.
+type Relay struct {
+	store   Store
+	broker  broker.Broker
+	cfg     Config
+	metrics Metrics
+	logger  *logrus.Logger
+}
+
+func NewRelay(store Store, broker broker.Broker, cfg Config, metrics Metrics, logger *logrus.Logger) *Relay {
+	return &Relay{
+		store:   store,
+		broker:  broker,
+		cfg:     cfg,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// Run relays events every poll interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var cleanedAt time.Time
+	for {
+		r.Relay(ctx)
+
+		if time.Since(cleanedAt) >= cleanupInterval {
+			r.cleanup(ctx)
+			cleanedAt = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes due events batch by batch until a batch gets nothing
+// done, and returns how many it published. An event the broker rejects
+// is retried later, or parked once it has used up its attempts, and the
+// rest of the batch goes on without it. Only failures of the outbox itself
+// are returned.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		claimed, err := r.store.Claim(ctx, r.cfg.BatchSize, claimLease)
+		if err != nil {
+			return total, err
+		}
+
+		parked := 0
+		published := make([]int64, 0, len(claimed))
+		for i := range claimed {
+			if err := r.broker.Publish(ctx, claimed[i].Envelope()); err != nil {
+				r.metrics.Failures.WithLabelValues(claimed[i].Topic).Inc()
+				park, err := r.fail(ctx, &claimed[i], err)
+				if err != nil {
+					return total, err
+				}
+				if park {
+					parked++
+				}
+
+				continue
+			}
+
+			r.metrics.Published.WithLabelValues(claimed[i].Topic).Inc()
+			published = append(published, claimed[i].Id)
+		}
+
+		if err := r.store.MarkPublished(ctx, published); err != nil {
+			return total, err
+		}
+
+		// Whatever is left is either not due yet or held back by an event
+		// that is, unless this batch published or parked something.
+		total += len(published)
+		if len(published) == 0 && parked == 0 {
+			break
+		}
+	}
+
+	return total, nil
+}
+
+// fail schedules the next attempt at event, or parks it if that was its
+// last attempt and reports so.
+func (r *Relay) fail(ctx context.Context, event *Event, publishErr error) (bool, error) {
+	park := event.Attempts >= r.cfg.MaxAttempts
+	retryIn := r.backoff(event.Attempts)
+
+	if err := r.store.MarkFailed(ctx, event.Id, publishErr.Error(), retryIn, park); err != nil {
+		return false, err
+	}
+
+	fields := logrus.Fields{
+		"module":   "outbox",
+		"func":     "Relay",
+		"eventId":  event.Id,
+		"topic":    event.Topic,
+		"key":      event.Key,
+		"attempts": event.Attempts,
+		"error":    publishErr.Error(),
+	}
+	if park {
+		r.metrics.Parked.WithLabelValues(event.Topic).Inc()
+		r.logger.WithFields(fields).Errorf("parked event after %d failed attempts: %v", event.Attempts, publishErr)
+
+		return true, nil
+	}
+
+	if ctx.Err() == nil {
+		r.logger.WithFields(fields).WithField("retryIn", retryIn.String()).Warnf("failed to publish event: %v", publishErr)
+	}
+
+	return false, nil
+}
+
+// backoff is how long to wait before attempt number attempts+1.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.cfg.PollInterval
+	for i := 1; i < attempts && delay < r.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > r.cfg.RetryMax {
+		delay = r.cfg.RetryMax
+	}
+
+	return delay
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	deleted, err := r.store.DeletePublished(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		return
+	}
+
+	if deleted > 0 {
+		r.logger.WithFields(logrus.Fields{
+			"module":  "outbox",
+			"func":    "cleanup",
+			"deleted": deleted,
+		}).Info("removed published events from the outbox")
+	}
+}
@@ -0,0 +1,281 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/events"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = Config{
+	PollInterval: time.Millisecond,
+	BatchSize:    2,
+	Retention:    time.Hour,
+	MaxAttempts:  3,
+	RetryMax:     4 * time.Millisecond,
+}
+
+// memoryStore is an outbox that hands out events the way PostgresStore
+// does, except that time doesn't pass: leases never run out, and failed
+// events wait for retryNow instead of their retry delay.
+type memoryStore struct {
+	mu        sync.Mutex
+	events    []*Event
+	published map[int64]bool
+	parked    map[int64]bool
+	waiting   map[int64]bool
+	retries   []time.Duration
+	deleted   int
+}
+
+func newMemoryStore(keys ...string) *memoryStore {
+	store := &memoryStore{
+		published: make(map[int64]bool),
+		parked:    make(map[int64]bool),
+		waiting:   make(map[int64]bool),
+	}
+	for i, key := range keys {
+		store.events = append(store.events, &Event{
+			Id:      int64(i + 1),
+			Topic:   events.TopicUserDeleted,
+			Key:     key,
+			Payload: []byte(`{"userId":` + key + `}`),
+		})
+	}
+
+	return store
+}
+
+func (s *memoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []Event
+	blocked := make(map[string]bool)
+	for _, event := range s.events {
+		if s.published[event.Id] || s.parked[event.Id] {
+			continue
+		}
+		if blocked[event.Key] {
+			continue
+		}
+		blocked[event.Key] = true
+
+		if len(claimed) < limit && !s.waiting[event.Id] {
+			event.Attempts++
+			claimed = append(claimed, *event)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (s *memoryStore) MarkPublished(ctx context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		s.published[id] = true
+	}
+
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, id int64, reason string, retryIn time.Duration, park bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retries = append(s.retries, retryIn)
+	s.waiting[id] = true
+	s.events[id-1].LastError = reason
+	if park {
+		s.parked[id] = true
+	}
+
+	return nil
+}
+
+// retryNow makes the failed events due again.
+func (s *memoryStore) retryNow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.waiting = make(map[int64]bool)
+}
+
+func (s *memoryStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleted++
+	return int64(len(s.published)), nil
+}
+
+// brokerFunc publishes with a plain function.
+type brokerFunc func(ctx context.Context, event *events.Envelope) error
+
+func (f brokerFunc) Publish(ctx context.Context, event *events.Envelope) error {
+	return f(ctx, event)
+}
+
+func testMetrics() Metrics {
+	counter := func(name string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: name}, []string{"topic"})
+	}
+
+	return Metrics{
+		Published: counter("published"),
+		Failures:  counter("failures"),
+		Parked:    counter("parked"),
+	}
+}
+
+func TestRelay_Relay(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := newMemoryStore("10", "20", "10", "30")
+	metrics := testMetrics()
+
+	var published []string
+	relay := NewRelay(store, brokerFunc(func(ctx context.Context, event *events.Envelope) error {
+		published = append(published, event.Id)
+		return nil
+	}), testConfig, metrics, logger)
+
+	count, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	// Event 3 waits for event 1, which has the same key.
+	assert.Equal(t, []string{"1", "2", "3", "4"}, sorted(published))
+	assert.Less(t, indexOf(published, "1"), indexOf(published, "3"))
+	assert.Equal(t, 4.0, testutil.ToFloat64(metrics.Published.WithLabelValues(events.TopicUserDeleted)))
+}
+
+func TestRelay_Relay_FailureDoesNotBlockOtherKeys(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := newMemoryStore("10", "20", "30")
+	metrics := testMetrics()
+	brokerErr := errors.New("broker down")
+
+	var published []string
+	relay := NewRelay(store, brokerFunc(func(ctx context.Context, event *events.Envelope) error {
+		if event.Id == "1" {
+			return brokerErr
+		}
+
+		published = append(published, event.Id)
+		return nil
+	}), testConfig, metrics, logger)
+
+	count, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"2", "3"}, sorted(published))
+	assert.Equal(t, brokerErr.Error(), store.events[0].LastError)
+	assert.False(t, store.parked[1])
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Failures.WithLabelValues(events.TopicUserDeleted)))
+}
+
+func TestRelay_Relay_ParksAfterMaxAttempts(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := newMemoryStore("10", "10")
+	metrics := testMetrics()
+
+	var published []string
+	relay := NewRelay(store, brokerFunc(func(ctx context.Context, event *events.Envelope) error {
+		if event.Id == "1" {
+			return errors.New("payload rejected")
+		}
+
+		published = append(published, event.Id)
+		return nil
+	}), testConfig, metrics, logger)
+
+	for attempt := 1; attempt < testConfig.MaxAttempts; attempt++ {
+		count, err := relay.Relay(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		assert.False(t, store.parked[1])
+
+		store.retryNow()
+	}
+
+	// Once event 1 is parked, event 2 of the same key goes ahead.
+	count, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.True(t, store.parked[1])
+	assert.Equal(t, []string{"2"}, published)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Parked.WithLabelValues(events.TopicUserDeleted)))
+
+	// Retries wait twice as long each time, up to RetryMax.
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}, store.retries)
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(nil, nil, Config{PollInterval: time.Second, RetryMax: 10 * time.Minute}, Metrics{}, nil)
+
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 2*time.Second, relay.backoff(2))
+	assert.Equal(t, 8*time.Second, relay.backoff(4))
+	assert.Equal(t, 10*time.Minute, relay.backoff(20))
+	assert.Equal(t, 10*time.Minute, relay.backoff(1000))
+}
+
+func TestRelay_Run(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	store := newMemoryStore("10")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := NewRelay(store, brokerFunc(func(context.Context, *events.Envelope) error {
+		cancel()
+		return nil
+	}), testConfig, testMetrics(), logger)
+
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("relay did not stop")
+	}
+
+	assert.True(t, store.published[1])
+	assert.Equal(t, 1, store.deleted)
+}
+
+func sorted(ids []string) []string {
+	sorted := append([]string(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := strconv.Atoi(sorted[i])
+		b, _ := strconv.Atoi(sorted[j])
+		return a < b
+	})
+
+	return sorted
+}
+
+func indexOf(ids []string, id string) int {
+	for i := range ids {
+		if ids[i] == id {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PostgresStore is an outbox table in PostgreSQL with the columns of the
+// outbox migrations of the services. Each statement runs on its own, so no
+// row stays locked while events are published.
+type PostgresStore struct {
+	db     *sql.DB
+	table  string
+	logger *logrus.Logger
+}
+
+// NewPostgresStore returns the outbox in table, which has to be a trusted
+// identifier since it becomes part of the queries.
+func NewPostgresStore(db *sql.DB, table string, logger *logrus.Logger) *PostgresStore {
+	return &PostgresStore{
+		db:     db,
+		table:  table,
+		logger: logger,
+	}
+}
+
+func (s *PostgresStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error) {
+	// An event is held back while an older event of its key is unpublished,
+	// even if that one isn't due yet, so that retries keep the order.
+	// SKIP LOCKED lets concurrent relays claim different events instead of
+	// waiting for each other; an event another relay is claiming right now
+	// still holds back the rest of its key.
+	query := fmt.Sprintf(`UPDATE %[1]s SET attempts = attempts + 1, available_at = now() + $2::bigint * interval '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM %[1]s AS pending
+			WHERE published_at IS NULL AND failed_at IS NULL AND available_at <= now()
+			AND NOT EXISTS (
+				SELECT 1 FROM %[1]s AS earlier
+				WHERE earlier.key = pending.key AND earlier.id < pending.id
+				AND earlier.published_at IS NULL AND earlier.failed_at IS NULL
+			)
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, key, payload, created_at, attempts, last_error`, s.table)
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		s.logError("Claim", err, "failed to claim events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claimed []Event
+	for rows.Next() {
+		var event Event
+		var payload []byte
+		if err := rows.Scan(&event.Id, &event.Topic, &event.Key, &payload, &event.CreatedAt, &event.Attempts, &event.LastError); err != nil {
+			s.logError("Claim", err, "failed to scan event")
+			return nil, err
+		}
+
+		event.Payload = payload
+		claimed = append(claimed, event)
+	}
+	if err := rows.Err(); err != nil {
+		s.logError("Claim", err, "failed to claim events")
+		return nil, err
+	}
+
+	// RETURNING comes in no particular order.
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].Id < claimed[j].Id })
+
+	return claimed, nil
+}
+
+func (s *PostgresStore) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET published_at = now() WHERE id IN (%s)`, s.table, strings.Join(placeholders, ", "))
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		s.logError("MarkPublished", err, "failed to mark events as published")
+		return err
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) MarkFailed(ctx context.Context, id int64, reason string, retryIn time.Duration, park bool) error {
+	query := fmt.Sprintf(`UPDATE %s SET last_error = $1, available_at = now() + $2::bigint * interval '1 millisecond',
+		failed_at = CASE WHEN $3::boolean THEN now() END
+		WHERE id = $4`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, reason, retryIn.Milliseconds(), park, id); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":  "outbox",
+			"func":    "MarkFailed",
+			"error":   err.Error(),
+			"eventId": id,
+		}).Errorf("failed to record publish failure: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE published_at < $1`, s.table), before)
+	if err != nil {
+		s.logError("DeletePublished", err, "failed to delete published events")
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *PostgresStore) logError(fn string, err error, msg string) {
+	s.logger.WithFields(logrus.Fields{
+		"module": "outbox",
+		"func":   fn,
+		"table":  s.table,
+		"error":  err.Error(),
+	}).Errorf("%s: %v", msg, err)
+}
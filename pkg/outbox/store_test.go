@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/pgtest"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSchema is the outbox table of the service migrations.
+const testSchema = `CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(64) NOT NULL,
+    key VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    failed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+)`
+
+func newTestStore(t *testing.T, keys ...string) (*PostgresStore, *sql.DB) {
+	db := pgtest.DB(t, "pgx")
+	_, err := db.Exec(testSchema)
+	require.NoError(t, err)
+
+	for _, key := range keys {
+		_, err := db.Exec(`INSERT INTO outbox (topic, key, payload) VALUES ('user.deleted', $1, '{}')`, key)
+		require.NoError(t, err)
+	}
+
+	logger, _ := test.NewNullLogger()
+	return NewPostgresStore(db, "outbox", logger), db
+}
+
+func claimedIds(t *testing.T, store *PostgresStore, limit int) []int64 {
+	claimed, err := store.Claim(context.Background(), limit, time.Minute)
+	require.NoError(t, err)
+
+	ids := []int64{}
+	for _, event := range claimed {
+		ids = append(ids, event.Id)
+	}
+
+	return ids
+}
+
+func TestPostgresStore_Claim(t *testing.T) {
+	store, _ := newTestStore(t, "a", "b", "a", "c")
+	ctx := context.Background()
+
+	claimed, err := store.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+
+	// Event 3 waits for event 1, which has the same key.
+	assert.Equal(t, int64(1), claimed[0].Id)
+	assert.Equal(t, int64(2), claimed[1].Id)
+	assert.Equal(t, int64(4), claimed[2].Id)
+	assert.Equal(t, "user.deleted", claimed[0].Topic)
+	assert.Equal(t, "a", claimed[0].Key)
+	assert.JSONEq(t, `{}`, string(claimed[0].Payload))
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	// Leased events aren't claimed again.
+	assert.Empty(t, claimedIds(t, store, 10))
+
+	require.NoError(t, store.MarkPublished(ctx, []int64{1, 2}))
+	assert.Equal(t, []int64{3}, claimedIds(t, store, 10))
+}
+
+func TestPostgresStore_Claim_Limit(t *testing.T) {
+	store, _ := newTestStore(t, "a", "b", "c")
+
+	assert.Equal(t, []int64{1, 2}, claimedIds(t, store, 2))
+	assert.Equal(t, []int64{3}, claimedIds(t, store, 2))
+}
+
+func TestPostgresStore_Claim_SkipsLocked(t *testing.T) {
+	store, db := newTestStore(t, "a", "b", "a")
+
+	// Another relay is claiming event 1 right now.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = tx.Exec(`SELECT id FROM outbox WHERE id = 1 FOR UPDATE`)
+	require.NoError(t, err)
+
+	// The claim neither waits for it nor overtakes it with event 3.
+	assert.Equal(t, []int64{2}, claimedIds(t, store, 10))
+}
+
+func TestPostgresStore_MarkFailed(t *testing.T) {
+	store, db := newTestStore(t, "a", "a")
+	ctx := context.Background()
+
+	assert.Equal(t, []int64{1}, claimedIds(t, store, 10))
+
+	// Due again straight away.
+	require.NoError(t, store.MarkFailed(ctx, 1, "broker down", 0, false))
+	claimed, err := store.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, int64(1), claimed[0].Id)
+	assert.Equal(t, 2, claimed[0].Attempts)
+	assert.Equal(t, "broker down", claimed[0].LastError)
+
+	// Waiting for its retry, it still holds back event 2.
+	require.NoError(t, store.MarkFailed(ctx, 1, "broker down", time.Hour, false))
+	assert.Empty(t, claimedIds(t, store, 10))
+
+	// Parked, it doesn't.
+	require.NoError(t, store.MarkFailed(ctx, 1, "payload rejected", 0, true))
+	assert.Equal(t, []int64{2}, claimedIds(t, store, 10))
+
+	var failedAt sql.NullTime
+	var lastError string
+	require.NoError(t, db.QueryRow(`SELECT failed_at, last_error FROM outbox WHERE id = 1`).Scan(&failedAt, &lastError))
+	assert.True(t, failedAt.Valid)
+	assert.Equal(t, "payload rejected", lastError)
+}
+
+func TestPostgresStore_DeletePublished(t *testing.T) {
+	store, db := newTestStore(t, "a", "b", "c")
+	ctx := context.Background()
+
+	require.NoError(t, store.MarkPublished(ctx, []int64{1}))
+	require.NoError(t, store.MarkFailed(ctx, 2, "payload rejected", 0, true))
+
+	deleted, err := store.DeletePublished(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// Parked and pending events stay.
+	var left int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM outbox`).Scan(&left))
+	assert.Equal(t, 2, left)
+}
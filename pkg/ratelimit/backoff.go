@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const backoffPrefix = keyPrefix + "backoff:"
+
+// recordFailure counts a failure and, once the free attempts are used up,
+// blocks the subject for base * 2^(failures - free - 1), capped at max.
+// Returns the block in milliseconds, zero while still within the free
+// attempts.
+var recordFailure = redis.NewScript(`
+local failures = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+
+local free = tonumber(ARGV[2])
+if failures <= free then
+	return 0
+end
+
+local delay = math.min(tonumber(ARGV[4]), tonumber(ARGV[3]) * 2 ^ (failures - free - 1))
+delay = math.floor(delay)
+-- SET refuses PX 0, and a block of no time is no block.
+if delay > 0 then
+	redis.call('SET', KEYS[2], '1', 'PX', delay)
+end
+
+return delay
+`)
+
+// BackoffClient is the subset of the redis client Backoff needs.
+type BackoffClient interface {
+	redis.Scripter
+	PTTL(ctx context.Context, key string) *redis.DurationCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Backoff blocks a subject, such as the email of a login, for exponentially
+// longer after each failure.
+type Backoff struct {
+	client BackoffClient
+	free   int
+	base   time.Duration
+	max    time.Duration
+	window time.Duration
+}
+
+// NewBackoff lets a subject fail free times within window before blocking
+// it, first for base and then twice as long after each further failure, up
+// to max.
+func NewBackoff(client BackoffClient, free int, base, max, window time.Duration) *Backoff {
+	return &Backoff{
+		client: client,
+		free:   free,
+		base:   base,
+		max:    max,
+		window: window,
+	}
+}
+
+// Check returns how long subject is still blocked for, zero if it isn't.
+func (b *Backoff) Check(ctx context.Context, subject string) (time.Duration, error) {
+	ttl, err := b.client.PTTL(ctx, blockedKey(subject)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL reports a missing key as a negative duration.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Failure records a failed attempt and returns how long subject is now
+// blocked for.
+func (b *Backoff) Failure(ctx context.Context, subject string) (time.Duration, error) {
+	keys := []string{failuresKey(subject), blockedKey(subject)}
+	delay, err := recordFailure.Run(ctx, b.client, keys, b.window.Milliseconds(), b.free, b.base.Milliseconds(), b.max.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(delay) * time.Millisecond, nil
+}
+
+// Reset forgets the failures of subject after a successful attempt.
+func (b *Backoff) Reset(ctx context.Context, subject string) error {
+	return b.client.Del(ctx, failuresKey(subject), blockedKey(subject)).Err()
+}
+
+func failuresKey(subject string) string {
+	return backoffPrefix + subject + ":failures"
+}
+
+func blockedKey(subject string) string {
+	return backoffPrefix + subject + ":blocked"
+}
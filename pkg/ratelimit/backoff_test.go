@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := NewBackoff(testClient(t), 2, time.Second, 3*time.Second, time.Hour)
+	ctx := context.Background()
+
+	// The free attempts don't block.
+	for i := 0; i < 2; i++ {
+		delay, err := backoff.Failure(ctx, "user@example.com")
+		require.NoError(t, err)
+		assert.Zero(t, delay)
+	}
+
+	blocked, err := backoff.Check(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, blocked)
+
+	// Then the block doubles up to the maximum.
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		delay, err := backoff.Failure(ctx, "user@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, want, delay)
+	}
+
+	blocked, err = backoff.Check(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.InDelta(t, (3 * time.Second).Seconds(), blocked.Seconds(), 0.5)
+
+	require.NoError(t, backoff.Reset(ctx, "user@example.com"))
+
+	blocked, err = backoff.Check(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, blocked)
+
+	delay, err := backoff.Failure(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, delay, "a reset gives the free attempts back")
+}
+
+func TestBackoff_NoDelay(t *testing.T) {
+	backoff := NewBackoff(testClient(t), 0, 0, 0, time.Hour)
+	ctx := context.Background()
+
+	delay, err := backoff.Failure(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, delay)
+
+	blocked, err := backoff.Check(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, blocked)
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const cooldownPrefix = keyPrefix + "cooldown:"
+
+// CooldownClient is the subset of the redis client Cooldown needs.
+type CooldownClient interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+}
+
+// Cooldown lets an action happen once per period for each subject, such as
+// sending a verification email to an address.
+type Cooldown struct {
+	client CooldownClient
+	name   string
+	period time.Duration
+}
+
+// NewCooldown returns the cooldown of the action called name, which keeps
+// the cooldowns of different actions apart.
+func NewCooldown(client CooldownClient, name string, period time.Duration) *Cooldown {
+	return &Cooldown{
+		client: client,
+		name:   name,
+		period: period,
+	}
+}
+
+// Start starts the cooldown of subject and reports whether the action may
+// go ahead, which is when no cooldown of subject was running yet. Without a
+// period there is no cooldown.
+func (c *Cooldown) Start(ctx context.Context, subject string) (bool, error) {
+	// SETNX without an expiry would block subject for good.
+	if c.period <= 0 {
+		return true, nil
+	}
+
+	return c.client.SetNX(ctx, cooldownPrefix+c.name+":"+subject, "1", c.period).Result()
+}
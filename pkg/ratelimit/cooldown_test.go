@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCooldown(t *testing.T) {
+	client := testClient(t)
+	cooldown := NewCooldown(client, "verification", time.Hour)
+	ctx := context.Background()
+
+	allowed, err := cooldown.Start(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = cooldown.Start(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, err = cooldown.Start(ctx, "other@example.com")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Cooldowns of other actions are separate.
+	allowed, err = NewCooldown(client, "reset", time.Hour).Start(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	ttl, err := client.PTTL(ctx, cooldownPrefix+"verification:user@example.com").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 5)
+}
+
+func TestCooldown_NoPeriod(t *testing.T) {
+	// Without a period redis isn't asked at all.
+	cooldown := NewCooldown(nil, "verification", 0)
+
+	for i := 0; i < 2; i++ {
+		allowed, err := cooldown.Start(context.Background(), "user@example.com")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+}
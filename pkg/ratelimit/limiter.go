@@ -0,0 +1,134 @@
+// Package ratelimit keeps the rate limits of the messenger services in
+// redis, so that every replica of a service enforces the same limits:
+// token buckets per route, the login backoff and cooldowns.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "ratelimit:"
+
+const (
+	HeaderRetryAfter         = "Retry-After"
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// tokenBucket refills the bucket for the time elapsed since the last request
+// and takes one token if there is one. It runs inside redis, on redis' clock,
+// so every replica sees the same bucket. Returns whether the request was
+// allowed, the tokens left, and the seconds until a token is available and
+// until the bucket is full again.
+var tokenBucket = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = limit
+	ts = now
+end
+
+tokens = math.min(limit, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = (1 - tokens) / rate
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(limit / rate * 1000))
+
+return {allowed, tostring(tokens), tostring(retry), tostring((limit - tokens) / rate)}
+`)
+
+// Result describes the bucket after a request.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Limiter applies token bucket policies stored in redis.
+type Limiter struct {
+	client redis.Scripter
+}
+
+func NewLimiter(client redis.Scripter) *Limiter {
+	return &Limiter{
+		client: client,
+	}
+}
+
+// Allow takes a token from the bucket of key under policy.
+func (l *Limiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	rate := float64(policy.Limit) / policy.Period.Seconds()
+
+	reply, err := tokenBucket.Run(ctx, l.client, []string{keyPrefix + key}, policy.Limit, rate).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(reply) != 4 {
+		return Result{}, fmt.Errorf("unexpected token bucket reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokens := parseFloat(reply[1])
+
+	return Result{
+		Allowed:    allowed == 1,
+		Limit:      policy.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		RetryAfter: seconds(parseFloat(reply[2])),
+		Reset:      seconds(parseFloat(reply[3])),
+	}, nil
+}
+
+// SetHeaders describes result in the X-RateLimit headers of a response,
+// and adds Retry-After if the request was refused.
+func SetHeaders(header http.Header, result Result) {
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	header.Set(HeaderRateLimitReset, strconv.Itoa(CeilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		header.Set(HeaderRetryAfter, strconv.Itoa(CeilSeconds(result.RetryAfter)))
+	}
+}
+
+// CeilSeconds rounds d up to whole seconds, as headers such as Retry-After
+// want it.
+func CeilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func parseFloat(value interface{}) float64 {
+	s, _ := value.(string)
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
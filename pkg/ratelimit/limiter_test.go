@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetHeaders(t *testing.T) {
+	header := http.Header{}
+	SetHeaders(header, Result{Allowed: true, Limit: 10, Remaining: 9, Reset: 5500 * time.Millisecond})
+
+	assert.Equal(t, "10", header.Get(HeaderRateLimitLimit))
+	assert.Equal(t, "9", header.Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "6", header.Get(HeaderRateLimitReset))
+	assert.Empty(t, header.Get(HeaderRetryAfter))
+
+	header = http.Header{}
+	SetHeaders(header, Result{Limit: 10, RetryAfter: 200 * time.Millisecond, Reset: time.Minute})
+
+	assert.Equal(t, "0", header.Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "60", header.Get(HeaderRateLimitReset))
+	assert.Equal(t, "1", header.Get(HeaderRetryAfter))
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter := NewLimiter(testClient(t))
+	ctx := context.Background()
+	policy := Policy{Limit: 3, Period: time.Hour}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := limiter.Allow(ctx, "ip:1.2.3.4:POST /auth/login", policy)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "ip:1.2.3.4:POST /auth/login", policy)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Zero(t, result.Remaining)
+	// A token comes back every 20 minutes.
+	assert.InDelta(t, (20 * time.Minute).Seconds(), result.RetryAfter.Seconds(), 5)
+	assert.InDelta(t, time.Hour.Seconds(), result.Reset.Seconds(), 5)
+
+	// Other keys have buckets of their own.
+	result, err = limiter.Allow(ctx, "ip:5.6.7.8:POST /auth/login", policy)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeUser = "user"
+	ScopeIP   = "ip"
+)
+
+// Policy is a token bucket that holds Limit tokens and refills all of them
+// over Period, so a client may burst Limit requests and then sustain
+// Limit/Period.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy parses "N/period", for example "120/1m".
+func ParsePolicy(s string) (Policy, error) {
+	limit, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q: want N/period", s)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid request count", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+
+	return Policy{Limit: n, Period: d}, nil
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s", p.Limit, p.Period)
+}
+
+// ScopePolicies are the policies of one scope: a default and per-route
+// overrides of it.
+type ScopePolicies struct {
+	Default Policy
+	Routes  map[string]Policy
+}
+
+// ParsePolicies parses the default policy of a scope and its route
+// overrides, which are written "METHOD /path=N/period" with the path as the
+// service names its routes, for example "PUT /user/:id".
+func ParsePolicies(defaultPolicy string, routes []string) (ScopePolicies, error) {
+	policy, err := ParsePolicy(defaultPolicy)
+	if err != nil {
+		return ScopePolicies{}, err
+	}
+
+	policies := ScopePolicies{
+		Default: policy,
+		Routes:  make(map[string]Policy, len(routes)),
+	}
+
+	for _, route := range routes {
+		pattern, limit, ok := strings.Cut(route, "=")
+		if !ok {
+			return ScopePolicies{}, fmt.Errorf("rate limit route %q: want METHOD /path=N/period", route)
+		}
+
+		policy, err := ParsePolicy(limit)
+		if err != nil {
+			return ScopePolicies{}, err
+		}
+
+		policies.Routes[strings.Join(strings.Fields(pattern), " ")] = policy
+	}
+
+	return policies, nil
+}
+
+// For returns the policy on route.
+func (p ScopePolicies) For(route string) Policy {
+	if policy, ok := p.Routes[route]; ok {
+		return policy
+	}
+
+	return p.Default
+}
+
+// Policies holds the policies of each scope. A route override only
+// replaces the default of its own scope, so a tight limit on the IPs
+// hitting a route leaves the users behind them alone.
+type Policies struct {
+	Enabled bool
+	User    ScopePolicies
+	IP      ScopePolicies
+}
+
+// For returns the policy for scope on route.
+func (p Policies) For(scope, route string) Policy {
+	if scope == ScopeUser {
+		return p.User.For(route)
+	}
+
+	return p.IP.For(route)
+}
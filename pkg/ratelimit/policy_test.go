@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{in: "120/1m", want: Policy{Limit: 120, Period: time.Minute}},
+		{in: " 5/30s ", want: Policy{Limit: 5, Period: 30 * time.Second}},
+		{in: "120", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "many/1m", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/minute", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			policy, err := ParsePolicy(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, policy)
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("300/1m", []string{"POST /auth/login=10/1m", "PUT   /user/:id=5/10s"})
+	require.NoError(t, err)
+
+	assert.Equal(t, Policy{Limit: 300, Period: time.Minute}, policies.Default)
+	assert.Equal(t, map[string]Policy{
+		"POST /auth/login": {Limit: 10, Period: time.Minute},
+		"PUT /user/:id":    {Limit: 5, Period: 10 * time.Second},
+	}, policies.Routes)
+
+	assert.Equal(t, Policy{Limit: 10, Period: time.Minute}, policies.For("POST /auth/login"))
+	assert.Equal(t, Policy{Limit: 300, Period: time.Minute}, policies.For("GET /auth/login"))
+}
+
+func TestParsePolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultPolicy string
+		routes        []string
+	}{
+		{name: "invalid default", defaultPolicy: "300", routes: nil},
+		{name: "route without limit", defaultPolicy: "300/1m", routes: []string{"POST /auth/login"}},
+		{name: "invalid route limit", defaultPolicy: "300/1m", routes: []string{"POST /auth/login=10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePolicies(tt.defaultPolicy, tt.routes)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPolicies_For(t *testing.T) {
+	policies := Policies{
+		User: ScopePolicies{
+			Default: Policy{Limit: 120, Period: time.Minute},
+			Routes:  map[string]Policy{"POST /messages": {Limit: 60, Period: time.Minute}},
+		},
+		IP: ScopePolicies{
+			Default: Policy{Limit: 300, Period: time.Minute},
+			Routes:  map[string]Policy{"POST /auth/login": {Limit: 10, Period: time.Minute}},
+		},
+	}
+
+	// An override only applies to its own scope.
+	assert.Equal(t, 10, policies.For(ScopeIP, "POST /auth/login").Limit)
+	assert.Equal(t, 120, policies.For(ScopeUser, "POST /auth/login").Limit)
+	assert.Equal(t, 60, policies.For(ScopeUser, "POST /messages").Limit)
+	assert.Equal(t, 300, policies.For(ScopeIP, "POST /messages").Limit)
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+// envRedisURL names the redis database the tests that run the scripts of
+// this package use, e.g. redis://localhost:6379/15. The database is flushed
+// before and after each test, so it mustn't be one anything else uses.
+const envRedisURL = "TEST_REDIS_URL"
+
+func testClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	url := os.Getenv(envRedisURL)
+	if url == "" {
+		t.Skipf("%s is not set", envRedisURL)
+	}
+
+	options, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	client := redis.NewClient(options)
+	require.NoError(t, client.FlushDB(context.Background()).Err())
+
+	t.Cleanup(func() {
+		client.FlushDB(context.Background())
+		client.Close()
+	})
+
+	return client
+}
@@ -0,0 +1,129 @@
+// Package shutdown takes a service instance out of rotation and then
+// closes what it holds, in order.
+package shutdown
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/health"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+type Config struct {
+	// DrainDelay is how long the instance reports not ready before it
+	// starts draining, for load balancers to notice.
+	DrainDelay time.Duration
+	// Timeout bounds how long in-flight requests may take to drain, across
+	// all servers together.
+	Timeout time.Duration
+}
+
+// Drain is a server whose in-flight requests are drained during shutdown.
+// Drain must return once ctx is done, cutting off whatever is left.
+type Drain struct {
+	Name  string
+	Drain func(ctx context.Context) error
+}
+
+// Step is one resource closed during shutdown, in the order given.
+type Step struct {
+	Name  string
+	Close func() error
+}
+
+// Graceful takes the instance out of rotation, waits for load balancers to
+// notice, drains every server concurrently under one deadline and then
+// closes the remaining resources in order.
+func Graceful(cfg Config, readiness *health.Readiness, logger *logrus.Logger, drains []Drain, steps ...Step) {
+	logger.WithFields(logrus.Fields{
+		"module":     "shutdown",
+		"func":       "Graceful",
+		"drainDelay": cfg.DrainDelay.String(),
+		"timeout":    cfg.Timeout.String(),
+	}).Info("shutting down")
+
+	readiness.SetReady(false)
+	time.Sleep(cfg.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, drain := range drains {
+		wg.Add(1)
+		go func(drain Drain) {
+			defer wg.Done()
+
+			if err := drain.Drain(ctx); err != nil {
+				logger.WithFields(logrus.Fields{
+					"module": "shutdown",
+					"func":   "Graceful",
+					"drain":  drain.Name,
+					"error":  err.Error(),
+				}).Errorf("failed to drain %s: %v", drain.Name, err)
+			}
+		}(drain)
+	}
+	wg.Wait()
+
+	for _, step := range steps {
+		if err := step.Close(); err != nil {
+			logger.WithFields(logrus.Fields{
+				"module": "shutdown",
+				"func":   "Graceful",
+				"step":   step.Name,
+				"error":  err.Error(),
+			}).Errorf("failed to close %s: %v", step.Name, err)
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"module": "shutdown",
+		"func":   "Graceful",
+	}).Info("shutdown complete")
+}
+
+func HTTP(server *http.Server) Drain {
+	return Drain{Name: "http", Drain: server.Shutdown}
+}
+
+// GRPC drains in-flight gRPC calls, cutting them off once ctx is done.
+func GRPC(server *grpc.Server) Drain {
+	return Drain{Name: "grpc", Drain: func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			server.Stop()
+			<-done
+		}
+
+		return nil
+	}}
+}
+
+func Closer(name string, closer io.Closer) Step {
+	return Step{Name: name, Close: closer.Close}
+}
+
+// Worker stops a background worker and waits for it to finish what it is
+// doing.
+func Worker(name string, stop context.CancelFunc, done <-chan struct{}) Step {
+	return Step{Name: name, Close: func() error {
+		stop()
+		<-done
+
+		return nil
+	}}
+}
@@ -0,0 +1,57 @@
+package shutdown
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/health"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraceful_DrainsUnderOneDeadline(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	readiness := health.NewReadiness()
+	readiness.SetReady(true)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	// Neither server finishes draining on its own, so both run until the
+	// shared deadline cuts them off.
+	stuck := func(name string) Drain {
+		return Drain{Name: name, Drain: func(ctx context.Context) error {
+			<-ctx.Done()
+			record(name)
+			return ctx.Err()
+		}}
+	}
+
+	timeout := 200 * time.Millisecond
+	start := time.Now()
+	Graceful(Config{Timeout: timeout}, readiness, logger,
+		[]Drain{stuck("http"), stuck("grpc")},
+		Step{Name: "database", Close: func() error {
+			record("database")
+			return nil
+		}},
+	)
+	elapsed := time.Since(start)
+
+	assert.False(t, readiness.Ready())
+	assert.GreaterOrEqual(t, elapsed, timeout)
+	assert.Less(t, elapsed, 2*timeout)
+	assert.ElementsMatch(t, []string{"http", "grpc"}, order[:2])
+	assert.Equal(t, "database", order[2])
+}
@@ -0,0 +1,103 @@
+// Package userclient connects other services to the gRPC API of the user
+// service.
+package userclient
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dmitriysta/messenger/pkg/userpb"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataServiceToken is the metadata key the user service reads the
+// token of the calling service from.
+const MetadataServiceToken = "x-service-token"
+
+// Dial connects to the user service at target, sending token with every
+// call and carrying the active span of each call over to the user service.
+// The connection is plaintext, for use inside the service network. opts
+// come last and can replace any of this.
+func Dial(target, token string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(serviceToken(token)),
+		grpc.WithChainUnaryInterceptor(TracingInterceptor(opentracing.GlobalTracer())),
+	}, opts...)
+
+	return grpc.Dial(target, opts...)
+}
+
+// New dials the user service like Dial and returns a client for it. Close
+// the connection once done with the client.
+func New(target, token string, opts ...grpc.DialOption) (userpb.UserServiceClient, *grpc.ClientConn, error) {
+	conn, err := Dial(target, token, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userpb.NewUserServiceClient(conn), conn, nil
+}
+
+// TracingInterceptor starts a client span for each call, child of the
+// span in its context, and sends it along in the metadata.
+func TracingInterceptor(tracer opentracing.Tracer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var parent opentracing.SpanContext
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			parent = span.Context()
+		}
+
+		span := tracer.StartSpan(method, opentracing.ChildOf(parent), opentracing.Tag{Key: "span.kind", Value: "client"})
+		defer span.Finish()
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		if err := tracer.Inject(span.Context(), opentracing.TextMap, MetadataCarrier(md)); err == nil {
+			ctx = metadata.NewOutgoingContext(ctx, md)
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			span.SetTag("error", true)
+			span.LogKV("error", err.Error())
+		}
+
+		return err
+	}
+}
+
+type serviceToken string
+
+func (t serviceToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{MetadataServiceToken: string(t)}, nil
+}
+
+func (t serviceToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// MetadataCarrier lets a tracer read and write span contexts in gRPC
+// metadata.
+type MetadataCarrier metadata.MD
+
+func (c MetadataCarrier) Set(key, val string) {
+	key = strings.ToLower(key)
+	c[key] = append(c[key], val)
+}
+
+func (c MetadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	for key, values := range c {
+		for _, value := range values {
+			if err := handler(key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
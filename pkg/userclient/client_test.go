@@ -0,0 +1,122 @@
+package userclient
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/dmitriysta/messenger/pkg/userpb"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// recordingServer answers GetUsers with nothing and keeps the metadata of
+// the last call.
+type recordingServer struct {
+	userpb.UnimplementedUserServiceServer
+
+	md metadata.MD
+}
+
+func (s *recordingServer) GetUsers(ctx context.Context, req *userpb.GetUsersRequest) (*userpb.GetUsersResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	return &userpb.GetUsersResponse{}, nil
+}
+
+// serve starts a user service on an in-memory listener and returns a
+// client connected to it with token and opts.
+func serve(t *testing.T, token string, opts ...grpc.DialOption) (userpb.UserServiceClient, *recordingServer) {
+	listener := bufconn.Listen(1 << 20)
+	recorder := &recordingServer{}
+
+	server := grpc.NewServer()
+	userpb.RegisterUserServiceServer(server, recorder)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+
+	client, conn, err := New("passthrough:///bufnet", token, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return client, recorder
+}
+
+func TestNew_SendsServiceToken(t *testing.T) {
+	client, recorder := serve(t, "service-token")
+
+	_, err := client.GetUsers(context.Background(), &userpb.GetUsersRequest{Ids: []int64{1}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"service-token"}, recorder.md.Get(MetadataServiceToken))
+}
+
+func TestTracingInterceptor_SendsSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	client, recorder := serve(t, "service-token", grpc.WithChainUnaryInterceptor(TracingInterceptor(tracer)))
+
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	_, err := client.GetUsers(ctx, &userpb.GetUsersRequest{Ids: []int64{1}})
+	require.NoError(t, err)
+	parent.Finish()
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, userpb.UserService_GetUsers_FullMethodName, span.OperationName)
+	assert.Equal(t, "client", span.Tag("span.kind"))
+	assert.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.SpanID, span.ParentID)
+
+	// The user service finds the client span in the metadata.
+	sent, err := tracer.Extract(opentracing.TextMap, MetadataCarrier(recorder.md))
+	require.NoError(t, err)
+	assert.Equal(t, span.SpanContext.SpanID, sent.(mocktracer.MockSpanContext).SpanID)
+	// The service token is still there next to the trace.
+	assert.Equal(t, []string{"service-token"}, recorder.md.Get(MetadataServiceToken))
+}
+
+func TestTracingInterceptor_KeepsOutgoingMetadata(t *testing.T) {
+	tracer := mocktracer.New()
+	interceptor := TracingInterceptor(tracer)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-for", "1.2.3.4")
+	original, _ := metadata.FromOutgoingContext(ctx)
+
+	var sent metadata.MD
+	err := interceptor(ctx, "/user.UserService/GetUsers", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.2.3.4"}, sent.Get("x-forwarded-for"))
+	assert.NotEmpty(t, sent.Get("mockpfx-ids-spanid"))
+	// The metadata of the caller isn't written to.
+	assert.Empty(t, original.Get("mockpfx-ids-spanid"))
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	carrier := MetadataCarrier(metadata.MD{})
+	carrier.Set("Uber-Trace-Id", "a")
+	carrier.Set("uber-trace-id", "b")
+
+	seen := map[string][]string{}
+	require.NoError(t, carrier.ForeachKey(func(key, val string) error {
+		seen[key] = append(seen[key], val)
+		return nil
+	}))
+
+	// gRPC metadata keys are lower case.
+	assert.Equal(t, map[string][]string{"uber-trace-id": {"a", "b"}}, seen)
+}
@@ -1,12 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dmitriysta/messenger/pkg/broker"
+	"github.com/dmitriysta/messenger/pkg/health"
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/pkg/shutdown"
 	"github.com/dmitriysta/messenger/user/internal/api"
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/pkg/avatar"
+	"github.com/dmitriysta/messenger/user/internal/pkg/cache"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/dmitriysta/messenger/user/internal/pkg/mail"
+	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/user/internal/pkg/oidc"
+	"github.com/dmitriysta/messenger/user/internal/pkg/secretbox"
 	"github.com/dmitriysta/messenger/user/internal/pkg/tracer"
 	"github.com/dmitriysta/messenger/user/internal/repository"
+	"github.com/dmitriysta/messenger/user/internal/rpc"
 	"github.com/dmitriysta/messenger/user/internal/service"
-	"github.com/joho/godotenv"
-	"os"
 
 	"github.com/sirupsen/logrus"
 )
@@ -16,13 +36,28 @@ func main() {
 	logger.SetLevel(logrus.InfoLevel)
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
-	err := godotenv.Load()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"module": "main",
 			"func":   "main",
-			"error":  err.Error(),
-		}).Fatalf("failed to load .env file: %v", err)
+		}).Fatalf("failed to load config: %v", err)
+	}
+
+	if level, err := logrus.ParseLevel(cfg.Log.Level); err == nil {
+		logger.SetLevel(level)
+	}
+
+	logger.WithFields(cfg.Fields()).Info("effective configuration")
+
+	if len(args) > 0 && args[0] == "migrate" {
+		db := repository.DatabaseConnect(cfg.Database, logger)
+
+		runMigrate(sqlDB(db, logger), logger, args[1:])
+		return
 	}
 
 	trace, closer, err := tracer.NewJaegerTracer("user", logger)
@@ -33,25 +68,178 @@ func main() {
 			"error":  err.Error(),
 		}).Fatalf("failed to create new tracer: %v", err)
 	}
-	defer closer.Close()
 
-	db := repository.DatabaseConnect(logger)
+	db := repository.DatabaseConnect(cfg.Database, logger)
+	migrator := newMigrator(sqlDB(db, logger), logger)
+	migrator.LogVersion(context.Background())
+
+	cache.InitRedis(cfg.Redis, logger)
+
+	policies, err := newRateLimitPolicies(cfg.RateLimit)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid rate limit configuration: %v", err)
+	}
+	limiter := ratelimit.NewLimiter(cache.RedisClient)
+	rateLimiter := api.NewRateLimiter(limiter, policies, cfg.RateLimit.TrustForwarded, logger)
+
+	mailer, err := mail.NewSender(cfg.Mail, logger)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid mail configuration: %v", err)
+	}
+
+	box, err := secretbox.NewBox(cfg.MFA.EncryptionKey)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid mfa encryption key: %v", err)
+	}
 
 	userRepo := repository.NewUserRepository(db, logger)
-	userService := service.NewUserService(userRepo, logger)
+	profileCache := cache.NewProfileCache(cache.RedisClient)
+	userService := service.NewUserService(userRepo, newLoginBackoff(cache.RedisClient, cfg.RateLimit), mailer, logger, cfg.JWT, cfg.Lockout, cfg.Verify, cfg.Reset, cfg.MFA, box, profileCache, ratelimit.NewCooldown(cache.RedisClient, "verification", cfg.Verify.ResendCooldown))
 	userHandler := api.NewUserHandler(userService, logger, trace)
 
-	router := api.SetupRouter(userHandler, logger)
+	providers, err := identityProviders(cfg.OIDC)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid oidc configuration: %v", err)
+	}
+	oidcService := service.NewOIDCService(userService, userRepo, providers, oidc.NewStateStore(cache.RedisClient), cfg.OIDC, logger)
+	oidcHandler := api.NewOIDCHandler(oidcService, cfg.OIDC, logger, trace)
+
+	avatars, err := avatar.NewLocalStore(cfg.Avatar.Dir, cfg.Avatar.BaseURL)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid avatar configuration: %v", err)
+	}
+	profileService := service.NewProfileService(userRepo, avatars, profileCache, cfg.Avatar, cfg.Search, cfg.Batch, logger)
+	profileHandler := api.NewProfileHandler(profileService, cfg.Avatar.MaxBytes, logger, trace)
+
+	readiness := health.NewReadiness()
+	checker := health.NewChecker(readiness, health.Metrics{Up: metrics.DependencyUp, Latency: metrics.DependencyLatency}, logger,
+		health.PostgresCheck(sqlDB(db, logger), cfg.Health.CheckTimeout),
+		health.RedisCheck(cache.RedisClient, cfg.Health.CheckTimeout),
+		health.MigrationsCheck(migrator, cfg.Health.CheckTimeout),
+	)
+
+	router := api.SetupRouter(userHandler, oidcHandler, profileHandler, checker, rateLimiter, cfg.Admin, cfg.Service, cfg.Avatar)
+
+	eventBroker, err := broker.New(cfg.Broker.Driver, cfg.Broker.StreamPrefix, cfg.Broker.StreamMaxLen, cache.RedisClient, logger)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("invalid broker configuration: %v", err)
+	}
+	relay := newOutboxRelay(sqlDB(db, logger), eventBroker, cfg.Outbox, logger)
 
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8081"
+	grpcRateLimiter := rpc.NewRateLimiter(limiter, policies, cfg.RateLimit.TrustForwarded, logger)
+	grpcServer := rpc.NewServer(rpc.NewUserServer(userService, profileService, logger), trace, cfg.Service.Tokens, grpcRateLimiter)
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: router,
 	}
 
-	if err := router.Run(":" + port); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"module": "main",
 			"func":   "main",
+			"error":  err.Error(),
 		}).Fatalf("failed to run server: %v", err)
 	}
+
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "main",
+			"func":   "main",
+			"error":  err.Error(),
+		}).Fatalf("failed to run grpc server: %v", err)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(listener)
+	}()
+	go func() {
+		serverErr <- grpcServer.Serve(grpcListener)
+	}()
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx)
+		close(relayDone)
+	}()
+
+	readiness.SetReady(true)
+	logger.WithFields(logrus.Fields{
+		"module": "main",
+		"func":   "main",
+		"addr":   server.Addr,
+		"grpc":   grpcListener.Addr().String(),
+	}).Info("server started")
+
+	select {
+	case err := <-serverErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.WithFields(logrus.Fields{
+				"module": "main",
+				"func":   "main",
+				"error":  err.Error(),
+			}).Fatalf("server stopped: %v", err)
+		}
+	case <-ctx.Done():
+		stop()
+		shutdown.Graceful(shutdown.Config{DrainDelay: cfg.Shutdown.DrainDelay, Timeout: cfg.Shutdown.Timeout}, readiness, logger,
+			[]shutdown.Drain{shutdown.HTTP(server), shutdown.GRPC(grpcServer)},
+			shutdown.Worker("outbox relay", stopRelay, relayDone),
+			shutdown.Closer("tracer", closer),
+			shutdown.Closer("cache", cache.RedisClient),
+			shutdown.Closer("database", sqlDB(db, logger)),
+		)
+	}
+}
+
+// identityProviders builds a client for each configured OpenID Connect
+// provider. No providers file means external login is off.
+func identityProviders(cfg config.OIDCConfig) (map[string]interfaces.IdentityProvider, error) {
+	providers := make(map[string]interfaces.IdentityProvider)
+	if cfg.ProvidersFile == "" {
+		return providers, nil
+	}
+
+	configs, err := oidc.LoadProviders(cfg.ProvidersFile)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: cfg.HTTPTimeout}
+	for _, pc := range configs {
+		providers[pc.Name] = oidc.NewClient(pc, httpClient)
+	}
+
+	return providers, nil
 }
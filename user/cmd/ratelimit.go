@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+)
+
+// newRateLimitPolicies parses the per-user and per-IP limits of the user
+// service.
+func newRateLimitPolicies(cfg config.RateLimitConfig) (ratelimit.Policies, error) {
+	user, err := ratelimit.ParsePolicies(cfg.User, cfg.UserRoutes)
+	if err != nil {
+		return ratelimit.Policies{}, err
+	}
+
+	ip, err := ratelimit.ParsePolicies(cfg.IP, cfg.IPRoutes)
+	if err != nil {
+		return ratelimit.Policies{}, err
+	}
+
+	return ratelimit.Policies{
+		Enabled: cfg.Enabled,
+		User:    user,
+		IP:      ip,
+	}, nil
+}
+
+// newLoginBackoff returns the backoff of failed logins per email.
+func newLoginBackoff(client ratelimit.BackoffClient, cfg config.RateLimitConfig) *ratelimit.Backoff {
+	return ratelimit.NewBackoff(client, cfg.LoginFreeAttempts, cfg.LoginBackoffBase, cfg.LoginBackoffMax, cfg.LoginWindow)
+}
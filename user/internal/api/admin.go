@@ -0,0 +1,218 @@
+package api
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"github.com/uber/jaeger-client-go"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type AdminUserResponse struct {
+	Id            int        `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"emailVerified"`
+	MFAEnabled    bool       `json:"mfaEnabled"`
+	SuspendedAt   *time.Time `json:"suspendedAt,omitempty"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func newAdminUserResponse(user *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		Id:            user.Id,
+		Username:      user.Name,
+		Email:         user.Email,
+		Role:          string(user.Role),
+		EmailVerified: user.IsEmailVerified(),
+		MFAEnabled:    user.MFAEnabled,
+		SuspendedAt:   user.SuspendedAt,
+		LockedUntil:   user.LockedUntil,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// ListUsersHandler pages through users, optionally only those with ?role=
+// or ?suspended=.
+func (h *UserHandler) ListUsersHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "ListUsersHandler")
+	defer span.Finish()
+
+	query, err := parseUserListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidQuery + ": " + err.Error()})
+		return
+	}
+
+	users, total, err := h.userService.ListUsers(ctx, query)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ListUsersHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.ErrorListingUsers)
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorListingUsers})
+		return
+	}
+
+	response := make([]AdminUserResponse, 0, len(users))
+	for i := range users {
+		response = append(response, newAdminUserResponse(&users[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":  response,
+		"total":  total,
+		"offset": query.Offset,
+		"limit":  query.Limit,
+	})
+}
+
+func parseUserListQuery(c *gin.Context) (models.UserListQuery, error) {
+	query := models.UserListQuery{Limit: defaultListLimit}
+
+	if value := c.Query("role"); value != "" {
+		role, err := models.ParseRole(value)
+		if err != nil {
+			return query, err
+		}
+		query.Role = &role
+	}
+
+	if value := c.Query("suspended"); value != "" {
+		suspended, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, stderrors.New("suspended must be true or false")
+		}
+		query.Suspended = &suspended
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return query, stderrors.New("offset must be a non-negative integer")
+		}
+		query.Offset = offset
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return query, stderrors.New("limit must be between 1 and " + strconv.Itoa(maxListLimit))
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+func (h *UserHandler) SuspendUserHandler(c *gin.Context) {
+	h.setSuspended(c, "SuspendUserHandler", true)
+}
+
+func (h *UserHandler) UnsuspendUserHandler(c *gin.Context) {
+	h.setSuspended(c, "UnsuspendUserHandler", false)
+}
+
+func (h *UserHandler) setSuspended(c *gin.Context, handler string, suspend bool) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), handler)
+	defer span.Finish()
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidUserId})
+		return
+	}
+
+	if suspend {
+		err = h.userService.SuspendUser(ctx, principal(c), userID)
+	} else {
+		err = h.userService.UnsuspendUser(ctx, principal(c), userID)
+	}
+	if err != nil {
+		h.adminError(c, span, handler, userID, err, errors.ErrorSuspendingUser)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":        userID,
+		"suspended": suspend,
+	})
+}
+
+func (h *UserHandler) SetUserRoleHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "SetUserRoleHandler")
+	defer span.Finish()
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidUserId})
+		return
+	}
+
+	var request SetRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	role, err := models.ParseRole(request.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidRole})
+		return
+	}
+
+	if err := h.userService.SetUserRole(ctx, principal(c), userID, role); err != nil {
+		h.adminError(c, span, "SetUserRoleHandler", userID, err, errors.ErrorChangingRole)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":   userID,
+		"role": role,
+	})
+}
+
+func (h *UserHandler) adminError(c *gin.Context, span opentracing.Span, handler string, userID int, err error, message string) {
+	traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+	entry := h.logger.WithFields(logrus.Fields{
+		"module":  "user",
+		"handler": handler,
+		"traceId": traceID,
+		"userId":  userID,
+		"actorId": c.GetInt("userId"),
+		"error":   err.Error(),
+	})
+
+	switch {
+	case stderrors.Is(err, models.ErrUserNotFound):
+		entry.Warn(errors.ErrorNoSuchUser)
+		c.JSON(http.StatusNotFound, gin.H{"error": errors.ErrorNoSuchUser})
+	case stderrors.Is(err, models.ErrForbidden):
+		entry.Warn(errors.ErrorForbidden)
+		c.JSON(http.StatusForbidden, gin.H{"error": errors.ErrorForbidden})
+	default:
+		entry.Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
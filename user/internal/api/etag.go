@@ -0,0 +1,35 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// formatETag renders a row version as a strong entity tag.
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch returns the version a client expects to overwrite. ok is false
+// when the header is absent or "*", in which case the write is unconditional.
+func parseIfMatch(c *gin.Context) (version int, ok bool, err error) {
+	value := strings.TrimSpace(c.GetHeader(HeaderIfMatch))
+	if value == "" || value == "*" {
+		return 0, false, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	version, err = strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, true, nil
+}
@@ -1,7 +1,12 @@
 package api
 
 import (
+	"context"
+	stderrors "errors"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
 	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
 	"github.com/dmitriysta/messenger/user/internal/pkg/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
@@ -9,14 +14,45 @@ import (
 	"github.com/uber/jaeger-client-go"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+// backgroundTimeout bounds the work a handler leaves running after it has
+// answered the request.
+const backgroundTimeout = 30 * time.Second
+
 type UserRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
 type UserHandler struct {
 	userService interfaces.UserService
 	logger      *logrus.Logger
@@ -120,10 +156,12 @@ func (h *UserHandler) GetUserHandler(c *gin.Context) {
 		return
 	}
 
+	c.Header(HeaderETag, formatETag(user.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"id":       user.Id,
 		"username": user.Name,
 		"email":    user.Email,
+		"version":  user.Version,
 	})
 }
 
@@ -161,12 +199,78 @@ func (h *UserHandler) UpdateUserHandler(c *gin.Context) {
 		return
 	}
 
+	h.updateUser(ctx, span, c, "UpdateUserHandler", userID, &models.UserPatch{
+		Name:     &userRequest.Username,
+		Email:    &userRequest.Email,
+		Password: &userRequest.Password,
+	})
+}
+
+func (h *UserHandler) PatchUserHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "PatchUserHandler")
+	defer span.Finish()
+
+	userIDStr := c.Param("id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "PatchUserHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidUserId)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidUserId})
+		return
+	}
+
+	if contentType := c.ContentType(); contentType != MIMEApplicationMergePatchJSON && contentType != MIMEApplicationJSON {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": errors.ErrorUnsupportedPatch})
+		return
+	}
+
+	patch, err := decodeUserPatch(c.Request.Body)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "PatchUserHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.ErrorInvalidPatch)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidPatch + ": " + err.Error()})
+		return
+	}
+
+	h.updateUser(ctx, span, c, "PatchUserHandler", userID, patch)
+}
+
+// updateUser is the read-modify-write shared by PUT and PATCH. It honours
+// If-Match against the stored version and maps a concurrent write to 409.
+func (h *UserHandler) updateUser(ctx context.Context, span opentracing.Span, c *gin.Context, handler string, userID int, patch *models.UserPatch) {
+	if err := patch.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidPatch + ": " + err.Error()})
+		return
+	}
+
+	expectedVersion, hasIfMatch, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidIfMatch})
+		return
+	}
+
 	user, err := h.userService.GetUserById(ctx, userID)
+	if stderrors.Is(err, models.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": errors.ErrorNoSuchUser})
+		return
+	}
 	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
 		h.logger.WithFields(logrus.Fields{
 			"module":  "user",
-			"handler": "UpdateUserHandler",
+			"handler": handler,
 			"traceId": traceID,
 			"userId":  userID,
 			"error":   err.Error(),
@@ -176,29 +280,56 @@ func (h *UserHandler) UpdateUserHandler(c *gin.Context) {
 		return
 	}
 
-	user.Name = userRequest.Username
-	user.Email = userRequest.Email
-	user.Password = userRequest.Password
+	if hasIfMatch && user.Version != expectedVersion {
+		c.Header(HeaderETag, formatETag(user.Version))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": errors.ErrorPreconditionFailed})
+		return
+	}
 
-	if err := h.userService.UpdateUser(ctx, user); err != nil {
+	oldName, oldEmail := user.Name, user.Email
+	changed, err := patch.Apply(user)
+	if err == nil && changed {
+		err = h.userService.UpdateUser(ctx, user)
+	}
+	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
 		h.logger.WithFields(logrus.Fields{
 			"module":   "user",
-			"handler":  "UpdateUserHandler",
+			"handler":  handler,
 			"traceId":  traceID,
-			"username": user.Name + " -> " + userRequest.Username,
-			"email":    user.Email + " -> " + userRequest.Email,
+			"username": oldName + " -> " + user.Name,
+			"email":    oldEmail + " -> " + user.Email,
 			"error":    err.Error(),
 		}).Error(errors.ErrorUpdatingUser)
 
+		if stderrors.Is(err, models.ErrVersionConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": errors.ErrorVersionConflict})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorUpdatingUser})
 		return
 	}
 
+	if user.Email != oldEmail {
+		if err := h.userService.ResendVerification(ctx, user.Email); err != nil {
+			traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+			h.logger.WithFields(logrus.Fields{
+				"module":  "user",
+				"handler": handler,
+				"traceId": traceID,
+				"userId":  user.Id,
+				"error":   err.Error(),
+			}).Warn(errors.ErrorSendingVerification)
+		}
+	}
+
+	c.Header(HeaderETag, formatETag(user.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"id":       user.Id,
 		"username": user.Name,
 		"email":    user.Email,
+		"version":  user.Version,
 	})
 }
 
@@ -262,14 +393,62 @@ func (h *UserHandler) AuthenticateUserHandler(c *gin.Context) {
 	user, token, err := h.userService.AuthenticateUser(ctx, userRequest.Email, userRequest.Password)
 	if err != nil {
 		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
-		if err.Error() == errors.ErrorUserNotFound {
+
+		var throttled *models.LoginThrottledError
+		if stderrors.As(err, &throttled) {
+			h.logger.WithFields(logrus.Fields{
+				"module":     "user",
+				"handler":    "AuthenticateUserHandler",
+				"traceId":    traceID,
+				"retryAfter": throttled.RetryAfter.String(),
+			}).Warn(errors.ErrorRateLimited)
+
+			c.Header(ratelimit.HeaderRetryAfter, strconv.Itoa(ratelimit.CeilSeconds(throttled.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": errors.ErrorRateLimited})
+			return
+		}
+
+		var mfaRequired *models.MFARequiredError
+		if stderrors.As(err, &mfaRequired) {
+			c.JSON(http.StatusOK, gin.H{
+				"mfaRequired": true,
+				"mfaToken":    mfaRequired.Token,
+			})
+			return
+		}
+
+		if stderrors.Is(err, models.ErrUserSuspended) {
+			h.logger.WithFields(logrus.Fields{
+				"module":  "user",
+				"handler": "AuthenticateUserHandler",
+				"traceId": traceID,
+				"email":   userRequest.Email,
+			}).Warn(errors.ErrorUserSuspended)
+
+			c.JSON(http.StatusForbidden, gin.H{"error": errors.ErrorUserSuspended})
+			return
+		}
+
+		if stderrors.Is(err, models.ErrEmailNotVerified) {
+			h.logger.WithFields(logrus.Fields{
+				"module":  "user",
+				"handler": "AuthenticateUserHandler",
+				"traceId": traceID,
+				"email":   userRequest.Email,
+			}).Warn(errors.ErrorEmailNotVerified)
+
+			c.JSON(http.StatusForbidden, gin.H{"error": errors.ErrorEmailNotVerified})
+			return
+		}
+
+		if stderrors.Is(err, models.ErrInvalidCredentials) {
 			h.logger.WithFields(logrus.Fields{
 				"module":  "user",
 				"handler": "AuthenticateUserHandler",
 				"traceId": traceID,
 				"email":   userRequest.Email,
 				"error":   err.Error(),
-			}).Error(errors.ErrorUserNotFound)
+			}).Warn(errors.ErrorUserNotFound)
 
 			c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorUserNotFound})
 			return
@@ -293,3 +472,351 @@ func (h *UserHandler) AuthenticateUserHandler(c *gin.Context) {
 		"token":    token,
 	})
 }
+
+func (h *UserHandler) UnlockUserHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "UnlockUserHandler")
+	defer span.Finish()
+
+	userIDStr := c.Param("id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "UnlockUserHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidUserId)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidUserId})
+		return
+	}
+
+	if err := h.userService.UnlockUser(ctx, principal(c), userID); err != nil {
+		h.adminError(c, span, "UnlockUserHandler", userID, err, errors.ErrorUnlockingUser)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+	})
+}
+
+func (h *UserHandler) VerifyEmailHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "VerifyEmailHandler")
+	defer span.Finish()
+
+	var request VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "VerifyEmailHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	if err := h.userService.VerifyEmail(ctx, request.Token); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+
+		if stderrors.Is(err, models.ErrInvalidToken) {
+			h.logger.WithFields(logrus.Fields{
+				"module":  "user",
+				"handler": "VerifyEmailHandler",
+				"traceId": traceID,
+			}).Warn(errors.ErrorInvalidToken)
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidToken})
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "VerifyEmailHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.ErrorVerifyingEmail)
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorVerifyingEmail})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerificationHandler answers the same way whether or not the email
+// belongs to an unverified account: the link is sent after the response,
+// so neither the status nor the time it takes tell them apart.
+func (h *UserHandler) ResendVerificationHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "ResendVerificationHandler")
+	defer span.Finish()
+
+	var request ResendVerificationRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ResendVerificationHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+	h.inBackground(ctx, "ResendVerificationHandler", traceID, errors.ErrorSendingVerification, func(ctx context.Context) error {
+		return h.userService.ResendVerification(ctx, request.Email)
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email belongs to an unverified account, a verification link has been sent",
+	})
+}
+
+// ForgotPasswordHandler answers the same way whether or not the email
+// belongs to an account: the reset is requested after the response, so
+// neither the status nor the time it takes tell the two apart.
+func (h *UserHandler) ForgotPasswordHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "ForgotPasswordHandler")
+	defer span.Finish()
+
+	var request ForgotPasswordRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ForgotPasswordHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+	h.inBackground(ctx, "ForgotPasswordHandler", traceID, errors.ErrorRequestingReset, func(ctx context.Context) error {
+		return h.userService.RequestPasswordReset(ctx, request.Email)
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email belongs to an account, a password reset link has been sent",
+	})
+}
+
+func (h *UserHandler) ResetPasswordHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "ResetPasswordHandler")
+	defer span.Finish()
+
+	var request ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ResetPasswordHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	if err := h.userService.ResetPassword(ctx, request.Token, request.Password); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+
+		if stderrors.Is(err, models.ErrInvalidToken) {
+			h.logger.WithFields(logrus.Fields{
+				"module":  "user",
+				"handler": "ResetPasswordHandler",
+				"traceId": traceID,
+			}).Warn(errors.ErrorInvalidToken)
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidToken})
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ResetPasswordHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.ErrorResettingPassword)
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorResettingPassword})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully",
+	})
+}
+
+func (h *UserHandler) EnrollMFAHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "EnrollMFAHandler")
+	defer span.Finish()
+
+	userID := c.GetInt("userId")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorMissingUserId})
+		return
+	}
+
+	enrollment, err := h.userService.EnrollMFA(ctx, userID)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "EnrollMFAHandler",
+			"traceId": traceID,
+			"userId":  userID,
+			"error":   err.Error(),
+		}).Error(errors.ErrorEnrollingMFA)
+
+		if stderrors.Is(err, models.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": errors.ErrorMFAAlreadyEnabled})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorEnrollingMFA})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret": enrollment.Secret,
+		"uri":    enrollment.URI,
+	})
+}
+
+func (h *UserHandler) ConfirmMFAHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "ConfirmMFAHandler")
+	defer span.Finish()
+
+	userID := c.GetInt("userId")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorMissingUserId})
+		return
+	}
+
+	var request MFACodeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ConfirmMFAHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	recoveryCodes, err := h.userService.ConfirmMFA(ctx, userID, request.Code)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "ConfirmMFAHandler",
+			"traceId": traceID,
+			"userId":  userID,
+			"error":   err.Error(),
+		}).Error(errors.ErrorEnrollingMFA)
+
+		switch {
+		case stderrors.Is(err, models.ErrInvalidMFACode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidMFACode})
+		case stderrors.Is(err, models.ErrMFAAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": errors.ErrorMFAAlreadyEnabled})
+		case stderrors.Is(err, models.ErrMFANotEnrolled):
+			c.JSON(http.StatusConflict, gin.H{"error": errors.ErrorMFANotEnrolled})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorEnrollingMFA})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func (h *UserHandler) VerifyMFAHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "VerifyMFAHandler")
+	defer span.Finish()
+
+	var request VerifyMFARequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "VerifyMFAHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Error(errors.InvalidRequestBody)
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	user, token, err := h.userService.VerifyMFA(ctx, request.MFAToken, request.Code)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"handler": "VerifyMFAHandler",
+			"traceId": traceID,
+			"error":   err.Error(),
+		}).Warn(errors.ErrorVerifyingMFA)
+
+		switch {
+		case stderrors.Is(err, models.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorInvalidToken})
+		case stderrors.Is(err, models.ErrInvalidMFACode):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorInvalidMFACode})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorVerifyingMFA})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       user.Id,
+		"username": user.Name,
+		"email":    user.Email,
+		"token":    token,
+	})
+}
+
+// inBackground runs task on a context that outlives the request, so that
+// the response doesn't wait for it. Its failure can only be logged.
+func (h *UserHandler) inBackground(ctx context.Context, handler, traceID, message string, task func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+
+	go func() {
+		defer cancel()
+
+		if err := task(ctx); err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"module":  "user",
+				"handler": handler,
+				"traceId": traceID,
+				"error":   err.Error(),
+			}).Error(message)
+		}
+	}()
+}
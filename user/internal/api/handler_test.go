@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uber/jaeger-client-go"
+)
+
+// withJaegerTracer installs a tracer that records nothing, since the
+// handlers read the trace id of their span off a jaeger span context.
+func withJaegerTracer(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(false), jaeger.NewNullReporter())
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() {
+		opentracing.SetGlobalTracer(previous)
+		closer.Close()
+	})
+}
+
+func TestUserHandler_PatchUserHandler_Lookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "missing user", err: models.ErrUserNotFound, wantStatus: http.StatusNotFound},
+		{name: "database failure", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withJaegerTracer(t)
+			gin.SetMode(gin.TestMode)
+			userService := new(mocks.UserService)
+			userService.On("GetUserById", mock.Anything, 7).Return(nil, tt.err)
+
+			logger, _ := test.NewNullLogger()
+			handler := NewUserHandler(userService, logger, opentracing.GlobalTracer())
+			router := gin.New()
+			router.PATCH("/users/:id", handler.PatchUserHandler)
+
+			r := httptest.NewRequest(http.MethodPatch, "/users/7", strings.NewReader(`{"username":"seven"}`))
+			r.Header.Set("Content-Type", MIMEApplicationMergePatchJSON)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			userService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+		})
+	}
+}
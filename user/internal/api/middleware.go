@@ -1,20 +1,25 @@
 package api
 
 import (
-	"fmt"
-	"github.com/dgrijalva/jwt-go"
+	"crypto/subtle"
+	stderrors "errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/errors"
 	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	BearerSchema = "Bearer "
+	BearerSchema       = "Bearer "
+	HeaderAdminToken   = "X-Admin-Token"
+	HeaderServiceToken = "X-Service-Token"
 )
 
 func PrometheusMiddleware() gin.HandlerFunc {
@@ -38,34 +43,150 @@ func PrometheusMiddleware() gin.HandlerFunc {
 	}
 }
 
-func JWTMiddleware(secretKey string) gin.HandlerFunc {
+// JWTMiddleware admits requests with a valid, unrevoked access token and
+// puts the id and role of its user into the context as "userId" and
+// "role".
+func JWTMiddleware(userService interfaces.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, BearerSchema) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header"})
+		if authenticate(c, userService) {
+			c.Next()
+		}
+	}
+}
+
+func authenticate(c *gin.Context, userService interfaces.UserService) bool {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, BearerSchema) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header"})
+		return false
+	}
+
+	tokenString := authHeader[len(BearerSchema):]
+
+	principal, err := userService.ValidateAccessToken(c.Request.Context(), tokenString)
+	if stderrors.Is(err, models.ErrInvalidToken) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return false
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorAuthenticatingUser})
+		return false
+	}
+
+	c.Set("userId", principal.UserId)
+	c.Set("role", principal.Role)
+
+	return true
+}
+
+// principal returns who JWTMiddleware or AdminMiddleware admitted.
+func principal(c *gin.Context) models.Principal {
+	role, _ := c.Get("role")
+	r, _ := role.(models.Role)
+
+	return models.Principal{UserId: c.GetInt("userId"), Role: r}
+}
+
+// AdminMiddleware admits moderators and admins by their access token.
+// Automation, and whoever appoints the first admin, can instead send the
+// configured token in X-Admin-Token, which acts as an admin; with no token
+// configured that header is refused.
+func AdminMiddleware(token string, userService interfaces.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminToken := c.GetHeader(HeaderAdminToken); adminToken != "" {
+			if token == "" {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrorAdminDisabled})
+				return
+			}
+
+			if subtle.ConstantTimeCompare([]byte(adminToken), []byte(token)) != 1 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorInvalidAdminToken})
+				return
+			}
+
+			c.Set("role", models.RoleAdmin)
+			c.Next()
 			return
 		}
 
-		tokenString := authHeader[len(BearerSchema):]
+		if !authenticate(c, userService) {
+			return
+		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if role := principal(c).Role; role != models.RoleAdmin && role != models.RoleModerator {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrorForbidden})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ServiceMiddleware admits other services that send one of tokens in
+// X-Service-Token. Users can't get in with their access tokens. With no
+// tokens configured every request is refused.
+func ServiceMiddleware(tokens []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(tokens) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrorServiceDisabled})
+			return
+		}
+
+		serviceToken := []byte(c.GetHeader(HeaderServiceToken))
+
+		// Every token is compared, so the time taken doesn't tell which
+		// one came close.
+		valid := 0
+		for _, token := range tokens {
+			valid |= subtle.ConstantTimeCompare(serviceToken, []byte(token))
+		}
+
+		if len(serviceToken) == 0 || valid != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorInvalidServiceToken})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole admits requests whose principal has one of roles. It has to
+// run after JWTMiddleware or AdminMiddleware.
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := principal(c).Role
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
 			}
-			return []byte(secretKey), nil
-		})
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrorForbidden})
+	}
+}
+
+// SelfOrAdminMiddleware admits requests about the user in the :id path
+// parameter from that user or an admin. It has to run after JWTMiddleware.
+func SelfOrAdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := principal(c)
+		if p.IsAdmin() {
+			c.Next()
+			return
+		}
 
+		userId, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errors.InvalidUserId})
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("userId", claims["userId"])
-			c.Next()
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if userId != p.UserId {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrorForbidden})
 			return
 		}
+
+		c.Next()
 	}
 }
@@ -0,0 +1,157 @@
+package api
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/dmitriysta/messenger/user/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"github.com/uber/jaeger-client-go"
+)
+
+// stateCookie holds the binding of the login the browser started, for the
+// callback to present.
+const stateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidcService interfaces.OIDCService
+	cfg         config.OIDCConfig
+	logger      *logrus.Logger
+	tracer      opentracing.Tracer
+}
+
+func NewOIDCHandler(oidcService interfaces.OIDCService, cfg config.OIDCConfig, logger *logrus.Logger, tracer opentracing.Tracer) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		cfg:         cfg,
+		logger:      logger,
+		tracer:      tracer,
+	}
+}
+
+// setStateCookie scopes the cookie to the provider's routes. It is sent
+// back on the provider's top-level redirect, which SameSite Lax allows,
+// and a maxAge below zero deletes it.
+func (h *OIDCHandler) setStateCookie(c *gin.Context, provider, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     "/auth/oidc/" + provider,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.cfg.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *OIDCHandler) LoginHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "OIDCLoginHandler")
+	defer span.Finish()
+
+	provider := c.Param("provider")
+
+	authURL, binding, err := h.oidcService.StartLogin(ctx, provider)
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+
+		if stderrors.Is(err, models.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errors.ErrorUnknownProvider})
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"handler":  "OIDCLoginHandler",
+			"traceId":  traceID,
+			"provider": provider,
+			"error":    err.Error(),
+		}).Error(errors.ErrorStartingOIDCLogin)
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errors.ErrorStartingOIDCLogin})
+		return
+	}
+
+	h.setStateCookie(c, provider, binding, int(h.cfg.StateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *OIDCHandler) CallbackHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "OIDCCallbackHandler")
+	defer span.Finish()
+
+	provider := c.Param("provider")
+
+	// The binding is good for one callback, whatever its outcome.
+	binding, _ := c.Cookie(stateCookie)
+	h.setStateCookie(c, provider, "", -1)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"handler":  "OIDCCallbackHandler",
+			"traceId":  traceID,
+			"provider": provider,
+			"error":    providerErr,
+		}).Warn(errors.ErrorProviderDenied)
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrorProviderDenied})
+		return
+	}
+
+	user, token, err := h.oidcService.CompleteLogin(ctx, provider, c.Query("state"), binding, c.Query("code"))
+	if err != nil {
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+
+		var mfaRequired *models.MFARequiredError
+		if stderrors.As(err, &mfaRequired) {
+			c.JSON(http.StatusOK, gin.H{
+				"mfaRequired": true,
+				"mfaToken":    mfaRequired.Token,
+			})
+			return
+		}
+
+		status, message := http.StatusInternalServerError, errors.ErrorCompletingOIDCLogin
+		switch {
+		case stderrors.Is(err, models.ErrUnknownProvider):
+			status, message = http.StatusNotFound, errors.ErrorUnknownProvider
+		case stderrors.Is(err, models.ErrInvalidToken):
+			status, message = http.StatusUnauthorized, errors.ErrorInvalidToken
+		case stderrors.Is(err, models.ErrExternalEmailNotVerified):
+			status, message = http.StatusForbidden, errors.ErrorExternalEmail
+		case stderrors.Is(err, models.ErrUserSuspended):
+			status, message = http.StatusForbidden, errors.ErrorUserSuspended
+		case stderrors.Is(err, models.ErrIdentityConflict):
+			status, message = http.StatusConflict, errors.ErrorIdentityConflict
+		}
+
+		entry := h.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"handler":  "OIDCCallbackHandler",
+			"traceId":  traceID,
+			"provider": provider,
+			"error":    err.Error(),
+		})
+		if status == http.StatusInternalServerError {
+			entry.Error(message)
+		} else {
+			entry.Warn(message)
+		}
+
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       user.Id,
+		"username": user.Name,
+		"email":    user.Email,
+		"token":    token,
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testOIDCConfig = config.OIDCConfig{StateTTL: 10 * time.Minute, SecureCookie: true}
+
+func newTestOIDCRouter(oidcService *mocks.OIDCService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger, _ := test.NewNullLogger()
+	handler := NewOIDCHandler(oidcService, testOIDCConfig, logger, opentracing.NoopTracer{})
+
+	router := gin.New()
+	router.GET("/auth/oidc/:provider/login", handler.LoginHandler)
+	router.GET("/auth/oidc/:provider/callback", handler.CallbackHandler)
+
+	return router
+}
+
+func stateCookieOf(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == stateCookie {
+			return cookie
+		}
+	}
+
+	require.Fail(t, "no state cookie set")
+	return nil
+}
+
+func TestOIDCHandler_LoginHandler_SetsStateCookie(t *testing.T) {
+	oidcService := new(mocks.OIDCService)
+	oidcService.On("StartLogin", mock.Anything, "stub").Return("https://idp.example.com/authorize", "binding", nil)
+
+	w := httptest.NewRecorder()
+	newTestOIDCRouter(oidcService).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/stub/login", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://idp.example.com/authorize", w.Header().Get("Location"))
+
+	cookie := stateCookieOf(t, w)
+	assert.Equal(t, "binding", cookie.Value)
+	assert.Equal(t, "/auth/oidc/stub", cookie.Path)
+	assert.Equal(t, 600, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+}
+
+func TestOIDCHandler_CallbackHandler_PresentsStateCookie(t *testing.T) {
+	oidcService := new(mocks.OIDCService)
+	oidcService.On("CompleteLogin", mock.Anything, "stub", "state", "binding", "code").
+		Return(&models.User{Id: 7, Name: "seven"}, "access", nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/auth/oidc/stub/callback?state=state&code=code", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: "binding"})
+	w := httptest.NewRecorder()
+	newTestOIDCRouter(oidcService).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	oidcService.AssertExpectations(t)
+
+	// The cookie is spent.
+	cookie := stateCookieOf(t, w)
+	assert.Empty(t, cookie.Value)
+	assert.Less(t, cookie.MaxAge, 0)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+)
+
+const (
+	MIMEApplicationJSON           = "application/json"
+	MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+)
+
+// decodeUserPatch reads a merge patch document using the same member names
+// as UserRequest. In merge patch semantics a null removes a member, which no
+// user field allows, and unknown members are rejected rather than ignored.
+func decodeUserPatch(body io.Reader) (*models.UserPatch, error) {
+	var document map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	patch := &models.UserPatch{}
+	for member, value := range document {
+		var target **string
+		switch member {
+		case "username":
+			target = &patch.Name
+		case "email":
+			target = &patch.Email
+		case "password":
+			target = &patch.Password
+		default:
+			return nil, fmt.Errorf("%s cannot be patched", member)
+		}
+
+		if string(value) == "null" {
+			return nil, fmt.Errorf("%s cannot be removed", member)
+		}
+
+		if err := json.Unmarshal(value, target); err != nil {
+			return nil, fmt.Errorf("%s: %w", member, err)
+		}
+	}
+
+	return patch, nil
+}
+
+// decodeProfilePatch reads a merge patch document against a profile. A
+// null clears a text member; searchable can only be set.
+func decodeProfilePatch(body io.Reader) (*models.ProfilePatch, error) {
+	var document map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	patch := &models.ProfilePatch{}
+	for member, value := range document {
+		if member == "searchable" {
+			if string(value) == "null" {
+				return nil, fmt.Errorf("%s cannot be removed", member)
+			}
+
+			if err := json.Unmarshal(value, &patch.Searchable); err != nil {
+				return nil, fmt.Errorf("%s: %w", member, err)
+			}
+
+			continue
+		}
+
+		var target **string
+		switch member {
+		case "displayName":
+			target = &patch.DisplayName
+		case "bio":
+			target = &patch.Bio
+		case "timezone":
+			target = &patch.Timezone
+		case "locale":
+			target = &patch.Locale
+		case "pronouns":
+			target = &patch.Pronouns
+		default:
+			return nil, fmt.Errorf("%s cannot be patched", member)
+		}
+
+		if string(value) == "null" {
+			*target = new(string)
+			continue
+		}
+
+		if err := json.Unmarshal(value, target); err != nil {
+			return nil, fmt.Errorf("%s: %w", member, err)
+		}
+	}
+
+	return patch, nil
+}
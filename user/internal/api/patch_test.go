@@ -0,0 +1,55 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestDecodeUserPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    *models.UserPatch
+		wantErr string
+	}{
+		{name: "empty document", body: `{}`, want: &models.UserPatch{}},
+		{
+			name: "all members",
+			body: `{"username":"name","email":"name@example.com","password":"password"}`,
+			want: &models.UserPatch{Name: stringPtr("name"), Email: stringPtr("name@example.com"), Password: stringPtr("password")},
+		},
+		{name: "one member", body: `{"email":"name@example.com"}`, want: &models.UserPatch{Email: stringPtr("name@example.com")}},
+		{name: "empty string is kept for Validate", body: `{"username":""}`, want: &models.UserPatch{Name: stringPtr("")}},
+		{name: "unknown member", body: `{"role":"admin"}`, wantErr: "role cannot be patched"},
+		{name: "field name instead of member name", body: `{"name":"name"}`, wantErr: "name cannot be patched"},
+		{name: "explicit null", body: `{"email":null}`, wantErr: "email cannot be removed"},
+		{name: "wrong type", body: `{"password":1}`, wantErr: "password: json: cannot unmarshal number"},
+		{name: "empty body", body: ``, wantErr: "EOF"},
+		{name: "not an object", body: `[]`, wantErr: "cannot unmarshal array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := decodeUserPatch(strings.NewReader(tt.body))
+			if tt.wantErr != "" {
+				// The wording of encoding/json errors differs between Go
+				// releases, so only their start is matched.
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, patch)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, patch)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"github.com/uber/jaeger-client-go"
+)
+
+// multipartOverhead is allowed on top of the avatar itself for the rest of
+// an upload's multipart body.
+const multipartOverhead = 64 << 10
+
+type ProfileHandler struct {
+	profileService interfaces.ProfileService
+	maxAvatarBytes int
+	logger         *logrus.Logger
+	tracer         opentracing.Tracer
+}
+
+func NewProfileHandler(profileService interfaces.ProfileService, maxAvatarBytes int, logger *logrus.Logger, tracer opentracing.Tracer) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		maxAvatarBytes: maxAvatarBytes,
+		logger:         logger,
+		tracer:         tracer,
+	}
+}
+
+// GetProfileHandler returns the public profile of the user in :id.
+func (h *ProfileHandler) GetProfileHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "GetProfileHandler")
+	defer span.Finish()
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidUserId})
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(ctx, userID)
+	if err != nil {
+		h.profileError(c, span, "GetProfileHandler", err, errors.ErrorGettingProfile)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// SearchUsersHandler finds users by ?query=, a page at a time: ?cursor=
+// takes the nextCursor of the previous page and ?limit= the page size.
+func (h *ProfileHandler) SearchUsersHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "SearchUsersHandler")
+	defer span.Finish()
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidQuery + ": limit must be a positive integer"})
+			return
+		}
+	}
+
+	page, err := h.profileService.SearchUsers(ctx, c.Query("query"), c.Query("cursor"), limit)
+	if err != nil {
+		h.profileError(c, span, "SearchUsersHandler", err, errors.ErrorSearchingUsers)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+type ProfileBatchRequest struct {
+	Ids []int `json:"ids" binding:"required"`
+}
+
+// GetProfilesHandler looks up many profiles at once for other services.
+func (h *ProfileHandler) GetProfilesHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "GetProfilesHandler")
+	defer span.Finish()
+
+	var request ProfileBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.InvalidRequestBody})
+		return
+	}
+
+	batch, err := h.profileService.GetProfiles(ctx, request.Ids)
+	if err != nil {
+		h.profileError(c, span, "GetProfilesHandler", err, errors.ErrorGettingProfiles)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *ProfileHandler) UpdateProfileHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "UpdateProfileHandler")
+	defer span.Finish()
+
+	if contentType := c.ContentType(); contentType != MIMEApplicationMergePatchJSON && contentType != MIMEApplicationJSON {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": errors.ErrorUnsupportedPatch})
+		return
+	}
+
+	patch, err := decodeProfilePatch(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidPatch + ": " + err.Error()})
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(ctx, c.GetInt("userId"), patch)
+	if err != nil {
+		h.profileError(c, span, "UpdateProfileHandler", err, errors.ErrorUpdatingProfile)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UploadAvatarHandler takes the image in the multipart field "avatar".
+func (h *ProfileHandler) UploadAvatarHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "UploadAvatarHandler")
+	defer span.Finish()
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(h.maxAvatarBytes)+multipartOverhead)
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errors.ErrorImageTooLarge})
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorMissingAvatar})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		h.profileError(c, span, "UploadAvatarHandler", err, errors.ErrorUploadingAvatar)
+		return
+	}
+	defer file.Close()
+
+	profile, err := h.profileService.SetAvatar(ctx, c.GetInt("userId"), file)
+	if err != nil {
+		h.profileError(c, span, "UploadAvatarHandler", err, errors.ErrorUploadingAvatar)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ProfileHandler) DeleteAvatarHandler(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContext(c.Request.Context(), "DeleteAvatarHandler")
+	defer span.Finish()
+
+	profile, err := h.profileService.DeleteAvatar(ctx, c.GetInt("userId"))
+	if err != nil {
+		h.profileError(c, span, "DeleteAvatarHandler", err, errors.ErrorUploadingAvatar)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ProfileHandler) profileError(c *gin.Context, span opentracing.Span, handler string, err error, message string) {
+	switch {
+	case stderrors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": errors.ErrorNoSuchUser})
+	case stderrors.Is(err, models.ErrInvalidProfile), stderrors.Is(err, models.ErrInvalidSearch), stderrors.Is(err, models.ErrInvalidBatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case stderrors.Is(err, models.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrorInvalidCursor})
+	case stderrors.Is(err, models.ErrInvalidImage):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": errors.ErrorInvalidImage})
+	case stderrors.Is(err, models.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errors.ErrorImageTooLarge})
+	default:
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		h.logger.WithFields(logrus.Fields{
+			"module":  "profile",
+			"handler": handler,
+			"traceId": traceID,
+			"userId":  c.GetInt("userId"),
+			"error":   err.Error(),
+		}).Error(message)
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
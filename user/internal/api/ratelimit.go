@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/pkg/errors"
+	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const HeaderForwardedFor = "X-Forwarded-For"
+
+// RateLimiter enforces token bucket limits per IP and per authenticated
+// user on each route. If redis can't be reached requests are let through:
+// an outage of the limiter shouldn't become an outage of the service.
+type RateLimiter struct {
+	limiter        interfaces.Limiter
+	policies       ratelimit.Policies
+	trustForwarded bool
+	logger         *logrus.Logger
+}
+
+func NewRateLimiter(limiter interfaces.Limiter, policies ratelimit.Policies, trustForwarded bool, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		limiter:        limiter,
+		policies:       policies,
+		trustForwarded: trustForwarded,
+		logger:         logger,
+	}
+}
+
+// IPMiddleware limits requests per client IP.
+func (rl *RateLimiter) IPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rl.allow(c, ratelimit.ScopeIP, rl.clientIP(c)) {
+			c.Next()
+		}
+	}
+}
+
+// UserMiddleware limits requests per user. It has to run after
+// JWTMiddleware, which puts the user id into the context.
+func (rl *RateLimiter) UserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, ok := c.Get("userId")
+		if !ok || userId == nil {
+			c.Next()
+			return
+		}
+
+		if rl.allow(c, ratelimit.ScopeUser, fmt.Sprint(userId)) {
+			c.Next()
+		}
+	}
+}
+
+func (rl *RateLimiter) allow(c *gin.Context, scope, subject string) bool {
+	if !rl.policies.Enabled {
+		return true
+	}
+
+	route := c.Request.Method + " " + c.FullPath()
+	policy := rl.policies.For(scope, route)
+
+	result, err := rl.limiter.Allow(c.Request.Context(), scope+":"+subject+":"+route, policy)
+	if err != nil {
+		rl.logger.WithFields(logrus.Fields{
+			"module": "api",
+			"func":   "RateLimiter.allow",
+			"scope":  scope,
+			"route":  route,
+			"error":  err.Error(),
+		}).Warnf("rate limit check failed, allowing request: %v", err)
+
+		return true
+	}
+
+	ratelimit.SetHeaders(c.Writer.Header(), result)
+
+	if result.Allowed {
+		return true
+	}
+
+	metrics.RateLimited.WithLabelValues(scope, route).Inc()
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": errors.ErrorRateLimited})
+
+	return false
+}
+
+func (rl *RateLimiter) clientIP(c *gin.Context) string {
+	if rl.trustForwarded {
+		if forwarded := c.GetHeader(HeaderForwardedFor); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+
+	return c.RemoteIP()
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testPolicies = ratelimit.Policies{
+	Enabled: true,
+	User:    ratelimit.ScopePolicies{Default: ratelimit.Policy{Limit: 120, Period: time.Minute}},
+	IP: ratelimit.ScopePolicies{
+		Default: ratelimit.Policy{Limit: 300, Period: time.Minute},
+		Routes:  map[string]ratelimit.Policy{"POST /auth/login": {Limit: 10, Period: time.Minute}},
+	},
+}
+
+// serveRateLimited sends a request through middleware to a route that
+// answers 200.
+func serveRateLimited(middleware gin.HandlerFunc, method, route, path string, userId interface{}) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	handlers := []gin.HandlerFunc{}
+	if userId != nil {
+		handlers = append(handlers, func(c *gin.Context) { c.Set("userId", userId) })
+	}
+	handlers = append(handlers, middleware, func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.Handle(method, route, handlers...)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRateLimiter_IPMiddleware(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, "ip:1.2.3.4:POST /auth/login", ratelimit.Policy{Limit: 10, Period: time.Minute}).
+		Return(ratelimit.Result{Allowed: true, Limit: 10, Remaining: 9, Reset: 6 * time.Second}, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, testPolicies, false, logger)
+
+	w := serveRateLimited(rl.IPMiddleware(), http.MethodPost, "/auth/login", "/auth/login", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get(ratelimit.HeaderRateLimitLimit))
+	assert.Equal(t, "9", w.Header().Get(ratelimit.HeaderRateLimitRemaining))
+	assert.Equal(t, "6", w.Header().Get(ratelimit.HeaderRateLimitReset))
+	assert.Empty(t, w.Header().Get(ratelimit.HeaderRetryAfter))
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_IPMiddleware_Refused(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, "ip:1.2.3.4:POST /auth/login", mock.Anything).
+		Return(ratelimit.Result{Limit: 10, RetryAfter: 1500 * time.Millisecond, Reset: time.Minute}, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, testPolicies, false, logger)
+
+	w := serveRateLimited(rl.IPMiddleware(), http.MethodPost, "/auth/login", "/auth/login", nil)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get(ratelimit.HeaderRetryAfter))
+	assert.Equal(t, "10", w.Header().Get(ratelimit.HeaderRateLimitLimit))
+	assert.Equal(t, "0", w.Header().Get(ratelimit.HeaderRateLimitRemaining))
+	assert.Equal(t, "60", w.Header().Get(ratelimit.HeaderRateLimitReset))
+	assert.JSONEq(t, `{"error":"Too many requests"}`, w.Body.String())
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_UserMiddleware(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	// The key names the route as registered, not the requested path.
+	limiter.On("Allow", mock.Anything, "user:7:PUT /user/:id", ratelimit.Policy{Limit: 120, Period: time.Minute}).
+		Return(ratelimit.Result{Allowed: true, Limit: 120, Remaining: 119}, nil).Once()
+
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, testPolicies, false, logger)
+
+	w := serveRateLimited(rl.UserMiddleware(), http.MethodPut, "/user/:id", "/user/7", 7)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "119", w.Header().Get(ratelimit.HeaderRateLimitRemaining))
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_FailsOpen(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	limiter.On("Allow", mock.Anything, mock.Anything, mock.Anything).Return(ratelimit.Result{}, errors.New("redis down")).Once()
+
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, testPolicies, false, logger)
+
+	w := serveRateLimited(rl.IPMiddleware(), http.MethodPost, "/auth/login", "/auth/login", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(ratelimit.HeaderRateLimitLimit))
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	limiter := new(mocks.Limiter)
+
+	policies := testPolicies
+	policies.Enabled = false
+
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, policies, false, logger)
+
+	w := serveRateLimited(rl.IPMiddleware(), http.MethodPost, "/auth/login", "/auth/login", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	limiter.AssertNotCalled(t, "Allow", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -1,40 +1,66 @@
 package api
 
 import (
+	"strings"
+
+	"github.com/dmitriysta/messenger/pkg/health"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/sirupsen/logrus"
-	"os"
 )
 
-func SetupRouter(userHandler *UserHandler, logger *logrus.Logger) *gin.Engine {
+func SetupRouter(userHandler *UserHandler, oidcHandler *OIDCHandler, profileHandler *ProfileHandler, checker *health.Checker, rateLimiter *RateLimiter, adminConfig config.AdminConfig, serviceConfig config.ServiceAuthConfig, avatarConfig config.AvatarConfig) *gin.Engine {
 	router := gin.Default()
 
 	router.Use(PrometheusMiddleware())
 
-	secret := getJWTSecret(logger)
-
-	authGroup := router.Group("/").Use(JWTMiddleware(secret))
+	authGroup := router.Group("/").Use(rateLimiter.IPMiddleware(), JWTMiddleware(userHandler.userService), rateLimiter.UserMiddleware())
 	{
 		authGroup.POST("/user", userHandler.CreateUserHandler)
 		authGroup.GET("/user", userHandler.GetUserHandler)
-		authGroup.PUT("/user/:id", userHandler.UpdateUserHandler)
-		authGroup.DELETE("/user/:id", userHandler.DeleteUserHandler)
+		authGroup.PUT("/user/:id", SelfOrAdminMiddleware(), userHandler.UpdateUserHandler)
+		authGroup.PATCH("/user/:id", SelfOrAdminMiddleware(), userHandler.PatchUserHandler)
+		authGroup.DELETE("/user/:id", SelfOrAdminMiddleware(), userHandler.DeleteUserHandler)
+		authGroup.POST("/user/mfa/enroll", userHandler.EnrollMFAHandler)
+		authGroup.POST("/user/mfa/confirm", userHandler.ConfirmMFAHandler)
+		authGroup.GET("/users", profileHandler.SearchUsersHandler)
+		authGroup.PATCH("/user/profile", profileHandler.UpdateProfileHandler)
+		authGroup.PUT("/user/avatar", profileHandler.UploadAvatarHandler)
+		authGroup.DELETE("/user/avatar", profileHandler.DeleteAvatarHandler)
 	}
 
-	router.POST("/auth/login", userHandler.AuthenticateUserHandler)
-
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	adminGroup := router.Group("/admin").Use(rateLimiter.IPMiddleware(), AdminMiddleware(adminConfig.Token, userHandler.userService))
+	{
+		adminGroup.GET("/users", userHandler.ListUsersHandler)
+		adminGroup.POST("/users/:id/suspend", userHandler.SuspendUserHandler)
+		adminGroup.POST("/users/:id/unsuspend", userHandler.UnsuspendUserHandler)
+		adminGroup.POST("/users/:id/unlock", userHandler.UnlockUserHandler)
+		adminGroup.PUT("/users/:id/role", RequireRole(models.RoleAdmin), userHandler.SetUserRoleHandler)
+		adminGroup.DELETE("/users/:id", RequireRole(models.RoleAdmin), userHandler.DeleteUserHandler)
+	}
 
-	return router
-}
+	router.GET("/users/:id/profile", rateLimiter.IPMiddleware(), profileHandler.GetProfileHandler)
+	router.POST("/users/batch", ServiceMiddleware(serviceConfig.Tokens), profileHandler.GetProfilesHandler)
 
-func getJWTSecret(logger *logrus.Logger) string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		logger.Warn("JWT_SECRET is not set, using default value")
-		return "default_secret"
+	// Avatars stored locally are served from here; a base URL elsewhere,
+	// such as a CDN in front of the directory, serves them itself.
+	if strings.HasPrefix(avatarConfig.BaseURL, "/") {
+		router.Static(avatarConfig.BaseURL, avatarConfig.Dir)
 	}
 
-	return secret
+	router.POST("/auth/login", rateLimiter.IPMiddleware(), userHandler.AuthenticateUserHandler)
+	router.POST("/auth/verify-email", rateLimiter.IPMiddleware(), userHandler.VerifyEmailHandler)
+	router.POST("/auth/verify-email/resend", rateLimiter.IPMiddleware(), userHandler.ResendVerificationHandler)
+	router.POST("/auth/password/forgot", rateLimiter.IPMiddleware(), userHandler.ForgotPasswordHandler)
+	router.POST("/auth/password/reset", rateLimiter.IPMiddleware(), userHandler.ResetPasswordHandler)
+	router.POST("/auth/mfa/verify", rateLimiter.IPMiddleware(), userHandler.VerifyMFAHandler)
+	router.GET("/auth/oidc/:provider/login", rateLimiter.IPMiddleware(), oidcHandler.LoginHandler)
+	router.GET("/auth/oidc/:provider/callback", rateLimiter.IPMiddleware(), oidcHandler.CallbackHandler)
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/livez", gin.WrapF(checker.LivenessHandler()))
+	router.GET("/readyz", gin.WrapF(checker.ReadinessHandler()))
+
+	return router
 }
@@ -0,0 +1,14 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+)
+
+// AvatarStore keeps processed avatar images under opaque keys.
+type AvatarStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
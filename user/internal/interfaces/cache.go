@@ -0,0 +1,16 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+)
+
+type ProfileCache interface {
+	GetMany(ctx context.Context, ids []int) (map[int]*models.Profile, map[int]int64, error)
+	SetMany(ctx context.Context, profiles []models.Profile, versions map[int]int64, ttl time.Duration) error
+	Delete(ctx context.Context, ids ...int) error
+}
@@ -0,0 +1,13 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+)
+
+type MailSender interface {
+	Send(ctx context.Context, email models.Email) error
+}
@@ -0,0 +1,177 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AvatarStore is an autogenerated mock type for the AvatarStore type
+type AvatarStore struct {
+	mock.Mock
+}
+
+type AvatarStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *AvatarStore) EXPECT() *AvatarStore_Expecter {
+	return &AvatarStore_Expecter{mock: &_m.Mock}
+}
+
+// Delete provides a mock function with given fields: ctx, key
+func (_m *AvatarStore) Delete(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AvatarStore_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type AvatarStore_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *AvatarStore_Expecter) Delete(ctx interface{}, key interface{}) *AvatarStore_Delete_Call {
+	return &AvatarStore_Delete_Call{Call: _e.mock.On("Delete", ctx, key)}
+}
+
+func (_c *AvatarStore_Delete_Call) Run(run func(ctx context.Context, key string)) *AvatarStore_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *AvatarStore_Delete_Call) Return(_a0 error) *AvatarStore_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AvatarStore_Delete_Call) RunAndReturn(run func(context.Context, string) error) *AvatarStore_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Put provides a mock function with given fields: ctx, key, data
+func (_m *AvatarStore) Put(ctx context.Context, key string, data []byte) error {
+	ret := _m.Called(ctx, key, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Put")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(ctx, key, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AvatarStore_Put_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Put'
+type AvatarStore_Put_Call struct {
+	*mock.Call
+}
+
+// Put is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - data []byte
+func (_e *AvatarStore_Expecter) Put(ctx interface{}, key interface{}, data interface{}) *AvatarStore_Put_Call {
+	return &AvatarStore_Put_Call{Call: _e.mock.On("Put", ctx, key, data)}
+}
+
+func (_c *AvatarStore_Put_Call) Run(run func(ctx context.Context, key string, data []byte)) *AvatarStore_Put_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte))
+	})
+	return _c
+}
+
+func (_c *AvatarStore_Put_Call) Return(_a0 error) *AvatarStore_Put_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AvatarStore_Put_Call) RunAndReturn(run func(context.Context, string, []byte) error) *AvatarStore_Put_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// URL provides a mock function with given fields: key
+func (_m *AvatarStore) URL(key string) string {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for URL")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// AvatarStore_URL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'URL'
+type AvatarStore_URL_Call struct {
+	*mock.Call
+}
+
+// URL is a helper method to define mock.On call
+//   - key string
+func (_e *AvatarStore_Expecter) URL(key interface{}) *AvatarStore_URL_Call {
+	return &AvatarStore_URL_Call{Call: _e.mock.On("URL", key)}
+}
+
+func (_c *AvatarStore_URL_Call) Run(run func(key string)) *AvatarStore_URL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *AvatarStore_URL_Call) Return(_a0 string) *AvatarStore_URL_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *AvatarStore_URL_Call) RunAndReturn(run func(string) string) *AvatarStore_URL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewAvatarStore creates a new instance of AvatarStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAvatarStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AvatarStore {
+	mock := &AvatarStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
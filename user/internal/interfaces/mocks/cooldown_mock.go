@@ -0,0 +1,93 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Cooldown is an autogenerated mock type for the Cooldown type
+type Cooldown struct {
+	mock.Mock
+}
+
+type Cooldown_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Cooldown) EXPECT() *Cooldown_Expecter {
+	return &Cooldown_Expecter{mock: &_m.Mock}
+}
+
+// Start provides a mock function with given fields: ctx, subject
+func (_m *Cooldown) Start(ctx context.Context, subject string) (bool, error) {
+	ret := _m.Called(ctx, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Start")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, subject)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Cooldown_Start_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Start'
+type Cooldown_Start_Call struct {
+	*mock.Call
+}
+
+// Start is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subject string
+func (_e *Cooldown_Expecter) Start(ctx interface{}, subject interface{}) *Cooldown_Start_Call {
+	return &Cooldown_Start_Call{Call: _e.mock.On("Start", ctx, subject)}
+}
+
+func (_c *Cooldown_Start_Call) Run(run func(ctx context.Context, subject string)) *Cooldown_Start_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Cooldown_Start_Call) Return(_a0 bool, _a1 error) *Cooldown_Start_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Cooldown_Start_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *Cooldown_Start_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewCooldown creates a new instance of Cooldown. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCooldown(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Cooldown {
+	mock := &Cooldown{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,199 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// LoginBackoff is an autogenerated mock type for the LoginBackoff type
+type LoginBackoff struct {
+	mock.Mock
+}
+
+type LoginBackoff_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *LoginBackoff) EXPECT() *LoginBackoff_Expecter {
+	return &LoginBackoff_Expecter{mock: &_m.Mock}
+}
+
+// Check provides a mock function with given fields: ctx, subject
+func (_m *LoginBackoff) Check(ctx context.Context, subject string) (time.Duration, error) {
+	ret := _m.Called(ctx, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Check")
+	}
+
+	var r0 time.Duration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (time.Duration, error)); ok {
+		return rf(ctx, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) time.Duration); ok {
+		r0 = rf(ctx, subject)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginBackoff_Check_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Check'
+type LoginBackoff_Check_Call struct {
+	*mock.Call
+}
+
+// Check is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subject string
+func (_e *LoginBackoff_Expecter) Check(ctx interface{}, subject interface{}) *LoginBackoff_Check_Call {
+	return &LoginBackoff_Check_Call{Call: _e.mock.On("Check", ctx, subject)}
+}
+
+func (_c *LoginBackoff_Check_Call) Run(run func(ctx context.Context, subject string)) *LoginBackoff_Check_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *LoginBackoff_Check_Call) Return(_a0 time.Duration, _a1 error) *LoginBackoff_Check_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *LoginBackoff_Check_Call) RunAndReturn(run func(context.Context, string) (time.Duration, error)) *LoginBackoff_Check_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Failure provides a mock function with given fields: ctx, subject
+func (_m *LoginBackoff) Failure(ctx context.Context, subject string) (time.Duration, error) {
+	ret := _m.Called(ctx, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Failure")
+	}
+
+	var r0 time.Duration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (time.Duration, error)); ok {
+		return rf(ctx, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) time.Duration); ok {
+		r0 = rf(ctx, subject)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginBackoff_Failure_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Failure'
+type LoginBackoff_Failure_Call struct {
+	*mock.Call
+}
+
+// Failure is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subject string
+func (_e *LoginBackoff_Expecter) Failure(ctx interface{}, subject interface{}) *LoginBackoff_Failure_Call {
+	return &LoginBackoff_Failure_Call{Call: _e.mock.On("Failure", ctx, subject)}
+}
+
+func (_c *LoginBackoff_Failure_Call) Run(run func(ctx context.Context, subject string)) *LoginBackoff_Failure_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *LoginBackoff_Failure_Call) Return(_a0 time.Duration, _a1 error) *LoginBackoff_Failure_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *LoginBackoff_Failure_Call) RunAndReturn(run func(context.Context, string) (time.Duration, error)) *LoginBackoff_Failure_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Reset provides a mock function with given fields: ctx, subject
+func (_m *LoginBackoff) Reset(ctx context.Context, subject string) error {
+	ret := _m.Called(ctx, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, subject)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LoginBackoff_Reset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reset'
+type LoginBackoff_Reset_Call struct {
+	*mock.Call
+}
+
+// Reset is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subject string
+func (_e *LoginBackoff_Expecter) Reset(ctx interface{}, subject interface{}) *LoginBackoff_Reset_Call {
+	return &LoginBackoff_Reset_Call{Call: _e.mock.On("Reset", ctx, subject)}
+}
+
+func (_c *LoginBackoff_Reset_Call) Run(run func(ctx context.Context, subject string)) *LoginBackoff_Reset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *LoginBackoff_Reset_Call) Return(_a0 error) *LoginBackoff_Reset_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *LoginBackoff_Reset_Call) RunAndReturn(run func(context.Context, string) error) *LoginBackoff_Reset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewLoginBackoff creates a new instance of LoginBackoff. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLoginBackoff(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LoginBackoff {
+	mock := &LoginBackoff{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,85 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/user/internal/models"
+)
+
+// MailSender is an autogenerated mock type for the MailSender type
+type MailSender struct {
+	mock.Mock
+}
+
+type MailSender_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MailSender) EXPECT() *MailSender_Expecter {
+	return &MailSender_Expecter{mock: &_m.Mock}
+}
+
+// Send provides a mock function with given fields: ctx, email
+func (_m *MailSender) Send(ctx context.Context, email models.Email) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Send")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Email) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MailSender_Send_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Send'
+type MailSender_Send_Call struct {
+	*mock.Call
+}
+
+// Send is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email models.Email
+func (_e *MailSender_Expecter) Send(ctx interface{}, email interface{}) *MailSender_Send_Call {
+	return &MailSender_Send_Call{Call: _e.mock.On("Send", ctx, email)}
+}
+
+func (_c *MailSender_Send_Call) Run(run func(ctx context.Context, email models.Email)) *MailSender_Send_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Email))
+	})
+	return _c
+}
+
+func (_c *MailSender_Send_Call) Return(_a0 error) *MailSender_Send_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MailSender_Send_Call) RunAndReturn(run func(context.Context, models.Email) error) *MailSender_Send_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMailSender creates a new instance of MailSender. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMailSender(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MailSender {
+	mock := &MailSender{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
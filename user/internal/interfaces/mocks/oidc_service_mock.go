@@ -0,0 +1,171 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/user/internal/models"
+)
+
+// OIDCService is an autogenerated mock type for the OIDCService type
+type OIDCService struct {
+	mock.Mock
+}
+
+type OIDCService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *OIDCService) EXPECT() *OIDCService_Expecter {
+	return &OIDCService_Expecter{mock: &_m.Mock}
+}
+
+// CompleteLogin provides a mock function with given fields: ctx, provider, state, binding, code
+func (_m *OIDCService) CompleteLogin(ctx context.Context, provider string, state string, binding string, code string) (*models.User, string, error) {
+	ret := _m.Called(ctx, provider, state, binding, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteLogin")
+	}
+
+	var r0 *models.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (*models.User, string, error)); ok {
+		return rf(ctx, provider, state, binding, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *models.User); ok {
+		r0 = rf(ctx, provider, state, binding, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) string); ok {
+		r1 = rf(ctx, provider, state, binding, code)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, string, string) error); ok {
+		r2 = rf(ctx, provider, state, binding, code)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// OIDCService_CompleteLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompleteLogin'
+type OIDCService_CompleteLogin_Call struct {
+	*mock.Call
+}
+
+// CompleteLogin is a helper method to define mock.On call
+//   - ctx context.Context
+//   - provider string
+//   - state string
+//   - binding string
+//   - code string
+func (_e *OIDCService_Expecter) CompleteLogin(ctx interface{}, provider interface{}, state interface{}, binding interface{}, code interface{}) *OIDCService_CompleteLogin_Call {
+	return &OIDCService_CompleteLogin_Call{Call: _e.mock.On("CompleteLogin", ctx, provider, state, binding, code)}
+}
+
+func (_c *OIDCService_CompleteLogin_Call) Run(run func(ctx context.Context, provider string, state string, binding string, code string)) *OIDCService_CompleteLogin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *OIDCService_CompleteLogin_Call) Return(_a0 *models.User, _a1 string, _a2 error) *OIDCService_CompleteLogin_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *OIDCService_CompleteLogin_Call) RunAndReturn(run func(context.Context, string, string, string, string) (*models.User, string, error)) *OIDCService_CompleteLogin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StartLogin provides a mock function with given fields: ctx, provider
+func (_m *OIDCService) StartLogin(ctx context.Context, provider string) (string, string, error) {
+	ret := _m.Called(ctx, provider)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartLogin")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, string, error)); ok {
+		return rf(ctx, provider)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, provider)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = rf(ctx, provider)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, provider)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// OIDCService_StartLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StartLogin'
+type OIDCService_StartLogin_Call struct {
+	*mock.Call
+}
+
+// StartLogin is a helper method to define mock.On call
+//   - ctx context.Context
+//   - provider string
+func (_e *OIDCService_Expecter) StartLogin(ctx interface{}, provider interface{}) *OIDCService_StartLogin_Call {
+	return &OIDCService_StartLogin_Call{Call: _e.mock.On("StartLogin", ctx, provider)}
+}
+
+func (_c *OIDCService_StartLogin_Call) Run(run func(ctx context.Context, provider string)) *OIDCService_StartLogin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *OIDCService_StartLogin_Call) Return(_a0 string, _a1 string, _a2 error) *OIDCService_StartLogin_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *OIDCService_StartLogin_Call) RunAndReturn(run func(context.Context, string) (string, string, error)) *OIDCService_StartLogin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewOIDCService creates a new instance of OIDCService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOIDCService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OIDCService {
+	mock := &OIDCService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
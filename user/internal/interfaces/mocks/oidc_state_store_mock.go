@@ -0,0 +1,148 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/user/internal/models"
+
+	time "time"
+)
+
+// OIDCStateStore is an autogenerated mock type for the OIDCStateStore type
+type OIDCStateStore struct {
+	mock.Mock
+}
+
+type OIDCStateStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *OIDCStateStore) EXPECT() *OIDCStateStore_Expecter {
+	return &OIDCStateStore_Expecter{mock: &_m.Mock}
+}
+
+// Save provides a mock function with given fields: ctx, key, state, ttl
+func (_m *OIDCStateStore) Save(ctx context.Context, key string, state models.OIDCState, ttl time.Duration) error {
+	ret := _m.Called(ctx, key, state, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Save")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.OIDCState, time.Duration) error); ok {
+		r0 = rf(ctx, key, state, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OIDCStateStore_Save_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Save'
+type OIDCStateStore_Save_Call struct {
+	*mock.Call
+}
+
+// Save is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - state models.OIDCState
+//   - ttl time.Duration
+func (_e *OIDCStateStore_Expecter) Save(ctx interface{}, key interface{}, state interface{}, ttl interface{}) *OIDCStateStore_Save_Call {
+	return &OIDCStateStore_Save_Call{Call: _e.mock.On("Save", ctx, key, state, ttl)}
+}
+
+func (_c *OIDCStateStore_Save_Call) Run(run func(ctx context.Context, key string, state models.OIDCState, ttl time.Duration)) *OIDCStateStore_Save_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(models.OIDCState), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *OIDCStateStore_Save_Call) Return(_a0 error) *OIDCStateStore_Save_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *OIDCStateStore_Save_Call) RunAndReturn(run func(context.Context, string, models.OIDCState, time.Duration) error) *OIDCStateStore_Save_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Take provides a mock function with given fields: ctx, key
+func (_m *OIDCStateStore) Take(ctx context.Context, key string) (*models.OIDCState, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Take")
+	}
+
+	var r0 *models.OIDCState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.OIDCState, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.OIDCState); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OIDCState)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OIDCStateStore_Take_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Take'
+type OIDCStateStore_Take_Call struct {
+	*mock.Call
+}
+
+// Take is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *OIDCStateStore_Expecter) Take(ctx interface{}, key interface{}) *OIDCStateStore_Take_Call {
+	return &OIDCStateStore_Take_Call{Call: _e.mock.On("Take", ctx, key)}
+}
+
+func (_c *OIDCStateStore_Take_Call) Run(run func(ctx context.Context, key string)) *OIDCStateStore_Take_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *OIDCStateStore_Take_Call) Return(_a0 *models.OIDCState, _a1 error) *OIDCStateStore_Take_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *OIDCStateStore_Take_Call) RunAndReturn(run func(context.Context, string) (*models.OIDCState, error)) *OIDCStateStore_Take_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewOIDCStateStore creates a new instance of OIDCStateStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOIDCStateStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OIDCStateStore {
+	mock := &OIDCStateStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,218 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/user/internal/models"
+
+	time "time"
+)
+
+// ProfileCache is an autogenerated mock type for the ProfileCache type
+type ProfileCache struct {
+	mock.Mock
+}
+
+type ProfileCache_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *ProfileCache) EXPECT() *ProfileCache_Expecter {
+	return &ProfileCache_Expecter{mock: &_m.Mock}
+}
+
+// Delete provides a mock function with given fields: ctx, ids
+func (_m *ProfileCache) Delete(ctx context.Context, ids ...int) error {
+	_va := make([]interface{}, len(ids))
+	for _i := range ids {
+		_va[_i] = ids[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...int) error); ok {
+		r0 = rf(ctx, ids...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ProfileCache_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type ProfileCache_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids ...int
+func (_e *ProfileCache_Expecter) Delete(ctx interface{}, ids ...interface{}) *ProfileCache_Delete_Call {
+	return &ProfileCache_Delete_Call{Call: _e.mock.On("Delete",
+		append([]interface{}{ctx}, ids...)...)}
+}
+
+func (_c *ProfileCache_Delete_Call) Run(run func(ctx context.Context, ids ...int)) *ProfileCache_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]int, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(int)
+			}
+		}
+		run(args[0].(context.Context), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *ProfileCache_Delete_Call) Return(_a0 error) *ProfileCache_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *ProfileCache_Delete_Call) RunAndReturn(run func(context.Context, ...int) error) *ProfileCache_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMany provides a mock function with given fields: ctx, ids
+func (_m *ProfileCache) GetMany(ctx context.Context, ids []int) (map[int]*models.Profile, map[int]int64, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMany")
+	}
+
+	var r0 map[int]*models.Profile
+	var r1 map[int]int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) (map[int]*models.Profile, map[int]int64, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) map[int]*models.Profile); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]*models.Profile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) map[int]int64); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(map[int]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []int) error); ok {
+		r2 = rf(ctx, ids)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ProfileCache_GetMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMany'
+type ProfileCache_GetMany_Call struct {
+	*mock.Call
+}
+
+// GetMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []int
+func (_e *ProfileCache_Expecter) GetMany(ctx interface{}, ids interface{}) *ProfileCache_GetMany_Call {
+	return &ProfileCache_GetMany_Call{Call: _e.mock.On("GetMany", ctx, ids)}
+}
+
+func (_c *ProfileCache_GetMany_Call) Run(run func(ctx context.Context, ids []int)) *ProfileCache_GetMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *ProfileCache_GetMany_Call) Return(_a0 map[int]*models.Profile, _a1 map[int]int64, _a2 error) *ProfileCache_GetMany_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *ProfileCache_GetMany_Call) RunAndReturn(run func(context.Context, []int) (map[int]*models.Profile, map[int]int64, error)) *ProfileCache_GetMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetMany provides a mock function with given fields: ctx, profiles, versions, ttl
+func (_m *ProfileCache) SetMany(ctx context.Context, profiles []models.Profile, versions map[int]int64, ttl time.Duration) error {
+	ret := _m.Called(ctx, profiles, versions, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMany")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Profile, map[int]int64, time.Duration) error); ok {
+		r0 = rf(ctx, profiles, versions, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ProfileCache_SetMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetMany'
+type ProfileCache_SetMany_Call struct {
+	*mock.Call
+}
+
+// SetMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - profiles []models.Profile
+//   - versions map[int]int64
+//   - ttl time.Duration
+func (_e *ProfileCache_Expecter) SetMany(ctx interface{}, profiles interface{}, versions interface{}, ttl interface{}) *ProfileCache_SetMany_Call {
+	return &ProfileCache_SetMany_Call{Call: _e.mock.On("SetMany", ctx, profiles, versions, ttl)}
+}
+
+func (_c *ProfileCache_SetMany_Call) Run(run func(ctx context.Context, profiles []models.Profile, versions map[int]int64, ttl time.Duration)) *ProfileCache_SetMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]models.Profile), args[2].(map[int]int64), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *ProfileCache_SetMany_Call) Return(_a0 error) *ProfileCache_SetMany_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *ProfileCache_SetMany_Call) RunAndReturn(run func(context.Context, []models.Profile, map[int]int64, time.Duration) error) *ProfileCache_SetMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewProfileCache creates a new instance of ProfileCache. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewProfileCache(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ProfileCache {
+	mock := &ProfileCache{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
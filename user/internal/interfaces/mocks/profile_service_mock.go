@@ -0,0 +1,398 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/dmitriysta/messenger/user/internal/models"
+)
+
+// ProfileService is an autogenerated mock type for the ProfileService type
+type ProfileService struct {
+	mock.Mock
+}
+
+type ProfileService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *ProfileService) EXPECT() *ProfileService_Expecter {
+	return &ProfileService_Expecter{mock: &_m.Mock}
+}
+
+// DeleteAvatar provides a mock function with given fields: ctx, userId
+func (_m *ProfileService) DeleteAvatar(ctx context.Context, userId int) (*models.Profile, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAvatar")
+	}
+
+	var r0 *models.Profile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Profile, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Profile); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Profile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileService_DeleteAvatar_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAvatar'
+type ProfileService_DeleteAvatar_Call struct {
+	*mock.Call
+}
+
+// DeleteAvatar is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *ProfileService_Expecter) DeleteAvatar(ctx interface{}, userId interface{}) *ProfileService_DeleteAvatar_Call {
+	return &ProfileService_DeleteAvatar_Call{Call: _e.mock.On("DeleteAvatar", ctx, userId)}
+}
+
+func (_c *ProfileService_DeleteAvatar_Call) Run(run func(ctx context.Context, userId int)) *ProfileService_DeleteAvatar_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *ProfileService_DeleteAvatar_Call) Return(_a0 *models.Profile, _a1 error) *ProfileService_DeleteAvatar_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ProfileService_DeleteAvatar_Call) RunAndReturn(run func(context.Context, int) (*models.Profile, error)) *ProfileService_DeleteAvatar_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetProfile provides a mock function with given fields: ctx, userId
+func (_m *ProfileService) GetProfile(ctx context.Context, userId int) (*models.Profile, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProfile")
+	}
+
+	var r0 *models.Profile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Profile, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Profile); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Profile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileService_GetProfile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetProfile'
+type ProfileService_GetProfile_Call struct {
+	*mock.Call
+}
+
+// GetProfile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *ProfileService_Expecter) GetProfile(ctx interface{}, userId interface{}) *ProfileService_GetProfile_Call {
+	return &ProfileService_GetProfile_Call{Call: _e.mock.On("GetProfile", ctx, userId)}
+}
+
+func (_c *ProfileService_GetProfile_Call) Run(run func(ctx context.Context, userId int)) *ProfileService_GetProfile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *ProfileService_GetProfile_Call) Return(_a0 *models.Profile, _a1 error) *ProfileService_GetProfile_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ProfileService_GetProfile_Call) RunAndReturn(run func(context.Context, int) (*models.Profile, error)) *ProfileService_GetProfile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetProfiles provides a mock function with given fields: ctx, userIds
+func (_m *ProfileService) GetProfiles(ctx context.Context, userIds []int) (*models.ProfileBatch, error) {
+	ret := _m.Called(ctx, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProfiles")
+	}
+
+	var r0 *models.ProfileBatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) (*models.ProfileBatch, error)); ok {
+		return rf(ctx, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) *models.ProfileBatch); ok {
+		r0 = rf(ctx, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProfileBatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileService_GetProfiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetProfiles'
+type ProfileService_GetProfiles_Call struct {
+	*mock.Call
+}
+
+// GetProfiles is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userIds []int
+func (_e *ProfileService_Expecter) GetProfiles(ctx interface{}, userIds interface{}) *ProfileService_GetProfiles_Call {
+	return &ProfileService_GetProfiles_Call{Call: _e.mock.On("GetProfiles", ctx, userIds)}
+}
+
+func (_c *ProfileService_GetProfiles_Call) Run(run func(ctx context.Context, userIds []int)) *ProfileService_GetProfiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *ProfileService_GetProfiles_Call) Return(_a0 *models.ProfileBatch, _a1 error) *ProfileService_GetProfiles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ProfileService_GetProfiles_Call) RunAndReturn(run func(context.Context, []int) (*models.ProfileBatch, error)) *ProfileService_GetProfiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SearchUsers provides a mock function with given fields: ctx, query, cursor, limit
+func (_m *ProfileService) SearchUsers(ctx context.Context, query string, cursor string, limit int) (*models.ProfilePage, error) {
+	ret := _m.Called(ctx, query, cursor, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 *models.ProfilePage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) (*models.ProfilePage, error)); ok {
+		return rf(ctx, query, cursor, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) *models.ProfilePage); ok {
+		r0 = rf(ctx, query, cursor, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProfilePage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, query, cursor, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileService_SearchUsers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SearchUsers'
+type ProfileService_SearchUsers_Call struct {
+	*mock.Call
+}
+
+// SearchUsers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - cursor string
+//   - limit int
+func (_e *ProfileService_Expecter) SearchUsers(ctx interface{}, query interface{}, cursor interface{}, limit interface{}) *ProfileService_SearchUsers_Call {
+	return &ProfileService_SearchUsers_Call{Call: _e.mock.On("SearchUsers", ctx, query, cursor, limit)}
+}
+
+func (_c *ProfileService_SearchUsers_Call) Run(run func(ctx context.Context, query string, cursor string, limit int)) *ProfileService_SearchUsers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *ProfileService_SearchUsers_Call) Return(_a0 *models.ProfilePage, _a1 error) *ProfileService_SearchUsers_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ProfileService_SearchUsers_Call) RunAndReturn(run func(context.Context, string, string, int) (*models.ProfilePage, error)) *ProfileService_SearchUsers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetAvatar provides a mock function with given fields: ctx, userId, image
+func (_m *ProfileService) SetAvatar(ctx context.Context, userId int, image io.Reader) (*models.Profile, error) {
+	ret := _m.Called(ctx, userId, image)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAvatar")
+	}
+
+	var r0 *models.Profile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Reader) (*models.Profile, error)); ok {
+		return rf(ctx, userId, image)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, io.Reader) *models.Profile); ok {
+		r0 = rf(ctx, userId, image)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Profile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, io.Reader) error); ok {
+		r1 = rf(ctx, userId, image)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileService_SetAvatar_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetAvatar'
+type ProfileService_SetAvatar_Call struct {
+	*mock.Call
+}
+
+// SetAvatar is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - image io.Reader
+func (_e *ProfileService_Expecter) SetAvatar(ctx interface{}, userId interface{}, image interface{}) *ProfileService_SetAvatar_Call {
+	return &ProfileService_SetAvatar_Call{Call: _e.mock.On("SetAvatar", ctx, userId, image)}
+}
+
+func (_c *ProfileService_SetAvatar_Call) Run(run func(ctx context.Context, userId int, image io.Reader)) *ProfileService_SetAvatar_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(io.Reader))
+	})
+	return _c
+}
+
+func (_c *ProfileService_SetAvatar_Call) Return(_a0 *models.Profile, _a1 error) *ProfileService_SetAvatar_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ProfileService_SetAvatar_Call) RunAndReturn(run func(context.Context, int, io.Reader) (*models.Profile, error)) *ProfileService_SetAvatar_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, userId, patch
+func (_m *ProfileService) UpdateProfile(ctx context.Context, userId int, patch *models.ProfilePatch) (*models.Profile, error) {
+	ret := _m.Called(ctx, userId, patch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 *models.Profile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.ProfilePatch) (*models.Profile, error)); ok {
+		return rf(ctx, userId, patch)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *models.ProfilePatch) *models.Profile); ok {
+		r0 = rf(ctx, userId, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Profile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *models.ProfilePatch) error); ok {
+		r1 = rf(ctx, userId, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileService_UpdateProfile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateProfile'
+type ProfileService_UpdateProfile_Call struct {
+	*mock.Call
+}
+
+// UpdateProfile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - patch *models.ProfilePatch
+func (_e *ProfileService_Expecter) UpdateProfile(ctx interface{}, userId interface{}, patch interface{}) *ProfileService_UpdateProfile_Call {
+	return &ProfileService_UpdateProfile_Call{Call: _e.mock.On("UpdateProfile", ctx, userId, patch)}
+}
+
+func (_c *ProfileService_UpdateProfile_Call) Run(run func(ctx context.Context, userId int, patch *models.ProfilePatch)) *ProfileService_UpdateProfile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(*models.ProfilePatch))
+	})
+	return _c
+}
+
+func (_c *ProfileService_UpdateProfile_Call) Return(_a0 *models.Profile, _a1 error) *ProfileService_UpdateProfile_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ProfileService_UpdateProfile_Call) RunAndReturn(run func(context.Context, int, *models.ProfilePatch) (*models.Profile, error)) *ProfileService_UpdateProfile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewProfileService creates a new instance of ProfileService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewProfileService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ProfileService {
+	mock := &ProfileService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -8,6 +8,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	models "github.com/dmitriysta/messenger/user/internal/models"
+
+	time "time"
 )
 
 // UserRepository is an autogenerated mock type for the UserRepository type
@@ -23,6 +25,100 @@ func (_m *UserRepository) EXPECT() *UserRepository_Expecter {
 	return &UserRepository_Expecter{mock: &_m.Mock}
 }
 
+// CreateIdentity provides a mock function with given fields: ctx, identity
+func (_m *UserRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	ret := _m.Called(ctx, identity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateIdentity")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.UserIdentity) error); ok {
+		r0 = rf(ctx, identity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_CreateIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateIdentity'
+type UserRepository_CreateIdentity_Call struct {
+	*mock.Call
+}
+
+// CreateIdentity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - identity *models.UserIdentity
+func (_e *UserRepository_Expecter) CreateIdentity(ctx interface{}, identity interface{}) *UserRepository_CreateIdentity_Call {
+	return &UserRepository_CreateIdentity_Call{Call: _e.mock.On("CreateIdentity", ctx, identity)}
+}
+
+func (_c *UserRepository_CreateIdentity_Call) Run(run func(ctx context.Context, identity *models.UserIdentity)) *UserRepository_CreateIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.UserIdentity))
+	})
+	return _c
+}
+
+func (_c *UserRepository_CreateIdentity_Call) Return(_a0 error) *UserRepository_CreateIdentity_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_CreateIdentity_Call) RunAndReturn(run func(context.Context, *models.UserIdentity) error) *UserRepository_CreateIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreatePasswordResetToken provides a mock function with given fields: ctx, token
+func (_m *UserRepository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePasswordResetToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordResetToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_CreatePasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreatePasswordResetToken'
+type UserRepository_CreatePasswordResetToken_Call struct {
+	*mock.Call
+}
+
+// CreatePasswordResetToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token *models.PasswordResetToken
+func (_e *UserRepository_Expecter) CreatePasswordResetToken(ctx interface{}, token interface{}) *UserRepository_CreatePasswordResetToken_Call {
+	return &UserRepository_CreatePasswordResetToken_Call{Call: _e.mock.On("CreatePasswordResetToken", ctx, token)}
+}
+
+func (_c *UserRepository_CreatePasswordResetToken_Call) Run(run func(ctx context.Context, token *models.PasswordResetToken)) *UserRepository_CreatePasswordResetToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PasswordResetToken))
+	})
+	return _c
+}
+
+func (_c *UserRepository_CreatePasswordResetToken_Call) Return(_a0 error) *UserRepository_CreatePasswordResetToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_CreatePasswordResetToken_Call) RunAndReturn(run func(context.Context, *models.PasswordResetToken) error) *UserRepository_CreatePasswordResetToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CreateUser provides a mock function with given fields: ctx, user
 func (_m *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	ret := _m.Called(ctx, user)
@@ -70,6 +166,54 @@ func (_c *UserRepository_CreateUser_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// CreateUserWithIdentity provides a mock function with given fields: ctx, user, identity
+func (_m *UserRepository) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	ret := _m.Called(ctx, user, identity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUserWithIdentity")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, *models.UserIdentity) error); ok {
+		r0 = rf(ctx, user, identity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_CreateUserWithIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateUserWithIdentity'
+type UserRepository_CreateUserWithIdentity_Call struct {
+	*mock.Call
+}
+
+// CreateUserWithIdentity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+//   - identity *models.UserIdentity
+func (_e *UserRepository_Expecter) CreateUserWithIdentity(ctx interface{}, user interface{}, identity interface{}) *UserRepository_CreateUserWithIdentity_Call {
+	return &UserRepository_CreateUserWithIdentity_Call{Call: _e.mock.On("CreateUserWithIdentity", ctx, user, identity)}
+}
+
+func (_c *UserRepository_CreateUserWithIdentity_Call) Run(run func(ctx context.Context, user *models.User, identity *models.UserIdentity)) *UserRepository_CreateUserWithIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User), args[2].(*models.UserIdentity))
+	})
+	return _c
+}
+
+func (_c *UserRepository_CreateUserWithIdentity_Call) Return(_a0 error) *UserRepository_CreateUserWithIdentity_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_CreateUserWithIdentity_Call) RunAndReturn(run func(context.Context, *models.User, *models.UserIdentity) error) *UserRepository_CreateUserWithIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteUser provides a mock function with given fields: ctx, userId
 func (_m *UserRepository) DeleteUser(ctx context.Context, userId int) error {
 	ret := _m.Called(ctx, userId)
@@ -117,6 +261,55 @@ func (_c *UserRepository_DeleteUser_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// EnableMFA provides a mock function with given fields: ctx, userId, step, codeHashes
+func (_m *UserRepository) EnableMFA(ctx context.Context, userId int, step int64, codeHashes []string) error {
+	ret := _m.Called(ctx, userId, step, codeHashes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableMFA")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, []string) error); ok {
+		r0 = rf(ctx, userId, step, codeHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_EnableMFA_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnableMFA'
+type UserRepository_EnableMFA_Call struct {
+	*mock.Call
+}
+
+// EnableMFA is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - step int64
+//   - codeHashes []string
+func (_e *UserRepository_Expecter) EnableMFA(ctx interface{}, userId interface{}, step interface{}, codeHashes interface{}) *UserRepository_EnableMFA_Call {
+	return &UserRepository_EnableMFA_Call{Call: _e.mock.On("EnableMFA", ctx, userId, step, codeHashes)}
+}
+
+func (_c *UserRepository_EnableMFA_Call) Run(run func(ctx context.Context, userId int, step int64, codeHashes []string)) *UserRepository_EnableMFA_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int64), args[3].([]string))
+	})
+	return _c
+}
+
+func (_c *UserRepository_EnableMFA_Call) Return(_a0 error) *UserRepository_EnableMFA_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_EnableMFA_Call) RunAndReturn(run func(context.Context, int, int64, []string) error) *UserRepository_EnableMFA_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetUserByEmail provides a mock function with given fields: ctx, email
 func (_m *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	ret := _m.Called(ctx, email)
@@ -235,17 +428,202 @@ func (_c *UserRepository_GetUserById_Call) RunAndReturn(run func(context.Context
 	return _c
 }
 
-// UpdateUser provides a mock function with given fields: ctx, user
-func (_m *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	ret := _m.Called(ctx, user)
+// GetUserByIdentity provides a mock function with given fields: ctx, provider, subject
+func (_m *UserRepository) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	ret := _m.Called(ctx, provider, subject)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateUser")
+		panic("no return value specified for GetUserByIdentity")
+	}
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.User, error)); ok {
+		return rf(ctx, provider, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.User); ok {
+		r0 = rf(ctx, provider, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_GetUserByIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserByIdentity'
+type UserRepository_GetUserByIdentity_Call struct {
+	*mock.Call
+}
+
+// GetUserByIdentity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - provider string
+//   - subject string
+func (_e *UserRepository_Expecter) GetUserByIdentity(ctx interface{}, provider interface{}, subject interface{}) *UserRepository_GetUserByIdentity_Call {
+	return &UserRepository_GetUserByIdentity_Call{Call: _e.mock.On("GetUserByIdentity", ctx, provider, subject)}
+}
+
+func (_c *UserRepository_GetUserByIdentity_Call) Run(run func(ctx context.Context, provider string, subject string)) *UserRepository_GetUserByIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *UserRepository_GetUserByIdentity_Call) Return(_a0 *models.User, _a1 error) *UserRepository_GetUserByIdentity_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_GetUserByIdentity_Call) RunAndReturn(run func(context.Context, string, string) (*models.User, error)) *UserRepository_GetUserByIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUsersByIds provides a mock function with given fields: ctx, userIds
+func (_m *UserRepository) GetUsersByIds(ctx context.Context, userIds []int) ([]models.User, error) {
+	ret := _m.Called(ctx, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsersByIds")
+	}
+
+	var r0 []models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) ([]models.User, error)); ok {
+		return rf(ctx, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) []models.User); ok {
+		r0 = rf(ctx, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_GetUsersByIds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUsersByIds'
+type UserRepository_GetUsersByIds_Call struct {
+	*mock.Call
+}
+
+// GetUsersByIds is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userIds []int
+func (_e *UserRepository_Expecter) GetUsersByIds(ctx interface{}, userIds interface{}) *UserRepository_GetUsersByIds_Call {
+	return &UserRepository_GetUsersByIds_Call{Call: _e.mock.On("GetUsersByIds", ctx, userIds)}
+}
+
+func (_c *UserRepository_GetUsersByIds_Call) Run(run func(ctx context.Context, userIds []int)) *UserRepository_GetUsersByIds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *UserRepository_GetUsersByIds_Call) Return(_a0 []models.User, _a1 error) *UserRepository_GetUsersByIds_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_GetUsersByIds_Call) RunAndReturn(run func(context.Context, []int) ([]models.User, error)) *UserRepository_GetUsersByIds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListUsers provides a mock function with given fields: ctx, query
+func (_m *UserRepository) ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, int64, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []models.User
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserListQuery) ([]models.User, int64, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserListQuery) []models.User); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.UserListQuery) int64); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, models.UserListQuery) error); ok {
+		r2 = rf(ctx, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UserRepository_ListUsers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListUsers'
+type UserRepository_ListUsers_Call struct {
+	*mock.Call
+}
+
+// ListUsers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query models.UserListQuery
+func (_e *UserRepository_Expecter) ListUsers(ctx interface{}, query interface{}) *UserRepository_ListUsers_Call {
+	return &UserRepository_ListUsers_Call{Call: _e.mock.On("ListUsers", ctx, query)}
+}
+
+func (_c *UserRepository_ListUsers_Call) Run(run func(ctx context.Context, query models.UserListQuery)) *UserRepository_ListUsers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.UserListQuery))
+	})
+	return _c
+}
+
+func (_c *UserRepository_ListUsers_Call) Return(_a0 []models.User, _a1 int64, _a2 error) *UserRepository_ListUsers_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *UserRepository_ListUsers_Call) RunAndReturn(run func(context.Context, models.UserListQuery) ([]models.User, int64, error)) *UserRepository_ListUsers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkEmailVerified provides a mock function with given fields: ctx, userId, email, at
+func (_m *UserRepository) MarkEmailVerified(ctx context.Context, userId int, email string, at time.Time) error {
+	ret := _m.Called(ctx, userId, email, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkEmailVerified")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
-		r0 = rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Time) error); ok {
+		r0 = rf(ctx, userId, email, at)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -253,26 +631,550 @@ func (_m *UserRepository) UpdateUser(ctx context.Context, user *models.User) err
 	return r0
 }
 
-// UserRepository_UpdateUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateUser'
-type UserRepository_UpdateUser_Call struct {
+// UserRepository_MarkEmailVerified_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkEmailVerified'
+type UserRepository_MarkEmailVerified_Call struct {
 	*mock.Call
 }
 
-// UpdateUser is a helper method to define mock.On call
+// MarkEmailVerified is a helper method to define mock.On call
 //   - ctx context.Context
-//   - user *models.User
-func (_e *UserRepository_Expecter) UpdateUser(ctx interface{}, user interface{}) *UserRepository_UpdateUser_Call {
-	return &UserRepository_UpdateUser_Call{Call: _e.mock.On("UpdateUser", ctx, user)}
+//   - userId int
+//   - email string
+//   - at time.Time
+func (_e *UserRepository_Expecter) MarkEmailVerified(ctx interface{}, userId interface{}, email interface{}, at interface{}) *UserRepository_MarkEmailVerified_Call {
+	return &UserRepository_MarkEmailVerified_Call{Call: _e.mock.On("MarkEmailVerified", ctx, userId, email, at)}
 }
 
-func (_c *UserRepository_UpdateUser_Call) Run(run func(ctx context.Context, user *models.User)) *UserRepository_UpdateUser_Call {
+func (_c *UserRepository_MarkEmailVerified_Call) Run(run func(ctx context.Context, userId int, email string, at time.Time)) *UserRepository_MarkEmailVerified_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(*models.User))
+		run(args[0].(context.Context), args[1].(int), args[2].(string), args[3].(time.Time))
 	})
 	return _c
 }
 
-func (_c *UserRepository_UpdateUser_Call) Return(_a0 error) *UserRepository_UpdateUser_Call {
+func (_c *UserRepository_MarkEmailVerified_Call) Return(_a0 error) *UserRepository_MarkEmailVerified_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_MarkEmailVerified_Call) RunAndReturn(run func(context.Context, int, string, time.Time) error) *UserRepository_MarkEmailVerified_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordLoginFailure provides a mock function with given fields: ctx, userId, threshold, lockFor
+func (_m *UserRepository) RecordLoginFailure(ctx context.Context, userId int, threshold int, lockFor time.Duration) (bool, error) {
+	ret := _m.Called(ctx, userId, threshold, lockFor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordLoginFailure")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, time.Duration) (bool, error)); ok {
+		return rf(ctx, userId, threshold, lockFor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, time.Duration) bool); ok {
+		r0 = rf(ctx, userId, threshold, lockFor)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, time.Duration) error); ok {
+		r1 = rf(ctx, userId, threshold, lockFor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_RecordLoginFailure_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordLoginFailure'
+type UserRepository_RecordLoginFailure_Call struct {
+	*mock.Call
+}
+
+// RecordLoginFailure is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - threshold int
+//   - lockFor time.Duration
+func (_e *UserRepository_Expecter) RecordLoginFailure(ctx interface{}, userId interface{}, threshold interface{}, lockFor interface{}) *UserRepository_RecordLoginFailure_Call {
+	return &UserRepository_RecordLoginFailure_Call{Call: _e.mock.On("RecordLoginFailure", ctx, userId, threshold, lockFor)}
+}
+
+func (_c *UserRepository_RecordLoginFailure_Call) Run(run func(ctx context.Context, userId int, threshold int, lockFor time.Duration)) *UserRepository_RecordLoginFailure_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *UserRepository_RecordLoginFailure_Call) Return(_a0 bool, _a1 error) *UserRepository_RecordLoginFailure_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_RecordLoginFailure_Call) RunAndReturn(run func(context.Context, int, int, time.Duration) (bool, error)) *UserRepository_RecordLoginFailure_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetLoginFailures provides a mock function with given fields: ctx, userId
+func (_m *UserRepository) ResetLoginFailures(ctx context.Context, userId int) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetLoginFailures")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_ResetLoginFailures_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetLoginFailures'
+type UserRepository_ResetLoginFailures_Call struct {
+	*mock.Call
+}
+
+// ResetLoginFailures is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *UserRepository_Expecter) ResetLoginFailures(ctx interface{}, userId interface{}) *UserRepository_ResetLoginFailures_Call {
+	return &UserRepository_ResetLoginFailures_Call{Call: _e.mock.On("ResetLoginFailures", ctx, userId)}
+}
+
+func (_c *UserRepository_ResetLoginFailures_Call) Run(run func(ctx context.Context, userId int)) *UserRepository_ResetLoginFailures_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *UserRepository_ResetLoginFailures_Call) Return(_a0 error) *UserRepository_ResetLoginFailures_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_ResetLoginFailures_Call) RunAndReturn(run func(context.Context, int) error) *UserRepository_ResetLoginFailures_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetPassword provides a mock function with given fields: ctx, tokenHash, passwordHash, now
+func (_m *UserRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) (int, error) {
+	ret := _m.Called(ctx, tokenHash, passwordHash, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (int, error)); ok {
+		return rf(ctx, tokenHash, passwordHash, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) int); ok {
+		r0 = rf(ctx, tokenHash, passwordHash, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, tokenHash, passwordHash, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_ResetPassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetPassword'
+type UserRepository_ResetPassword_Call struct {
+	*mock.Call
+}
+
+// ResetPassword is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenHash string
+//   - passwordHash string
+//   - now time.Time
+func (_e *UserRepository_Expecter) ResetPassword(ctx interface{}, tokenHash interface{}, passwordHash interface{}, now interface{}) *UserRepository_ResetPassword_Call {
+	return &UserRepository_ResetPassword_Call{Call: _e.mock.On("ResetPassword", ctx, tokenHash, passwordHash, now)}
+}
+
+func (_c *UserRepository_ResetPassword_Call) Run(run func(ctx context.Context, tokenHash string, passwordHash string, now time.Time)) *UserRepository_ResetPassword_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(time.Time))
+	})
+	return _c
+}
+
+func (_c *UserRepository_ResetPassword_Call) Return(_a0 int, _a1 error) *UserRepository_ResetPassword_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_ResetPassword_Call) RunAndReturn(run func(context.Context, string, string, time.Time) (int, error)) *UserRepository_ResetPassword_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SearchUsers provides a mock function with given fields: ctx, search
+func (_m *UserRepository) SearchUsers(ctx context.Context, search models.UserSearch) ([]models.UserMatch, error) {
+	ret := _m.Called(ctx, search)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 []models.UserMatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserSearch) ([]models.UserMatch, error)); ok {
+		return rf(ctx, search)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserSearch) []models.UserMatch); ok {
+		r0 = rf(ctx, search)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.UserMatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.UserSearch) error); ok {
+		r1 = rf(ctx, search)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_SearchUsers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SearchUsers'
+type UserRepository_SearchUsers_Call struct {
+	*mock.Call
+}
+
+// SearchUsers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - search models.UserSearch
+func (_e *UserRepository_Expecter) SearchUsers(ctx interface{}, search interface{}) *UserRepository_SearchUsers_Call {
+	return &UserRepository_SearchUsers_Call{Call: _e.mock.On("SearchUsers", ctx, search)}
+}
+
+func (_c *UserRepository_SearchUsers_Call) Run(run func(ctx context.Context, search models.UserSearch)) *UserRepository_SearchUsers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.UserSearch))
+	})
+	return _c
+}
+
+func (_c *UserRepository_SearchUsers_Call) Return(_a0 []models.UserMatch, _a1 error) *UserRepository_SearchUsers_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_SearchUsers_Call) RunAndReturn(run func(context.Context, models.UserSearch) ([]models.UserMatch, error)) *UserRepository_SearchUsers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetAvatar provides a mock function with given fields: ctx, userId, key
+func (_m *UserRepository) SetAvatar(ctx context.Context, userId int, key *string) (*string, error) {
+	ret := _m.Called(ctx, userId, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAvatar")
+	}
+
+	var r0 *string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *string) (*string, error)); ok {
+		return rf(ctx, userId, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *string) *string); ok {
+		r0 = rf(ctx, userId, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *string) error); ok {
+		r1 = rf(ctx, userId, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_SetAvatar_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetAvatar'
+type UserRepository_SetAvatar_Call struct {
+	*mock.Call
+}
+
+// SetAvatar is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - key *string
+func (_e *UserRepository_Expecter) SetAvatar(ctx interface{}, userId interface{}, key interface{}) *UserRepository_SetAvatar_Call {
+	return &UserRepository_SetAvatar_Call{Call: _e.mock.On("SetAvatar", ctx, userId, key)}
+}
+
+func (_c *UserRepository_SetAvatar_Call) Run(run func(ctx context.Context, userId int, key *string)) *UserRepository_SetAvatar_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(*string))
+	})
+	return _c
+}
+
+func (_c *UserRepository_SetAvatar_Call) Return(_a0 *string, _a1 error) *UserRepository_SetAvatar_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_SetAvatar_Call) RunAndReturn(run func(context.Context, int, *string) (*string, error)) *UserRepository_SetAvatar_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetMFASecret provides a mock function with given fields: ctx, userId, secret
+func (_m *UserRepository) SetMFASecret(ctx context.Context, userId int, secret string) error {
+	ret := _m.Called(ctx, userId, secret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMFASecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) error); ok {
+		r0 = rf(ctx, userId, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_SetMFASecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetMFASecret'
+type UserRepository_SetMFASecret_Call struct {
+	*mock.Call
+}
+
+// SetMFASecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - secret string
+func (_e *UserRepository_Expecter) SetMFASecret(ctx interface{}, userId interface{}, secret interface{}) *UserRepository_SetMFASecret_Call {
+	return &UserRepository_SetMFASecret_Call{Call: _e.mock.On("SetMFASecret", ctx, userId, secret)}
+}
+
+func (_c *UserRepository_SetMFASecret_Call) Run(run func(ctx context.Context, userId int, secret string)) *UserRepository_SetMFASecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *UserRepository_SetMFASecret_Call) Return(_a0 error) *UserRepository_SetMFASecret_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_SetMFASecret_Call) RunAndReturn(run func(context.Context, int, string) error) *UserRepository_SetMFASecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetRole provides a mock function with given fields: ctx, userId, role
+func (_m *UserRepository) SetRole(ctx context.Context, userId int, role models.Role) error {
+	ret := _m.Called(ctx, userId, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRole")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, models.Role) error); ok {
+		r0 = rf(ctx, userId, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_SetRole_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetRole'
+type UserRepository_SetRole_Call struct {
+	*mock.Call
+}
+
+// SetRole is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - role models.Role
+func (_e *UserRepository_Expecter) SetRole(ctx interface{}, userId interface{}, role interface{}) *UserRepository_SetRole_Call {
+	return &UserRepository_SetRole_Call{Call: _e.mock.On("SetRole", ctx, userId, role)}
+}
+
+func (_c *UserRepository_SetRole_Call) Run(run func(ctx context.Context, userId int, role models.Role)) *UserRepository_SetRole_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(models.Role))
+	})
+	return _c
+}
+
+func (_c *UserRepository_SetRole_Call) Return(_a0 error) *UserRepository_SetRole_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_SetRole_Call) RunAndReturn(run func(context.Context, int, models.Role) error) *UserRepository_SetRole_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetSuspended provides a mock function with given fields: ctx, userId, at
+func (_m *UserRepository) SetSuspended(ctx context.Context, userId int, at *time.Time) error {
+	ret := _m.Called(ctx, userId, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSuspended")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *time.Time) error); ok {
+		r0 = rf(ctx, userId, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_SetSuspended_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetSuspended'
+type UserRepository_SetSuspended_Call struct {
+	*mock.Call
+}
+
+// SetSuspended is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - at *time.Time
+func (_e *UserRepository_Expecter) SetSuspended(ctx interface{}, userId interface{}, at interface{}) *UserRepository_SetSuspended_Call {
+	return &UserRepository_SetSuspended_Call{Call: _e.mock.On("SetSuspended", ctx, userId, at)}
+}
+
+func (_c *UserRepository_SetSuspended_Call) Run(run func(ctx context.Context, userId int, at *time.Time)) *UserRepository_SetSuspended_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(*time.Time))
+	})
+	return _c
+}
+
+func (_c *UserRepository_SetSuspended_Call) Return(_a0 error) *UserRepository_SetSuspended_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_SetSuspended_Call) RunAndReturn(run func(context.Context, int, *time.Time) error) *UserRepository_SetSuspended_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, user
+func (_m *UserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_UpdateProfile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateProfile'
+type UserRepository_UpdateProfile_Call struct {
+	*mock.Call
+}
+
+// UpdateProfile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *UserRepository_Expecter) UpdateProfile(ctx interface{}, user interface{}) *UserRepository_UpdateProfile_Call {
+	return &UserRepository_UpdateProfile_Call{Call: _e.mock.On("UpdateProfile", ctx, user)}
+}
+
+func (_c *UserRepository_UpdateProfile_Call) Run(run func(ctx context.Context, user *models.User)) *UserRepository_UpdateProfile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *UserRepository_UpdateProfile_Call) Return(_a0 error) *UserRepository_UpdateProfile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserRepository_UpdateProfile_Call) RunAndReturn(run func(context.Context, *models.User) error) *UserRepository_UpdateProfile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateUser provides a mock function with given fields: ctx, user
+func (_m *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserRepository_UpdateUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateUser'
+type UserRepository_UpdateUser_Call struct {
+	*mock.Call
+}
+
+// UpdateUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *UserRepository_Expecter) UpdateUser(ctx interface{}, user interface{}) *UserRepository_UpdateUser_Call {
+	return &UserRepository_UpdateUser_Call{Call: _e.mock.On("UpdateUser", ctx, user)}
+}
+
+func (_c *UserRepository_UpdateUser_Call) Run(run func(ctx context.Context, user *models.User)) *UserRepository_UpdateUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *UserRepository_UpdateUser_Call) Return(_a0 error) *UserRepository_UpdateUser_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
@@ -282,6 +1184,123 @@ func (_c *UserRepository_UpdateUser_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// UseRecoveryCode provides a mock function with given fields: ctx, userId, codeHash, now
+func (_m *UserRepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string, now time.Time) (bool, error) {
+	ret := _m.Called(ctx, userId, codeHash, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRecoveryCode")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Time) (bool, error)); ok {
+		return rf(ctx, userId, codeHash, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Time) bool); ok {
+		r0 = rf(ctx, userId, codeHash, now)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, time.Time) error); ok {
+		r1 = rf(ctx, userId, codeHash, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_UseRecoveryCode_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UseRecoveryCode'
+type UserRepository_UseRecoveryCode_Call struct {
+	*mock.Call
+}
+
+// UseRecoveryCode is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - codeHash string
+//   - now time.Time
+func (_e *UserRepository_Expecter) UseRecoveryCode(ctx interface{}, userId interface{}, codeHash interface{}, now interface{}) *UserRepository_UseRecoveryCode_Call {
+	return &UserRepository_UseRecoveryCode_Call{Call: _e.mock.On("UseRecoveryCode", ctx, userId, codeHash, now)}
+}
+
+func (_c *UserRepository_UseRecoveryCode_Call) Run(run func(ctx context.Context, userId int, codeHash string, now time.Time)) *UserRepository_UseRecoveryCode_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(string), args[3].(time.Time))
+	})
+	return _c
+}
+
+func (_c *UserRepository_UseRecoveryCode_Call) Return(_a0 bool, _a1 error) *UserRepository_UseRecoveryCode_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_UseRecoveryCode_Call) RunAndReturn(run func(context.Context, int, string, time.Time) (bool, error)) *UserRepository_UseRecoveryCode_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UseTOTPStep provides a mock function with given fields: ctx, userId, step
+func (_m *UserRepository) UseTOTPStep(ctx context.Context, userId int, step int64) (bool, error) {
+	ret := _m.Called(ctx, userId, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPStep")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) (bool, error)); ok {
+		return rf(ctx, userId, step)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) bool); ok {
+		r0 = rf(ctx, userId, step)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64) error); ok {
+		r1 = rf(ctx, userId, step)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserRepository_UseTOTPStep_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UseTOTPStep'
+type UserRepository_UseTOTPStep_Call struct {
+	*mock.Call
+}
+
+// UseTOTPStep is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - step int64
+func (_e *UserRepository_Expecter) UseTOTPStep(ctx interface{}, userId interface{}, step interface{}) *UserRepository_UseTOTPStep_Call {
+	return &UserRepository_UseTOTPStep_Call{Call: _e.mock.On("UseTOTPStep", ctx, userId, step)}
+}
+
+func (_c *UserRepository_UseTOTPStep_Call) Run(run func(ctx context.Context, userId int, step int64)) *UserRepository_UseTOTPStep_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *UserRepository_UseTOTPStep_Call) Return(_a0 bool, _a1 error) *UserRepository_UseTOTPStep_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserRepository_UseTOTPStep_Call) RunAndReturn(run func(context.Context, int, int64) (bool, error)) *UserRepository_UseTOTPStep_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewUserRepository creates a new instance of UserRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserRepository(t interface {
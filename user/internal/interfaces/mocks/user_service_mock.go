@@ -24,7 +24,7 @@ func (_m *UserService) EXPECT() *UserService_Expecter {
 }
 
 // AuthenticateUser provides a mock function with given fields: ctx, email, password
-func (_m *UserService) AuthenticateUser(ctx context.Context, email string, password string) (*models.User, error) {
+func (_m *UserService) AuthenticateUser(ctx context.Context, email string, password string) (*models.User, string, error) {
 	ret := _m.Called(ctx, email, password)
 
 	if len(ret) == 0 {
@@ -32,8 +32,9 @@ func (_m *UserService) AuthenticateUser(ctx context.Context, email string, passw
 	}
 
 	var r0 *models.User
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.User, error)); ok {
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.User, string, error)); ok {
 		return rf(ctx, email, password)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.User); ok {
@@ -44,13 +45,19 @@ func (_m *UserService) AuthenticateUser(ctx context.Context, email string, passw
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) string); ok {
 		r1 = rf(ctx, email, password)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
+		r2 = rf(ctx, email, password)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // UserService_AuthenticateUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AuthenticateUser'
@@ -73,12 +80,72 @@ func (_c *UserService_AuthenticateUser_Call) Run(run func(ctx context.Context, e
 	return _c
 }
 
-func (_c *UserService_AuthenticateUser_Call) Return(_a0 *models.User, _a1 error) *UserService_AuthenticateUser_Call {
+func (_c *UserService_AuthenticateUser_Call) Return(_a0 *models.User, _a1 string, _a2 error) *UserService_AuthenticateUser_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *UserService_AuthenticateUser_Call) RunAndReturn(run func(context.Context, string, string) (*models.User, string, error)) *UserService_AuthenticateUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ConfirmMFA provides a mock function with given fields: ctx, userId, code
+func (_m *UserService) ConfirmMFA(ctx context.Context, userId int, code string) ([]string, error) {
+	ret := _m.Called(ctx, userId, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmMFA")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) ([]string, error)); ok {
+		return rf(ctx, userId, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) []string); ok {
+		r0 = rf(ctx, userId, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string) error); ok {
+		r1 = rf(ctx, userId, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserService_ConfirmMFA_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConfirmMFA'
+type UserService_ConfirmMFA_Call struct {
+	*mock.Call
+}
+
+// ConfirmMFA is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - code string
+func (_e *UserService_Expecter) ConfirmMFA(ctx interface{}, userId interface{}, code interface{}) *UserService_ConfirmMFA_Call {
+	return &UserService_ConfirmMFA_Call{Call: _e.mock.On("ConfirmMFA", ctx, userId, code)}
+}
+
+func (_c *UserService_ConfirmMFA_Call) Run(run func(ctx context.Context, userId int, code string)) *UserService_ConfirmMFA_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_ConfirmMFA_Call) Return(_a0 []string, _a1 error) *UserService_ConfirmMFA_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *UserService_AuthenticateUser_Call) RunAndReturn(run func(context.Context, string, string) (*models.User, error)) *UserService_AuthenticateUser_Call {
+func (_c *UserService_ConfirmMFA_Call) RunAndReturn(run func(context.Context, int, string) ([]string, error)) *UserService_ConfirmMFA_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -191,6 +258,65 @@ func (_c *UserService_DeleteUser_Call) RunAndReturn(run func(context.Context, in
 	return _c
 }
 
+// EnrollMFA provides a mock function with given fields: ctx, userId
+func (_m *UserService) EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollMFA")
+	}
+
+	var r0 *models.MFAEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.MFAEnrollment, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.MFAEnrollment); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.MFAEnrollment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserService_EnrollMFA_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnrollMFA'
+type UserService_EnrollMFA_Call struct {
+	*mock.Call
+}
+
+// EnrollMFA is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *UserService_Expecter) EnrollMFA(ctx interface{}, userId interface{}) *UserService_EnrollMFA_Call {
+	return &UserService_EnrollMFA_Call{Call: _e.mock.On("EnrollMFA", ctx, userId)}
+}
+
+func (_c *UserService_EnrollMFA_Call) Run(run func(ctx context.Context, userId int)) *UserService_EnrollMFA_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *UserService_EnrollMFA_Call) Return(_a0 *models.MFAEnrollment, _a1 error) *UserService_EnrollMFA_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserService_EnrollMFA_Call) RunAndReturn(run func(context.Context, int) (*models.MFAEnrollment, error)) *UserService_EnrollMFA_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetUserById provides a mock function with given fields: ctx, userId
 func (_m *UserService) GetUserById(ctx context.Context, userId int) (*models.User, error) {
 	ret := _m.Called(ctx, userId)
@@ -250,6 +376,407 @@ func (_c *UserService_GetUserById_Call) RunAndReturn(run func(context.Context, i
 	return _c
 }
 
+// ListUsers provides a mock function with given fields: ctx, query
+func (_m *UserService) ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, int64, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []models.User
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserListQuery) ([]models.User, int64, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserListQuery) []models.User); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.UserListQuery) int64); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, models.UserListQuery) error); ok {
+		r2 = rf(ctx, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UserService_ListUsers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListUsers'
+type UserService_ListUsers_Call struct {
+	*mock.Call
+}
+
+// ListUsers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query models.UserListQuery
+func (_e *UserService_Expecter) ListUsers(ctx interface{}, query interface{}) *UserService_ListUsers_Call {
+	return &UserService_ListUsers_Call{Call: _e.mock.On("ListUsers", ctx, query)}
+}
+
+func (_c *UserService_ListUsers_Call) Run(run func(ctx context.Context, query models.UserListQuery)) *UserService_ListUsers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.UserListQuery))
+	})
+	return _c
+}
+
+func (_c *UserService_ListUsers_Call) Return(_a0 []models.User, _a1 int64, _a2 error) *UserService_ListUsers_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *UserService_ListUsers_Call) RunAndReturn(run func(context.Context, models.UserListQuery) ([]models.User, int64, error)) *UserService_ListUsers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RequestPasswordReset provides a mock function with given fields: ctx, email
+func (_m *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestPasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_RequestPasswordReset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RequestPasswordReset'
+type UserService_RequestPasswordReset_Call struct {
+	*mock.Call
+}
+
+// RequestPasswordReset is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+func (_e *UserService_Expecter) RequestPasswordReset(ctx interface{}, email interface{}) *UserService_RequestPasswordReset_Call {
+	return &UserService_RequestPasswordReset_Call{Call: _e.mock.On("RequestPasswordReset", ctx, email)}
+}
+
+func (_c *UserService_RequestPasswordReset_Call) Run(run func(ctx context.Context, email string)) *UserService_RequestPasswordReset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_RequestPasswordReset_Call) Return(_a0 error) *UserService_RequestPasswordReset_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_RequestPasswordReset_Call) RunAndReturn(run func(context.Context, string) error) *UserService_RequestPasswordReset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResendVerification provides a mock function with given fields: ctx, email
+func (_m *UserService) ResendVerification(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_ResendVerification_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResendVerification'
+type UserService_ResendVerification_Call struct {
+	*mock.Call
+}
+
+// ResendVerification is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+func (_e *UserService_Expecter) ResendVerification(ctx interface{}, email interface{}) *UserService_ResendVerification_Call {
+	return &UserService_ResendVerification_Call{Call: _e.mock.On("ResendVerification", ctx, email)}
+}
+
+func (_c *UserService_ResendVerification_Call) Run(run func(ctx context.Context, email string)) *UserService_ResendVerification_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_ResendVerification_Call) Return(_a0 error) *UserService_ResendVerification_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_ResendVerification_Call) RunAndReturn(run func(context.Context, string) error) *UserService_ResendVerification_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password
+func (_m *UserService) ResetPassword(ctx context.Context, token string, password string) error {
+	ret := _m.Called(ctx, token, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_ResetPassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetPassword'
+type UserService_ResetPassword_Call struct {
+	*mock.Call
+}
+
+// ResetPassword is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+//   - password string
+func (_e *UserService_Expecter) ResetPassword(ctx interface{}, token interface{}, password interface{}) *UserService_ResetPassword_Call {
+	return &UserService_ResetPassword_Call{Call: _e.mock.On("ResetPassword", ctx, token, password)}
+}
+
+func (_c *UserService_ResetPassword_Call) Run(run func(ctx context.Context, token string, password string)) *UserService_ResetPassword_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_ResetPassword_Call) Return(_a0 error) *UserService_ResetPassword_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_ResetPassword_Call) RunAndReturn(run func(context.Context, string, string) error) *UserService_ResetPassword_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetUserRole provides a mock function with given fields: ctx, actor, userId, role
+func (_m *UserService) SetUserRole(ctx context.Context, actor models.Principal, userId int, role models.Role) error {
+	ret := _m.Called(ctx, actor, userId, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserRole")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Principal, int, models.Role) error); ok {
+		r0 = rf(ctx, actor, userId, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_SetUserRole_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetUserRole'
+type UserService_SetUserRole_Call struct {
+	*mock.Call
+}
+
+// SetUserRole is a helper method to define mock.On call
+//   - ctx context.Context
+//   - actor models.Principal
+//   - userId int
+//   - role models.Role
+func (_e *UserService_Expecter) SetUserRole(ctx interface{}, actor interface{}, userId interface{}, role interface{}) *UserService_SetUserRole_Call {
+	return &UserService_SetUserRole_Call{Call: _e.mock.On("SetUserRole", ctx, actor, userId, role)}
+}
+
+func (_c *UserService_SetUserRole_Call) Run(run func(ctx context.Context, actor models.Principal, userId int, role models.Role)) *UserService_SetUserRole_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Principal), args[2].(int), args[3].(models.Role))
+	})
+	return _c
+}
+
+func (_c *UserService_SetUserRole_Call) Return(_a0 error) *UserService_SetUserRole_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_SetUserRole_Call) RunAndReturn(run func(context.Context, models.Principal, int, models.Role) error) *UserService_SetUserRole_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SuspendUser provides a mock function with given fields: ctx, actor, userId
+func (_m *UserService) SuspendUser(ctx context.Context, actor models.Principal, userId int) error {
+	ret := _m.Called(ctx, actor, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SuspendUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Principal, int) error); ok {
+		r0 = rf(ctx, actor, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_SuspendUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SuspendUser'
+type UserService_SuspendUser_Call struct {
+	*mock.Call
+}
+
+// SuspendUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - actor models.Principal
+//   - userId int
+func (_e *UserService_Expecter) SuspendUser(ctx interface{}, actor interface{}, userId interface{}) *UserService_SuspendUser_Call {
+	return &UserService_SuspendUser_Call{Call: _e.mock.On("SuspendUser", ctx, actor, userId)}
+}
+
+func (_c *UserService_SuspendUser_Call) Run(run func(ctx context.Context, actor models.Principal, userId int)) *UserService_SuspendUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Principal), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *UserService_SuspendUser_Call) Return(_a0 error) *UserService_SuspendUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_SuspendUser_Call) RunAndReturn(run func(context.Context, models.Principal, int) error) *UserService_SuspendUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UnlockUser provides a mock function with given fields: ctx, actor, userId
+func (_m *UserService) UnlockUser(ctx context.Context, actor models.Principal, userId int) error {
+	ret := _m.Called(ctx, actor, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnlockUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Principal, int) error); ok {
+		r0 = rf(ctx, actor, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_UnlockUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnlockUser'
+type UserService_UnlockUser_Call struct {
+	*mock.Call
+}
+
+// UnlockUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - actor models.Principal
+//   - userId int
+func (_e *UserService_Expecter) UnlockUser(ctx interface{}, actor interface{}, userId interface{}) *UserService_UnlockUser_Call {
+	return &UserService_UnlockUser_Call{Call: _e.mock.On("UnlockUser", ctx, actor, userId)}
+}
+
+func (_c *UserService_UnlockUser_Call) Run(run func(ctx context.Context, actor models.Principal, userId int)) *UserService_UnlockUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Principal), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *UserService_UnlockUser_Call) Return(_a0 error) *UserService_UnlockUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_UnlockUser_Call) RunAndReturn(run func(context.Context, models.Principal, int) error) *UserService_UnlockUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UnsuspendUser provides a mock function with given fields: ctx, actor, userId
+func (_m *UserService) UnsuspendUser(ctx context.Context, actor models.Principal, userId int) error {
+	ret := _m.Called(ctx, actor, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnsuspendUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Principal, int) error); ok {
+		r0 = rf(ctx, actor, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_UnsuspendUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnsuspendUser'
+type UserService_UnsuspendUser_Call struct {
+	*mock.Call
+}
+
+// UnsuspendUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - actor models.Principal
+//   - userId int
+func (_e *UserService_Expecter) UnsuspendUser(ctx interface{}, actor interface{}, userId interface{}) *UserService_UnsuspendUser_Call {
+	return &UserService_UnsuspendUser_Call{Call: _e.mock.On("UnsuspendUser", ctx, actor, userId)}
+}
+
+func (_c *UserService_UnsuspendUser_Call) Run(run func(ctx context.Context, actor models.Principal, userId int)) *UserService_UnsuspendUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Principal), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *UserService_UnsuspendUser_Call) Return(_a0 error) *UserService_UnsuspendUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_UnsuspendUser_Call) RunAndReturn(run func(context.Context, models.Principal, int) error) *UserService_UnsuspendUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateUser provides a mock function with given fields: ctx, user
 func (_m *UserService) UpdateUser(ctx context.Context, user *models.User) error {
 	ret := _m.Called(ctx, user)
@@ -297,6 +824,179 @@ func (_c *UserService_UpdateUser_Call) RunAndReturn(run func(context.Context, *m
 	return _c
 }
 
+// ValidateAccessToken provides a mock function with given fields: ctx, token
+func (_m *UserService) ValidateAccessToken(ctx context.Context, token string) (*models.Principal, error) {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateAccessToken")
+	}
+
+	var r0 *models.Principal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Principal, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Principal); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Principal)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserService_ValidateAccessToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidateAccessToken'
+type UserService_ValidateAccessToken_Call struct {
+	*mock.Call
+}
+
+// ValidateAccessToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *UserService_Expecter) ValidateAccessToken(ctx interface{}, token interface{}) *UserService_ValidateAccessToken_Call {
+	return &UserService_ValidateAccessToken_Call{Call: _e.mock.On("ValidateAccessToken", ctx, token)}
+}
+
+func (_c *UserService_ValidateAccessToken_Call) Run(run func(ctx context.Context, token string)) *UserService_ValidateAccessToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_ValidateAccessToken_Call) Return(_a0 *models.Principal, _a1 error) *UserService_ValidateAccessToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *UserService_ValidateAccessToken_Call) RunAndReturn(run func(context.Context, string) (*models.Principal, error)) *UserService_ValidateAccessToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *UserService) VerifyEmail(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserService_VerifyEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'VerifyEmail'
+type UserService_VerifyEmail_Call struct {
+	*mock.Call
+}
+
+// VerifyEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *UserService_Expecter) VerifyEmail(ctx interface{}, token interface{}) *UserService_VerifyEmail_Call {
+	return &UserService_VerifyEmail_Call{Call: _e.mock.On("VerifyEmail", ctx, token)}
+}
+
+func (_c *UserService_VerifyEmail_Call) Run(run func(ctx context.Context, token string)) *UserService_VerifyEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_VerifyEmail_Call) Return(_a0 error) *UserService_VerifyEmail_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *UserService_VerifyEmail_Call) RunAndReturn(run func(context.Context, string) error) *UserService_VerifyEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// VerifyMFA provides a mock function with given fields: ctx, mfaToken, code
+func (_m *UserService) VerifyMFA(ctx context.Context, mfaToken string, code string) (*models.User, string, error) {
+	ret := _m.Called(ctx, mfaToken, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyMFA")
+	}
+
+	var r0 *models.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.User, string, error)); ok {
+		return rf(ctx, mfaToken, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.User); ok {
+		r0 = rf(ctx, mfaToken, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) string); ok {
+		r1 = rf(ctx, mfaToken, code)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
+		r2 = rf(ctx, mfaToken, code)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UserService_VerifyMFA_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'VerifyMFA'
+type UserService_VerifyMFA_Call struct {
+	*mock.Call
+}
+
+// VerifyMFA is a helper method to define mock.On call
+//   - ctx context.Context
+//   - mfaToken string
+//   - code string
+func (_e *UserService_Expecter) VerifyMFA(ctx interface{}, mfaToken interface{}, code interface{}) *UserService_VerifyMFA_Call {
+	return &UserService_VerifyMFA_Call{Call: _e.mock.On("VerifyMFA", ctx, mfaToken, code)}
+}
+
+func (_c *UserService_VerifyMFA_Call) Run(run func(ctx context.Context, mfaToken string, code string)) *UserService_VerifyMFA_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *UserService_VerifyMFA_Call) Return(_a0 *models.User, _a1 string, _a2 error) *UserService_VerifyMFA_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *UserService_VerifyMFA_Call) RunAndReturn(run func(context.Context, string, string) (*models.User, string, error)) *UserService_VerifyMFA_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewUserService creates a new instance of UserService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserService(t interface {
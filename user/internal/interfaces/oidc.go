@@ -0,0 +1,20 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+)
+
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*models.ExternalIdentity, error)
+}
+
+type OIDCStateStore interface {
+	Save(ctx context.Context, key string, state models.OIDCState, ttl time.Duration) error
+	Take(ctx context.Context, key string) (*models.OIDCState, error)
+}
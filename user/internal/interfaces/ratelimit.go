@@ -0,0 +1,24 @@
+//go:generate mockery
+
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+)
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy ratelimit.Policy) (ratelimit.Result, error)
+}
+
+type LoginBackoff interface {
+	Check(ctx context.Context, subject string) (time.Duration, error)
+	Failure(ctx context.Context, subject string) (time.Duration, error)
+	Reset(ctx context.Context, subject string) error
+}
+
+type Cooldown interface {
+	Start(ctx context.Context, subject string) (bool, error)
+}
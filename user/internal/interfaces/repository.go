@@ -4,6 +4,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/dmitriysta/messenger/user/internal/models"
 )
@@ -12,6 +13,25 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserById(ctx context.Context, userId int) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUsersByIds(ctx context.Context, userIds []int) ([]models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, userId int) error
+	RecordLoginFailure(ctx context.Context, userId, threshold int, lockFor time.Duration) (bool, error)
+	ResetLoginFailures(ctx context.Context, userId int) error
+	MarkEmailVerified(ctx context.Context, userId int, email string, at time.Time) error
+	CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int, error)
+	SetMFASecret(ctx context.Context, userId int, secret string) error
+	EnableMFA(ctx context.Context, userId int, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userId int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string, now time.Time) (bool, error)
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error
+	ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, int64, error)
+	SetSuspended(ctx context.Context, userId int, at *time.Time) error
+	SetRole(ctx context.Context, userId int, role models.Role) error
+	UpdateProfile(ctx context.Context, user *models.User) error
+	SetAvatar(ctx context.Context, userId int, key *string) (*string, error)
+	SearchUsers(ctx context.Context, search models.UserSearch) ([]models.UserMatch, error)
 }
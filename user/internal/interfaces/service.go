@@ -4,6 +4,7 @@ package interfaces
 
 import (
 	"context"
+	"io"
 
 	"github.com/dmitriysta/messenger/user/internal/models"
 )
@@ -14,4 +15,31 @@ type UserService interface {
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, userId int) error
 	AuthenticateUser(ctx context.Context, email, password string) (*models.User, string, error)
+	UnlockUser(ctx context.Context, actor models.Principal, userId int) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	ValidateAccessToken(ctx context.Context, token string) (*models.Principal, error)
+	EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, userId int, code string) ([]string, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string) (*models.User, string, error)
+	ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, int64, error)
+	SuspendUser(ctx context.Context, actor models.Principal, userId int) error
+	UnsuspendUser(ctx context.Context, actor models.Principal, userId int) error
+	SetUserRole(ctx context.Context, actor models.Principal, userId int, role models.Role) error
+}
+
+type OIDCService interface {
+	StartLogin(ctx context.Context, provider string) (string, string, error)
+	CompleteLogin(ctx context.Context, provider, state, binding, code string) (*models.User, string, error)
+}
+
+type ProfileService interface {
+	GetProfile(ctx context.Context, userId int) (*models.Profile, error)
+	GetProfiles(ctx context.Context, userIds []int) (*models.ProfileBatch, error)
+	UpdateProfile(ctx context.Context, userId int, patch *models.ProfilePatch) (*models.Profile, error)
+	SetAvatar(ctx context.Context, userId int, image io.Reader) (*models.Profile, error)
+	DeleteAvatar(ctx context.Context, userId int) (*models.Profile, error)
+	SearchUsers(ctx context.Context, query, cursor string, limit int) (*models.ProfilePage, error)
 }
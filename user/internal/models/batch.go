@@ -0,0 +1,13 @@
+package models
+
+import "errors"
+
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// ProfileBatch answers a batch lookup. Users are in the order their ids
+// were asked for; Missing holds the ids with no public profile, whether
+// the user doesn't exist or is suspended.
+type ProfileBatch struct {
+	Users   []Profile `json:"users"`
+	Missing []int     `json:"missing"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownProvider          = errors.New("unknown identity provider")
+	ErrExternalEmailNotVerified = errors.New("identity provider did not verify the email")
+	ErrIdentityConflict         = errors.New("email belongs to an unverified account")
+)
+
+// UserIdentity links a user to an account at an external identity
+// provider.
+type UserIdentity struct {
+	Id        int       `gorm:"primaryKey;autoIncrement"`
+	UserId    int       `gorm:"not null"`
+	Provider  string    `gorm:"type:varchar(64)"`
+	Subject   string    `gorm:"type:varchar(255)"`
+	Email     string    `gorm:"type:varchar(255)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (i *UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+// ExternalIdentity is who an identity provider says signed in.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCState is what a login remembers between sending the user to the
+// provider and the provider sending them back.
+type OIDCState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// BindingHash is the hash of the binding handed to the browser that
+	// started the login.
+	BindingHash string `json:"bindingHash"`
+}
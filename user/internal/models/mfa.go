@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrMFARequired       = errors.New("second factor required")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not being enrolled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+)
+
+// MFARequiredError is returned by a login with the right password to an
+// account with two-factor authentication. Token stands in for the access
+// token until the second factor is checked. It matches ErrMFARequired.
+type MFARequiredError struct {
+	Token string
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+// MFAEnrollment is what a user needs to add the account to an
+// authenticator app.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFARecoveryCode is a one-time replacement for a TOTP code. Only the
+// SHA-256 of the code is stored.
+type MFARecoveryCode struct {
+	Id        int    `gorm:"primaryKey;autoIncrement"`
+	UserId    int    `gorm:"not null"`
+	CodeHash  string `gorm:"type:char(64)"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (c *MFARecoveryCode) TableName() string {
+	return "mfa_recovery_code"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// OutboxEvent is a domain event waiting in the outbox table to be
+// published by the outbox relay. It is written in the same transaction as
+// the change it describes, so the event exists if and only if the change
+// was committed.
+type OutboxEvent struct {
+	Id        int64           `gorm:"primaryKey"`
+	Topic     string          `gorm:"type:varchar(64);not null"`
+	Key       string          `gorm:"type:varchar(64);not null"`
+	Payload   json.RawMessage `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time       `gorm:"autoCreateTime"`
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "user_outbox"
+}
+
+func NewOutboxEvent(topic string, key int, payload interface{}) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		Topic:   topic,
+		Key:     strconv.Itoa(key),
+		Payload: data,
+	}, nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken is an emailed, single-use permission to set a new
+// password. Only the SHA-256 of the token is stored.
+type PasswordResetToken struct {
+	Id        int       `gorm:"primaryKey;autoIncrement"`
+	UserId    int       `gorm:"not null"`
+	TokenHash string    `gorm:"type:char(64);unique"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (t *PasswordResetToken) TableName() string {
+	return "password_reset_token"
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// Timezones are validated against the embedded database so they
+	// don't depend on the host having one.
+	_ "time/tzdata"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxPronounsLength    = 32
+)
+
+var (
+	ErrInvalidProfile = errors.New("invalid profile")
+	ErrInvalidImage   = errors.New("not a supported image")
+	ErrImageTooLarge  = errors.New("image is too large")
+)
+
+// Profile is the public view of a user. It must never carry the email,
+// the password hash or anything else only the user should see.
+type Profile struct {
+	Id          int    `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName,omitempty"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	Timezone    string `json:"timezone,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	Pronouns    string `json:"pronouns,omitempty"`
+	Searchable  bool   `json:"searchable"`
+}
+
+// ProfilePatch holds the fields of a JSON Merge Patch against a profile. A
+// nil field was not present in the patch and stays unchanged; an empty one
+// clears the field.
+type ProfilePatch struct {
+	DisplayName *string
+	Bio         *string
+	Timezone    *string
+	Locale      *string
+	Pronouns    *string
+	Searchable  *bool
+}
+
+// Validate checks the supplied fields and normalises them: text is
+// trimmed and the locale put into its canonical BCP 47 form.
+func (p *ProfilePatch) Validate() error {
+	for _, field := range []struct {
+		name  string
+		value *string
+		max   int
+	}{
+		{"displayName", p.DisplayName, maxDisplayNameLength},
+		{"bio", p.Bio, maxBioLength},
+		{"pronouns", p.Pronouns, maxPronounsLength},
+	} {
+		if field.value == nil {
+			continue
+		}
+
+		*field.value = strings.TrimSpace(*field.value)
+		if utf8.RuneCountInString(*field.value) > field.max {
+			return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidProfile, field.name, field.max)
+		}
+
+		for _, r := range *field.value {
+			if unicode.IsControl(r) && !(field.name == "bio" && r == '\n') {
+				return fmt.Errorf("%w: %s contains control characters", ErrInvalidProfile, field.name)
+			}
+		}
+	}
+
+	if p.Timezone != nil && *p.Timezone != "" {
+		if *p.Timezone == "Local" {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidProfile, *p.Timezone)
+		}
+
+		if _, err := time.LoadLocation(*p.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidProfile, *p.Timezone)
+		}
+	}
+
+	if p.Locale != nil && *p.Locale != "" {
+		tag, err := language.Parse(*p.Locale)
+		if err != nil {
+			return fmt.Errorf("%w: invalid locale %q", ErrInvalidProfile, *p.Locale)
+		}
+
+		*p.Locale = tag.String()
+	}
+
+	return nil
+}
+
+// Apply copies the supplied fields onto u and reports whether anything
+// changed.
+func (p *ProfilePatch) Apply(u *User) bool {
+	changed := false
+
+	for _, field := range []struct {
+		patch *string
+		user  *string
+	}{
+		{p.DisplayName, &u.DisplayName},
+		{p.Bio, &u.Bio},
+		{p.Timezone, &u.Timezone},
+		{p.Locale, &u.Locale},
+		{p.Pronouns, &u.Pronouns},
+	} {
+		if field.patch != nil && *field.patch != *field.user {
+			*field.user = *field.patch
+			changed = true
+		}
+	}
+
+	if p.Searchable != nil && *p.Searchable != u.Searchable {
+		u.Searchable = *p.Searchable
+		changed = true
+	}
+
+	return changed
+}
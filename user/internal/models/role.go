@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidRole   = errors.New("invalid role")
+	ErrForbidden     = errors.New("not allowed")
+	ErrUserSuspended = errors.New("user is suspended")
+)
+
+// Role decides what a user may do beyond managing their own account.
+// Moderators can list and suspend ordinary users; admins can do anything.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func ParseRole(s string) (Role, error) {
+	switch role := Role(s); role {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return role, nil
+	default:
+		return "", ErrInvalidRole
+	}
+}
+
+// Principal is who an access token was issued to.
+type Principal struct {
+	UserId int
+	Role   Role
+}
+
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// UserListQuery filters and pages the admin listing of users. Nil filters
+// match everything.
+type UserListQuery struct {
+	Role      *Role
+	Suspended *bool
+	Offset    int
+	Limit     int
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidSearch = errors.New("invalid search")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// UserSearch asks for users whose name or display name starts with or
+// resembles Term. Results come best match first: prefix matches, then by
+// trigram similarity, then by id.
+type UserSearch struct {
+	Term  string
+	After *SearchCursor
+	Limit int
+}
+
+// UserMatch is a user found by a search and where it ranks.
+type UserMatch struct {
+	User
+	Prefix bool
+	Score  float64
+}
+
+// SearchCursor is the rank of the last match on a page; the next page
+// starts after it.
+type SearchCursor struct {
+	Prefix bool    `json:"p"`
+	Score  float64 `json:"s"`
+	Id     int     `json:"i"`
+}
+
+func (m *UserMatch) Cursor() *SearchCursor {
+	return &SearchCursor{Prefix: m.Prefix, Score: m.Score, Id: m.Id}
+}
+
+// Encode returns the cursor as an opaque string for clients.
+func (c *SearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeSearchCursor(s string) (*SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor SearchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// ProfilePage is one page of search results. NextCursor is empty on the
+// last page.
+type ProfilePage struct {
+	Users      []Profile `json:"users"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
@@ -10,16 +10,98 @@ const (
 	ErrInvalidName     = "invalid name"
 	ErrInvalidEmail    = "invalid email"
 	ErrInvalidPassword = "invalid password"
+
+	passwordCost = 14
+)
+
+var (
+	ErrVersionConflict = errors.New("user was modified concurrently")
+	ErrLoginThrottled  = errors.New("too many failed login attempts")
+	ErrUserNotFound    = errors.New("user not found")
+	// ErrInvalidCredentials is the only error a login reports for an
+	// unknown email, a wrong password or a locked account, so the response
+	// doesn't tell them apart.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrEmailNotVerified   = errors.New("email is not verified")
 )
 
+// LoginThrottledError is returned instead of checking the password while
+// the email is backing off after failed logins. It matches
+// ErrLoginThrottled.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrLoginThrottled.Error()
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
+
 type User struct {
-	Id        int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string    `json:"name" gorm:"type:varchar(255)"`
-	Email     string    `json:"email" gorm:"type:varchar(255);unique"`
-	Password  string    `json:"password" gorm:"type:varchar(255)"`
-	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
-	DeletedAt time.Time `json:"-" gorm:"autoDeleteTime"`
+	Id       int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name     string `json:"name" gorm:"column:username;type:varchar(255)"`
+	Email    string `json:"email" gorm:"type:varchar(255);unique"`
+	Password string `json:"-" gorm:"type:varchar(255)"`
+	Version  int    `json:"version" gorm:"not null;default:1"`
+	// FailedLogins counts failed logins since the last successful one or
+	// the last lockout.
+	FailedLogins int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil  *time.Time `json:"-"`
+	// EmailVerifiedAt is nil until the owner of Email confirms it.
+	EmailVerifiedAt *time.Time `json:"-"`
+	// TokenVersion is embedded in access tokens; bumping it revokes every
+	// token issued before.
+	TokenVersion int `json:"-" gorm:"not null;default:1"`
+	// MFASecret is the sealed TOTP secret, set from enrolment on. Codes are
+	// only required once MFAEnabled is set by confirming the enrolment.
+	MFASecret   *string `json:"-" gorm:"column:mfa_secret"`
+	MFAEnabled  bool    `json:"-" gorm:"column:mfa_enabled;not null;default:false"`
+	MFALastStep *int64  `json:"-" gorm:"column:mfa_last_step"`
+	Role        Role    `json:"role" gorm:"type:varchar(16);not null;default:user"`
+	// SuspendedAt is set while an admin or moderator has suspended the
+	// account, which refuses its logins and tokens.
+	SuspendedAt *time.Time `json:"-"`
+	// Profile fields, shown to everyone through Profile.
+	DisplayName string `json:"displayName" gorm:"type:varchar(64);not null;default:''"`
+	Bio         string `json:"bio" gorm:"type:text;not null;default:''"`
+	Timezone    string `json:"timezone" gorm:"type:varchar(64);not null;default:''"`
+	Locale      string `json:"locale" gorm:"type:varchar(35);not null;default:''"`
+	Pronouns    string `json:"pronouns" gorm:"type:varchar(32);not null;default:''"`
+	// AvatarKey names the stored avatar image, if one was uploaded.
+	AvatarKey *string `json:"-"`
+	// Searchable is cleared by users who don't want to be found by search.
+	Searchable bool      `json:"searchable" gorm:"not null;default:true"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt  time.Time `json:"-" gorm:"autoDeleteTime"`
+}
+
+// TableName maps User onto the "user" table created by the migrations
+// instead of gorm's pluralised default.
+func (u *User) TableName() string {
+	return "user"
+}
+
+// IsLocked reports whether logins to u are refused at now.
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// CheckPassword reports whether password matches the stored hash.
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
 }
 
 func (u *User) Validate() error {
@@ -45,15 +127,116 @@ func NewUser(name, email, password string) (*User, error) {
 	}
 
 	return &User{
-		Name:      name,
-		Email:     email,
-		Password:  hashedPassword,
-		CreatedAt: time.Now(),
+		Name:         name,
+		Email:        email,
+		Password:     hashedPassword,
+		Version:      1,
+		TokenVersion: 1,
+		Role:         RoleUser,
+		Searchable:   true,
+		CreatedAt:    time.Now(),
 	}, nil
 }
 
+// SetPassword stores a bcrypt hash of password. When password already
+// matches the stored hash nothing changes and false is returned, so an
+// unchanged password isn't needlessly re-hashed.
+func (u *User) SetPassword(password string) (bool, error) {
+	if password == "" {
+		return false, errors.New(ErrInvalidPassword)
+	}
+
+	if u.Password != "" && bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil {
+		return false, nil
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return false, err
+	}
+
+	u.Password = hashedPassword
+	return true, nil
+}
+
+// UserPatch holds the fields of a JSON Merge Patch (RFC 7396) against a user.
+// A nil field was not present in the patch and stays unchanged.
+type UserPatch struct {
+	Name     *string
+	Email    *string
+	Password *string
+}
+
+// Validate checks only the fields that were supplied.
+func (p *UserPatch) Validate() error {
+	if p.Name != nil && *p.Name == "" {
+		return errors.New(ErrInvalidName)
+	}
+
+	if p.Email != nil && *p.Email == "" {
+		return errors.New(ErrInvalidEmail)
+	}
+
+	if p.Password != nil && *p.Password == "" {
+		return errors.New(ErrInvalidPassword)
+	}
+
+	return nil
+}
+
+// Apply copies the supplied fields onto u and reports whether anything
+// actually changed. The password is re-hashed only if it differs from the
+// current one. A new email has to be verified again.
+func (p *UserPatch) Apply(u *User) (bool, error) {
+	changed := false
+
+	if p.Name != nil && *p.Name != u.Name {
+		u.Name = *p.Name
+		changed = true
+	}
+
+	if p.Email != nil && *p.Email != u.Email {
+		u.Email = *p.Email
+		u.EmailVerifiedAt = nil
+		changed = true
+	}
+
+	if p.Password != nil {
+		passwordChanged, err := u.SetPassword(*p.Password)
+		if err != nil {
+			return false, err
+		}
+
+		changed = changed || passwordChanged
+	}
+
+	return changed, nil
+}
+
+// HashPassword returns the bcrypt hash stored for password.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New(ErrInvalidPassword)
+	}
+
+	return hashPassword(password)
+}
+
+// NewDummyUser returns a user that isn't stored, with the hash of a fixed
+// password. A login for an unknown email is checked against it, so that it
+// does the same bcrypt work as a login for a known email. The id is 0,
+// which matches no row.
+func NewDummyUser() *User {
+	hashedPassword, err := hashPassword("dummy password")
+	if err != nil {
+		panic(err)
+	}
+
+	return &User{Password: hashedPassword}
+}
+
 func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// testHash is the hash of "password", made once since bcrypt at
+// passwordCost is slow.
+var testHash = func() string {
+	hashedPassword, err := hashPassword("password")
+	if err != nil {
+		panic(err)
+	}
+
+	return hashedPassword
+}()
+
+func TestUser_SetPassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     string
+		password    string
+		wantChanged bool
+		wantErr     string
+	}{
+		{name: "empty", current: testHash, password: "", wantErr: ErrInvalidPassword},
+		{name: "unchanged", current: testHash, password: "password", wantChanged: false},
+		{name: "changed", current: testHash, password: "new password", wantChanged: true},
+		{name: "first password", current: "", password: "password", wantChanged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Password: tt.current}
+
+			changed, err := u.SetPassword(tt.password)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Equal(t, tt.current, u.Password)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+			if !tt.wantChanged {
+				// Not re-hashed, so the stored hash is the very same.
+				assert.Equal(t, tt.current, u.Password)
+				return
+			}
+
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(tt.password)))
+		})
+	}
+}
+
+func TestUserPatch_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   UserPatch
+		wantErr string
+	}{
+		{name: "empty patch", patch: UserPatch{}},
+		{name: "all fields", patch: UserPatch{Name: stringPtr("name"), Email: stringPtr("name@example.com"), Password: stringPtr("password")}},
+		{name: "empty name", patch: UserPatch{Name: stringPtr("")}, wantErr: ErrInvalidName},
+		{name: "empty email", patch: UserPatch{Email: stringPtr("")}, wantErr: ErrInvalidEmail},
+		{name: "empty password", patch: UserPatch{Password: stringPtr("")}, wantErr: ErrInvalidPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.patch.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestUserPatch_Apply(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name         string
+		patch        UserPatch
+		wantChanged  bool
+		wantName     string
+		wantEmail    string
+		wantVerified bool
+		wantRehashed bool
+		wantErr      string
+	}{
+		{
+			name:         "empty patch",
+			patch:        UserPatch{},
+			wantName:     "name",
+			wantEmail:    "name@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "same values",
+			patch:        UserPatch{Name: stringPtr("name"), Email: stringPtr("name@example.com"), Password: stringPtr("password")},
+			wantName:     "name",
+			wantEmail:    "name@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "new name",
+			patch:        UserPatch{Name: stringPtr("other")},
+			wantChanged:  true,
+			wantName:     "other",
+			wantEmail:    "name@example.com",
+			wantVerified: true,
+		},
+		{
+			name:        "new email is unverified",
+			patch:       UserPatch{Email: stringPtr("other@example.com")},
+			wantChanged: true,
+			wantName:    "name",
+			wantEmail:   "other@example.com",
+		},
+		{
+			name:         "new password",
+			patch:        UserPatch{Password: stringPtr("new password")},
+			wantChanged:  true,
+			wantRehashed: true,
+			wantName:     "name",
+			wantEmail:    "name@example.com",
+			wantVerified: true,
+		},
+		{
+			name:    "empty password",
+			patch:   UserPatch{Password: stringPtr("")},
+			wantErr: ErrInvalidPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Name: "name", Email: "name@example.com", Password: testHash, EmailVerifiedAt: &verifiedAt}
+
+			changed, err := tt.patch.Apply(u)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.False(t, changed)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+			assert.Equal(t, tt.wantName, u.Name)
+			assert.Equal(t, tt.wantEmail, u.Email)
+			assert.Equal(t, tt.wantVerified, u.EmailVerifiedAt != nil)
+			// Only a different password is re-hashed.
+			assert.Equal(t, tt.wantRehashed, u.Password != testHash)
+		})
+	}
+}
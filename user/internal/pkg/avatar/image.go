@@ -0,0 +1,119 @@
+package avatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+)
+
+// maxPixels bounds the decoded size of an upload. A small compressed file
+// can declare huge dimensions, so they are checked before decoding.
+const maxPixels = 4096 * 4096
+
+// Process reads an uploaded JPEG, PNG or GIF of at most maxBytes, crops
+// it to the centred square and scales it to size by size pixels. The
+// result is encoded as PNG.
+func Process(r io.Reader, maxBytes int, size int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBytes {
+		return nil, models.ErrImageTooLarge
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, models.ErrInvalidImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, models.ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrInvalidImage
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Resize(CropSquare(src), size)); err != nil {
+		return nil, fmt.Errorf("encode avatar: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// CropSquare returns the largest centred square of img.
+func CropSquare(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	square := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, image.Point{X: x, Y: y}, draw.Src)
+
+	return square
+}
+
+// Resize scales the square src to size by size. Each output pixel
+// averages the source pixels it covers, weighted by coverage, which keeps
+// downscaled photos from aliasing; upscaling repeats pixels.
+func Resize(src *image.NRGBA, size int) *image.NRGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scale := float64(side) / float64(size)
+
+	for dy := 0; dy < size; dy++ {
+		y0, y1 := float64(dy)*scale, float64(dy+1)*scale
+		for dx := 0; dx < size; dx++ {
+			x0, x1 := float64(dx)*scale, float64(dx+1)*scale
+
+			var r, g, b, a, total float64
+			for sy := int(y0); float64(sy) < y1 && sy < side; sy++ {
+				wy := overlap(y0, y1, sy)
+				for sx := int(x0); float64(sx) < x1 && sx < side; sx++ {
+					w := wy * overlap(x0, x1, sx)
+					c := src.NRGBAAt(src.Rect.Min.X+sx, src.Rect.Min.Y+sy)
+					// Colours are weighted by alpha so transparent
+					// pixels don't bleed their colour into the edges.
+					alpha := float64(c.A) * w
+					r += float64(c.R) * alpha
+					g += float64(c.G) * alpha
+					b += float64(c.B) * alpha
+					a += alpha
+					total += w
+				}
+			}
+
+			if a == 0 || total == 0 {
+				continue
+			}
+
+			dst.SetNRGBA(dx, dy, color.NRGBA{
+				R: uint8(r/a + 0.5),
+				G: uint8(g/a + 0.5),
+				B: uint8(b/a + 0.5),
+				A: uint8(a/total + 0.5),
+			})
+		}
+	}
+
+	return dst
+}
+
+// overlap is how much of source pixel i lies within [lo, hi).
+func overlap(lo, hi float64, i int) float64 {
+	return min(hi, float64(i+1)) - max(lo, float64(i))
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const profileKeyPrefix = "profile:"
+
+// ProfileCache keeps public profiles in redis as JSON, one key per user and
+// version. Each user has a version counter; invalidating a profile bumps it,
+// so a profile loaded before the invalidation is written under a version
+// nobody reads any more and can't bring back stale data.
+type ProfileCache struct {
+	client redis.Cmdable
+}
+
+func NewProfileCache(client redis.Cmdable) *ProfileCache {
+	return &ProfileCache{client: client}
+}
+
+// GetMany returns the cached profiles among ids, and the current version of
+// every id for caching the ones that were missing with SetMany. Ids that
+// aren't cached are left out of the profiles.
+func (c *ProfileCache) GetMany(ctx context.Context, ids []int) (map[int]*models.Profile, map[int]int64, error) {
+	profiles := make(map[int]*models.Profile, len(ids))
+	versions := make(map[int]int64, len(ids))
+	if len(ids) == 0 {
+		return profiles, versions, nil
+	}
+
+	counters, err := c.client.MGet(ctx, versionKeys(ids)...).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		if counter, ok := counters[i].(string); ok {
+			versions[id], _ = strconv.ParseInt(counter, 10, 64)
+		}
+		keys[i] = profileKey(id, versions[id])
+	}
+
+	values, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var profile models.Profile
+		if err := json.Unmarshal([]byte(data), &profile); err != nil {
+			// Something unreadable is treated as a miss and overwritten
+			// once the profile is loaded again.
+			continue
+		}
+
+		profiles[ids[i]] = &profile
+	}
+
+	return profiles, versions, nil
+}
+
+// SetMany caches profiles under the versions GetMany returned before they
+// were loaded. Profiles invalidated since then land on a stale version and
+// are never read.
+func (c *ProfileCache) SetMany(ctx context.Context, profiles []models.Profile, versions map[int]int64, ttl time.Duration) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range profiles {
+			data, err := json.Marshal(&profiles[i])
+			if err != nil {
+				return err
+			}
+
+			pipe.Set(ctx, profileKey(profiles[i].Id, versions[profiles[i].Id]), data, ttl)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// Delete invalidates the cached profiles of ids by bumping their versions.
+// Profiles cached under the old versions expire with their TTL.
+func (c *ProfileCache) Delete(ctx context.Context, ids ...int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range versionKeys(ids) {
+			pipe.Incr(ctx, key)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func profileKey(id int, version int64) string {
+	return profileKeyPrefix + strconv.Itoa(id) + ":v" + strconv.FormatInt(version, 10)
+}
+
+func versionKey(id int) string {
+	return profileKeyPrefix + strconv.Itoa(id) + ":version"
+}
+
+func versionKeys(ids []int) []string {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = versionKey(id)
+	}
+
+	return keys
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envRedisURL names the redis database these tests use, e.g.
+// redis://localhost:6379/15. The database is flushed before and after each
+// test, so it mustn't be one anything else uses.
+const envRedisURL = "TEST_REDIS_URL"
+
+func testClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	url := os.Getenv(envRedisURL)
+	if url == "" {
+		t.Skipf("%s is not set", envRedisURL)
+	}
+
+	options, err := redis.ParseURL(url)
+	require.NoError(t, err)
+
+	client := redis.NewClient(options)
+	require.NoError(t, client.FlushDB(context.Background()).Err())
+
+	t.Cleanup(func() {
+		client.FlushDB(context.Background())
+		client.Close()
+	})
+
+	return client
+}
+
+func TestProfileCache_SetMany_AfterInvalidation(t *testing.T) {
+	cache := NewProfileCache(testClient(t))
+	ctx := context.Background()
+
+	profiles, versions, err := cache.GetMany(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.Empty(t, profiles)
+
+	// User 1 changes while the profiles are being loaded.
+	require.NoError(t, cache.Delete(ctx, 1))
+
+	loaded := []models.Profile{{Id: 1, Username: "stale"}, {Id: 2, Username: "two"}}
+	require.NoError(t, cache.SetMany(ctx, loaded, versions, time.Minute))
+
+	profiles, _, err = cache.GetMany(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.NotContains(t, profiles, 1)
+	require.Contains(t, profiles, 2)
+	assert.Equal(t, "two", profiles[2].Username)
+}
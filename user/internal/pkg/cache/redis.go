@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+var RedisClient *redis.Client
+
+// InitRedis creates the redis client. Redis being down at startup is not
+// fatal: the client reconnects on its own, and until it does the features
+// that depend on it fail open.
+func InitRedis(cfg config.RedisConfig, logger *logrus.Logger) {
+	RedisClient = redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	_, err := RedisClient.Ping(RedisClient.Context()).Result()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "cache",
+			"func":   "InitRedis",
+			"error":  err.Error(),
+		}).Warnf("failed to ping redis: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/configload"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	Server    ServerConfig
+	GRPC      GRPCConfig
+	Log       LogConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	RateLimit RateLimitConfig
+	Lockout   LockoutConfig
+	Admin     AdminConfig
+	Mail      MailConfig
+	Verify    VerificationConfig
+	Reset     PasswordResetConfig
+	MFA       MFAConfig
+	OIDC      OIDCConfig
+	Avatar    AvatarConfig
+	Search    SearchConfig
+	Batch     BatchConfig
+	Service   ServiceAuthConfig
+	Outbox    OutboxConfig
+	Broker    BrokerConfig
+	Shutdown  ShutdownConfig
+	Health    HealthConfig
+}
+
+type ServerConfig struct {
+	Port string `env:"SERVER_PORT" flag:"port" default:"8081" required:"true" usage:"HTTP listen port"`
+}
+
+type GRPCConfig struct {
+	Port string `env:"GRPC_PORT" flag:"grpc-port" default:"9091" required:"true" usage:"gRPC listen port"`
+}
+
+type LogConfig struct {
+	Level string `env:"LOG_LEVEL" flag:"log-level" default:"info" usage:"log level (debug, info, warn, error)"`
+}
+
+type DatabaseConfig struct {
+	Host     string `env:"DB_HOST" flag:"db-host" required:"true" usage:"PostgreSQL host"`
+	Port     string `env:"DB_PORT" flag:"db-port" default:"5432" usage:"PostgreSQL port"`
+	User     string `env:"DB_USER" flag:"db-user" required:"true" usage:"PostgreSQL user"`
+	Password string `env:"DB_PASSWORD" flag:"db-password" secret:"true" usage:"PostgreSQL password"`
+	Name     string `env:"DB_NAME" flag:"db-name" required:"true" usage:"PostgreSQL database name"`
+	SSLMode  string `env:"DB_SSLMODE" flag:"db-sslmode" default:"disable" usage:"PostgreSQL sslmode"`
+}
+
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s", c.Host, c.User, c.Password, c.Name, c.Port, c.SSLMode)
+}
+
+type RedisConfig struct {
+	Addr     string `env:"REDIS_HOST" flag:"redis-host" required:"true" usage:"Redis address, host:port"`
+	Password string `env:"REDIS_PASSWORD" flag:"redis-password" secret:"true" usage:"Redis password"`
+	DB       int    `env:"REDIS_DB" flag:"redis-db" default:"0" usage:"Redis database number"`
+}
+
+type JWTConfig struct {
+	Secret string        `env:"JWT_SECRET" flag:"jwt-secret" required:"true" secret:"true" usage:"HMAC key used to sign and verify access tokens"`
+	TTL    time.Duration `env:"JWT_TTL" flag:"jwt-ttl" default:"24h" usage:"lifetime of issued access tokens"`
+}
+
+type RateLimitConfig struct {
+	Enabled        bool     `env:"RATE_LIMIT_ENABLED" flag:"rate-limit-enabled" default:"true" usage:"enable rate limiting"`
+	User           string   `env:"RATE_LIMIT_USER" flag:"rate-limit-user" default:"120/1m" usage:"default per-user limit on each route, N/period"`
+	IP             string   `env:"RATE_LIMIT_IP" flag:"rate-limit-ip" default:"300/1m" usage:"default per-IP limit on each route, N/period"`
+	UserRoutes     []string `env:"RATE_LIMIT_USER_ROUTES" flag:"rate-limit-user-routes" usage:"comma separated per-route overrides of the per-user limit, METHOD /path=N/period"`
+	IPRoutes       []string `env:"RATE_LIMIT_IP_ROUTES" flag:"rate-limit-ip-routes" default:"POST /auth/login=10/1m,POST /auth/verify-email/resend=5/1m,POST /auth/password/forgot=5/1m,POST /auth/mfa/verify=10/1m" usage:"comma separated per-route overrides of the per-IP limit, METHOD /path=N/period"`
+	TrustForwarded bool     `env:"RATE_LIMIT_TRUST_FORWARDED" flag:"rate-limit-trust-forwarded" default:"false" usage:"take the client IP from X-Forwarded-For, or x-forwarded-for metadata on gRPC calls"`
+
+	LoginFreeAttempts int           `env:"LOGIN_BACKOFF_FREE_ATTEMPTS" flag:"login-backoff-free-attempts" default:"3" usage:"failed logins per email before backoff starts"`
+	LoginBackoffBase  time.Duration `env:"LOGIN_BACKOFF_BASE" flag:"login-backoff-base" default:"1s" usage:"delay after the first failed login past the free attempts, doubled on each further failure"`
+	LoginBackoffMax   time.Duration `env:"LOGIN_BACKOFF_MAX" flag:"login-backoff-max" default:"15m" usage:"upper bound of the login backoff"`
+	LoginWindow       time.Duration `env:"LOGIN_BACKOFF_WINDOW" flag:"login-backoff-window" default:"1h" usage:"how long failed logins are remembered"`
+}
+
+type LockoutConfig struct {
+	Threshold int           `env:"LOGIN_LOCKOUT_THRESHOLD" flag:"login-lockout-threshold" default:"10" usage:"failed logins to an account before it is locked"`
+	Duration  time.Duration `env:"LOGIN_LOCKOUT_DURATION" flag:"login-lockout-duration" default:"30m" usage:"how long a locked account stays locked"`
+}
+
+type AdminConfig struct {
+	Token string `env:"ADMIN_TOKEN" flag:"admin-token" secret:"true" usage:"token that acts as an admin when sent in X-Admin-Token, e.g. to appoint the first admin; disabled when empty"`
+}
+
+type MailConfig struct {
+	Driver       string `env:"MAIL_DRIVER" flag:"mail-driver" default:"log" usage:"how mail is delivered: smtp, file or log"`
+	From         string `env:"MAIL_FROM" flag:"mail-from" default:"no-reply@messenger.local" usage:"sender address of outgoing mail"`
+	SMTPHost     string `env:"SMTP_HOST" flag:"smtp-host" usage:"SMTP relay host"`
+	SMTPPort     string `env:"SMTP_PORT" flag:"smtp-port" default:"587" usage:"SMTP relay port"`
+	SMTPUser     string `env:"SMTP_USER" flag:"smtp-user" usage:"SMTP user, no authentication when empty"`
+	SMTPPassword string `env:"SMTP_PASSWORD" flag:"smtp-password" secret:"true" usage:"SMTP password"`
+	File         string `env:"MAIL_FILE" flag:"mail-file" default:"mail.log" usage:"file the file driver appends mail to"`
+}
+
+type VerificationConfig struct {
+	Secret         string        `env:"EMAIL_VERIFICATION_SECRET" flag:"email-verification-secret" required:"true" secret:"true" usage:"HMAC key used to sign email verification tokens"`
+	TTL            time.Duration `env:"EMAIL_VERIFICATION_TTL" flag:"email-verification-ttl" default:"24h" usage:"lifetime of email verification tokens"`
+	URL            string        `env:"EMAIL_VERIFICATION_URL" flag:"email-verification-url" default:"http://localhost:3000/verify-email" usage:"page the verification link points to, the token is appended as ?token="`
+	Required       bool          `env:"EMAIL_VERIFICATION_REQUIRED" flag:"email-verification-required" default:"false" usage:"refuse logins until the email is verified"`
+	ResendCooldown time.Duration `env:"EMAIL_VERIFICATION_RESEND_COOLDOWN" flag:"email-verification-resend-cooldown" default:"1m" usage:"minimum time between two verification emails to the same address"`
+}
+
+type PasswordResetConfig struct {
+	TTL time.Duration `env:"PASSWORD_RESET_TTL" flag:"password-reset-ttl" default:"1h" usage:"lifetime of password reset tokens"`
+	URL string        `env:"PASSWORD_RESET_URL" flag:"password-reset-url" default:"http://localhost:3000/reset-password" usage:"page the reset link points to, the token is appended as ?token="`
+}
+
+type MFAConfig struct {
+	EncryptionKey string        `env:"MFA_ENCRYPTION_KEY" flag:"mfa-encryption-key" required:"true" secret:"true" usage:"base64 encoded 32 byte AES key TOTP secrets are encrypted with"`
+	Issuer        string        `env:"MFA_ISSUER" flag:"mfa-issuer" default:"Messenger" usage:"issuer shown in authenticator apps"`
+	PendingTTL    time.Duration `env:"MFA_PENDING_TTL" flag:"mfa-pending-ttl" default:"5m" usage:"how long a login has to enter its second factor"`
+	RecoveryCodes int           `env:"MFA_RECOVERY_CODES" flag:"mfa-recovery-codes" default:"10" usage:"number of recovery codes issued on enrolment"`
+}
+
+type OIDCConfig struct {
+	ProvidersFile string        `env:"OIDC_PROVIDERS_FILE" flag:"oidc-providers-file" usage:"JSON file listing OpenID Connect providers, external login is off when empty"`
+	StateTTL      time.Duration `env:"OIDC_STATE_TTL" flag:"oidc-state-ttl" default:"10m" usage:"how long a user has to come back from the provider"`
+	HTTPTimeout   time.Duration `env:"OIDC_HTTP_TIMEOUT" flag:"oidc-http-timeout" default:"10s" usage:"timeout of requests to providers"`
+	SecureCookie  bool          `env:"OIDC_SECURE_COOKIE" flag:"oidc-secure-cookie" default:"true" usage:"send the login state cookie over HTTPS only"`
+}
+
+type AvatarConfig struct {
+	Dir      string `env:"AVATAR_DIR" flag:"avatar-dir" default:"data/avatars" usage:"directory avatars are stored in"`
+	BaseURL  string `env:"AVATAR_BASE_URL" flag:"avatar-base-url" default:"/avatars" usage:"URL avatars are served under; a path is served by this service from the avatar directory"`
+	MaxBytes int    `env:"AVATAR_MAX_BYTES" flag:"avatar-max-bytes" default:"5242880" usage:"largest avatar upload accepted"`
+	Size     int    `env:"AVATAR_SIZE" flag:"avatar-size" default:"256" usage:"width and height avatars are scaled to"`
+}
+
+type SearchConfig struct {
+	DefaultLimit   int `env:"SEARCH_DEFAULT_LIMIT" flag:"search-default-limit" default:"20" usage:"users returned per search page when the client asks for no limit"`
+	MaxLimit       int `env:"SEARCH_MAX_LIMIT" flag:"search-max-limit" default:"50" usage:"most users a search page returns"`
+	MinQueryLength int `env:"SEARCH_MIN_QUERY_LENGTH" flag:"search-min-query-length" default:"2" usage:"shortest search query accepted"`
+}
+
+type BatchConfig struct {
+	MaxIds   int           `env:"USER_BATCH_MAX_IDS" flag:"user-batch-max-ids" default:"500" usage:"most user ids one batch lookup may ask for"`
+	CacheTTL time.Duration `env:"PROFILE_CACHE_TTL" flag:"profile-cache-ttl" default:"10m" usage:"how long profiles stay cached for batch lookups"`
+}
+
+type ServiceAuthConfig struct {
+	Tokens []string `env:"SERVICE_TOKENS" flag:"service-tokens" secret:"true" usage:"comma separated tokens other services send in X-Service-Token, more than one allows rotation; service endpoints are disabled when empty"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" flag:"outbox-poll-interval" default:"1s" usage:"how often the outbox is checked for events to publish"`
+	BatchSize    int           `env:"OUTBOX_BATCH_SIZE" flag:"outbox-batch-size" default:"100" usage:"most events published from the outbox in one go"`
+	Retention    time.Duration `env:"OUTBOX_RETENTION" flag:"outbox-retention" default:"168h" usage:"how long published events are kept in the outbox"`
+	MaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" flag:"outbox-max-attempts" default:"20" usage:"how often publishing an event is tried before it is parked in the outbox"`
+	RetryMax     time.Duration `env:"OUTBOX_RETRY_MAX" flag:"outbox-retry-max" default:"10m" usage:"longest wait before publishing a failed event is tried again"`
+}
+
+type BrokerConfig struct {
+	Driver       string `env:"BROKER_DRIVER" flag:"broker-driver" default:"redis" usage:"where events are published: redis (Redis Streams) or memory (in-process, for local use)"`
+	StreamPrefix string `env:"BROKER_STREAM_PREFIX" flag:"broker-stream-prefix" default:"events:" usage:"prefix of the Redis stream each topic is published to"`
+	StreamMaxLen int    `env:"BROKER_STREAM_MAX_LEN" flag:"broker-stream-max-len" default:"100000" usage:"approximate number of events kept in each stream"`
+}
+
+type ShutdownConfig struct {
+	Timeout    time.Duration `env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" default:"15s" usage:"how long in-flight requests may take to drain"`
+	DrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" flag:"shutdown-drain-delay" default:"5s" usage:"how long to report not ready before draining"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" flag:"health-check-timeout" default:"2s" usage:"timeout of each readiness dependency check"`
+}
+
+// Load builds the user service configuration from command line flags, the
+// environment and an optional config file, in that order of precedence.
+// args are the command line arguments without the program name; whatever
+// follows the flags, such as a subcommand, is returned.
+func Load(args []string) (*Config, []string, error) {
+	cfg := &Config{}
+	rest, err := configload.Load(cfg, "user", args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, rest, nil
+}
+
+// Fields returns the effective configuration for logging, with secrets
+// redacted.
+func (c *Config) Fields() logrus.Fields {
+	return configload.Fields(c)
+}
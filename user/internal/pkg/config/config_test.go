@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/dmitriysta/messenger/pkg/configload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Fields_RedactsSecrets(t *testing.T) {
+	cfg := &Config{}
+	cfg.Database.Host = "db"
+	cfg.Database.Password = "db-password"
+	cfg.JWT.Secret = "jwt-secret"
+	cfg.Service.Tokens = []string{"service-token"}
+
+	got := cfg.Fields()
+	assert.Equal(t, "db", got["DB_HOST"])
+	assert.Equal(t, configload.Redacted, got["DB_PASSWORD"])
+	assert.Equal(t, configload.Redacted, got["JWT_SECRET"])
+	assert.Equal(t, configload.Redacted, got["SERVICE_TOKENS"])
+	for _, value := range got {
+		assert.NotContains(t, value, "secret")
+		assert.NotContains(t, value, "password")
+	}
+}
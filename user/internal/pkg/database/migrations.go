@@ -0,0 +1,29 @@
+package database
+
+import (
+	"embed"
+	"io/fs"
+)
+
+const (
+	// MigrationsTable is prefixed with the service name because both
+	// services may share one PostgreSQL database.
+	MigrationsTable = "user_schema_migrations"
+	// OutboxTable is prefixed for the same reason, and so that the relay
+	// of each service only publishes its own events.
+	OutboxTable = "user_outbox"
+	// MigrationsLockId is the pg_advisory_lock key held while migrating.
+	MigrationsLockId = 72_002
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+func Migrations() fs.FS {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
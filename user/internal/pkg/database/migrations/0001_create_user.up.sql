@@ -1,15 +1,10 @@
-#schema.sql
-
-BEGIN;
-
 CREATE TABLE IF NOT EXISTS "user" (
     id SERIAL PRIMARY KEY UNIQUE NOT NULL,
     username VARCHAR(255) NOT NULL UNIQUE,
     email VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
     deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
 );
-
-COMMIT;
\ No newline at end of file
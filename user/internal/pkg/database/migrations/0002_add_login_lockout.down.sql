@@ -0,0 +1,3 @@
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_logins;
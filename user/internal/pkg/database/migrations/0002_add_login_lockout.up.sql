@@ -0,0 +1,3 @@
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS failed_logins INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE DEFAULT NULL;
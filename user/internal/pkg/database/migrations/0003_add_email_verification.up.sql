@@ -0,0 +1,4 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+-- Accounts created before verification existed stay usable.
+UPDATE "user" SET email_verified_at = created_at WHERE email_verified_at IS NULL;
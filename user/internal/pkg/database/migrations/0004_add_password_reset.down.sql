@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS password_reset_token;
+
+ALTER TABLE "user" DROP COLUMN IF EXISTS token_version;
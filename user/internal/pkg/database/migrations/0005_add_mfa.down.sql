@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS mfa_recovery_code;
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS mfa_last_step,
+    DROP COLUMN IF EXISTS mfa_enabled,
+    DROP COLUMN IF EXISTS mfa_secret;
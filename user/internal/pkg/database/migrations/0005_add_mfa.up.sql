@@ -0,0 +1,13 @@
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS mfa_secret TEXT DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_code (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_role_idx;
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,7 @@
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS avatar_key,
+    DROP COLUMN IF EXISTS pronouns,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS display_name;
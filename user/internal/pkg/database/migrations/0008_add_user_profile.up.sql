@@ -0,0 +1,7 @@
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS pronouns VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS avatar_key VARCHAR(255) DEFAULT NULL;
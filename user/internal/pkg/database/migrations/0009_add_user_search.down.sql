@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_display_name_trgm_idx;
+DROP INDEX IF EXISTS user_username_trgm_idx;
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS searchable;
@@ -0,0 +1,10 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS searchable BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE INDEX IF NOT EXISTS user_username_trgm_idx ON "user"
+    USING GIN (lower(username) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS user_display_name_trgm_idx ON "user"
+    USING GIN (lower(display_name) gin_trgm_ops);
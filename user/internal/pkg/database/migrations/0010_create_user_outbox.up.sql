@@ -0,0 +1,23 @@
+-- The table is prefixed with the service name because both services may
+-- share one PostgreSQL database, and each relay must only see its own events.
+CREATE TABLE IF NOT EXISTS "user_outbox" (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(64) NOT NULL,
+    key VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    -- When the event may next be handed to a relay: after the lease of
+    -- the relay that has it, or after the retry delay of a failed attempt.
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Set when the event ran out of attempts. It is kept for inspection
+    -- and never published.
+    failed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+-- The relay only ever looks at pending events, oldest first and by key.
+CREATE INDEX IF NOT EXISTS user_outbox_pending_idx ON "user_outbox" (id) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS user_outbox_pending_key_idx ON "user_outbox" (key, id) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS user_outbox_published_at_idx ON "user_outbox" (published_at) WHERE published_at IS NOT NULL;
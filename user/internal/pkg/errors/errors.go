@@ -1,13 +1,59 @@
 package errors
 
 const (
-	InvalidUserId           = "Invalid user id"
-	InvalidRequestBody      = "Invalid request body"
-	ErrorCreatingUser       = "Error creating user"
-	ErrorEmptyUserId        = "User id is empty"
-	ErrorGettingUser        = "Error getting user"
-	ErrorUpdatingUser       = "Error updating user"
-	ErrorDeletingUser       = "Error deleting user"
-	ErrorUserNotFound       = "Invalid username or password"
-	ErrorAuthenticatingUser = "Error authenticating user"
+	InvalidUserId            = "Invalid user id"
+	InvalidRequestBody       = "Invalid request body"
+	ErrorCreatingUser        = "Error creating user"
+	ErrorEmptyUserId         = "User id is empty"
+	ErrorGettingUser         = "Error getting user"
+	ErrorUpdatingUser        = "Error updating user"
+	ErrorDeletingUser        = "Error deleting user"
+	ErrorUserNotFound        = "Invalid username or password"
+	ErrorAuthenticatingUser  = "Error authenticating user"
+	ErrorInvalidIfMatch      = "Invalid If-Match header"
+	ErrorPreconditionFailed  = "User has been modified since it was read"
+	ErrorVersionConflict     = "User was modified concurrently"
+	ErrorInvalidPatch        = "Invalid merge patch"
+	ErrorUnsupportedPatch    = "Content-Type must be application/merge-patch+json"
+	ErrorRateLimited         = "Too many requests"
+	ErrorNoSuchUser          = "User not found"
+	ErrorUnlockingUser       = "Error unlocking user"
+	ErrorAdminDisabled       = "Admin API is disabled"
+	ErrorInvalidAdminToken   = "Invalid admin token"
+	ErrorEmailNotVerified    = "Email is not verified"
+	ErrorInvalidToken        = "Invalid or expired token"
+	ErrorVerifyingEmail      = "Error verifying email"
+	ErrorSendingVerification = "Error sending verification email"
+	ErrorRequestingReset     = "Error requesting password reset"
+	ErrorResettingPassword   = "Error resetting password"
+	ErrorMissingUserId       = "No user in request context"
+	ErrorMFAAlreadyEnabled   = "Two-factor authentication is already enabled"
+	ErrorMFANotEnrolled      = "Two-factor authentication is not being enrolled"
+	ErrorInvalidMFACode      = "Invalid two-factor code"
+	ErrorEnrollingMFA        = "Error enrolling two-factor authentication"
+	ErrorVerifyingMFA        = "Error verifying two-factor code"
+	ErrorUnknownProvider     = "Unknown identity provider"
+	ErrorProviderDenied      = "Identity provider did not sign the user in"
+	ErrorStartingOIDCLogin   = "Error starting external login"
+	ErrorCompletingOIDCLogin = "Error completing external login"
+	ErrorExternalEmail       = "Identity provider did not verify the email"
+	ErrorIdentityConflict    = "An account with this email exists but its email is not verified"
+	ErrorForbidden           = "Not allowed"
+	ErrorUserSuspended       = "Account is suspended"
+	ErrorInvalidRole         = "Invalid role"
+	ErrorInvalidQuery        = "Invalid query parameters"
+	ErrorListingUsers        = "Error listing users"
+	ErrorSuspendingUser      = "Error suspending user"
+	ErrorChangingRole        = "Error changing role"
+	ErrorGettingProfile      = "Error getting profile"
+	ErrorUpdatingProfile     = "Error updating profile"
+	ErrorInvalidImage        = "Avatar must be a JPEG, PNG or GIF image"
+	ErrorImageTooLarge       = "Avatar image is too large"
+	ErrorMissingAvatar       = "Multipart field avatar is required"
+	ErrorUploadingAvatar     = "Error uploading avatar"
+	ErrorSearchingUsers      = "Error searching users"
+	ErrorInvalidCursor       = "Invalid cursor"
+	ErrorServiceDisabled     = "Service API is disabled"
+	ErrorInvalidServiceToken = "Invalid service token"
+	ErrorGettingProfiles     = "Error getting profiles"
 )
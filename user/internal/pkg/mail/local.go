@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FileSender appends every message to a file instead of delivering it, for
+// local development and tests.
+type FileSender struct {
+	path   string
+	from   string
+	logger *logrus.Logger
+
+	mu sync.Mutex
+}
+
+func NewFileSender(path, from string, logger *logrus.Logger) *FileSender {
+	return &FileSender{
+		path:   path,
+		from:   from,
+		logger: logger,
+	}
+}
+
+func (s *FileSender) Send(ctx context.Context, email models.Email) error {
+	if err := validAddress(email.To); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "mail",
+			"func":   "Send",
+			"error":  err.Error(),
+		}).Errorf("failed to open mail file: %v", err)
+
+		return err
+	}
+	defer file.Close()
+
+	message := append(format(s.from, email, time.Now()), "\r\n\r\n"...)
+	if _, err := file.Write(message); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "mail",
+			"func":   "Send",
+			"error":  err.Error(),
+		}).Errorf("failed to write mail file: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// LogSender logs every message instead of delivering it.
+type LogSender struct {
+	logger *logrus.Logger
+}
+
+func NewLogSender(logger *logrus.Logger) *LogSender {
+	return &LogSender{
+		logger: logger,
+	}
+}
+
+func (s *LogSender) Send(ctx context.Context, email models.Email) error {
+	if err := validAddress(email.To); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module":  "mail",
+		"func":    "Send",
+		"to":      email.To,
+		"subject": email.Subject,
+		"body":    email.Body,
+	}).Info("mail not delivered, logged instead")
+
+	return nil
+}
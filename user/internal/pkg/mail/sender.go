@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// NewSender returns the mail sender selected by cfg.Driver.
+func NewSender(cfg config.MailConfig, logger *logrus.Logger) (interfaces.MailSender, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("mail driver %q needs SMTP_HOST", cfg.Driver)
+		}
+
+		return NewSMTPSender(cfg, logger), nil
+	case DriverFile:
+		return NewFileSender(cfg.File, cfg.From, logger), nil
+	case DriverLog:
+		return NewLogSender(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// format renders email as an RFC 5322 message.
+func format(from string, email models.Email, now time.Time) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + email.To + "\r\n")
+	b.WriteString("Subject: " + email.Subject + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(email.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// validAddress rejects addresses that would inject extra headers.
+func validAddress(address string) error {
+	if address == "" || strings.ContainsAny(address, "\r\n") {
+		return fmt.Errorf("invalid mail address %q", address)
+	}
+
+	return nil
+}
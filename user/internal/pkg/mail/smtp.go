@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SMTPSender delivers mail through an SMTP relay, authenticating with PLAIN
+// when a user is configured.
+type SMTPSender struct {
+	addr   string
+	from   string
+	auth   smtp.Auth
+	logger *logrus.Logger
+}
+
+func NewSMTPSender(cfg config.MailConfig, logger *logrus.Logger) *SMTPSender {
+	var auth smtp.Auth
+	if cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	return &SMTPSender{
+		addr:   net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		from:   cfg.From,
+		auth:   auth,
+		logger: logger,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, email models.Email) error {
+	if err := validAddress(email.To); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{email.To}, format(s.from, email, time.Now())); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "mail",
+			"func":   "Send",
+			"error":  err.Error(),
+		}).Errorf("failed to send mail: %v", err)
+
+		return err
+	}
+
+	return nil
+}
@@ -29,4 +29,98 @@ var (
 		},
 		[]string{"method", "endpoint", "http_status"},
 	)
+
+	GRPCResponseTime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "user_grpc_response_time_seconds",
+			Help: "Response time of gRPC calls to user service",
+		},
+		[]string{"method"},
+	)
+
+	GRPCRequestCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_grpc_request_count",
+			Help: "gRPC call count of user service",
+		},
+		[]string{"method", "code"},
+	)
+
+	GRPCErrorCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_grpc_error_count",
+			Help: "gRPC error count of user service",
+		},
+		[]string{"method", "code"},
+	)
+
+	DependencyUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "user_dependency_up",
+			Help: "Whether the last readiness check of a dependency of user service succeeded",
+		},
+		[]string{"dependency"},
+	)
+
+	DependencyLatency = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "user_dependency_check_latency_seconds",
+			Help: "Latency of the last readiness check of a dependency of user service",
+		},
+		[]string{"dependency"},
+	)
+
+	RateLimited = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_rate_limited_total",
+			Help: "Requests to user service rejected by rate limiting",
+		},
+		[]string{"scope", "route"},
+	)
+
+	LoginFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_login_failures_total",
+			Help: "Failed logins to user service by reason",
+		},
+		[]string{"reason"},
+	)
+
+	AccountLockouts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_account_lockouts_total",
+			Help: "Accounts locked after too many failed logins",
+		},
+	)
+
+	AccountUnlocks = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_account_unlocks_total",
+			Help: "Locked accounts unlocked by an admin",
+		},
+	)
+
+	OutboxPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_outbox_published_total",
+			Help: "Events published from the outbox of user service by topic",
+		},
+		[]string{"topic"},
+	)
+
+	OutboxPublishFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_outbox_publish_failures_total",
+			Help: "Failed attempts to publish an event from the outbox of user service by topic",
+		},
+		[]string{"topic"},
+	)
+
+	OutboxParked = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_outbox_parked_total",
+			Help: "Events of user service parked in the outbox after running out of publish attempts by topic",
+		},
+		[]string{"topic"},
+	)
 )
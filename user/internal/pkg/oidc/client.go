@@ -0,0 +1,317 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dmitriysta/messenger/user/internal/models"
+)
+
+// Client runs the authorization code flow with PKCE against one provider
+// and verifies the ID tokens it issues. The discovery document and the
+// signing keys are fetched on first use and cached; an ID token signed
+// with an unknown key triggers one refetch of the keys, which is how
+// provider key rotation is picked up.
+type Client struct {
+	cfg  ProviderConfig
+	http *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]*rsa.PublicKey
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewClient(cfg ProviderConfig, httpClient *http.Client) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Client{
+		cfg:  cfg,
+		http: httpClient,
+	}
+}
+
+// AuthCodeURL returns where to send the user to sign in. challenge is the
+// S256 PKCE challenge of the verifier later passed to Exchange.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	d, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", c.cfg.ClientID)
+	values.Set("redirect_uri", c.cfg.RedirectURL)
+	values.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", challenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return d.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange redeems code for tokens and returns the identity in the ID
+// token, after checking its signature, issuer, audience, expiry and nonce.
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (*models.ExternalIdentity, error) {
+	d, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := c.do(req, &tokens); err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response without id_token")
+	}
+
+	return c.verify(ctx, d, tokens.IDToken, nonce)
+}
+
+func (c *Client) verify(ctx context.Context, d *discovery, idToken, nonce string) (*models.ExternalIdentity, error) {
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, d, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify id token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid id token")
+	}
+
+	if !claims.VerifyIssuer(d.Issuer, true) {
+		return nil, fmt.Errorf("id token issued by %v, not %s", claims["iss"], d.Issuer)
+	}
+
+	if !claims.VerifyAudience(c.cfg.ClientID, true) && !audienceContains(claims["aud"], c.cfg.ClientID) {
+		return nil, fmt.Errorf("id token not issued for %s", c.cfg.ClientID)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("id token without expiry")
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id token without subject")
+	}
+
+	identity := &models.ExternalIdentity{
+		Provider: c.cfg.Name,
+		Subject:  subject,
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	if username, ok := claims["preferred_username"].(string); ok && username != "" {
+		identity.Name = username
+	}
+
+	// Some providers send email_verified as a string.
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+// audienceContains handles an aud array, which jwt-go v3 doesn't.
+func audienceContains(aud interface{}, clientID string) bool {
+	list, ok := aud.([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, item := range list {
+		if item == clientID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// discover fetches the discovery document once. The fetch runs without
+// c.mu held, so a slow provider doesn't hold up key lookups; concurrent
+// first calls may both fetch, and the first to finish is kept.
+func (c *Client) discover(ctx context.Context) (*discovery, error) {
+	c.mu.Lock()
+	cached := c.discovery
+	c.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.cfg.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var d discovery
+	if err := c.do(req, &d); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+
+	if d.Issuer != c.cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %s does not match %s", d.Issuer, c.cfg.Issuer)
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery == nil {
+		c.discovery = &d
+	}
+
+	return c.discovery, nil
+}
+
+func (c *Client) key(ctx context.Context, d *discovery, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[kid]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	keys, err := c.fetchKeys(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (c *Client) fetchKeys(ctx context.Context, d *discovery) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.do(req, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %s: %w", jwk.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %s: %w", jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Redacted(), resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, out)
+}
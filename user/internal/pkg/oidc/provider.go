@@ -0,0 +1,65 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ProviderConfig describes one OpenID Connect provider. Providers are read
+// from a JSON array in OIDC_PROVIDERS_FILE, so adding one takes no code.
+type ProviderConfig struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes"`
+}
+
+func (c ProviderConfig) validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("provider without a name")
+	case c.Issuer == "":
+		return fmt.Errorf("provider %s: issuer is required", c.Name)
+	case c.ClientID == "":
+		return fmt.Errorf("provider %s: clientId is required", c.Name)
+	case c.RedirectURL == "":
+		return fmt.Errorf("provider %s: redirectUrl is required", c.Name)
+	}
+
+	return nil
+}
+
+// LoadProviders reads the provider list at path. An empty path means no
+// providers.
+func LoadProviders(path string) ([]ProviderConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read oidc providers: %w", err)
+	}
+
+	var providers []ProviderConfig
+	if err := json.Unmarshal(data, &providers); err != nil {
+		return nil, fmt.Errorf("parse oidc providers %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(providers))
+	for _, provider := range providers {
+		if err := provider.validate(); err != nil {
+			return nil, err
+		}
+
+		if seen[provider.Name] {
+			return nil, fmt.Errorf("provider %s is configured twice", provider.Name)
+		}
+		seen[provider.Name] = true
+	}
+
+	return providers, nil
+}
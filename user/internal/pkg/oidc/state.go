@@ -0,0 +1,65 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const statePrefix = "oidc:state:"
+
+// HashBinding returns what is stored of the binding the browser that
+// started a login keeps in a cookie. The binding is a random value of its
+// own, unrelated to the state, so seeing the state in a callback URL
+// doesn't give it away, and a copy of the stored state doesn't either.
+func HashBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// StateStore keeps the state of logins in flight in redis, so the
+// callback can land on any replica.
+type StateStore struct {
+	client redis.Cmdable
+}
+
+func NewStateStore(client redis.Cmdable) *StateStore {
+	return &StateStore{
+		client: client,
+	}
+}
+
+func (s *StateStore) Save(ctx context.Context, key string, state models.OIDCState, ttl time.Duration) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, statePrefix+key, data, ttl).Err()
+}
+
+// Take returns and forgets the state saved under key, so each can be used
+// once. It returns models.ErrInvalidToken if there is none.
+func (s *StateStore) Take(ctx context.Context, key string) (*models.OIDCState, error) {
+	data, err := s.client.GetDel(ctx, statePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, models.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state models.OIDCState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
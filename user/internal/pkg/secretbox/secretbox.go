@@ -0,0 +1,74 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of an AES-256 key.
+const KeySize = 32
+
+// Box encrypts small secrets for storage with AES-256-GCM. Sealed values
+// are base64 of the nonce followed by the ciphertext.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox takes a base64 encoded 32 byte key.
+func NewBox(encodedKey string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{
+		aead: aead,
+	}, nil
+}
+
+// Seal encrypts plaintext. context is authenticated but not stored; Open
+// has to be given the same context, which binds the value to, say, the row
+// it is stored in.
+func (b *Box) Seal(plaintext []byte, context string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, plaintext, []byte(context))
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *Box) Open(sealed, context string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("decode sealed value: %w", err)
+	}
+
+	if len(data) < b.aead.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+
+	return b.aead.Open(nil, nonce, ciphertext, []byte(context))
+}
@@ -0,0 +1,113 @@
+package secretbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(fill byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, KeySize))
+}
+
+func newTestBox(t *testing.T) *Box {
+	box, err := NewBox(testKey(1))
+	require.NoError(t, err)
+
+	return box
+}
+
+func TestNewBox(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "valid", key: testKey(1)},
+		{name: "not base64", key: "not a key!", wantErr: true},
+		{name: "too short", key: base64.StdEncoding.EncodeToString(make([]byte, 16)), wantErr: true},
+		{name: "too long", key: base64.StdEncoding.EncodeToString(make([]byte, 33)), wantErr: true},
+		{name: "empty", key: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box, err := NewBox(tt.key)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, box)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, box)
+		})
+	}
+}
+
+func TestBox_RoundTrip(t *testing.T) {
+	box := newTestBox(t)
+	secret := []byte("12345678901234567890")
+
+	sealed, err := box.Seal(secret, "user:1:mfa")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, string(secret))
+
+	opened, err := box.Open(sealed, "user:1:mfa")
+	require.NoError(t, err)
+	assert.Equal(t, secret, opened)
+}
+
+func TestBox_Seal_FreshNonce(t *testing.T) {
+	box := newTestBox(t)
+
+	a, err := box.Seal([]byte("secret"), "user:1:mfa")
+	require.NoError(t, err)
+	b, err := box.Seal([]byte("secret"), "user:1:mfa")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+}
+
+func TestBox_Open_Rejects(t *testing.T) {
+	box := newTestBox(t)
+
+	sealed, err := box.Seal([]byte("12345678901234567890"), "user:1:mfa")
+	require.NoError(t, err)
+
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	require.NoError(t, err)
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 0x01
+
+	otherBox, err := NewBox(testKey(2))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		box     *Box
+		sealed  string
+		context string
+	}{
+		// A secret copied to the row of another user doesn't open there.
+		{name: "other user", box: box, sealed: sealed, context: "user:2:mfa"},
+		{name: "other purpose", box: box, sealed: sealed, context: "user:1:other"},
+		{name: "no context", box: box, sealed: sealed, context: ""},
+		{name: "tampered ciphertext", box: box, sealed: base64.StdEncoding.EncodeToString(tampered), context: "user:1:mfa"},
+		{name: "truncated", box: box, sealed: base64.StdEncoding.EncodeToString(raw[:8]), context: "user:1:mfa"},
+		{name: "not base64", box: box, sealed: "%%%", context: "user:1:mfa"},
+		{name: "other key", box: otherBox, sealed: sealed, context: "user:1:mfa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := tt.box.Open(tt.sealed, tt.context)
+
+			assert.Error(t, err)
+			assert.Nil(t, opened)
+		})
+	}
+}
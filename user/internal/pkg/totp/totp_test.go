@@ -0,0 +1,116 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA-1 key of the test vectors in RFC 4226 and RFC 6238.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode_HOTPVectors(t *testing.T) {
+	// RFC 4226 appendix D.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range want {
+		assert.Equal(t, code, Code(rfcSecret, int64(counter)), "counter %d", counter)
+	}
+}
+
+func TestCode_TOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1. The RFC lists eight digits; the last six
+	// are the six digit code.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, Code(rfcSecret, Step(time.Unix(tt.unix, 0))), "time %d", tt.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int64
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "current step", code: Code(rfcSecret, current), skew: 1, wantStep: current, wantOk: true},
+		{name: "previous step within skew", code: Code(rfcSecret, current-1), skew: 1, wantStep: current - 1, wantOk: true},
+		{name: "next step within skew", code: Code(rfcSecret, current+1), skew: 1, wantStep: current + 1, wantOk: true},
+		{name: "previous step without skew", code: Code(rfcSecret, current-1), skew: 0},
+		{name: "two steps back", code: Code(rfcSecret, current-2), skew: 1},
+		{name: "two steps ahead", code: Code(rfcSecret, current+2), skew: 1},
+		{name: "wrong code", code: "000000", skew: 1},
+		{name: "too short", code: Code(rfcSecret, current)[:5], skew: 1},
+		{name: "too long", code: Code(rfcSecret, current) + "0", skew: 1},
+		{name: "empty", code: "", skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantStep, step)
+		})
+	}
+}
+
+// Replays are refused by the caller remembering the last step used, so a
+// code has to report the step it was made for at every time it is valid.
+func TestValidate_ReportsStepOfCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := Code(rfcSecret, Step(now))
+
+	first, ok := Validate(rfcSecret, code, now, 1)
+	require.True(t, ok)
+
+	replayed, ok := Validate(rfcSecret, code, now.Add(Period*time.Second), 1)
+	require.True(t, ok)
+
+	assert.Equal(t, first, replayed)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	require.NoError(t, err)
+	b, err := GenerateSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, a, SecretSize)
+	assert.NotEqual(t, a, b)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Messenger", "user@example.com", rfcSecret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Messenger:user@example.com", uri.Path)
+
+	query := uri.Query()
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", query.Get("secret"))
+	assert.Equal(t, "Messenger", query.Get("issuer"))
+	assert.Equal(t, "SHA1", query.Get("algorithm"))
+	assert.Equal(t, "6", query.Get("digits"))
+	assert.Equal(t, "30", query.Get("period"))
+}
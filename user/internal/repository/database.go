@@ -1,24 +1,14 @@
 package repository
 
 import (
-	"fmt"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"os"
 )
 
-func DatabaseConnect(logger *logrus.Logger) *gorm.DB {
-
-	host := os.Getenv("DB_HOST")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	port := os.Getenv("DB_PORT")
-
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, user, password, dbname, port)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func DatabaseConnect(cfg config.DatabaseConfig, logger *logrus.Logger) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"module": "repository",
@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/dmitriysta/messenger/pkg/events"
 	"github.com/dmitriysta/messenger/user/internal/models"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -32,7 +38,14 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 		return err
 	}
 
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return createUserCreatedEvent(tx, user)
+	})
+	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "CreateUser",
@@ -49,6 +62,10 @@ func (r *UserRepository) GetUserById(ctx context.Context, userId int) (*models.U
 	var user models.User
 	err := r.db.WithContext(ctx).Where("id = ?", userId).First(&user).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserNotFound
+		}
+
 		r.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "GetUserById",
@@ -65,6 +82,10 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	var user models.User
 	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserNotFound
+		}
+
 		r.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "GetUserByEmail",
@@ -77,6 +98,23 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	return &user, nil
 }
 
+// GetUsersByIds returns the users among userIds that exist, in no
+// particular order.
+func (r *UserRepository) GetUsersByIds(ctx context.Context, userIds []int) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.WithContext(ctx).Where("id IN ?", userIds).Find(&users).Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "GetUsersByIds",
+			"error":  err.Error(),
+		}).Errorf("failed to get users by ids: %v", err)
+
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	if err := user.Validate(); err != nil {
 		r.logger.WithFields(logrus.Fields{
@@ -88,7 +126,16 @@ func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) erro
 		return err
 	}
 
-	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
+	// Conditioning the update on the version the caller read turns a lost
+	// update into an explicit conflict instead of a silent overwrite.
+	result := r.db.WithContext(ctx).Model(user).Where("version = ?", user.Version).Updates(map[string]interface{}{
+		"username":          user.Name,
+		"email":             user.Email,
+		"password":          user.Password,
+		"email_verified_at": user.EmailVerifiedAt,
+		"version":           gorm.Expr("version + 1"),
+	})
+	if err := result.Error; err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "UpdateUser",
@@ -98,11 +145,42 @@ func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) erro
 		return err
 	}
 
+	if result.RowsAffected == 0 {
+		r.logger.WithFields(logrus.Fields{
+			"module":  "user",
+			"func":    "UpdateUser",
+			"userId":  user.Id,
+			"version": user.Version,
+		}).Warn("user version conflict")
+
+		return models.ErrVersionConflict
+	}
+
+	user.Version++
+
 	return nil
 }
 
+// DeleteUser deletes userId and records user.deleted in the outbox in the
+// same transaction. Deleting a user that is already gone records nothing.
 func (r *UserRepository) DeleteUser(ctx context.Context, userId int) error {
-	if err := r.db.WithContext(ctx).Delete(&models.User{}, userId).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.User{}, userId)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		event, err := models.NewOutboxEvent(events.TopicUserDeleted, userId, events.UserDeleted{
+			UserId:    userId,
+			DeletedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(event).Error
+	})
+	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "DeleteUser",
@@ -114,3 +192,546 @@ func (r *UserRepository) DeleteUser(ctx context.Context, userId int) error {
 
 	return nil
 }
+
+// RecordLoginFailure counts a failed login of userId. The failure that
+// reaches threshold locks the account for lockFor and starts the count
+// again, and the returned flag reports whether this one did. Failures while
+// the account is locked aren't counted, but still cost the same update, as
+// does a userId that matches no row.
+func (r *UserRepository) RecordLoginFailure(ctx context.Context, userId, threshold int, lockFor time.Duration) (bool, error) {
+	var result struct {
+		Locked bool
+	}
+
+	// The CASEs read the row as it was before the update, so the increment
+	// and the lockout decision are a single atomic step. now() is fixed for
+	// the transaction, so only a lock set by this update equals it plus
+	// lockFor.
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE "user" SET
+			failed_logins = CASE
+				WHEN locked_until > now() THEN failed_logins
+				WHEN failed_logins + 1 >= ? THEN 0
+				ELSE failed_logins + 1 END,
+			locked_until = CASE
+				WHEN locked_until > now() THEN locked_until
+				WHEN failed_logins + 1 >= ? THEN now() + ? * interval '1 millisecond'
+				ELSE locked_until END
+		WHERE id = ?
+		RETURNING COALESCE(locked_until = now() + ? * interval '1 millisecond', false) AS locked`,
+		threshold, threshold, lockFor.Milliseconds(), userId, lockFor.Milliseconds(),
+	).Scan(&result).Error
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "RecordLoginFailure",
+			"error":  err.Error(),
+		}).Errorf("failed to record login failure: %v", err)
+
+		return false, err
+	}
+
+	return result.Locked, nil
+}
+
+// ResetLoginFailures clears the failure count and any lockout of userId.
+func (r *UserRepository) ResetLoginFailures(ctx context.Context, userId int) error {
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}).Error
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ResetLoginFailures",
+			"error":  err.Error(),
+		}).Errorf("failed to reset login failures: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that userId confirmed email. It does nothing if
+// the email has changed since, or was already verified.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userId int, email string, at time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND email = ? AND email_verified_at IS NULL", userId, email).
+		Update("email_verified_at", at).Error
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "MarkEmailVerified",
+			"error":  err.Error(),
+		}).Errorf("failed to mark email verified: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserRepository) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "CreatePasswordResetToken",
+			"error":  err.Error(),
+		}).Errorf("failed to create password reset token: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ResetPassword uses up the reset token with tokenHash and sets the
+// password of its user to passwordHash. In the same transaction it voids
+// the user's other reset tokens, lifts any lockout and bumps the token
+// version, which revokes every access token issued so far. A token that is
+// unknown, used or expired at now yields ErrInvalidToken.
+func (r *UserRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int, error) {
+	var userId int
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+
+		result := tx.Model(&token).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "user_id"}}}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrInvalidToken
+		}
+
+		userId = token.UserId
+
+		err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userId).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+			"password":      passwordHash,
+			"failed_logins": 0,
+			"locked_until":  nil,
+			"token_version": gorm.Expr("token_version + 1"),
+			"version":       gorm.Expr("version + 1"),
+		}).Error
+	})
+	if errors.Is(err, models.ErrInvalidToken) {
+		return 0, err
+	}
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ResetPassword",
+			"error":  err.Error(),
+		}).Errorf("failed to reset password: %v", err)
+
+		return 0, err
+	}
+
+	return userId, nil
+}
+
+// SetMFASecret stores the sealed secret of an enrolment that still has to
+// be confirmed, replacing any earlier unconfirmed one.
+func (r *UserRepository) SetMFASecret(ctx context.Context, userId int, secret string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND mfa_enabled = FALSE", userId).
+		Updates(map[string]interface{}{
+			"mfa_secret":    secret,
+			"mfa_last_step": nil,
+		})
+	if err := result.Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "SetMFASecret",
+			"error":  err.Error(),
+		}).Errorf("failed to set mfa secret: %v", err)
+
+		return err
+	}
+
+	if result.RowsAffected == 0 {
+		return models.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableMFA confirms the enrolment of userId, recording step as used, and
+// replaces its recovery codes.
+func (r *UserRepository) EnableMFA(ctx context.Context, userId int, step int64, codeHashes []string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND mfa_enabled = FALSE AND mfa_secret IS NOT NULL", userId).
+			Updates(map[string]interface{}{
+				"mfa_enabled":   true,
+				"mfa_last_step": step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrMFAAlreadyEnabled
+		}
+
+		if err := tx.Where("user_id = ?", userId).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.MFARecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.MFARecoveryCode{UserId: userId, CodeHash: hash})
+		}
+
+		return tx.Create(&codes).Error
+	})
+	if errors.Is(err, models.ErrMFAAlreadyEnabled) {
+		return err
+	}
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "EnableMFA",
+			"error":  err.Error(),
+		}).Errorf("failed to enable mfa: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// UseTOTPStep records that a code of step was accepted for userId. It
+// reports false if that step or a later one was already used, which is
+// how a code is kept from being replayed.
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userId int, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND (mfa_last_step IS NULL OR mfa_last_step < ?)", userId, step).
+		Update("mfa_last_step", step)
+	if err := result.Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "UseTOTPStep",
+			"error":  err.Error(),
+		}).Errorf("failed to use totp step: %v", err)
+
+		return false, err
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// UseRecoveryCode uses up the recovery code of userId with codeHash and
+// reports whether there was an unused one.
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", now)
+	if err := result.Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "UseRecoveryCode",
+			"error":  err.Error(),
+		}).Errorf("failed to use recovery code: %v", err)
+
+		return false, err
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// GetUserByIdentity returns the user linked to subject at provider.
+func (r *UserRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Joins(`JOIN user_identity ON user_identity.user_id = "user".id`).
+		Where("user_identity.provider = ? AND user_identity.subject = ?", provider, subject).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "GetUserByIdentity",
+			"error":  err.Error(),
+		}).Errorf("failed to get user by identity: %v", err)
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	if err := r.db.WithContext(ctx).Create(identity).Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "CreateIdentity",
+			"error":  err.Error(),
+		}).Errorf("failed to create identity: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity creates user and links identity to it in one
+// transaction.
+func (r *UserRepository) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		identity.UserId = user.Id
+
+		if err := tx.Create(identity).Error; err != nil {
+			return err
+		}
+
+		return createUserCreatedEvent(tx, user)
+	})
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "CreateUserWithIdentity",
+			"error":  err.Error(),
+		}).Errorf("failed to create user with identity: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ListUsers returns a page of users matching query, oldest first, and how
+// many match in total.
+func (r *UserRepository) ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.User{})
+	if query.Role != nil {
+		db = db.Where("role = ?", *query.Role)
+	}
+	if query.Suspended != nil {
+		if *query.Suspended {
+			db = db.Where("suspended_at IS NOT NULL")
+		} else {
+			db = db.Where("suspended_at IS NULL")
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ListUsers",
+			"error":  err.Error(),
+		}).Errorf("failed to count users: %v", err)
+
+		return nil, 0, err
+	}
+
+	var users []models.User
+	if err := db.Order("id").Offset(query.Offset).Limit(query.Limit).Find(&users).Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ListUsers",
+			"error":  err.Error(),
+		}).Errorf("failed to list users: %v", err)
+
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// SetSuspended suspends userId as of at, or lifts its suspension when at
+// is nil. Either way the user's tokens are revoked.
+func (r *UserRepository) SetSuspended(ctx context.Context, userId int, at *time.Time) error {
+	return r.updateAccess(ctx, "SetSuspended", userId, map[string]interface{}{
+		"suspended_at": at,
+	})
+}
+
+// SetRole changes the role of userId and revokes its tokens, which carry
+// the old role.
+func (r *UserRepository) SetRole(ctx context.Context, userId int, role models.Role) error {
+	return r.updateAccess(ctx, "SetRole", userId, map[string]interface{}{
+		"role": role,
+	})
+}
+
+func (r *UserRepository) updateAccess(ctx context.Context, fn string, userId int, updates map[string]interface{}) error {
+	updates["token_version"] = gorm.Expr("token_version + 1")
+	updates["version"] = gorm.Expr("version + 1")
+
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userId).Updates(updates)
+	if err := result.Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   fn,
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to update user: %v", err)
+
+		return err
+	}
+
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateProfile writes the profile fields of user.
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.Id).Updates(map[string]interface{}{
+		"display_name": user.DisplayName,
+		"bio":          user.Bio,
+		"timezone":     user.Timezone,
+		"locale":       user.Locale,
+		"pronouns":     user.Pronouns,
+		"searchable":   user.Searchable,
+		"version":      gorm.Expr("version + 1"),
+	})
+	if err := result.Error; err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "UpdateProfile",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to update profile: %v", err)
+
+		return err
+	}
+
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	user.Version++
+
+	return nil
+}
+
+// SetAvatar points userId at the avatar stored under key, or at none when
+// key is nil, and returns the key it replaces so its image can be removed.
+func (r *UserRepository) SetAvatar(ctx context.Context, userId int, key *string) (*string, error) {
+	var previous *string
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "avatar_key").First(&user, userId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		previous = user.AvatarKey
+
+		return tx.Model(&models.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+			"avatar_key": key,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	})
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "SetAvatar",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to set avatar: %v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
+// SearchUsers finds searchable, unsuspended users for search. Both the
+// prefix and the similarity conditions can use the trigram indexes on
+// lower(username) and lower(display_name). Pages are keyset paginated on
+// (prefix, score, id), so a page costs the same however deep it is.
+func (r *UserRepository) SearchUsers(ctx context.Context, search models.UserSearch) ([]models.UserMatch, error) {
+	term := strings.ToLower(search.Term)
+	prefix := escapeLike(term) + "%"
+
+	inner := r.db.WithContext(ctx).Model(&models.User{}).
+		Select(`*,
+			(lower(username) LIKE ? OR lower(display_name) LIKE ?) AS prefix,
+			GREATEST(similarity(lower(username), ?), similarity(lower(display_name), ?)) AS score`,
+			prefix, prefix, term, term).
+		Where("suspended_at IS NULL AND searchable").
+		Where(`lower(username) LIKE @prefix OR lower(display_name) LIKE @prefix
+			OR lower(username) % @term OR lower(display_name) % @term`,
+			sql.Named("prefix", prefix), sql.Named("term", term))
+
+	query := r.db.WithContext(ctx).Table("(?) AS matches", inner)
+	if after := search.After; after != nil {
+		// Ordered by prefix and score descending and id ascending, the
+		// matches after the cursor are those below it on (prefix, score,
+		// -id).
+		query = query.Where("(prefix::int, score, -id) < (?, ?, ?)", boolToInt(after.Prefix), after.Score, -after.Id)
+	}
+
+	var matches []models.UserMatch
+	err := query.Order("prefix DESC, score DESC, id").Limit(search.Limit).Find(&matches).Error
+	if err != nil {
+		r.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "SearchUsers",
+			"error":  err.Error(),
+		}).Errorf("failed to search users: %v", err)
+
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// escapeLike quotes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// createUserCreatedEvent records user.created for user in the outbox as part
+// of tx, the transaction that created the user.
+func createUserCreatedEvent(tx *gorm.DB, user *models.User) error {
+	event, err := models.NewOutboxEvent(events.TopicUserCreated, user.Id, events.UserCreated{
+		UserId:    user.Id,
+		Username:  user.Name,
+		CreatedAt: user.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(event).Error
+}
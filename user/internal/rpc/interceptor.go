@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/userclient"
+	"github.com/dmitriysta/messenger/pkg/userpb"
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	pkgerrors "github.com/dmitriysta/messenger/user/internal/pkg/errors"
+	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const bearerSchema = "Bearer "
+
+// userMethods can also be called by users with their own access token.
+// The rest are for other services only.
+var userMethods = map[string]bool{
+	userpb.UserService_GetUser_FullMethodName:  true,
+	userpb.UserService_GetUsers_FullMethodName: true,
+}
+
+type principalKey struct{}
+
+// TracingInterceptor starts a server span for each call, continuing the
+// trace of the caller when it sent one.
+func TracingInterceptor(tracer opentracing.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		parent, _ := tracer.Extract(opentracing.TextMap, userclient.MetadataCarrier(md))
+
+		span := tracer.StartSpan(info.FullMethod, opentracing.ChildOf(parent), opentracing.Tag{Key: "span.kind", Value: "server"})
+		defer span.Finish()
+
+		resp, err := handler(opentracing.ContextWithSpan(ctx, span), req)
+		if err != nil {
+			span.SetTag("error", true)
+			span.LogKV("error", err.Error())
+		}
+
+		return resp, err
+	}
+}
+
+// MetricsInterceptor counts and times calls by method and status code.
+func MetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		elapsed := time.Since(start)
+		code := status.Code(err).String()
+
+		metrics.GRPCRequestCount.WithLabelValues(info.FullMethod, code).Inc()
+		metrics.GRPCResponseTime.WithLabelValues(info.FullMethod).Observe(elapsed.Seconds())
+
+		if err != nil {
+			metrics.GRPCErrorCount.WithLabelValues(info.FullMethod, code).Inc()
+		}
+
+		return resp, err
+	}
+}
+
+// AuthInterceptor admits other services that send one of serviceTokens in
+// x-service-token metadata. For userMethods it also admits users with a
+// valid, unrevoked access token in authorization, and puts who they are
+// into the context. With no service tokens configured only those users
+// get in.
+func AuthInterceptor(serviceTokens []string, userService interfaces.UserService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if token := first(md, userclient.MetadataServiceToken); token != "" {
+			if len(serviceTokens) == 0 {
+				return nil, status.Error(codes.PermissionDenied, pkgerrors.ErrorServiceDisabled)
+			}
+			if !validServiceToken(token, serviceTokens) {
+				return nil, status.Error(codes.Unauthenticated, pkgerrors.ErrorInvalidServiceToken)
+			}
+
+			return handler(ctx, req)
+		}
+
+		authorization := first(md, "authorization")
+		if !userMethods[info.FullMethod] || !strings.HasPrefix(authorization, bearerSchema) {
+			return nil, status.Error(codes.Unauthenticated, "missing credentials")
+		}
+
+		principal, err := userService.ValidateAccessToken(ctx, authorization[len(bearerSchema):])
+		if errors.Is(err, models.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, pkgerrors.ErrorAuthenticatingUser)
+		}
+
+		return handler(context.WithValue(ctx, principalKey{}, *principal), req)
+	}
+}
+
+// principalFromContext returns the user AuthInterceptor admitted, if it
+// wasn't a service.
+func principalFromContext(ctx context.Context) (models.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(models.Principal)
+	return principal, ok
+}
+
+// validServiceToken compares token with every one of tokens, so the time
+// taken doesn't tell which one came close.
+func validServiceToken(token string, tokens []string) bool {
+	valid := 0
+	for _, t := range tokens {
+		valid |= subtle.ConstantTimeCompare([]byte(token), []byte(t))
+	}
+
+	return valid == 1
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,217 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dmitriysta/messenger/pkg/userclient"
+	"github.com/dmitriysta/messenger/pkg/userpb"
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// callRecorder is a handler that remembers whether and with what context
+// it was called.
+type callRecorder struct {
+	called bool
+	ctx    context.Context
+}
+
+func (r *callRecorder) handle(ctx context.Context, req interface{}) (interface{}, error) {
+	r.called = true
+	r.ctx = ctx
+	return "ok", nil
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	principal := &models.Principal{UserId: 7, Role: models.RoleUser}
+
+	tests := []struct {
+		name          string
+		serviceTokens []string
+		method        string
+		md            metadata.MD
+		validate      func(userService *mocks.UserService)
+		wantCode      codes.Code
+		wantPrincipal bool
+	}{
+		{
+			name:          "service on a service method",
+			serviceTokens: []string{"old", "current"},
+			method:        userpb.UserService_Authenticate_FullMethodName,
+			md:            metadata.Pairs(userclient.MetadataServiceToken, "current"),
+			wantCode:      codes.OK,
+		},
+		{
+			name:          "service on a user method",
+			serviceTokens: []string{"current"},
+			method:        userpb.UserService_GetUsers_FullMethodName,
+			md:            metadata.Pairs(userclient.MetadataServiceToken, "current"),
+			wantCode:      codes.OK,
+		},
+		{
+			name:          "wrong service token",
+			serviceTokens: []string{"current"},
+			method:        userpb.UserService_GetUsers_FullMethodName,
+			md:            metadata.Pairs(userclient.MetadataServiceToken, "guess"),
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:     "service token without any configured",
+			method:   userpb.UserService_GetUsers_FullMethodName,
+			md:       metadata.Pairs(userclient.MetadataServiceToken, "current"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			// A service token that doesn't match isn't tried as a user
+			// token instead.
+			name:          "wrong service token next to a user token",
+			serviceTokens: []string{"current"},
+			method:        userpb.UserService_GetUser_FullMethodName,
+			md:            metadata.Pairs(userclient.MetadataServiceToken, "guess", "authorization", "Bearer access"),
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:   "user on a user method",
+			method: userpb.UserService_GetUser_FullMethodName,
+			md:     metadata.Pairs("authorization", "Bearer access"),
+			validate: func(userService *mocks.UserService) {
+				userService.On("ValidateAccessToken", mock.Anything, "access").Return(principal, nil)
+			},
+			wantCode:      codes.OK,
+			wantPrincipal: true,
+		},
+		{
+			name:          "user on a service method",
+			serviceTokens: []string{"current"},
+			method:        userpb.UserService_Authenticate_FullMethodName,
+			md:            metadata.Pairs("authorization", "Bearer access"),
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "user validating tokens",
+			serviceTokens: []string{"current"},
+			method:        userpb.UserService_ValidateToken_FullMethodName,
+			md:            metadata.Pairs("authorization", "Bearer access"),
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:   "invalid user token",
+			method: userpb.UserService_GetUser_FullMethodName,
+			md:     metadata.Pairs("authorization", "Bearer revoked"),
+			validate: func(userService *mocks.UserService) {
+				userService.On("ValidateAccessToken", mock.Anything, "revoked").Return(nil, models.ErrInvalidToken)
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:   "token check failing",
+			method: userpb.UserService_GetUser_FullMethodName,
+			md:     metadata.Pairs("authorization", "Bearer access"),
+			validate: func(userService *mocks.UserService) {
+				userService.On("ValidateAccessToken", mock.Anything, "access").Return(nil, errors.New("connection reset"))
+			},
+			wantCode: codes.Internal,
+		},
+		{
+			name:     "not a bearer token",
+			method:   userpb.UserService_GetUser_FullMethodName,
+			md:       metadata.Pairs("authorization", "Basic dXNlcjpwYXNz"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "no credentials",
+			method:   userpb.UserService_GetUser_FullMethodName,
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService := new(mocks.UserService)
+			if tt.validate != nil {
+				tt.validate(userService)
+			}
+
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			handler := &callRecorder{}
+			interceptor := AuthInterceptor(tt.serviceTokens, userService)
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler.handle)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCode == codes.OK, handler.called)
+			if handler.called {
+				got, ok := principalFromContext(handler.ctx)
+				assert.Equal(t, tt.wantPrincipal, ok)
+				if tt.wantPrincipal {
+					assert.Equal(t, *principal, got)
+				}
+			}
+			userService.AssertExpectations(t)
+		})
+	}
+}
+
+// The client interceptor of userclient and the server interceptor here
+// have to agree on how a trace travels in metadata.
+func TestTracingInterceptor_ContinuesClientTrace(t *testing.T) {
+	tracer := mocktracer.New()
+
+	parent := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	var sent metadata.MD
+	client := userclient.TracingInterceptor(tracer)
+	err := client(ctx, userpb.UserService_GetUsers_FullMethodName, nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	})
+	require.NoError(t, err)
+	parent.Finish()
+
+	handler := &callRecorder{}
+	server := TracingInterceptor(tracer)
+	_, err = server(metadata.NewIncomingContext(context.Background(), sent), nil, &grpc.UnaryServerInfo{FullMethod: userpb.UserService_GetUsers_FullMethodName}, handler.handle)
+	require.NoError(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 3)
+	clientSpan, serverSpan := spans[0], spans[2]
+
+	assert.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.SpanID, clientSpan.ParentID)
+	assert.Equal(t, clientSpan.SpanContext.TraceID, serverSpan.SpanContext.TraceID)
+	assert.Equal(t, clientSpan.SpanContext.SpanID, serverSpan.ParentID)
+	assert.Equal(t, "server", serverSpan.Tag("span.kind"))
+	assert.Equal(t, serverSpan, opentracing.SpanFromContext(handler.ctx))
+}
+
+func TestTracingInterceptor_MarksErrors(t *testing.T) {
+	tracer := mocktracer.New()
+
+	server := TracingInterceptor(tracer)
+	_, err := server(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: userpb.UserService_GetUser_FullMethodName}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	require.Error(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, true, spans[0].Tag("error"))
+	// Without a trace in the metadata the span starts a new one.
+	assert.Zero(t, spans[0].ParentID)
+}
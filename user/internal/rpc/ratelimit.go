@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/pkg/userpb"
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	pkgerrors "github.com/dmitriysta/messenger/user/internal/pkg/errors"
+	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const metadataForwardedFor = "x-forwarded-for"
+
+// limitedMethods maps the methods that check credentials to the HTTP
+// route doing the same. A call is counted against the per-IP limit of that
+// route, in the same bucket, so switching API doesn't buy more guesses.
+// Other methods are called by services, with no client IP to limit by.
+var limitedMethods = map[string]string{
+	userpb.UserService_Authenticate_FullMethodName: "POST /auth/login",
+}
+
+// RateLimiter applies the per-IP limits of the HTTP API to limitedMethods.
+// The client IP is the peer address, or with trustForwarded the first
+// address in x-forwarded-for metadata, which a service passing on a login
+// sets to the IP of its client. Like the HTTP limiter it lets calls through
+// when redis can't be reached.
+type RateLimiter struct {
+	limiter        interfaces.Limiter
+	policies       ratelimit.Policies
+	trustForwarded bool
+	logger         *logrus.Logger
+}
+
+func NewRateLimiter(limiter interfaces.Limiter, policies ratelimit.Policies, trustForwarded bool, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		limiter:        limiter,
+		policies:       policies,
+		trustForwarded: trustForwarded,
+		logger:         logger,
+	}
+}
+
+// Interceptor fails calls over the limit with RESOURCE_EXHAUSTED.
+func (rl *RateLimiter) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		route, ok := limitedMethods[info.FullMethod]
+		if !ok || !rl.policies.Enabled {
+			return handler(ctx, req)
+		}
+
+		subject := rl.clientIP(ctx)
+		policy := rl.policies.For(ratelimit.ScopeIP, route)
+
+		result, err := rl.limiter.Allow(ctx, ratelimit.ScopeIP+":"+subject+":"+route, policy)
+		if err != nil {
+			rl.logger.WithFields(logrus.Fields{
+				"module": "rpc",
+				"func":   "RateLimiter.Interceptor",
+				"method": info.FullMethod,
+				"route":  route,
+				"error":  err.Error(),
+			}).Warnf("rate limit check failed, allowing call: %v", err)
+
+			return handler(ctx, req)
+		}
+
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(ratelimit.ScopeIP, route).Inc()
+			retryAfter := time.Duration(ratelimit.CeilSeconds(result.RetryAfter)) * time.Second
+			return nil, status.Errorf(codes.ResourceExhausted, "%s, retry after %s", pkgerrors.ErrorRateLimited, retryAfter)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (rl *RateLimiter) clientIP(ctx context.Context) string {
+	if rl.trustForwarded {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if forwarded := first(md, metadataForwardedFor); forwarded != "" {
+			client, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(client)
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/ratelimit"
+	"github.com/dmitriysta/messenger/pkg/userpb"
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var testPolicies = ratelimit.Policies{
+	Enabled: true,
+	IP: ratelimit.ScopePolicies{
+		Default: ratelimit.Policy{Limit: 300, Period: time.Minute},
+		Routes:  map[string]ratelimit.Policy{"POST /auth/login": {Limit: 10, Period: time.Minute}},
+	},
+}
+
+var loginPolicy = ratelimit.Policy{Limit: 10, Period: time.Minute}
+
+// peerContext is the context of a call from addr, with md as metadata.
+func peerContext(addr string, md metadata.MD) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	if md != nil {
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+
+	return ctx
+}
+
+func interceptRateLimited(ctx context.Context, rl *RateLimiter, method string) (*callRecorder, error) {
+	handler := &callRecorder{}
+	_, err := rl.Interceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler.handle)
+
+	return handler, err
+}
+
+func TestRateLimiter_Interceptor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustForwarded bool
+		md             metadata.MD
+		wantKey        string
+	}{
+		{name: "peer address", wantKey: "ip:1.2.3.4:POST /auth/login"},
+		{
+			name:    "forwarded but not trusted",
+			md:      metadata.Pairs("x-forwarded-for", "5.6.7.8"),
+			wantKey: "ip:1.2.3.4:POST /auth/login",
+		},
+		{
+			name:           "forwarded and trusted",
+			trustForwarded: true,
+			md:             metadata.Pairs("x-forwarded-for", "5.6.7.8, 10.0.0.1"),
+			wantKey:        "ip:5.6.7.8:POST /auth/login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := new(mocks.Limiter)
+			logger, _ := test.NewNullLogger()
+			rl := NewRateLimiter(limiter, testPolicies, tt.trustForwarded, logger)
+
+			// The same key as the HTTP login, so both APIs share a bucket.
+			limiter.On("Allow", mock.Anything, tt.wantKey, loginPolicy).Return(ratelimit.Result{Allowed: true}, nil).Once()
+
+			handler, err := interceptRateLimited(peerContext("1.2.3.4:5678", tt.md), rl, userpb.UserService_Authenticate_FullMethodName)
+
+			assert.NoError(t, err)
+			assert.True(t, handler.called)
+			limiter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRateLimiter_Interceptor_Limited(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, testPolicies, false, logger)
+
+	limiter.On("Allow", mock.Anything, "ip:1.2.3.4:POST /auth/login", loginPolicy).
+		Return(ratelimit.Result{Allowed: false, RetryAfter: 1500 * time.Millisecond}, nil)
+
+	handler, err := interceptRateLimited(peerContext("1.2.3.4:5678", nil), rl, userpb.UserService_Authenticate_FullMethodName)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "retry after 2s")
+	assert.False(t, handler.called)
+}
+
+func TestRateLimiter_Interceptor_NotLimited(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies ratelimit.Policies
+		method   string
+	}{
+		{name: "service method", policies: testPolicies, method: userpb.UserService_GetUsers_FullMethodName},
+		{name: "disabled", policies: ratelimit.Policies{}, method: userpb.UserService_Authenticate_FullMethodName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := new(mocks.Limiter)
+			logger, _ := test.NewNullLogger()
+			rl := NewRateLimiter(limiter, tt.policies, false, logger)
+
+			handler, err := interceptRateLimited(peerContext("1.2.3.4:5678", nil), rl, tt.method)
+
+			assert.NoError(t, err)
+			assert.True(t, handler.called)
+			limiter.AssertNotCalled(t, "Allow", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRateLimiter_Interceptor_FailsOpen(t *testing.T) {
+	limiter := new(mocks.Limiter)
+	logger, _ := test.NewNullLogger()
+	rl := NewRateLimiter(limiter, testPolicies, false, logger)
+
+	limiter.On("Allow", mock.Anything, mock.Anything, mock.Anything).Return(ratelimit.Result{}, errors.New("connection refused"))
+
+	handler, err := interceptRateLimited(peerContext("1.2.3.4:5678", nil), rl, userpb.UserService_Authenticate_FullMethodName)
+
+	assert.NoError(t, err)
+	assert.True(t, handler.called)
+}
@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/userpb"
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserServer serves the gRPC API of the user service on top of the same
+// services as the HTTP handlers.
+type UserServer struct {
+	userpb.UnimplementedUserServiceServer
+	userService    interfaces.UserService
+	profileService interfaces.ProfileService
+	logger         *logrus.Logger
+}
+
+func NewUserServer(userService interfaces.UserService, profileService interfaces.ProfileService, logger *logrus.Logger) *UserServer {
+	return &UserServer{
+		userService:    userService,
+		profileService: profileService,
+		logger:         logger,
+	}
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.Profile, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+
+	profile, err := s.profileService.GetProfile(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, s.statusError(ctx, "GetUser", err)
+	}
+
+	return profileMessage(profile), nil
+}
+
+func (s *UserServer) GetUsers(ctx context.Context, req *userpb.GetUsersRequest) (*userpb.GetUsersResponse, error) {
+	ids := make([]int, len(req.GetIds()))
+	for i, id := range req.GetIds() {
+		ids[i] = int(id)
+	}
+
+	batch, err := s.profileService.GetProfiles(ctx, ids)
+	if err != nil {
+		return nil, s.statusError(ctx, "GetUsers", err)
+	}
+
+	resp := &userpb.GetUsersResponse{
+		Users:   make([]*userpb.Profile, len(batch.Users)),
+		Missing: make([]int64, len(batch.Missing)),
+	}
+	for i := range batch.Users {
+		resp.Users[i] = profileMessage(&batch.Users[i])
+	}
+	for i, id := range batch.Missing {
+		resp.Missing[i] = int64(id)
+	}
+
+	return resp, nil
+}
+
+func (s *UserServer) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.Principal, error) {
+	principal, err := s.userService.ValidateAccessToken(ctx, req.GetToken())
+	if err != nil {
+		return nil, s.statusError(ctx, "ValidateToken", err)
+	}
+
+	return &userpb.Principal{UserId: int64(principal.UserId), Role: string(principal.Role)}, nil
+}
+
+func (s *UserServer) Authenticate(ctx context.Context, req *userpb.AuthenticateRequest) (*userpb.AuthenticateResponse, error) {
+	user, token, err := s.userService.AuthenticateUser(ctx, req.GetEmail(), req.GetPassword())
+
+	var mfaRequired *models.MFARequiredError
+	if errors.As(err, &mfaRequired) {
+		return &userpb.AuthenticateResponse{
+			Result: &userpb.AuthenticateResponse_MfaToken{MfaToken: mfaRequired.Token},
+		}, nil
+	}
+	if err != nil {
+		return nil, s.statusError(ctx, "Authenticate", err)
+	}
+
+	return &userpb.AuthenticateResponse{
+		UserId: int64(user.Id),
+		Result: &userpb.AuthenticateResponse_AccessToken{AccessToken: token},
+	}, nil
+}
+
+// statusError turns err into the gRPC status a client can act on. Anything
+// unexpected is logged and hidden behind INTERNAL.
+func (s *UserServer) statusError(ctx context.Context, method string, err error) error {
+	var throttled *models.LoginThrottledError
+
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, models.ErrInvalidBatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrInvalidToken), errors.Is(err, models.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.As(err, &throttled):
+		return status.Errorf(codes.ResourceExhausted, "%v, retry after %s", err, throttled.RetryAfter.Round(time.Second))
+	case errors.Is(err, models.ErrUserSuspended):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, models.ErrEmailNotVerified):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	fields := logrus.Fields{
+		"module": "rpc",
+		"method": method,
+		"error":  err.Error(),
+	}
+	if principal, ok := principalFromContext(ctx); ok {
+		fields["userId"] = principal.UserId
+	}
+	s.logger.WithFields(fields).Errorf("failed to serve %s: %v", method, err)
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func profileMessage(profile *models.Profile) *userpb.Profile {
+	return &userpb.Profile{
+		Id:          int64(profile.Id),
+		Username:    profile.Username,
+		DisplayName: profile.DisplayName,
+		AvatarUrl:   profile.AvatarURL,
+		Bio:         profile.Bio,
+		Timezone:    profile.Timezone,
+		Locale:      profile.Locale,
+		Pronouns:    profile.Pronouns,
+	}
+}
+
+// NewServer returns a gRPC server for userServer. Calls are traced first
+// and measured next, so rejected calls show up in both, then
+// authenticated, and only calls that got in count against rate limits.
+func NewServer(userServer *UserServer, tracer opentracing.Tracer, serviceTokens []string, rateLimiter *RateLimiter) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		TracingInterceptor(tracer),
+		MetricsInterceptor(),
+		AuthInterceptor(serviceTokens, userServer.userService),
+		rateLimiter.Interceptor(),
+	))
+	userpb.RegisterUserServiceServer(server, userServer)
+
+	return server
+}
@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/pkg/userpb"
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestServer() (*UserServer, *mocks.UserService, *mocks.ProfileService) {
+	userService := new(mocks.UserService)
+	profileService := new(mocks.ProfileService)
+	logger, _ := test.NewNullLogger()
+
+	return NewUserServer(userService, profileService, logger), userService, profileService
+}
+
+func TestUserServer_StatusError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{name: "not found", err: models.ErrUserNotFound, wantCode: codes.NotFound, wantMessage: models.ErrUserNotFound.Error()},
+		{name: "wrapped not found", err: fmt.Errorf("get profile: %w", models.ErrUserNotFound), wantCode: codes.NotFound},
+		{name: "invalid batch", err: models.ErrInvalidBatch, wantCode: codes.InvalidArgument},
+		{name: "invalid token", err: models.ErrInvalidToken, wantCode: codes.Unauthenticated},
+		{name: "invalid credentials", err: models.ErrInvalidCredentials, wantCode: codes.Unauthenticated},
+		{
+			name:        "throttled",
+			err:         &models.LoginThrottledError{RetryAfter: 90*time.Second + 300*time.Millisecond},
+			wantCode:    codes.ResourceExhausted,
+			wantMessage: models.ErrLoginThrottled.Error() + ", retry after 1m30s",
+		},
+		{name: "suspended", err: models.ErrUserSuspended, wantCode: codes.PermissionDenied},
+		{name: "email not verified", err: models.ErrEmailNotVerified, wantCode: codes.FailedPrecondition},
+		// Anything else could leak details, so only the code goes out.
+		{name: "unexpected", err: errors.New("pq: connection reset"), wantCode: codes.Internal, wantMessage: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, _ := newTestServer()
+
+			err := server.statusError(context.Background(), "GetUser", tt.err)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, status.Convert(err).Message())
+			}
+		})
+	}
+}
+
+func TestUserServer_GetUser(t *testing.T) {
+	server, _, profileService := newTestServer()
+	ctx := context.Background()
+
+	profileService.On("GetProfile", ctx, 7).Return(&models.Profile{Id: 7, Username: "seven", DisplayName: "Seven"}, nil)
+	profileService.On("GetProfile", ctx, 8).Return(nil, models.ErrUserNotFound)
+
+	profile, err := server.GetUser(ctx, &userpb.GetUserRequest{Id: 7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), profile.GetId())
+	assert.Equal(t, "seven", profile.GetUsername())
+	assert.Equal(t, "Seven", profile.GetDisplayName())
+
+	_, err = server.GetUser(ctx, &userpb.GetUserRequest{Id: 8})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.GetUser(ctx, &userpb.GetUserRequest{Id: 0})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	profileService.AssertNotCalled(t, "GetProfile", ctx, 0)
+}
+
+func TestUserServer_GetUsers(t *testing.T) {
+	server, _, profileService := newTestServer()
+	ctx := context.Background()
+
+	profileService.On("GetProfiles", ctx, []int{7, 9}).Return(&models.ProfileBatch{
+		Users:   []models.Profile{{Id: 7, Username: "seven"}},
+		Missing: []int{9},
+	}, nil)
+
+	resp, err := server.GetUsers(ctx, &userpb.GetUsersRequest{Ids: []int64{7, 9}})
+	require.NoError(t, err)
+	require.Len(t, resp.GetUsers(), 1)
+	assert.Equal(t, int64(7), resp.GetUsers()[0].GetId())
+	assert.Equal(t, []int64{9}, resp.GetMissing())
+}
+
+func TestUserServer_Authenticate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("access token", func(t *testing.T) {
+		server, userService, _ := newTestServer()
+		userService.On("AuthenticateUser", ctx, "user@example.com", "password").Return(&models.User{Id: 7}, "access", nil)
+
+		resp, err := server.Authenticate(ctx, &userpb.AuthenticateRequest{Email: "user@example.com", Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), resp.GetUserId())
+		assert.Equal(t, "access", resp.GetAccessToken())
+	})
+
+	t.Run("second factor", func(t *testing.T) {
+		server, userService, _ := newTestServer()
+		userService.On("AuthenticateUser", ctx, "user@example.com", "password").Return(nil, "", &models.MFARequiredError{Token: "pending"})
+
+		resp, err := server.Authenticate(ctx, &userpb.AuthenticateRequest{Email: "user@example.com", Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, "pending", resp.GetMfaToken())
+		assert.Empty(t, resp.GetAccessToken())
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		server, userService, _ := newTestServer()
+		userService.On("AuthenticateUser", ctx, "user@example.com", "wrong").Return(nil, "", models.ErrInvalidCredentials)
+
+		_, err := server.Authenticate(ctx, &userpb.AuthenticateRequest{Email: "user@example.com", Password: "wrong"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func (s *UserService) ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, int64, error) {
+	users, total, err := s.repo.ListUsers(ctx, query)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ListUsers",
+			"error":  err.Error(),
+		}).Errorf("failed to list users: %v", err)
+
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// SuspendUser refuses further logins to userId and revokes its tokens.
+// Suspending a suspended user changes nothing.
+func (s *UserService) SuspendUser(ctx context.Context, actor models.Principal, userId int) error {
+	user, err := s.managedUser(ctx, actor, userId, "SuspendUser")
+	if err != nil {
+		return err
+	}
+
+	if user.IsSuspended() {
+		return nil
+	}
+
+	now := s.now()
+	if err := s.repo.SetSuspended(ctx, userId, &now); err != nil {
+		return err
+	}
+
+	forgetProfile(ctx, s.cache, s.logger, "SuspendUser", userId)
+
+	s.logger.WithFields(logrus.Fields{
+		"module":  "user",
+		"func":    "SuspendUser",
+		"userId":  userId,
+		"actorId": actor.UserId,
+	}).Info("account suspended")
+
+	return nil
+}
+
+func (s *UserService) UnsuspendUser(ctx context.Context, actor models.Principal, userId int) error {
+	user, err := s.managedUser(ctx, actor, userId, "UnsuspendUser")
+	if err != nil {
+		return err
+	}
+
+	if !user.IsSuspended() {
+		return nil
+	}
+
+	if err := s.repo.SetSuspended(ctx, userId, nil); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module":  "user",
+		"func":    "UnsuspendUser",
+		"userId":  userId,
+		"actorId": actor.UserId,
+	}).Info("account suspension lifted")
+
+	return nil
+}
+
+// SetUserRole gives userId role. Only admins can change roles, and not
+// their own, so the last admin can't demote themselves by mistake.
+func (s *UserService) SetUserRole(ctx context.Context, actor models.Principal, userId int, role models.Role) error {
+	if !actor.IsAdmin() {
+		return models.ErrForbidden
+	}
+
+	user, err := s.managedUser(ctx, actor, userId, "SetUserRole")
+	if err != nil {
+		return err
+	}
+
+	if user.Role == role {
+		return nil
+	}
+
+	if err := s.repo.SetRole(ctx, userId, role); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module":  "user",
+		"func":    "SetUserRole",
+		"userId":  userId,
+		"actorId": actor.UserId,
+		"from":    user.Role,
+		"to":      role,
+	}).Info("role changed")
+
+	return nil
+}
+
+// managedUser loads userId for actor to act on. Nobody acts on their own
+// account this way, and moderators only act on ordinary users.
+func (s *UserService) managedUser(ctx context.Context, actor models.Principal, userId int, fn string) (*models.User, error) {
+	if actor.UserId == userId {
+		return nil, models.ErrForbidden
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   fn,
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return nil, err
+	}
+
+	if !actor.IsAdmin() && user.Role != models.RoleUser {
+		return nil, models.ErrForbidden
+	}
+
+	return user, nil
+}
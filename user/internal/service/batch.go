@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetProfiles returns the public profiles of userIds in one go, for other
+// services rendering many users at once. Profiles are served from the
+// cache where possible and the rest loaded together and cached. The cache
+// fails open: if it can't be reached, everything is loaded from the
+// database and nothing is cached.
+func (s *ProfileService) GetProfiles(ctx context.Context, userIds []int) (*models.ProfileBatch, error) {
+	ids := make([]int, 0, len(userIds))
+	seen := make(map[int]bool, len(userIds))
+	for _, id := range userIds {
+		if id <= 0 {
+			return nil, fmt.Errorf("%w: %d is not a user id", models.ErrInvalidBatch, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: no user ids", models.ErrInvalidBatch)
+	}
+	if len(ids) > s.batch.MaxIds {
+		return nil, fmt.Errorf("%w: at most %d user ids", models.ErrInvalidBatch, s.batch.MaxIds)
+	}
+
+	profiles, versions, err := s.cache.GetMany(ctx, ids)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "profile",
+			"func":   "GetProfiles",
+			"error":  err.Error(),
+		}).Warnf("failed to read cached profiles: %v", err)
+
+		profiles = make(map[int]*models.Profile, len(ids))
+	}
+
+	var misses []int
+	for _, id := range ids {
+		if profiles[id] == nil {
+			misses = append(misses, id)
+		}
+	}
+
+	if len(misses) > 0 {
+		users, err := s.repo.GetUsersByIds(ctx, misses)
+		if err != nil {
+			return nil, err
+		}
+
+		loaded := make([]models.Profile, 0, len(users))
+		for i := range users {
+			// Suspended users have no public profile. Nothing is cached
+			// for them, so lifting the suspension needs no invalidation
+			// to show them again.
+			if users[i].IsSuspended() {
+				continue
+			}
+
+			profile := s.profile(&users[i])
+			profiles[profile.Id] = profile
+			loaded = append(loaded, *profile)
+		}
+
+		// Without the versions read before the load, a profile changed in
+		// the meantime could be cached stale, so nothing is cached.
+		if versions != nil {
+			if err := s.cache.SetMany(ctx, loaded, versions, s.batch.CacheTTL); err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"module": "profile",
+					"func":   "GetProfiles",
+					"error":  err.Error(),
+				}).Warnf("failed to cache profiles: %v", err)
+			}
+		}
+	}
+
+	batch := &models.ProfileBatch{
+		Users:   make([]models.Profile, 0, len(ids)),
+		Missing: make([]int, 0),
+	}
+	for _, id := range ids {
+		if profile := profiles[id]; profile != nil {
+			batch.Users = append(batch.Users, *profile)
+		} else {
+			batch.Missing = append(batch.Missing, id)
+		}
+	}
+
+	return batch, nil
+}
+
+// forgetProfile invalidates the cached profile of userId after it changed.
+// Failing to is only logged: the change is already saved, and the stale
+// profile expires with the cache TTL.
+func forgetProfile(ctx context.Context, cache interfaces.ProfileCache, logger *logrus.Logger, fn string, userId int) {
+	if err := cache.Delete(ctx, userId); err != nil {
+		logger.WithFields(logrus.Fields{
+			"module": "profile",
+			"func":   fn,
+			"userId": userId,
+			"error":  err.Error(),
+		}).Warnf("failed to invalidate cached profile: %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/user/internal/pkg/totp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	mfaPendingPurpose = "mfa_pending"
+
+	// totpSkew accepts the codes of one step either side of the current
+	// one, for clocks that are a little off.
+	totpSkew = 1
+)
+
+// EnrollMFA starts two-factor enrolment of userId with a new TOTP secret.
+// Codes aren't required until the enrolment is confirmed with ConfirmMFA;
+// enrolling again before that replaces the secret.
+func (s *UserService) EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error) {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "EnrollMFA",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.box.Seal(secret, mfaSecretContext(user.Id))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "EnrollMFA",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to seal mfa secret: %v", err)
+
+		return nil, err
+	}
+
+	if err := s.repo.SetMFASecret(ctx, user.Id, sealed); err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(s.mfa.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA turns two-factor authentication on once code shows the
+// authenticator app was set up, and returns the recovery codes. They are
+// shown this once; only their hashes are kept.
+func (s *UserService) ConfirmMFA(ctx context.Context, userId int, code string) ([]string, error) {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ConfirmMFA",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	if user.MFASecret == nil {
+		return nil, models.ErrMFANotEnrolled
+	}
+
+	secret, err := s.box.Open(*user.MFASecret, mfaSecretContext(user.Id))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ConfirmMFA",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to open mfa secret: %v", err)
+
+		return nil, err
+	}
+
+	step, ok := totp.Validate(secret, normalizeCode(code), s.now(), totpSkew)
+	if !ok {
+		return nil, models.ErrInvalidMFACode
+	}
+
+	codes := make([]string, s.mfa.RecoveryCodes)
+	hashes := make([]string, s.mfa.RecoveryCodes)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashToken(normalizeCode(codes[i]))
+	}
+
+	if err := s.repo.EnableMFA(ctx, user.Id, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module": "user",
+		"func":   "ConfirmMFA",
+		"userId": user.Id,
+	}).Info("two-factor authentication enabled")
+
+	return codes, nil
+}
+
+// VerifyMFA completes a login that AuthenticateUser answered with an
+// MFARequiredError. code is either the current TOTP code or an unused
+// recovery code. Wrong codes count towards the account lockout like wrong
+// passwords do.
+func (s *UserService) VerifyMFA(ctx context.Context, mfaToken, code string) (*models.User, string, error) {
+	userId, version, err := s.parsePendingToken(mfaToken)
+	if err != nil {
+		return nil, "", models.ErrInvalidToken
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil, "", models.ErrInvalidToken
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "VerifyMFA",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return nil, "", err
+	}
+
+	if user.TokenVersion != version || !user.MFAEnabled || user.MFASecret == nil {
+		return nil, "", models.ErrInvalidToken
+	}
+
+	if user.IsLocked(s.now()) {
+		metrics.LoginFailures.WithLabelValues("locked").Inc()
+		return nil, "", models.ErrInvalidMFACode
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !ok {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "VerifyMFA",
+			"userId": user.Id,
+		}).Warn("failed to authenticate user: wrong second factor")
+
+		metrics.LoginFailures.WithLabelValues("wrong_mfa_code").Inc()
+		s.recordAccountFailure(ctx, user)
+
+		return nil, "", models.ErrInvalidMFACode
+	}
+
+	s.resetAccountFailures(ctx, user)
+
+	token, err := s.accessToken(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, token, nil
+}
+
+func (s *UserService) checkSecondFactor(ctx context.Context, user *models.User, code string) (bool, error) {
+	code = normalizeCode(code)
+
+	if _, err := strconv.Atoi(code); err != nil || len(code) != totp.Digits {
+		return s.repo.UseRecoveryCode(ctx, user.Id, hashToken(code), s.now())
+	}
+
+	secret, err := s.box.Open(*user.MFASecret, mfaSecretContext(user.Id))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "checkSecondFactor",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to open mfa secret: %v", err)
+
+		return false, err
+	}
+
+	step, ok := totp.Validate(secret, code, s.now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	return s.repo.UseTOTPStep(ctx, user.Id, step)
+}
+
+// pendingToken is what AuthenticateUser hands out instead of an access
+// token when a second factor is needed. Its purpose claim keeps it from
+// being accepted as an access token.
+func (s *UserService) pendingToken(user *models.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":      user.Id,
+		"ver":     user.TokenVersion,
+		"purpose": mfaPendingPurpose,
+		"exp":     s.now().Add(s.mfa.PendingTTL).Unix(),
+	})
+
+	return token.SignedString([]byte(s.jwt.Secret))
+}
+
+func (s *UserService) parsePendingToken(tokenString string) (int, int, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.jwt.Secret), nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != mfaPendingPurpose {
+		return 0, 0, errors.New("not an mfa pending token")
+	}
+
+	userId, ok := claims["id"].(float64)
+	version, versionOk := claims["ver"].(float64)
+	if !ok || !versionOk {
+		return 0, 0, errors.New("malformed mfa pending token")
+	}
+
+	return int(userId), int(version), nil
+}
+
+// mfaSecretContext binds a sealed secret to its user, so it can't be
+// copied onto another account.
+func mfaSecretContext(userId int) string {
+	return "user:" + strconv.Itoa(userId) + ":mfa"
+}
+
+// newRecoveryCode returns ten random base32 characters as xxxxx-xxxxx.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeCode drops the separators people type into codes.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
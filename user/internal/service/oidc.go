@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/dmitriysta/messenger/user/internal/pkg/oidc"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OIDCService signs users in through external OpenID Connect providers.
+type OIDCService struct {
+	users     *UserService
+	repo      interfaces.UserRepository
+	providers map[string]interfaces.IdentityProvider
+	states    interfaces.OIDCStateStore
+	cfg       config.OIDCConfig
+	logger    *logrus.Logger
+}
+
+func NewOIDCService(users *UserService, repo interfaces.UserRepository, providers map[string]interfaces.IdentityProvider, states interfaces.OIDCStateStore, cfg config.OIDCConfig, logger *logrus.Logger) *OIDCService {
+	return &OIDCService{
+		users:     users,
+		repo:      repo,
+		providers: providers,
+		states:    states,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// StartLogin returns the provider URL to send the user to, and the binding
+// the browser has to present to CompleteLogin. The state, nonce and PKCE
+// verifier of the attempt are kept for CompleteLogin.
+func (s *OIDCService) StartLogin(ctx context.Context, provider string) (string, string, error) {
+	idp, ok := s.providers[provider]
+	if !ok {
+		return "", "", models.ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	binding, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := idp.AuthCodeURL(ctx, state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"func":     "StartLogin",
+			"provider": provider,
+			"error":    err.Error(),
+		}).Errorf("failed to build authorization url: %v", err)
+
+		return "", "", err
+	}
+
+	err = s.states.Save(ctx, state, models.OIDCState{
+		Provider:    provider,
+		Nonce:       nonce,
+		Verifier:    verifier,
+		BindingHash: oidc.HashBinding(binding),
+	}, s.cfg.StateTTL)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"func":     "StartLogin",
+			"provider": provider,
+			"error":    err.Error(),
+		}).Errorf("failed to save login state: %v", err)
+
+		return "", "", err
+	}
+
+	return authURL, binding, nil
+}
+
+// CompleteLogin handles the provider's redirect back and signs the user in.
+//
+// The browser has to present the binding StartLogin handed to it, so a
+// callback URL of someone else's login is refused.
+//
+// An identity seen before signs in its linked user. Otherwise it is linked
+// by email, which the provider has to have verified: to the existing account
+// with that email if there is one, or to a new account provisioned on the
+// spot. An existing account whose email was never verified isn't linked,
+// since whoever registered it hasn't shown they own the address.
+//
+// Suspended accounts are refused. Accounts with two-factor authentication
+// get an MFARequiredError like a password login does.
+func (s *OIDCService) CompleteLogin(ctx context.Context, provider, state, binding, code string) (*models.User, string, error) {
+	idp, ok := s.providers[provider]
+	if !ok {
+		return nil, "", models.ErrUnknownProvider
+	}
+
+	saved, err := s.states.Take(ctx, state)
+	if errors.Is(err, models.ErrInvalidToken) {
+		return nil, "", err
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"func":     "CompleteLogin",
+			"provider": provider,
+			"error":    err.Error(),
+		}).Errorf("failed to load login state: %v", err)
+
+		return nil, "", err
+	}
+
+	// The state is used up either way, so a callback from another browser
+	// can be tried only once.
+	if saved.BindingHash == "" || subtle.ConstantTimeCompare([]byte(saved.BindingHash), []byte(oidc.HashBinding(binding))) != 1 {
+		return nil, "", models.ErrInvalidToken
+	}
+
+	if saved.Provider != provider {
+		return nil, "", models.ErrInvalidToken
+	}
+
+	identity, err := idp.Exchange(ctx, code, saved.Verifier, saved.Nonce)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"func":     "CompleteLogin",
+			"provider": provider,
+			"error":    err.Error(),
+		}).Warnf("failed to exchange authorization code: %v", err)
+
+		return nil, "", models.ErrInvalidToken
+	}
+
+	user, err := s.resolveUser(ctx, identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if user.IsSuspended() {
+		return nil, "", models.ErrUserSuspended
+	}
+
+	if user.MFAEnabled {
+		pending, err := s.users.pendingToken(user)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return nil, "", &models.MFARequiredError{Token: pending}
+	}
+
+	token, err := s.users.accessToken(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, token, nil
+}
+
+func (s *OIDCService) resolveUser(ctx context.Context, identity *models.ExternalIdentity) (*models.User, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, models.ErrExternalEmailNotVerified
+	}
+
+	link := &models.UserIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	user, err = s.repo.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if !user.IsEmailVerified() {
+			s.logger.WithFields(logrus.Fields{
+				"module":   "oidc",
+				"func":     "resolveUser",
+				"provider": identity.Provider,
+				"userId":   user.Id,
+			}).Warn("refused to link identity to unverified account")
+
+			return nil, models.ErrIdentityConflict
+		}
+
+		link.UserId = user.Id
+		if err := s.repo.CreateIdentity(ctx, link); err != nil {
+			return nil, err
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"func":     "resolveUser",
+			"provider": identity.Provider,
+			"userId":   user.Id,
+		}).Info("linked identity to existing account")
+
+		return user, nil
+	case errors.Is(err, models.ErrUserNotFound):
+		return s.provision(ctx, identity, link)
+	default:
+		return nil, err
+	}
+}
+
+// provision creates an account for an identity. It gets a random password
+// nobody knows; the user can set one through a password reset.
+func (s *OIDCService) provision(ctx context.Context, identity *models.ExternalIdentity, link *models.UserIdentity) (*models.User, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	user, err := models.NewUser(name, identity.Email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiedAt := s.users.now()
+	user.EmailVerifiedAt = &verifiedAt
+
+	// Usernames are unique; if the provider's is taken, retry with a
+	// random suffix.
+	for attempt := 0; ; attempt++ {
+		err = s.repo.CreateUserWithIdentity(ctx, user, link)
+		if err == nil || attempt == 2 {
+			break
+		}
+
+		suffix := make([]byte, 2)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		user.Name = name + "-" + hex.EncodeToString(suffix)
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module":   "oidc",
+			"func":     "provision",
+			"provider": identity.Provider,
+			"error":    err.Error(),
+		}).Errorf("failed to provision user: %v", err)
+
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module":   "oidc",
+		"func":     "provision",
+		"provider": identity.Provider,
+		"userId":   user.Id,
+	}).Info("provisioned user for external identity")
+
+	return user, nil
+}
@@ -0,0 +1,471 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/dmitriysta/messenger/user/internal/pkg/oidc"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testClientID = "messenger"
+
+var testOIDCConfig = config.OIDCConfig{
+	StateTTL: 10 * time.Minute,
+}
+
+// stubIdP is a minimal OpenID Connect provider: it serves discovery, its
+// signing keys and a token endpoint that enforces PKCE. Tests play the
+// user's browser by calling approve with the authorization URL.
+type stubIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]stubGrant
+}
+
+type stubGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &stubIdP{key: key, grants: make(map[string]stubGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		p.mu.Lock()
+		grant, ok := p.grants[r.PostForm.Get("code")]
+		delete(p.grants, r.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		if !ok || oidc.Challenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(p.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, map[string]string{"access_token": "opaque", "id_token": idToken})
+	})
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// approve signs the user in at the provider and returns the state and
+// code it redirects back with. claims override the defaults of the ID
+// token.
+func (p *stubIdP) approve(t *testing.T, authURL string, claims jwt.MapClaims) (string, string) {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	query := u.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.NotEmpty(t, query.Get("code_challenge"))
+
+	idClaims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   query.Get("client_id"),
+		"sub":   "external-1",
+		"nonce": query.Get("nonce"),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+	}
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+
+	code, err := oidc.RandomString()
+	require.NoError(t, err)
+
+	p.mu.Lock()
+	p.grants[code] = stubGrant{challenge: query.Get("code_challenge"), claims: idClaims}
+	p.mu.Unlock()
+
+	return query.Get("state"), code
+}
+
+func (p *stubIdP) provider() oidc.ProviderConfig {
+	return oidc.ProviderConfig{
+		Name:        "stub",
+		Issuer:      p.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "https://example.com/auth/oidc/stub/callback",
+	}
+}
+
+// newTestStateStore returns a state store mock that keeps what is saved
+// and hands it out once.
+func newTestStateStore() *mocks.OIDCStateStore {
+	var mu sync.Mutex
+	states := make(map[string]models.OIDCState)
+
+	store := new(mocks.OIDCStateStore)
+	store.On("Save", mock.Anything, mock.Anything, mock.Anything, testOIDCConfig.StateTTL).
+		Run(func(args mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			states[args.String(1)] = args.Get(2).(models.OIDCState)
+		}).
+		Return(nil)
+	store.On("Take", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, key string) (*models.OIDCState, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			state, ok := states[key]
+			if !ok {
+				return nil, models.ErrInvalidToken
+			}
+			delete(states, key)
+			return &state, nil
+		})
+
+	return store
+}
+
+func newTestOIDCService(t *testing.T, repo *mocks.UserRepository) (*OIDCService, *stubIdP) {
+	idp := newStubIdP(t)
+	logger, _ := test.NewNullLogger()
+
+	users := newTestUserService(userServiceDeps{repo: repo})
+	providers := map[string]interfaces.IdentityProvider{
+		"stub": oidc.NewClient(idp.provider(), idp.server.Client()),
+	}
+
+	return NewOIDCService(users, repo, providers, newTestStateStore(), testOIDCConfig, logger), idp
+}
+
+// startOIDCLogin returns the state, binding and code of a login approved
+// at idp.
+func startOIDCLogin(t *testing.T, s *OIDCService, idp *stubIdP, claims jwt.MapClaims) (string, string, string) {
+	authURL, binding, err := s.StartLogin(context.Background(), "stub")
+	require.NoError(t, err)
+
+	state, code := idp.approve(t, authURL, claims)
+	assert.NotContains(t, binding, state)
+
+	return state, binding, code
+}
+
+func TestOIDCService_CompleteLogin_ExistingIdentity(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	user := &models.User{Id: 1, Name: "test", Email: "test@example.com", TokenVersion: 1}
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(user, nil)
+
+	state, binding, code := startOIDCLogin(t, s, idp, nil)
+
+	result, token, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+
+	require.NoError(t, err)
+	assert.Equal(t, user, result)
+	assert.NotEmpty(t, token)
+	mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestOIDCService_CompleteLogin_LinksVerifiedEmail(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	verifiedAt := time.Now()
+	user := &models.User{Id: 1, Name: "test", Email: "test@example.com", EmailVerifiedAt: &verifiedAt, TokenVersion: 1}
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(nil, models.ErrUserNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(user, nil)
+	mockRepo.On("CreateIdentity", mock.Anything, &models.UserIdentity{
+		UserId:   1,
+		Provider: "stub",
+		Subject:  "external-1",
+		Email:    "test@example.com",
+	}).Return(nil)
+
+	state, binding, code := startOIDCLogin(t, s, idp, jwt.MapClaims{
+		"email":          "test@example.com",
+		"email_verified": true,
+	})
+
+	result, token, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+
+	require.NoError(t, err)
+	assert.Equal(t, user, result)
+	assert.NotEmpty(t, token)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOIDCService_CompleteLogin_UnverifiedLocalAccount(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	user := &models.User{Id: 1, Name: "test", Email: "test@example.com"}
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(nil, models.ErrUserNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(user, nil)
+
+	state, binding, code := startOIDCLogin(t, s, idp, jwt.MapClaims{
+		"email":          "test@example.com",
+		"email_verified": true,
+	})
+
+	_, _, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+
+	assert.ErrorIs(t, err, models.ErrIdentityConflict)
+	mockRepo.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything)
+}
+
+func TestOIDCService_CompleteLogin_ProvisionsUser(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(nil, models.ErrUserNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "new@example.com").Return(nil, models.ErrUserNotFound)
+	var identity *models.UserIdentity
+	mockRepo.On("CreateUserWithIdentity", mock.Anything, mock.AnythingOfType("*models.User"), mock.AnythingOfType("*models.UserIdentity")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*models.User).Id = 7
+			identity = args.Get(2).(*models.UserIdentity)
+		}).
+		Return(nil)
+
+	state, binding, code := startOIDCLogin(t, s, idp, jwt.MapClaims{
+		"email":              "new@example.com",
+		"email_verified":     "true",
+		"preferred_username": "newbie",
+	})
+
+	result, token, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, 7, result.Id)
+	assert.Equal(t, "newbie", result.Name)
+	assert.Equal(t, "new@example.com", result.Email)
+	assert.True(t, result.IsEmailVerified())
+
+	require.NotNil(t, identity)
+	assert.Equal(t, "stub", identity.Provider)
+	assert.Equal(t, "external-1", identity.Subject)
+}
+
+func TestOIDCService_CompleteLogin_EmailNotVerified(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(nil, models.ErrUserNotFound)
+
+	state, binding, code := startOIDCLogin(t, s, idp, jwt.MapClaims{
+		"email":          "test@example.com",
+		"email_verified": false,
+	})
+
+	_, _, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+
+	assert.ErrorIs(t, err, models.ErrExternalEmailNotVerified)
+	mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestOIDCService_CompleteLogin_MFARequired(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	user := &models.User{Id: 1, Name: "test", MFAEnabled: true, TokenVersion: 1}
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(user, nil)
+
+	state, binding, code := startOIDCLogin(t, s, idp, nil)
+
+	_, token, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+
+	var mfaRequired *models.MFARequiredError
+	require.ErrorAs(t, err, &mfaRequired)
+	assert.NotEmpty(t, mfaRequired.Token)
+	assert.Empty(t, token)
+}
+
+func TestOIDCService_CompleteLogin_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		mangle func(state, binding, code string) (string, string, string)
+	}{
+		{
+			name:   "nonce mismatch",
+			claims: jwt.MapClaims{"nonce": "someone else's"},
+		},
+		{
+			name:   "wrong audience",
+			claims: jwt.MapClaims{"aud": "another-client"},
+		},
+		{
+			name:   "wrong issuer",
+			claims: jwt.MapClaims{"iss": "https://evil.example.com"},
+		},
+		{
+			name:   "expired id token",
+			claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()},
+		},
+		{
+			name: "unknown state",
+			mangle: func(state, binding, code string) (string, string, string) {
+				return "forged", binding, code
+			},
+		},
+		{
+			name:   "unknown code",
+			mangle: func(state, binding, code string) (string, string, string) { return state, binding, "forged" },
+		},
+		{
+			// The callback URL of someone else's login, opened in a
+			// browser that didn't start it.
+			name:   "other browser",
+			mangle: func(state, binding, code string) (string, string, string) { return state, "mine", code },
+		},
+		{
+			// Only a hash of the binding is stored, and the state alone
+			// doesn't tell what it is.
+			name: "binding derived from the state",
+			mangle: func(state, binding, code string) (string, string, string) {
+				return state, oidc.HashBinding(state), code
+			},
+		},
+		{
+			name:   "no binding",
+			mangle: func(state, binding, code string) (string, string, string) { return state, "", code },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			s, idp := newTestOIDCService(t, mockRepo)
+
+			state, binding, code := startOIDCLogin(t, s, idp, tt.claims)
+			if tt.mangle != nil {
+				state, binding, code = tt.mangle(state, binding, code)
+			}
+
+			_, _, err := s.CompleteLogin(context.Background(), "stub", state, binding, code)
+
+			assert.ErrorIs(t, err, models.ErrInvalidToken)
+			mockRepo.AssertNotCalled(t, "GetUserByIdentity", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOIDCService_CompleteLogin_StateIsSingleUse(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	user := &models.User{Id: 1, Name: "test", TokenVersion: 1}
+	mockRepo.On("GetUserByIdentity", mock.Anything, "stub", "external-1").Return(user, nil)
+
+	state, binding, code := startOIDCLogin(t, s, idp, nil)
+
+	_, _, err := s.CompleteLogin(ctx, "stub", state, binding, code)
+	require.NoError(t, err)
+
+	_, _, err = s.CompleteLogin(ctx, "stub", state, binding, code)
+	assert.ErrorIs(t, err, models.ErrInvalidToken)
+}
+
+func TestOIDCService_CompleteLogin_WrongBindingUsesUpState(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+	s, idp := newTestOIDCService(t, mockRepo)
+
+	state, binding, code := startOIDCLogin(t, s, idp, nil)
+
+	_, _, err := s.CompleteLogin(ctx, "stub", state, "forged", code)
+	require.ErrorIs(t, err, models.ErrInvalidToken)
+
+	// A forged callback gets one try; the login has to be started again.
+	_, _, err = s.CompleteLogin(ctx, "stub", state, binding, code)
+	assert.ErrorIs(t, err, models.ErrInvalidToken)
+	mockRepo.AssertNotCalled(t, "GetUserByIdentity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCService_StartLogin_BindingIsRandom(t *testing.T) {
+	s, _ := newTestOIDCService(t, new(mocks.UserRepository))
+
+	_, first, err := s.StartLogin(context.Background(), "stub")
+	require.NoError(t, err)
+	_, second, err := s.StartLogin(context.Background(), "stub")
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestOIDCService_UnknownProvider(t *testing.T) {
+	s, _ := newTestOIDCService(t, new(mocks.UserRepository))
+
+	_, _, err := s.StartLogin(context.Background(), "nope")
+	assert.ErrorIs(t, err, models.ErrUnknownProvider)
+
+	_, _, err = s.CompleteLogin(context.Background(), "nope", "state", "binding", "code")
+	assert.ErrorIs(t, err, models.ErrUnknownProvider)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestPasswordReset emails a reset link to email. Unknown addresses are
+// silently ignored, so the result doesn't reveal whether an account
+// exists.
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "RequestPasswordReset",
+			"error":  err.Error(),
+		}).Errorf("failed to get user by email: %v", err)
+
+		return err
+	}
+
+	token, err := newResetToken()
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "RequestPasswordReset",
+			"error":  err.Error(),
+		}).Errorf("failed to generate reset token: %v", err)
+
+		return err
+	}
+
+	err = s.repo.CreatePasswordResetToken(ctx, &models.PasswordResetToken{
+		UserId:    user.Id,
+		TokenHash: hashToken(token),
+		ExpiresAt: s.now().Add(s.reset.TTL),
+	})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "RequestPasswordReset",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to store reset token: %v", err)
+
+		return err
+	}
+
+	link := s.reset.URL + "?token=" + url.QueryEscape(token)
+
+	err = s.mail.Send(ctx, models.Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nset a new password by opening the link below. It can be used once and expires in %s.\n\n%s\n\nIf you didn't ask for this, ignore this email; your password stays the same.\n",
+			user.Name, s.reset.TTL, link),
+	})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "RequestPasswordReset",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to send reset email: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a token from RequestPasswordReset.
+// The token can't be used again, and every session of the user is revoked.
+func (s *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	passwordHash, err := models.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	userId, err := s.repo.ResetPassword(ctx, hashToken(token), passwordHash, s.now())
+	if errors.Is(err, models.ErrInvalidToken) {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ResetPassword",
+		}).Warn("invalid password reset token")
+
+		return err
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ResetPassword",
+			"error":  err.Error(),
+		}).Errorf("failed to reset password: %v", err)
+
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"module": "user",
+		"func":   "ResetPassword",
+		"userId": userId,
+	}).Info("password reset, sessions revoked")
+
+	return nil
+}
+
+// newResetToken returns 256 random bits, URL-safe encoded.
+func newResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what is stored for a reset token or recovery code. Both
+// are random enough that a fast unsalted hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
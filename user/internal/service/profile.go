@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/avatar"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxSearchLength bounds search queries; names aren't longer.
+const maxSearchLength = 64
+
+// ProfileService manages the public profile of users and their avatars.
+type ProfileService struct {
+	repo    interfaces.UserRepository
+	avatars interfaces.AvatarStore
+	cache   interfaces.ProfileCache
+	cfg     config.AvatarConfig
+	search  config.SearchConfig
+	batch   config.BatchConfig
+	logger  *logrus.Logger
+}
+
+func NewProfileService(repo interfaces.UserRepository, avatars interfaces.AvatarStore, cache interfaces.ProfileCache, cfg config.AvatarConfig, search config.SearchConfig, batch config.BatchConfig, logger *logrus.Logger) *ProfileService {
+	return &ProfileService{
+		repo:    repo,
+		avatars: avatars,
+		cache:   cache,
+		cfg:     cfg,
+		search:  search,
+		batch:   batch,
+		logger:  logger,
+	}
+}
+
+// GetProfile returns the public profile of userId. Suspended users have
+// none.
+func (s *ProfileService) GetProfile(ctx context.Context, userId int) (*models.Profile, error) {
+	user, err := s.user(ctx, userId, "GetProfile")
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsSuspended() {
+		return nil, models.ErrUserNotFound
+	}
+
+	return s.profile(user), nil
+}
+
+func (s *ProfileService) UpdateProfile(ctx context.Context, userId int, patch *models.ProfilePatch) (*models.Profile, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := s.user(ctx, userId, "UpdateProfile")
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Apply(user) {
+		if err := s.repo.UpdateProfile(ctx, user); err != nil {
+			return nil, err
+		}
+
+		forgetProfile(ctx, s.cache, s.logger, "UpdateProfile", userId)
+	}
+
+	return s.profile(user), nil
+}
+
+// SetAvatar stores image, cropped and scaled, as the avatar of userId and
+// removes the one it replaces. Every upload gets a new key, so caches of
+// the old URL never serve the wrong image.
+func (s *ProfileService) SetAvatar(ctx context.Context, userId int, image io.Reader) (*models.Profile, error) {
+	user, err := s.user(ctx, userId, "SetAvatar")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := avatar.Process(image, s.cfg.MaxBytes, s.cfg.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := avatarKey(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.avatars.Put(ctx, key, data); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "profile",
+			"func":   "SetAvatar",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to store avatar: %v", err)
+
+		return nil, err
+	}
+
+	previous, err := s.repo.SetAvatar(ctx, userId, &key)
+	if err != nil {
+		s.removeAvatar(ctx, userId, key)
+		return nil, err
+	}
+
+	forgetProfile(ctx, s.cache, s.logger, "SetAvatar", userId)
+
+	if previous != nil {
+		s.removeAvatar(ctx, userId, *previous)
+	}
+
+	user.AvatarKey = &key
+
+	return s.profile(user), nil
+}
+
+func (s *ProfileService) DeleteAvatar(ctx context.Context, userId int) (*models.Profile, error) {
+	user, err := s.user(ctx, userId, "DeleteAvatar")
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AvatarKey == nil {
+		return s.profile(user), nil
+	}
+
+	previous, err := s.repo.SetAvatar(ctx, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	forgetProfile(ctx, s.cache, s.logger, "DeleteAvatar", userId)
+
+	if previous != nil {
+		s.removeAvatar(ctx, userId, *previous)
+	}
+
+	user.AvatarKey = nil
+
+	return s.profile(user), nil
+}
+
+// SearchUsers returns a page of profiles whose name or display name starts
+// with or resembles query, for finding someone to message or mention.
+// Users who opted out of search and suspended users are never returned.
+// cursor is the NextCursor of the previous page, or empty for the first.
+// limit is capped at the configured maximum; zero means the default.
+func (s *ProfileService) SearchUsers(ctx context.Context, query, cursor string, limit int) (*models.ProfilePage, error) {
+	query = strings.TrimSpace(query)
+	if length := utf8.RuneCountInString(query); length < s.search.MinQueryLength || length > maxSearchLength {
+		return nil, fmt.Errorf("%w: query must be %d to %d characters", models.ErrInvalidSearch, s.search.MinQueryLength, maxSearchLength)
+	}
+
+	if limit <= 0 {
+		limit = s.search.DefaultLimit
+	}
+	limit = min(limit, s.search.MaxLimit)
+
+	search := models.UserSearch{Term: query, Limit: limit + 1}
+	if cursor != "" {
+		after, err := models.DecodeSearchCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		search.After = after
+	}
+
+	matches, err := s.repo.SearchUsers(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.ProfilePage{Users: make([]models.Profile, 0, min(len(matches), limit))}
+
+	// One match more than the page holds was asked for, to tell whether
+	// there is a next page.
+	if len(matches) > limit {
+		matches = matches[:limit]
+		page.NextCursor = matches[limit-1].Cursor().Encode()
+	}
+
+	for i := range matches {
+		page.Users = append(page.Users, *s.profile(&matches[i].User))
+	}
+
+	return page, nil
+}
+
+func (s *ProfileService) user(ctx context.Context, userId int, fn string) (*models.User, error) {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "profile",
+			"func":   fn,
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *ProfileService) profile(user *models.User) *models.Profile {
+	profile := &models.Profile{
+		Id:          user.Id,
+		Username:    user.Name,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+		Pronouns:    user.Pronouns,
+		Searchable:  user.Searchable,
+	}
+
+	if user.AvatarKey != nil {
+		profile.AvatarURL = s.avatars.URL(*user.AvatarKey)
+	}
+
+	return profile
+}
+
+// removeAvatar deletes an image no longer referenced. Failing to is only
+// logged: the worst outcome is an orphaned file.
+func (s *ProfileService) removeAvatar(ctx context.Context, userId int, key string) {
+	if err := s.avatars.Delete(ctx, key); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "profile",
+			"func":   "removeAvatar",
+			"userId": userId,
+			"key":    key,
+			"error":  err.Error(),
+		}).Warnf("failed to delete avatar: %v", err)
+	}
+}
+
+func avatarKey(userId int) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d-%s.png", userId, hex.EncodeToString(b)), nil
+}
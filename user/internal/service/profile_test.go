@@ -0,0 +1,438 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
+	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testAvatarConfig = config.AvatarConfig{
+	MaxBytes: 1 << 20,
+	Size:     64,
+}
+
+var testSearchConfig = config.SearchConfig{
+	DefaultLimit:   2,
+	MaxLimit:       3,
+	MinQueryLength: 2,
+}
+
+var testBatchConfig = config.BatchConfig{
+	MaxIds:   3,
+	CacheTTL: time.Minute,
+}
+
+func newTestProfileService(repo *mocks.UserRepository, avatars *mocks.AvatarStore) *ProfileService {
+	return newTestCachedProfileService(repo, avatars, newTestProfileCache())
+}
+
+func newTestCachedProfileService(repo *mocks.UserRepository, avatars *mocks.AvatarStore, cache *mocks.ProfileCache) *ProfileService {
+	logger, _ := test.NewNullLogger()
+
+	avatars.On("URL", mock.AnythingOfType("string")).Return(func(key string) string {
+		return "/avatars/" + key
+	}).Maybe()
+
+	return NewProfileService(repo, avatars, cache, testAvatarConfig, testSearchConfig, testBatchConfig, logger)
+}
+
+// newTestProfileCache returns a cache that is empty and accepts anything,
+// for tests that aren't about caching.
+func newTestProfileCache() *mocks.ProfileCache {
+	cache := new(mocks.ProfileCache)
+	cache.On("GetMany", mock.Anything, mock.Anything).Return(map[int]*models.Profile{}, map[int]int64{}, nil).Maybe()
+	cache.On("SetMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	cache.On("Delete", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	return cache
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestProfileService_GetProfile(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{
+		Id:          1,
+		Name:        "test",
+		Email:       "test@example.com",
+		Password:    "hash",
+		DisplayName: "Test User",
+		Pronouns:    "they/them",
+		AvatarKey:   stringPtr("1-abc.png"),
+	}, nil)
+
+	service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+	profile, err := service.GetProfile(ctx, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.Profile{
+		Id:          1,
+		Username:    "test",
+		DisplayName: "Test User",
+		AvatarURL:   "/avatars/1-abc.png",
+		Pronouns:    "they/them",
+	}, profile)
+}
+
+func TestProfileService_GetProfile_Suspended(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	suspendedAt := time.Now()
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, SuspendedAt: &suspendedAt}, nil)
+
+	service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+	_, err := service.GetProfile(ctx, 1)
+
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+}
+
+func TestProfileService_UpdateProfile(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	user := &models.User{Id: 1, Name: "test", Bio: "old bio"}
+	mockRepo.On("GetUserById", ctx, 1).Return(user, nil)
+	mockRepo.On("UpdateProfile", ctx, user).Return(nil)
+
+	service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+	profile, err := service.UpdateProfile(ctx, 1, &models.ProfilePatch{
+		DisplayName: stringPtr("  Test User  "),
+		Bio:         stringPtr(""),
+		Timezone:    stringPtr("Europe/Berlin"),
+		Locale:      stringPtr("en-us"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Test User", profile.DisplayName)
+	assert.Empty(t, profile.Bio)
+	assert.Equal(t, "Europe/Berlin", profile.Timezone)
+	assert.Equal(t, "en-US", profile.Locale)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProfileService_UpdateProfile_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch *models.ProfilePatch
+	}{
+		{name: "display name too long", patch: &models.ProfilePatch{DisplayName: stringPtr(strings.Repeat("a", 65))}},
+		{name: "control characters", patch: &models.ProfilePatch{DisplayName: stringPtr("a\x00b")}},
+		{name: "bio too long", patch: &models.ProfilePatch{Bio: stringPtr(strings.Repeat("é", 501))}},
+		{name: "unknown timezone", patch: &models.ProfilePatch{Timezone: stringPtr("Mars/Olympus")}},
+		{name: "local timezone", patch: &models.ProfilePatch{Timezone: stringPtr("Local")}},
+		{name: "invalid locale", patch: &models.ProfilePatch{Locale: stringPtr("not a locale")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+			_, err := service.UpdateProfile(context.Background(), 1, tt.patch)
+
+			assert.ErrorIs(t, err, models.ErrInvalidProfile)
+			mockRepo.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestProfileService_SetAvatar(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockAvatars := new(mocks.AvatarStore)
+	ctx := context.Background()
+
+	src := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			src.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var upload bytes.Buffer
+	require.NoError(t, jpeg.Encode(&upload, src, nil))
+
+	var stored []byte
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, Name: "test"}, nil)
+	mockAvatars.On("Put", ctx, mock.AnythingOfType("string"), mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(2).([]byte) }).
+		Return(nil)
+	mockRepo.On("SetAvatar", ctx, 1, mock.AnythingOfType("*string")).Return(stringPtr("1-old.png"), nil)
+	mockAvatars.On("Delete", ctx, "1-old.png").Return(nil)
+
+	service := newTestProfileService(mockRepo, mockAvatars)
+
+	profile, err := service.SetAvatar(ctx, 1, &upload)
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(profile.AvatarURL, "/avatars/1-"))
+	mockRepo.AssertExpectations(t)
+	mockAvatars.AssertExpectations(t)
+
+	avatar, err := png.Decode(bytes.NewReader(stored))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), avatar.Bounds())
+
+	r, g, b, _ := avatar.At(32, 32).RGBA()
+	assert.InDelta(t, 200, r>>8, 3)
+	assert.InDelta(t, 100, g>>8, 3)
+	assert.InDelta(t, 50, b>>8, 3)
+}
+
+func TestProfileService_SetAvatar_Rejected(t *testing.T) {
+	var huge bytes.Buffer
+	require.NoError(t, png.Encode(&huge, image.NewGray(image.Rect(0, 0, 5000, 5000))))
+
+	tests := []struct {
+		name    string
+		upload  []byte
+		wantErr error
+	}{
+		{name: "not an image", upload: []byte("definitely not a picture"), wantErr: models.ErrInvalidImage},
+		{name: "too many bytes", upload: bytes.Repeat([]byte{0}, testAvatarConfig.MaxBytes+1), wantErr: models.ErrImageTooLarge},
+		{name: "too many pixels", upload: huge.Bytes(), wantErr: models.ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockAvatars := new(mocks.AvatarStore)
+			ctx := context.Background()
+
+			mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1}, nil)
+
+			service := newTestProfileService(mockRepo, mockAvatars)
+
+			_, err := service.SetAvatar(ctx, 1, bytes.NewReader(tt.upload))
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockAvatars.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "SetAvatar", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestProfileService_DeleteAvatar(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockAvatars := new(mocks.AvatarStore)
+	ctx := context.Background()
+
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, AvatarKey: stringPtr("1-old.png")}, nil)
+	mockRepo.On("SetAvatar", ctx, 1, (*string)(nil)).Return(stringPtr("1-old.png"), nil)
+	mockAvatars.On("Delete", ctx, "1-old.png").Return(nil)
+
+	service := newTestProfileService(mockRepo, mockAvatars)
+
+	profile, err := service.DeleteAvatar(ctx, 1)
+
+	require.NoError(t, err)
+	assert.Empty(t, profile.AvatarURL)
+	mockRepo.AssertExpectations(t)
+	mockAvatars.AssertExpectations(t)
+}
+
+func TestProfileService_SearchUsers(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	matches := []models.UserMatch{
+		{User: models.User{Id: 4, Name: "john", Searchable: true}, Prefix: true, Score: 1},
+		{User: models.User{Id: 2, Name: "johanna", DisplayName: "Jo", Searchable: true}, Prefix: true, Score: 0.5},
+		{User: models.User{Id: 9, Name: "jon", Searchable: true}, Prefix: false, Score: 0.4},
+	}
+
+	// The default limit is 2, so 3 are asked for to see if there is more.
+	mockRepo.On("SearchUsers", ctx, models.UserSearch{Term: "jo", Limit: 3}).Return(matches, nil)
+
+	service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+	page, err := service.SearchUsers(ctx, "  jo ", "", 0)
+
+	require.NoError(t, err)
+	require.Len(t, page.Users, 2)
+	assert.Equal(t, 4, page.Users[0].Id)
+	assert.Equal(t, 2, page.Users[1].Id)
+	require.NotEmpty(t, page.NextCursor)
+
+	cursor, err := models.DecodeSearchCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, &models.SearchCursor{Prefix: true, Score: 0.5, Id: 2}, cursor)
+
+	mockRepo.On("SearchUsers", ctx, models.UserSearch{Term: "jo", After: cursor, Limit: 3}).Return(matches[2:], nil)
+
+	page, err = service.SearchUsers(ctx, "jo", page.NextCursor, 0)
+
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, 9, page.Users[0].Id)
+	assert.Empty(t, page.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProfileService_SearchUsers_LimitCapped(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	mockRepo.On("SearchUsers", ctx, models.UserSearch{Term: "jo", Limit: testSearchConfig.MaxLimit + 1}).Return(nil, nil)
+
+	service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+	page, err := service.SearchUsers(ctx, "jo", "", 1000)
+
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProfileService_SearchUsers_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		cursor  string
+		wantErr error
+	}{
+		{name: "too short", query: " j ", wantErr: models.ErrInvalidSearch},
+		{name: "too long", query: strings.Repeat("j", 65), wantErr: models.ErrInvalidSearch},
+		{name: "garbage cursor", query: "jo", cursor: "not a cursor", wantErr: models.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+			_, err := service.SearchUsers(context.Background(), tt.query, tt.cursor, 0)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockRepo.AssertNotCalled(t, "SearchUsers", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestProfileService_GetProfiles(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockCache := new(mocks.ProfileCache)
+	ctx := context.Background()
+	suspendedAt := time.Now()
+
+	cached := &models.Profile{Id: 2, Username: "cached"}
+	versions := map[int]int64{3: 0, 2: 4, 1: 7}
+	mockCache.On("GetMany", ctx, []int{3, 2, 1}).Return(map[int]*models.Profile{2: cached}, versions, nil)
+	mockRepo.On("GetUsersByIds", ctx, []int{3, 1}).Return([]models.User{
+		{Id: 1, Name: "one", Searchable: true},
+		{Id: 3, Name: "suspended", SuspendedAt: &suspendedAt},
+	}, nil)
+	mockCache.On("SetMany", ctx, []models.Profile{{Id: 1, Username: "one", Searchable: true}}, versions, testBatchConfig.CacheTTL).Return(nil)
+
+	service := newTestCachedProfileService(mockRepo, new(mocks.AvatarStore), mockCache)
+
+	batch, err := service.GetProfiles(ctx, []int{3, 2, 1, 2})
+	require.NoError(t, err)
+	require.Len(t, batch.Users, 2)
+	assert.Equal(t, 2, batch.Users[0].Id)
+	assert.Equal(t, "cached", batch.Users[0].Username)
+	assert.Equal(t, 1, batch.Users[1].Id)
+	assert.Equal(t, []int{3}, batch.Missing)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestProfileService_GetProfiles_AllCached(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockCache := new(mocks.ProfileCache)
+	ctx := context.Background()
+
+	mockCache.On("GetMany", ctx, []int{1}).Return(map[int]*models.Profile{1: {Id: 1, Username: "one"}}, map[int]int64{1: 0}, nil)
+
+	service := newTestCachedProfileService(mockRepo, new(mocks.AvatarStore), mockCache)
+
+	batch, err := service.GetProfiles(ctx, []int{1})
+	require.NoError(t, err)
+	require.Len(t, batch.Users, 1)
+	assert.Empty(t, batch.Missing)
+
+	mockRepo.AssertNotCalled(t, "GetUsersByIds", mock.Anything, mock.Anything)
+	mockCache.AssertNotCalled(t, "SetMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProfileService_GetProfiles_CacheUnavailable(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockCache := new(mocks.ProfileCache)
+	ctx := context.Background()
+
+	mockCache.On("GetMany", ctx, []int{1}).Return(nil, nil, errors.New("connection refused"))
+	mockRepo.On("GetUsersByIds", ctx, []int{1}).Return([]models.User{{Id: 1, Name: "one"}}, nil)
+
+	service := newTestCachedProfileService(mockRepo, new(mocks.AvatarStore), mockCache)
+
+	batch, err := service.GetProfiles(ctx, []int{1})
+	require.NoError(t, err)
+	require.Len(t, batch.Users, 1)
+	assert.Equal(t, "one", batch.Users[0].Username)
+
+	// Without the versions the load can't be told apart from a stale one.
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "SetMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProfileService_GetProfiles_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+	}{
+		{name: "empty", ids: nil},
+		{name: "not an id", ids: []int{1, 0}},
+		{name: "too many", ids: []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			service := newTestProfileService(mockRepo, new(mocks.AvatarStore))
+
+			_, err := service.GetProfiles(context.Background(), tt.ids)
+			assert.ErrorIs(t, err, models.ErrInvalidBatch)
+
+			mockRepo.AssertNotCalled(t, "GetUsersByIds", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestProfileService_UpdateProfile_InvalidatesCache(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockCache := new(mocks.ProfileCache)
+	ctx := context.Background()
+
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, Name: "test"}, nil)
+	mockRepo.On("UpdateProfile", ctx, mock.AnythingOfType("*models.User")).Return(nil)
+	mockCache.On("Delete", ctx, 1).Return(nil)
+
+	service := newTestCachedProfileService(mockRepo, new(mocks.AvatarStore), mockCache)
+
+	_, err := service.UpdateProfile(ctx, 1, &models.ProfilePatch{DisplayName: stringPtr("New Name")})
+	require.NoError(t, err)
+
+	mockCache.AssertExpectations(t)
+}
@@ -2,25 +2,59 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dmitriysta/messenger/user/internal/interfaces"
 	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/dmitriysta/messenger/user/internal/pkg/metrics"
+	"github.com/dmitriysta/messenger/user/internal/pkg/secretbox"
 
 	"github.com/sirupsen/logrus"
 )
 
 type UserService struct {
-	repo   interfaces.UserRepository
-	logger *logrus.Logger
+	repo    interfaces.UserRepository
+	backoff interfaces.LoginBackoff
+	mail    interfaces.MailSender
+	cache   interfaces.ProfileCache
+	resend  interfaces.Cooldown
+	logger  *logrus.Logger
+	jwt     config.JWTConfig
+	lockout config.LockoutConfig
+	verify  config.VerificationConfig
+	reset   config.PasswordResetConfig
+	mfa     config.MFAConfig
+	box     *secretbox.Box
+	dummy   *models.User
+	now     func() time.Time
 }
 
-func NewUserService(repo interfaces.UserRepository, logger *logrus.Logger) *UserService {
+// dummyUser is hashed once per process, the first time a service is built
+// rather than on the first login for an unknown email.
+var dummyUser = sync.OnceValue(models.NewDummyUser)
+
+func NewUserService(repo interfaces.UserRepository, backoff interfaces.LoginBackoff, mail interfaces.MailSender, logger *logrus.Logger, jwt config.JWTConfig, lockout config.LockoutConfig, verify config.VerificationConfig, reset config.PasswordResetConfig, mfa config.MFAConfig, box *secretbox.Box, cache interfaces.ProfileCache, resend interfaces.Cooldown) *UserService {
 	return &UserService{
-		repo:   repo,
-		logger: logger,
+		repo:    repo,
+		backoff: backoff,
+		mail:    mail,
+		cache:   cache,
+		resend:  resend,
+		logger:  logger,
+		jwt:     jwt,
+		lockout: lockout,
+		verify:  verify,
+		reset:   reset,
+		mfa:     mfa,
+		box:     box,
+		dummy:   dummyUser(),
+		now:     time.Now,
 	}
 }
 
@@ -48,6 +82,10 @@ func (s *UserService) CreateUser(ctx context.Context, name, email, password stri
 		return nil, err
 	}
 
+	// The account exists either way; if the email doesn't go out the user
+	// can ask for another one.
+	_ = s.sendVerification(ctx, user)
+
 	return user, nil
 }
 
@@ -77,6 +115,8 @@ func (s *UserService) UpdateUser(ctx context.Context, user *models.User) error {
 		return err
 	}
 
+	forgetProfile(ctx, s.cache, s.logger, "UpdateUser", user.Id)
+
 	return nil
 }
 
@@ -91,12 +131,42 @@ func (s *UserService) DeleteUser(ctx context.Context, userId int) error {
 		return err
 	}
 
+	forgetProfile(ctx, s.cache, s.logger, "DeleteUser", userId)
+
 	return nil
 }
 
+// AuthenticateUser checks the credentials and issues an access token.
+//
+// Failures are limited twice over. Each failure for an email makes the next
+// attempt wait longer; while it has to wait, the password isn't checked at
+// all. The backoff fails open: if it can't be reached, logins carry on
+// without it. Failures against an existing account are also counted on the
+// account, which is locked for a while once they reach the threshold.
+//
+// An unknown email, a wrong password and a locked account all fail with
+// ErrInvalidCredentials after the same bcrypt comparison and the same
+// failure writes, so neither the response nor its timing tells them apart.
+// Only with the right password can a login fail with ErrUserSuspended,
+// with ErrEmailNotVerified when verification is required, or with an
+// MFARequiredError, whose token VerifyMFA exchanges for the access token.
 func (s *UserService) AuthenticateUser(ctx context.Context, email, password string) (*models.User, string, error) {
-	user, err := s.repo.GetUserByEmail(ctx, email)
+	subject := loginSubject(email)
+
+	retryAfter, err := s.backoff.Check(ctx, subject)
 	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "AuthenticateUser",
+			"error":  err.Error(),
+		}).Warnf("failed to check login backoff: %v", err)
+	}
+	if retryAfter > 0 {
+		return nil, "", &models.LoginThrottledError{RetryAfter: retryAfter}
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
 		s.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "AuthenticateUser",
@@ -106,25 +176,267 @@ func (s *UserService) AuthenticateUser(ctx context.Context, email, password stri
 		return nil, "", err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	// An unknown email is checked against the dummy user, and every
+	// failure below does the same bcrypt comparison and the same writes.
+	if user == nil {
+		user = s.dummy
+	}
+
+	passwordOk := user.CheckPassword(password)
+
+	reason := ""
+	switch {
+	case user == s.dummy:
+		reason = "unknown_email"
+	case user.IsLocked(s.now()):
+		s.logger.WithFields(logrus.Fields{
+			"module":      "user",
+			"func":        "AuthenticateUser",
+			"userId":      user.Id,
+			"lockedUntil": user.LockedUntil.String(),
+		}).Warn("login to locked account refused")
+
+		reason = "locked"
+	case !passwordOk:
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "AuthenticateUser",
+			"userId": user.Id,
+		}).Warn("failed to authenticate user: wrong password")
+
+		reason = "wrong_password"
+	}
+
+	if reason != "" {
+		metrics.LoginFailures.WithLabelValues(reason).Inc()
+		s.recordLoginFailure(ctx, subject)
+		s.recordAccountFailure(ctx, user)
+
+		return nil, "", models.ErrInvalidCredentials
+	}
+
+	if err := s.backoff.Reset(ctx, subject); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"module": "user",
 			"func":   "AuthenticateUser",
 			"error":  err.Error(),
-		}).Errorf("failed to authenticate user: %v", err)
+		}).Warnf("failed to reset login backoff: %v", err)
+	}
+
+	if user.IsSuspended() {
+		metrics.LoginFailures.WithLabelValues("suspended").Inc()
+		return nil, "", models.ErrUserSuspended
+	}
+
+	if s.verify.Required && !user.IsEmailVerified() {
+		s.resetAccountFailures(ctx, user)
+		metrics.LoginFailures.WithLabelValues("email_not_verified").Inc()
+		return nil, "", models.ErrEmailNotVerified
+	}
+
+	// The account failures are kept until the second factor is checked
+	// too, since wrong codes count towards the same lockout.
+	if user.MFAEnabled {
+		pending, err := s.pendingToken(user)
+		if err != nil {
+			return nil, "", err
+		}
 
+		return nil, "", &models.MFARequiredError{Token: pending}
+	}
+
+	s.resetAccountFailures(ctx, user)
+
+	tokenString, err := s.accessToken(user)
+	if err != nil {
 		return nil, "", err
 	}
 
+	return user, tokenString, nil
+}
+
+func (s *UserService) accessToken(user *models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":  user.Id,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
+		"id":   user.Id,
+		"ver":  user.TokenVersion,
+		"role": user.Role,
+		"exp":  time.Now().Add(s.jwt.TTL).Unix(),
 	})
 
-	tokenString, err := token.SignedString([]byte("secret"))
+	return token.SignedString([]byte(s.jwt.Secret))
+}
+
+func (s *UserService) resetAccountFailures(ctx context.Context, user *models.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+
+	if err := s.repo.ResetLoginFailures(ctx, user.Id); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "resetAccountFailures",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Warnf("failed to reset login failures: %v", err)
+	}
+}
+
+// ValidateAccessToken checks an access token issued by AuthenticateUser and
+// returns who it was issued to. Besides the signature and expiry it checks
+// the token version against the user's, so tokens issued before a password
+// reset, a role change or a suspension are refused. Tokens from before
+// versioning count as version 1, and tokens from before roles as issued
+// to an ordinary user.
+func (s *UserService) ValidateAccessToken(ctx context.Context, tokenString string) (*models.Principal, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.jwt.Secret), nil
+	})
 	if err != nil {
-		return nil, "", err
+		return nil, models.ErrInvalidToken
 	}
 
-	return user, tokenString, nil
+	// Tokens with a purpose, such as MFA pending tokens, are signed with
+	// the same key but aren't access tokens.
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if _, hasPurpose := claims["purpose"]; !ok || !token.Valid || hasPurpose {
+		return nil, models.ErrInvalidToken
+	}
+
+	userId, ok := claims["id"].(float64)
+	if !ok {
+		return nil, models.ErrInvalidToken
+	}
+
+	version := 1.0
+	if ver, ok := claims["ver"]; ok {
+		if version, ok = ver.(float64); !ok {
+			return nil, models.ErrInvalidToken
+		}
+	}
+
+	role := models.RoleUser
+	if claim, ok := claims["role"]; ok {
+		name, _ := claim.(string)
+		if role, err = models.ParseRole(name); err != nil {
+			return nil, models.ErrInvalidToken
+		}
+	}
+
+	user, err := s.repo.GetUserById(ctx, int(userId))
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil, models.ErrInvalidToken
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ValidateAccessToken",
+			"userId": int(userId),
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return nil, err
+	}
+
+	if int(version) != user.TokenVersion || role != user.Role || user.IsSuspended() {
+		return nil, models.ErrInvalidToken
+	}
+
+	return &models.Principal{UserId: user.Id, Role: role}, nil
+}
+
+// loginSubject is what the login backoff of email is kept under.
+func loginSubject(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *UserService) recordLoginFailure(ctx context.Context, subject string) {
+	retryAfter, err := s.backoff.Failure(ctx, subject)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "recordLoginFailure",
+			"error":  err.Error(),
+		}).Warnf("failed to record failed login: %v", err)
+
+		return
+	}
+
+	if retryAfter > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"module":     "user",
+			"func":       "recordLoginFailure",
+			"retryAfter": retryAfter.String(),
+		}).Warn("login backoff started")
+	}
+}
+
+func (s *UserService) recordAccountFailure(ctx context.Context, user *models.User) {
+	locked, err := s.repo.RecordLoginFailure(ctx, user.Id, s.lockout.Threshold, s.lockout.Duration)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "recordAccountFailure",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Warnf("failed to record failed login: %v", err)
+
+		return
+	}
+
+	if locked {
+		metrics.AccountLockouts.Inc()
+
+		s.logger.WithFields(logrus.Fields{
+			"module":   "user",
+			"func":     "recordAccountFailure",
+			"userId":   user.Id,
+			"duration": s.lockout.Duration.String(),
+		}).Warn("account locked after too many failed logins")
+	}
+}
+
+// UnlockUser lifts a lockout of userId, clears its failure count and ends
+// the login backoff of its email. Like SuspendUser, moderators can only
+// unlock ordinary users.
+func (s *UserService) UnlockUser(ctx context.Context, actor models.Principal, userId int) error {
+	user, err := s.managedUser(ctx, actor, userId, "UnlockUser")
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ResetLoginFailures(ctx, userId); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "UnlockUser",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to unlock user: %v", err)
+
+		return err
+	}
+
+	// The email may still be backing off. The backoff fails open, so if
+	// redis can't be reached now, it won't hold up logins either.
+	if err := s.backoff.Reset(ctx, loginSubject(user.Email)); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "UnlockUser",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Warnf("failed to reset login backoff: %v", err)
+	}
+
+	metrics.AccountUnlocks.Inc()
+
+	s.logger.WithFields(logrus.Fields{
+		"module":  "user",
+		"func":    "UnlockUser",
+		"userId":  userId,
+		"actorId": actor.UserId,
+	}).Info("account unlocked")
+
+	return nil
 }
@@ -2,17 +2,90 @@ package service
 
 import (
 	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/dmitriysta/messenger/user/internal/interfaces/mocks"
 	"github.com/dmitriysta/messenger/user/internal/models"
+	"github.com/dmitriysta/messenger/user/internal/pkg/config"
+	"github.com/dmitriysta/messenger/user/internal/pkg/secretbox"
+	"github.com/dmitriysta/messenger/user/internal/pkg/totp"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
 	"time"
 )
 
+var testJWTConfig = config.JWTConfig{
+	Secret: "test secret",
+	TTL:    time.Hour,
+}
+
+var testLockoutConfig = config.LockoutConfig{
+	Threshold: 5,
+	Duration:  time.Hour,
+}
+
+var testVerificationConfig = config.VerificationConfig{
+	Secret: "test verification secret",
+	TTL:    time.Hour,
+	URL:    "https://example.com/verify-email",
+}
+
+var testResetConfig = config.PasswordResetConfig{
+	TTL: time.Hour,
+	URL: "https://example.com/reset-password",
+}
+
+var testMFAConfig = config.MFAConfig{
+	Issuer:        "Messenger",
+	PendingTTL:    5 * time.Minute,
+	RecoveryCodes: 3,
+}
+
+var testBox, _ = secretbox.NewBox("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+// userServiceDeps are the collaborators of a UserService under test. Any
+// left nil is a fresh mock without expectations, and verify defaults to
+// testVerificationConfig, so a test only names what it sets expectations
+// on.
+type userServiceDeps struct {
+	repo    *mocks.UserRepository
+	backoff *mocks.LoginBackoff
+	mail    *mocks.MailSender
+	cache   *mocks.ProfileCache
+	resend  *mocks.Cooldown
+	verify  *config.VerificationConfig
+}
+
+func newTestUserService(deps userServiceDeps) *UserService {
+	if deps.repo == nil {
+		deps.repo = new(mocks.UserRepository)
+	}
+	if deps.backoff == nil {
+		deps.backoff = new(mocks.LoginBackoff)
+	}
+	if deps.mail == nil {
+		deps.mail = new(mocks.MailSender)
+	}
+	if deps.cache == nil {
+		deps.cache = newTestProfileCache()
+	}
+	if deps.resend == nil {
+		deps.resend = new(mocks.Cooldown)
+	}
+	verify := testVerificationConfig
+	if deps.verify != nil {
+		verify = *deps.verify
+	}
+
+	logger, _ := test.NewNullLogger()
+	return NewUserService(deps.repo, deps.backoff, deps.mail, logger, testJWTConfig, testLockoutConfig, verify, testResetConfig, testMFAConfig, testBox, deps.cache, deps.resend)
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	mockRepo := new(mocks.UserRepository)
 	ctx := context.Background()
@@ -26,9 +99,10 @@ func TestUserService_CreateUser(t *testing.T) {
 
 	mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*models.User")).Return(nil)
 
-	logger, _ := test.NewNullLogger()
+	mockMail := new(mocks.MailSender)
+	mockMail.On("Send", ctx, mock.AnythingOfType("models.Email")).Return(nil)
 
-	service := NewUserService(mockRepo, logger)
+	service := newTestUserService(userServiceDeps{repo: mockRepo, mail: mockMail})
 
 	result, err := service.CreateUser(ctx, testUser.Name, testUser.Email, testUser.Password)
 
@@ -40,7 +114,9 @@ func TestUserService_CreateUser(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.WithinDuration(t, currentTime, result.CreatedAt, time.Minute)
+	assert.False(t, result.IsEmailVerified())
 	mockRepo.AssertExpectations(t)
+	mockMail.AssertExpectations(t)
 }
 
 func TestUserService_GetUserById(t *testing.T) {
@@ -57,9 +133,7 @@ func TestUserService_GetUserById(t *testing.T) {
 
 	mockRepo.On("GetUserById", ctx, 1).Return(testUser, nil)
 
-	logger, _ := test.NewNullLogger()
-
-	service := NewUserService(mockRepo, logger)
+	service := newTestUserService(userServiceDeps{repo: mockRepo})
 
 	result, err := service.GetUserById(ctx, 1)
 
@@ -84,9 +158,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 
 	mockRepo.On("UpdateUser", ctx, testUser).Return(nil)
 
-	logger, _ := test.NewNullLogger()
-
-	service := NewUserService(mockRepo, logger)
+	service := newTestUserService(userServiceDeps{repo: mockRepo})
 
 	err := service.UpdateUser(ctx, testUser)
 
@@ -94,20 +166,43 @@ func TestUserService_UpdateUser(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUserService_UpdateUser_VersionConflict(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	testUser := &models.User{
+		Id:       1,
+		Name:     "test username",
+		Password: "test password",
+		Version:  2,
+	}
+
+	mockRepo.On("UpdateUser", ctx, testUser).Return(models.ErrVersionConflict)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+	err := service.UpdateUser(ctx, testUser)
+
+	assert.ErrorIs(t, err, models.ErrVersionConflict)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUserService_DeleteUser(t *testing.T) {
 	mockRepo := new(mocks.UserRepository)
 	ctx := context.Background()
 
 	mockRepo.On("DeleteUser", ctx, 1).Return(nil)
 
-	logger, _ := test.NewNullLogger()
+	mockCache := new(mocks.ProfileCache)
+	mockCache.On("Delete", ctx, 1).Return(nil)
 
-	service := NewUserService(mockRepo, logger)
+	service := newTestUserService(userServiceDeps{repo: mockRepo, cache: mockCache})
 
 	err := service.DeleteUser(ctx, 1)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
 }
 
 func TestUserService_AuthenticateUser(t *testing.T) {
@@ -127,8 +222,11 @@ func TestUserService_AuthenticateUser(t *testing.T) {
 
 	mockRepo.On("GetUserByEmail", ctx, "test email").Return(testUser, nil)
 
-	logger, _ := test.NewNullLogger()
-	service := NewUserService(mockRepo, logger)
+	mockBackoff := new(mocks.LoginBackoff)
+	mockBackoff.On("Check", ctx, "test email").Return(time.Duration(0), nil)
+	mockBackoff.On("Reset", ctx, "test email").Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
 
 	result, token, err := service.AuthenticateUser(ctx, "test email", "test password")
 
@@ -139,4 +237,1000 @@ func TestUserService_AuthenticateUser(t *testing.T) {
 	assert.WithinDuration(t, currentTime, result.CreatedAt, time.Second)
 	assert.NotEmpty(t, token)
 	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_AuthenticateUser_RecordsFailure(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	testUser := &models.User{
+		Id:       1,
+		Email:    "user@example.com",
+		Password: string(hashedPassword),
+	}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, " User@Example.com").Return(testUser, nil)
+	mockBackoff.On("Failure", ctx, "user@example.com").Return(2*time.Second, nil)
+	mockRepo.On("RecordLoginFailure", ctx, 1, testLockoutConfig.Threshold, testLockoutConfig.Duration).Return(false, nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, " User@Example.com", "wrong password")
+
+	assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+	mockBackoff.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_AuthenticateUser_Throttled(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(4*time.Second, nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "test password")
+
+	assert.ErrorIs(t, err, models.ErrLoginThrottled)
+	var throttled *models.LoginThrottledError
+	require.ErrorAs(t, err, &throttled)
+	assert.Equal(t, 4*time.Second, throttled.RetryAfter)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+	mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_AuthenticateUser_BackoffUnavailable(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword)}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), errors.New("connection refused"))
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(errors.New("connection refused"))
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "test password")
+
+	require.NoError(t, err)
+	assert.Equal(t, testUser.Id, result.Id)
+	assert.NotEmpty(t, token)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_AuthenticateUser_UnknownEmail(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	mockBackoff.On("Check", ctx, "nobody@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, "nobody@example.com").Return(nil, models.ErrUserNotFound)
+	mockBackoff.On("Failure", ctx, "nobody@example.com").Return(time.Duration(0), nil)
+	// The update for the dummy user matches no row, but costs as much as
+	// one for a real account.
+	mockRepo.On("RecordLoginFailure", ctx, 0, testLockoutConfig.Threshold, testLockoutConfig.Duration).Return(false, nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "nobody@example.com", "test password")
+
+	assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+	assert.NotErrorIs(t, err, models.ErrUserNotFound)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_AuthenticateUser_LocksAccount(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword), FailedLogins: 4}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+	mockBackoff.On("Failure", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("RecordLoginFailure", ctx, 1, testLockoutConfig.Threshold, testLockoutConfig.Duration).Return(true, nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "wrong password")
+
+	assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_AuthenticateUser_Locked(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "correct password", password: "test password"},
+		{name: "wrong password", password: "wrong password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockBackoff := new(mocks.LoginBackoff)
+			ctx := context.Background()
+
+			lockedUntil := time.Now().Add(time.Minute)
+			testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword), LockedUntil: &lockedUntil}
+
+			mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+			mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+			// A locked account fails with the same writes as a wrong
+			// password; the repository doesn't count it.
+			mockBackoff.On("Failure", ctx, "user@example.com").Return(time.Duration(0), nil)
+			mockRepo.On("RecordLoginFailure", ctx, 1, testLockoutConfig.Threshold, testLockoutConfig.Duration).Return(false, nil)
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+			result, token, err := service.AuthenticateUser(ctx, "user@example.com", tt.password)
+
+			assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+			assert.Nil(t, result)
+			assert.Empty(t, token)
+			mockBackoff.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+			mockRepo.AssertExpectations(t)
+			mockBackoff.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_AuthenticateUser_ResetsFailures(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	lockedUntil := time.Now().Add(-time.Minute)
+	testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword), LockedUntil: &lockedUntil}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(nil)
+	mockRepo.On("ResetLoginFailures", ctx, 1).Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "test password")
+
+	require.NoError(t, err)
+	assert.Equal(t, testUser.Id, result.Id)
+	assert.NotEmpty(t, token)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_UnlockUser(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+	moderator := models.Principal{UserId: 9, Role: models.RoleModerator}
+
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, Email: "User@Example.com", Role: models.RoleUser}, nil)
+	mockRepo.On("ResetLoginFailures", ctx, 1).Return(nil)
+	// The email backoff goes too, or the user keeps getting throttled.
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(nil).Once()
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	err := service.UnlockUser(ctx, moderator, 1)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_UnlockUser_BackoffUnavailable(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+	moderator := models.Principal{UserId: 9, Role: models.RoleModerator}
+
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, Email: "user@example.com", Role: models.RoleUser}, nil)
+	mockRepo.On("ResetLoginFailures", ctx, 1).Return(nil)
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(errors.New("connection refused"))
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	err := service.UnlockUser(ctx, moderator, 1)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_UnlockUser_Forbidden(t *testing.T) {
+	admin := models.Principal{UserId: 1, Role: models.RoleAdmin}
+	moderator := models.Principal{UserId: 1, Role: models.RoleModerator}
+
+	tests := []struct {
+		name    string
+		actor   models.Principal
+		target  *models.User
+		wantErr error
+	}{
+		{
+			name:   "admin unlocks moderator",
+			actor:  admin,
+			target: &models.User{Id: 2, Email: "user@example.com", Role: models.RoleModerator},
+		},
+		{
+			name:    "moderator unlocks moderator",
+			actor:   moderator,
+			target:  &models.User{Id: 2, Email: "user@example.com", Role: models.RoleModerator},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "moderator unlocks admin",
+			actor:   moderator,
+			target:  &models.User{Id: 2, Email: "user@example.com", Role: models.RoleAdmin},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "self",
+			actor:   models.Principal{UserId: 2, Role: models.RoleAdmin},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:    "not found",
+			actor:   admin,
+			wantErr: models.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockBackoff := new(mocks.LoginBackoff)
+			ctx := context.Background()
+
+			if tt.target != nil {
+				mockRepo.On("GetUserById", ctx, 2).Return(tt.target, nil)
+			} else {
+				mockRepo.On("GetUserById", ctx, 2).Return(nil, models.ErrUserNotFound)
+			}
+			if tt.wantErr == nil {
+				mockRepo.On("ResetLoginFailures", ctx, 2).Return(nil)
+				mockBackoff.On("Reset", ctx, "user@example.com").Return(nil)
+			}
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+			err := service.UnlockUser(ctx, tt.actor, 2)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "ResetLoginFailures", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_CreateUser_SendsVerification(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockMail := new(mocks.MailSender)
+	ctx := context.Background()
+
+	mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).Id = 7
+	}).Return(nil)
+
+	var sent models.Email
+	mockMail.On("Send", ctx, mock.AnythingOfType("models.Email")).Run(func(args mock.Arguments) {
+		sent = args.Get(1).(models.Email)
+	}).Return(errors.New("connection refused"))
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, mail: mockMail})
+
+	result, err := service.CreateUser(ctx, "test username", "user@example.com", "test password")
+
+	require.NoError(t, err, "a failed email must not fail the registration")
+	assert.Equal(t, 7, result.Id)
+	assert.Equal(t, "user@example.com", sent.To)
+
+	_, token, found := strings.Cut(sent.Body, testVerificationConfig.URL+"?token=")
+	require.True(t, found)
+	token, _, _ = strings.Cut(token, "\n")
+
+	userId, email, err := service.parseVerificationToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 7, userId)
+	assert.Equal(t, "user@example.com", email)
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	signer := newTestUserService(userServiceDeps{})
+
+	verifiedAt := time.Now().Add(-time.Hour)
+	unverified := &models.User{Id: 1, Email: "user@example.com"}
+
+	validToken, err := signer.verificationToken(unverified)
+	require.NoError(t, err)
+
+	expiredSigner := newTestUserService(userServiceDeps{verify: &config.VerificationConfig{Secret: testVerificationConfig.Secret, TTL: -time.Minute}})
+	expiredToken, err := expiredSigner.verificationToken(unverified)
+	require.NoError(t, err)
+
+	otherSigner := newTestUserService(userServiceDeps{verify: &config.VerificationConfig{Secret: "other secret", TTL: time.Hour}})
+	forgedToken, err := otherSigner.verificationToken(unverified)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		token     string
+		stored    *models.User
+		storedErr error
+		marks     bool
+		wantErr   error
+	}{
+		{name: "valid", token: validToken, stored: &models.User{Id: 1, Email: "user@example.com"}, marks: true},
+		{name: "already verified", token: validToken, stored: &models.User{Id: 1, Email: "user@example.com", EmailVerifiedAt: &verifiedAt}},
+		{name: "email changed", token: validToken, stored: &models.User{Id: 1, Email: "new@example.com"}, wantErr: models.ErrInvalidToken},
+		{name: "user deleted", token: validToken, storedErr: models.ErrUserNotFound, wantErr: models.ErrInvalidToken},
+		{name: "expired", token: expiredToken, wantErr: models.ErrInvalidToken},
+		{name: "wrong secret", token: forgedToken, wantErr: models.ErrInvalidToken},
+		{name: "access token", token: signToken(t, testVerificationConfig.Secret, jwt.MapClaims{"id": 1, "exp": time.Now().Add(time.Hour).Unix()}), wantErr: models.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			if tt.stored != nil || tt.storedErr != nil {
+				mockRepo.On("GetUserById", ctx, 1).Return(tt.stored, tt.storedErr)
+			}
+			if tt.marks {
+				mockRepo.On("MarkEmailVerified", ctx, 1, "user@example.com", mock.AnythingOfType("time.Time")).Return(nil)
+			}
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			err := service.VerifyEmail(ctx, tt.token)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if !tt.marks {
+				mockRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_ResendVerification(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name        string
+		cooling     bool
+		cooldownErr error
+		stored      *models.User
+		storedErr   error
+		sends       bool
+	}{
+		{name: "unverified", stored: &models.User{Id: 1, Email: "user@example.com"}, sends: true},
+		{name: "verified", stored: &models.User{Id: 1, Email: "user@example.com", EmailVerifiedAt: &verifiedAt}},
+		{name: "unknown email", storedErr: models.ErrUserNotFound},
+		{name: "within cooldown", cooling: true},
+		{name: "cooldown unavailable", cooldownErr: errors.New("redis down"), stored: &models.User{Id: 1, Email: "user@example.com"}, sends: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockMail := new(mocks.MailSender)
+			mockCooldown := new(mocks.Cooldown)
+			ctx := context.Background()
+
+			mockCooldown.On("Start", ctx, "user@example.com").Return(!tt.cooling && tt.cooldownErr == nil, tt.cooldownErr)
+			if !tt.cooling {
+				mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(tt.stored, tt.storedErr)
+			}
+			if tt.sends {
+				mockMail.On("Send", ctx, mock.MatchedBy(func(email models.Email) bool {
+					return email.To == "user@example.com"
+				})).Return(nil)
+			}
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo, mail: mockMail, resend: mockCooldown})
+
+			err := service.ResendVerification(ctx, "user@example.com")
+
+			assert.NoError(t, err)
+			if !tt.sends {
+				mockMail.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+			}
+			if tt.cooling {
+				mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+			}
+			mockRepo.AssertExpectations(t)
+			mockMail.AssertExpectations(t)
+			mockCooldown.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_AuthenticateUser_EmailNotVerified(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword)}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(nil)
+
+	verification := testVerificationConfig
+	verification.Required = true
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff, verify: &verification})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "test password")
+
+	assert.ErrorIs(t, err, models.ErrEmailNotVerified)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+	mockRepo.AssertExpectations(t)
+	mockBackoff.AssertExpectations(t)
+}
+
+// signToken signs claims the way AuthenticateUser does.
+func signToken(t *testing.T, secret string, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestUserService_RequestPasswordReset(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockMail := new(mocks.MailSender)
+	ctx := context.Background()
+
+	testUser := &models.User{Id: 1, Name: "test username", Email: "user@example.com"}
+
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+
+	var stored *models.PasswordResetToken
+	mockRepo.On("CreatePasswordResetToken", ctx, mock.AnythingOfType("*models.PasswordResetToken")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*models.PasswordResetToken)
+	}).Return(nil)
+
+	var sent models.Email
+	mockMail.On("Send", ctx, mock.AnythingOfType("models.Email")).Run(func(args mock.Arguments) {
+		sent = args.Get(1).(models.Email)
+	}).Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, mail: mockMail})
+
+	err := service.RequestPasswordReset(ctx, "user@example.com")
+
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, 1, stored.UserId)
+	assert.WithinDuration(t, time.Now().Add(testResetConfig.TTL), stored.ExpiresAt, time.Minute)
+	assert.Equal(t, "user@example.com", sent.To)
+
+	_, token, found := strings.Cut(sent.Body, testResetConfig.URL+"?token=")
+	require.True(t, found)
+	token, _, _ = strings.Cut(token, "\n")
+
+	assert.NotContains(t, stored.TokenHash, token, "only a hash of the token may be stored")
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+	mockRepo.AssertExpectations(t)
+	mockMail.AssertExpectations(t)
+}
+
+func TestUserService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockMail := new(mocks.MailSender)
+	ctx := context.Background()
+
+	mockRepo.On("GetUserByEmail", ctx, "nobody@example.com").Return(nil, models.ErrUserNotFound)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, mail: mockMail})
+
+	err := service.RequestPasswordReset(ctx, "nobody@example.com")
+
+	assert.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "CreatePasswordResetToken", mock.Anything, mock.Anything)
+	mockMail.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoErr error
+		wantErr error
+	}{
+		{name: "valid token"},
+		{name: "used or expired token", repoErr: models.ErrInvalidToken, wantErr: models.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			var passwordHash string
+			mockRepo.On("ResetPassword", ctx, hashToken("reset token"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Run(func(args mock.Arguments) {
+				passwordHash = args.String(2)
+			}).Return(1, tt.repoErr)
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			err := service.ResetPassword(ctx, "reset token", "new password")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte("new password")))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_ValidateAccessToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	suspendedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		token    string
+		stored   *models.User
+		wantRole models.Role
+		wantErr  error
+	}{
+		{
+			name:     "current version",
+			token:    signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 2, "role": "user", "exp": exp}),
+			stored:   &models.User{Id: 1, TokenVersion: 2, Role: models.RoleUser},
+			wantRole: models.RoleUser,
+		},
+		{
+			name:     "issued before versioning",
+			token:    signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "exp": exp}),
+			stored:   &models.User{Id: 1, TokenVersion: 1, Role: models.RoleUser},
+			wantRole: models.RoleUser,
+		},
+		{
+			name:     "admin",
+			token:    signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 1, "role": "admin", "exp": exp}),
+			stored:   &models.User{Id: 1, TokenVersion: 1, Role: models.RoleAdmin},
+			wantRole: models.RoleAdmin,
+		},
+		{
+			name:    "role no longer held",
+			token:   signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 1, "role": "admin", "exp": exp}),
+			stored:  &models.User{Id: 1, TokenVersion: 1, Role: models.RoleUser},
+			wantErr: models.ErrInvalidToken,
+		},
+		{
+			name:    "unknown role",
+			token:   signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 1, "role": "root", "exp": exp}),
+			wantErr: models.ErrInvalidToken,
+		},
+		{
+			name:    "suspended",
+			token:   signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 1, "role": "user", "exp": exp}),
+			stored:  &models.User{Id: 1, TokenVersion: 1, Role: models.RoleUser, SuspendedAt: &suspendedAt},
+			wantErr: models.ErrInvalidToken,
+		},
+		{
+			name:    "revoked by password reset",
+			token:   signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 1, "exp": exp}),
+			stored:  &models.User{Id: 1, TokenVersion: 2, Role: models.RoleUser},
+			wantErr: models.ErrInvalidToken,
+		},
+		{
+			name:    "expired",
+			token:   signToken(t, testJWTConfig.Secret, jwt.MapClaims{"id": 1, "ver": 1, "exp": time.Now().Add(-time.Minute).Unix()}),
+			wantErr: models.ErrInvalidToken,
+		},
+		{
+			name:    "wrong secret",
+			token:   signToken(t, "other secret", jwt.MapClaims{"id": 1, "ver": 1, "exp": exp}),
+			wantErr: models.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			if tt.stored != nil {
+				mockRepo.On("GetUserById", ctx, 1).Return(tt.stored, nil)
+			}
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			principal, err := service.ValidateAccessToken(ctx, tt.token)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 1, principal.UserId)
+				assert.Equal(t, tt.wantRole, principal.Role)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_EnrollMFA(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, Email: "user@example.com"}, nil)
+
+	var sealed string
+	mockRepo.On("SetMFASecret", ctx, 1, mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		sealed = args.String(2)
+	}).Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+	enrollment, err := service.EnrollMFA(ctx, 1)
+
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/Messenger:user@example.com?")
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.NotContains(t, sealed, enrollment.Secret, "the secret must be stored encrypted")
+
+	secret, err := testBox.Open(sealed, mfaSecretContext(1))
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, totp.EncodeSecret(secret))
+
+	_, err = testBox.Open(sealed, mfaSecretContext(2))
+	assert.Error(t, err, "a sealed secret must not open for another user")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_ConfirmMFA(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	sealed, err := testBox.Seal(secret, mfaSecretContext(1))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		code    string
+		enables bool
+		wantErr error
+	}{
+		{name: "current code", code: totp.Code(secret, totp.Step(time.Now())), enables: true},
+		{name: "wrong code", code: "000000x", wantErr: models.ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, MFASecret: &sealed}, nil)
+
+			var hashes []string
+			if tt.enables {
+				mockRepo.On("EnableMFA", ctx, 1, mock.AnythingOfType("int64"), mock.AnythingOfType("[]string")).Run(func(args mock.Arguments) {
+					hashes = args.Get(3).([]string)
+				}).Return(nil)
+			}
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			codes, err := service.ConfirmMFA(ctx, 1, tt.code)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "EnableMFA", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, codes, testMFAConfig.RecoveryCodes)
+			for i, code := range codes {
+				assert.Equal(t, hashToken(normalizeCode(code)), hashes[i])
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_AuthenticateUser_MFARequired(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	sealed := "sealed"
+	testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword), TokenVersion: 1, MFASecret: &sealed, MFAEnabled: true}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "test password")
+
+	assert.ErrorIs(t, err, models.ErrMFARequired)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+
+	var mfaRequired *models.MFARequiredError
+	require.ErrorAs(t, err, &mfaRequired)
+
+	_, err = service.ValidateAccessToken(ctx, mfaRequired.Token)
+	assert.ErrorIs(t, err, models.ErrInvalidToken, "a pending token must not work as an access token")
+	mockRepo.AssertNotCalled(t, "GetUserById", mock.Anything, mock.Anything)
+	mockBackoff.AssertExpectations(t)
+}
+
+func TestUserService_VerifyMFA(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	sealed, err := testBox.Seal(secret, mfaSecretContext(1))
+	require.NoError(t, err)
+
+	step := totp.Step(time.Now())
+
+	tests := []struct {
+		name         string
+		tokenVersion int
+		code         string
+		totpFresh    bool
+		recoveryOk   bool
+		wantErr      error
+	}{
+		{name: "totp code", tokenVersion: 1, code: totp.Code(secret, step), totpFresh: true},
+		{name: "replayed totp code", tokenVersion: 1, code: totp.Code(secret, step), wantErr: models.ErrInvalidMFACode},
+		{name: "wrong totp code", tokenVersion: 1, code: totp.Code(secret, step+10), wantErr: models.ErrInvalidMFACode},
+		{name: "recovery code", tokenVersion: 1, code: "ABCDE-fghij", recoveryOk: true},
+		{name: "used recovery code", tokenVersion: 1, code: "abcde-fghij", wantErr: models.ErrInvalidMFACode},
+		{name: "sessions revoked since login", tokenVersion: 2, code: totp.Code(secret, step), wantErr: models.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			pending, err := service.pendingToken(&models.User{Id: 1, TokenVersion: 1})
+			require.NoError(t, err)
+
+			mockRepo.On("GetUserById", ctx, 1).Return(&models.User{Id: 1, TokenVersion: tt.tokenVersion, MFASecret: &sealed, MFAEnabled: true}, nil)
+			mockRepo.On("UseTOTPStep", ctx, 1, step).Return(tt.totpFresh, nil).Maybe()
+			mockRepo.On("UseRecoveryCode", ctx, 1, hashToken("abcdefghij"), mock.AnythingOfType("time.Time")).Return(tt.recoveryOk, nil).Maybe()
+			if tt.wantErr == models.ErrInvalidMFACode {
+				mockRepo.On("RecordLoginFailure", ctx, 1, testLockoutConfig.Threshold, testLockoutConfig.Duration).Return(false, nil)
+			}
+
+			user, token, err := service.VerifyMFA(ctx, pending, tt.code)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+				assert.Empty(t, token)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 1, user.Id)
+				assert.NotEmpty(t, token)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_AuthenticateUser_Suspended(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockBackoff := new(mocks.LoginBackoff)
+	ctx := context.Background()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("test password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	suspendedAt := time.Now()
+	testUser := &models.User{Id: 1, Email: "user@example.com", Password: string(hashedPassword), SuspendedAt: &suspendedAt}
+
+	mockBackoff.On("Check", ctx, "user@example.com").Return(time.Duration(0), nil)
+	mockRepo.On("GetUserByEmail", ctx, "user@example.com").Return(testUser, nil)
+	mockBackoff.On("Reset", ctx, "user@example.com").Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo, backoff: mockBackoff})
+
+	result, token, err := service.AuthenticateUser(ctx, "user@example.com", "test password")
+
+	assert.ErrorIs(t, err, models.ErrUserSuspended)
+	assert.Nil(t, result)
+	assert.Empty(t, token)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_SuspendUser(t *testing.T) {
+	admin := models.Principal{UserId: 1, Role: models.RoleAdmin}
+	moderator := models.Principal{UserId: 1, Role: models.RoleModerator}
+	suspendedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		actor     models.Principal
+		target    *models.User
+		wantWrite bool
+		wantErr   error
+	}{
+		{
+			name:      "admin suspends user",
+			actor:     admin,
+			target:    &models.User{Id: 2, Role: models.RoleUser},
+			wantWrite: true,
+		},
+		{
+			name:      "admin suspends moderator",
+			actor:     admin,
+			target:    &models.User{Id: 2, Role: models.RoleModerator},
+			wantWrite: true,
+		},
+		{
+			name:      "moderator suspends user",
+			actor:     moderator,
+			target:    &models.User{Id: 2, Role: models.RoleUser},
+			wantWrite: true,
+		},
+		{
+			name:    "moderator suspends admin",
+			actor:   moderator,
+			target:  &models.User{Id: 2, Role: models.RoleAdmin},
+			wantErr: models.ErrForbidden,
+		},
+		{
+			name:   "already suspended",
+			actor:  admin,
+			target: &models.User{Id: 2, Role: models.RoleUser, SuspendedAt: &suspendedAt},
+		},
+		{
+			name:    "self",
+			actor:   models.Principal{UserId: 2, Role: models.RoleAdmin},
+			wantErr: models.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			if tt.target != nil {
+				mockRepo.On("GetUserById", ctx, 2).Return(tt.target, nil)
+			}
+			if tt.wantWrite {
+				mockRepo.On("SetSuspended", ctx, 2, mock.AnythingOfType("*time.Time")).Return(nil)
+			}
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			err := service.SuspendUser(ctx, tt.actor, 2)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			if !tt.wantWrite {
+				mockRepo.AssertNotCalled(t, "SetSuspended", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestUserService_UnsuspendUser(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	suspendedAt := time.Now()
+	mockRepo.On("GetUserById", ctx, 2).Return(&models.User{Id: 2, Role: models.RoleUser, SuspendedAt: &suspendedAt}, nil)
+	mockRepo.On("SetSuspended", ctx, 2, (*time.Time)(nil)).Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+	err := service.UnsuspendUser(ctx, models.Principal{UserId: 1, Role: models.RoleModerator}, 2)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_SetUserRole(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	ctx := context.Background()
+
+	mockRepo.On("GetUserById", ctx, 2).Return(&models.User{Id: 2, Role: models.RoleUser}, nil)
+	mockRepo.On("SetRole", ctx, 2, models.RoleModerator).Return(nil)
+
+	service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+	err := service.SetUserRole(ctx, models.Principal{UserId: 1, Role: models.RoleAdmin}, 2, models.RoleModerator)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_SetUserRole_Forbidden(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor models.Principal
+	}{
+		{name: "moderator", actor: models.Principal{UserId: 1, Role: models.RoleModerator}},
+		{name: "own role", actor: models.Principal{UserId: 2, Role: models.RoleAdmin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			ctx := context.Background()
+
+			service := newTestUserService(userServiceDeps{repo: mockRepo})
+
+			err := service.SetUserRole(ctx, tt.actor, 2, models.RoleUser)
+
+			assert.ErrorIs(t, err, models.ErrForbidden)
+			mockRepo.AssertNotCalled(t, "SetRole", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
 }
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dmitriysta/messenger/user/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const verifyEmailPurpose = "verify_email"
+
+// VerifyEmail marks the email a verification token was issued for as
+// verified. Tokens are JWTs signed with their own secret, so one can't stand
+// in for an access token, and carry the email they were sent to, so a
+// token sent before an email change no longer verifies anything.
+// Verifying twice is not an error.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	userId, email, err := s.parseVerificationToken(token)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "VerifyEmail",
+			"error":  err.Error(),
+		}).Warnf("invalid verification token: %v", err)
+
+		return models.ErrInvalidToken
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return models.ErrInvalidToken
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "VerifyEmail",
+			"userId": userId,
+			"error":  err.Error(),
+		}).Errorf("failed to get user by id: %v", err)
+
+		return err
+	}
+
+	if !strings.EqualFold(user.Email, email) {
+		return models.ErrInvalidToken
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	if err := s.repo.MarkEmailVerified(ctx, user.Id, user.Email, s.now()); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "VerifyEmail",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to verify email: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ResendVerification sends a new verification link to email. Unknown and
+// already verified addresses are silently ignored, so the result doesn't
+// reveal whether an account exists. Neither is an address that was sent a
+// link within the resend cooldown, which keeps the endpoint from being used
+// to flood an inbox from many IPs.
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	allowed, err := s.resend.Start(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ResendVerification",
+			"error":  err.Error(),
+		}).Warnf("failed to check resend cooldown: %v", err)
+	}
+	if err == nil && !allowed {
+		return nil
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "ResendVerification",
+			"error":  err.Error(),
+		}).Errorf("failed to get user by email: %v", err)
+
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+func (s *UserService) sendVerification(ctx context.Context, user *models.User) error {
+	token, err := s.verificationToken(user)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "sendVerification",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to sign verification token: %v", err)
+
+		return err
+	}
+
+	link := s.verify.URL + "?token=" + url.QueryEscape(token)
+
+	err = s.mail.Send(ctx, models.Email{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nconfirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you didn't sign up, ignore this email.\n",
+			user.Name, s.verify.TTL, link),
+	})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"module": "user",
+			"func":   "sendVerification",
+			"userId": user.Id,
+			"error":  err.Error(),
+		}).Errorf("failed to send verification email: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserService) verificationToken(user *models.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     user.Id,
+		"email":   user.Email,
+		"purpose": verifyEmailPurpose,
+		"exp":     s.now().Add(s.verify.TTL).Unix(),
+	})
+
+	return token.SignedString([]byte(s.verify.Secret))
+}
+
+func (s *UserService) parseVerificationToken(tokenString string) (int, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.verify.Secret), nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != verifyEmailPurpose {
+		return 0, "", errors.New("not a verification token")
+	}
+
+	userId, ok := claims["sub"].(float64)
+	email, emailOk := claims["email"].(string)
+	if !ok || !emailOk {
+		return 0, "", errors.New("malformed verification token")
+	}
+
+	return int(userId), email, nil
+}